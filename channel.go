@@ -3,14 +3,19 @@ package disgord
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/andersfylling/disgord/internal/endpoint"
 	"github.com/andersfylling/disgord/internal/httd"
@@ -31,13 +36,14 @@ const (
 
 // Attachment https://discord.com/developers/docs/resources/channel#attachment-object
 type Attachment struct {
-	ID       Snowflake `json:"id"`
-	Filename string    `json:"filename"`
-	Size     uint      `json:"size"`
-	URL      string    `json:"url"`
-	ProxyURL string    `json:"proxy_url"`
-	Height   uint      `json:"height"`
-	Width    uint      `json:"width"`
+	ID          Snowflake `json:"id"`
+	Filename    string    `json:"filename"`
+	Description string    `json:"description"` // alt text
+	Size        uint      `json:"size"`
+	URL         string    `json:"url"`
+	ProxyURL    string    `json:"proxy_url"`
+	Height      uint      `json:"height"`
+	Width       uint      `json:"width"`
 
 	SpoilerTag bool `json:"-"`
 }
@@ -51,13 +57,14 @@ func (a *Attachment) updateInternals() {
 // DeepCopy see interface at struct.go#DeepCopier
 func (a *Attachment) DeepCopy() (copy interface{}) {
 	copy = &Attachment{
-		ID:       a.ID,
-		Filename: a.Filename,
-		Size:     a.Size,
-		URL:      a.URL,
-		ProxyURL: a.ProxyURL,
-		Height:   a.Height,
-		Width:    a.Width,
+		ID:          a.ID,
+		Filename:    a.Filename,
+		Description: a.Description,
+		Size:        a.Size,
+		URL:         a.URL,
+		ProxyURL:    a.ProxyURL,
+		Height:      a.Height,
+		Width:       a.Width,
 	}
 
 	return
@@ -287,7 +294,7 @@ func (c *Channel) SendMsg(ctx context.Context, client MessageSender, message *Me
 		err = newErrorMissingSnowflake("snowflake ID not set for channel")
 		return
 	}
-	nonce := fmt.Sprint(message.Nonce)
+	nonce := message.NonceString()
 	if len(nonce) > 25 {
 		return nil, errors.New("nonce can not be longer than 25 characters")
 	}
@@ -321,6 +328,10 @@ func (c clientQueryBuilder) Channel(id Snowflake) ChannelQueryBuilder {
 type ChannelQueryBuilder interface {
 	WithContext(ctx context.Context) ChannelQueryBuilder
 
+	// FollowNewsChannel Follow a News Channel to send messages to a target channel. Requires the 'MANAGE_WEBHOOKS'
+	// permission in the target channel. Returns a followed channel object.
+	FollowNewsChannel(targetChannelID Snowflake, flags ...Flag) (*FollowedChannel, error)
+
 	// TriggerTypingIndicator Post a typing indicator for the specified channel. Generally bots should not implement
 	// this route. However, if a bot is responding to a command and expects the computation to take a few seconds, this
 	// endpoint may be called to let the user know that the bot is processing their message. Returns a 204 empty response
@@ -373,6 +384,10 @@ type ChannelQueryBuilder interface {
 	// GetPinnedMessages Returns all pinned messages in the channel as an array of message objects.
 	GetPinnedMessages(flags ...Flag) ([]*Message, error)
 
+	// PruneMessages Deletes the messages in the channel that matches the given options, most recent first. Returns
+	// the number of deleted messages.
+	PruneMessages(opts PruneOptions, flags ...Flag) (deleted int, err error)
+
 	// DeleteMessages Delete multiple messages in a single request. This endpoint can only be used on guild
 	// Channels and requires the 'MANAGE_MESSAGES' permission. Returns a 204 empty response on success. Fires multiple
 	// Message Delete Gateway events.Any message IDs given that do not exist or are invalid will count towards
@@ -384,6 +399,8 @@ type ChannelQueryBuilder interface {
 	// the 'VIEW_CHANNEL' permission to be present on the current user. If the current user is missing
 	// the 'READ_MESSAGE_HISTORY' permission in the channel then this will return no messages
 	// (since they cannot read the message history). Returns an array of message objects on success.
+	//
+	// On failure, the messages fetched so far are returned with a *ErrPartialResult.
 	GetMessages(params *GetMessagesParams, flags ...Flag) ([]*Message, error)
 
 	// CreateMessage Post a message to a guild text or DM channel. If operating on a guild channel, this
@@ -521,6 +538,56 @@ func (c channelQueryBuilder) TriggerTypingIndicator(flags ...Flag) (err error) {
 	return err
 }
 
+// FollowedChannel https://discord.com/developers/docs/resources/channel#followed-channel-object
+type FollowedChannel struct {
+	ChannelID Snowflake `json:"channel_id"` // source channel id
+	WebhookID Snowflake `json:"webhook_id"` // created target webhook id
+}
+
+type followNewsChannelParams struct {
+	WebhookChannelID Snowflake `json:"webhook_channel_id"`
+}
+
+// FollowNewsChannel [REST] Follow a News Channel to send messages to a target channel. Requires the 'MANAGE_WEBHOOKS'
+// permission in the target channel. Returns a followed channel object.
+//  Method                  POST
+//  Endpoint                /channels/{channel.id}/followers
+//  Discord documentation   https://discord.com/developers/docs/resources/channel#follow-news-channel
+//  Reviewed                2020-11-28
+//  Comment                 The channel.id must be a news channel, while the target channel is where
+//                          the messages are posted using a newly created webhook.
+func (c channelQueryBuilder) FollowNewsChannel(targetChannelID Snowflake, flags ...Flag) (*FollowedChannel, error) {
+	if c.cid.IsZero() {
+		return nil, errors.New("channelID must be set to follow a news channel")
+	}
+	if targetChannelID.IsZero() {
+		return nil, errors.New("a target channel must be specified")
+	}
+
+	r := c.client.newRESTRequest(&httd.Request{
+		Method:      httd.MethodPost,
+		Ctx:         c.ctx,
+		Endpoint:    endpoint.ChannelFollowers(c.cid),
+		Body:        &followNewsChannelParams{WebhookChannelID: targetChannelID},
+		ContentType: httd.ContentTypeJSON,
+	}, flags)
+	r.factory = func() interface{} {
+		return &FollowedChannel{}
+	}
+
+	v, err := exec(r.Execute, flags...)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*FollowedChannel), nil
+}
+
+// FollowNewsChannel Follow a News Channel to send messages to a target channel. Requires the 'MANAGE_WEBHOOKS'
+// permission in the target channel, as a webhook is created there.
+func (c *Client) FollowNewsChannel(channelID, targetChannelID Snowflake, flags ...Flag) (*FollowedChannel, error) {
+	return c.Channel(channelID).FollowNewsChannel(targetChannelID, flags...)
+}
+
 // UpdateChannelPermissionsParams https://discord.com/developers/docs/resources/channel#edit-channel-permissions-json-params
 type UpdateChannelPermissionsParams struct {
 	Allow PermissionBit `json:"allow"` // the bitwise value of all allowed permissions
@@ -749,7 +816,7 @@ var _ URLQueryStringer = (*GetMessagesParams)(nil)
 //  Reviewed                2018-06-10
 //  Comment                 The before, after, and around keys are mutually exclusive, only one may
 //                          be passed at a time. see ReqGetChannelMessagesParams.
-func (c channelQueryBuilder) getMessages(params URLQueryStringer, flags ...Flag) (ret []*Message, err error) {
+func (c channelQueryBuilder) getMessages(params URLQueryStringer, priority httd.Priority, flags ...Flag) (ret []*Message, err error) {
 	if c.cid.IsZero() {
 		err = errors.New("channelID must be set to get channel messages")
 		return
@@ -763,6 +830,7 @@ func (c channelQueryBuilder) getMessages(params URLQueryStringer, flags ...Flag)
 	r := c.client.newRESTRequest(&httd.Request{
 		Endpoint: endpoint.ChannelMessages(c.cid) + query,
 		Ctx:      c.ctx,
+		Priority: priority,
 	}, flags)
 	r.factory = func() interface{} {
 		tmp := make([]*Message, 0)
@@ -773,7 +841,36 @@ func (c channelQueryBuilder) getMessages(params URLQueryStringer, flags ...Flag)
 }
 
 // GetMessages bypasses discord limitations and iteratively fetches messages until the set filters are met.
+//
+// When filter.Around is set and the limit is above 100, the around message counts as one of the messages and the
+// rest of the limit is split between the messages before and after it. Any remainder goes to the messages after.
+//
+// If a request fails after some messages were fetched, the fetched messages are returned together with a
+// *ErrPartialResult that wraps the error. This means messages can be non-nil even when err is not nil.
 func (c channelQueryBuilder) GetMessages(filter *GetMessagesParams, flags ...Flag) (messages []*Message, err error) {
+	// fetching more than a page is background work that should not hold up other requests
+	priority := httd.PriorityNormal
+	if filter != nil && filter.Limit > 100 {
+		priority = httd.PriorityLow
+	}
+	page := func(params *GetMessagesParams) ([]*Message, error) {
+		return c.getMessages(params, priority, flags...)
+	}
+	single := func(messageID Snowflake) (*Message, error) {
+		return c.Message(messageID).Get(c.ctx, flags...)
+	}
+	messages, err = getMessagesIteratively(filter, page, single)
+	c.client.backfillGuildID(c.cid, messages, mergeFlags(flags))
+	return messages, err
+}
+
+// getMessagesIteratively see channelQueryBuilder.GetMessages. page fetches at most 100 messages, while single
+// fetches one specific message.
+func getMessagesIteratively(
+	filter *GetMessagesParams,
+	page func(*GetMessagesParams) ([]*Message, error),
+	single func(Snowflake) (*Message, error),
+) (messages []*Message, err error) {
 	// discord values
 	const filterLimit = 100
 	const filterDefault = 50
@@ -782,14 +879,26 @@ func (c channelQueryBuilder) GetMessages(filter *GetMessagesParams, flags ...Fla
 		return nil, err
 	}
 
-	if filter.Limit == 0 {
-		filter.Limit = filterDefault
+	params := *filter // don't modify the caller's filter
+	if params.Limit == 0 {
+		params.Limit = filterDefault
 		// we hardcode it here in case discord goes dumb and decided to randomly change it.
 		// This avoids that the bot do not experience a new, random, behaviour on API changes
 	}
 
-	if filter.Limit <= filterLimit {
-		return c.getMessages(filter, flags...)
+	if params.Limit <= filterLimit {
+		return page(&params)
+	}
+
+	fail := func(err error) ([]*Message, error) {
+		var partial *ErrPartialResult
+		if errors.As(err, &partial) {
+			err = partial.Err
+		}
+		if len(messages) == 0 {
+			return nil, err
+		}
+		return messages, &ErrPartialResult{Err: err}
 	}
 
 	latestSnowflake := func(msgs []*Message) (latest Snowflake) {
@@ -804,7 +913,7 @@ func (c channelQueryBuilder) GetMessages(filter *GetMessagesParams, flags ...Fla
 	earliestSnowflake := func(msgs []*Message) (earliest Snowflake) {
 		for i := range msgs {
 			// if msgs[i].ID.Date().Before(earliest.Date()) {
-			if msgs[i].ID < earliest {
+			if earliest.IsZero() || msgs[i].ID < earliest {
 				earliest = msgs[i].ID
 			}
 		}
@@ -812,32 +921,39 @@ func (c channelQueryBuilder) GetMessages(filter *GetMessagesParams, flags ...Fla
 	}
 
 	// scenario#1: filter.Around is not 0 AND filter.Limit is above 100
-	//  divide the limit by half and use .Before and .After tags on each quotient limit.
+	//  the around message takes up one spot, and the rest is divided in half using .Before and .After.
 	//  Use the .After on potential remainder.
 	//  Note! This method can be used recursively
-	if !filter.Around.IsZero() {
-		beforeParams := *filter
+	if !params.Around.IsZero() {
+		remaining := params.Limit - 1
+
+		beforeParams := params
 		beforeParams.Before = beforeParams.Around
 		beforeParams.Around = 0
-		beforeParams.Limit = filter.Limit / 2
-		befores, err := c.GetMessages(&beforeParams, flags...)
+		beforeParams.Limit = remaining / 2
+		befores, err := getMessagesIteratively(&beforeParams, page, single)
+		messages = append(messages, befores...)
 		if err != nil {
-			return nil, err
+			return fail(err)
 		}
-		messages = append(messages, befores...)
 
-		afterParams := *filter
+		afterParams := params
 		afterParams.After = afterParams.Around
 		afterParams.Around = 0
-		afterParams.Limit = filter.Limit / 2
-		afters, err := c.GetMessages(&afterParams, flags...)
+		afterParams.Limit = remaining - beforeParams.Limit
+		afters, err := getMessagesIteratively(&afterParams, page, single)
+		messages = append(messages, afters...)
 		if err != nil {
-			return nil, err
+			return fail(err)
 		}
-		messages = append(messages, afters...)
 
 		// filter.Around includes the given ID, so should .Before and .After iterations do as well
-		if msg, _ := c.Message(filter.Around).Get(c.ctx, flags...); msg != nil {
+		for i := range messages {
+			if messages[i].ID == params.Around {
+				return messages, nil
+			}
+		}
+		if msg, _ := single(params.Around); msg != nil {
 			// assumption: error here can be caused by the message ID not actually being a real message
 			//             and that it was used to get messages in the vicinity. Therefore the err is ignored.
 			// TODO: const discord errors.
@@ -847,26 +963,26 @@ func (c channelQueryBuilder) GetMessages(filter *GetMessagesParams, flags ...Fla
 		// scenario#3: filter.After or filter.Before is set.
 		// note that none might be set, which will cause filter.Before to be set after the first 100 messages.
 		//
-		for {
-			if filter.Limit <= 0 {
-				break
-			}
-
-			f := *filter
-			if f.Limit > 100 {
-				f.Limit = 100
+		for params.Limit > 0 {
+			f := params
+			if f.Limit > filterLimit {
+				f.Limit = filterLimit
 			}
-			filter.Limit -= f.Limit
-			msgs, err := c.getMessages(&f, flags...)
+			params.Limit -= f.Limit
+			msgs, err := page(&f)
 			if err != nil {
-				return nil, err
+				return fail(err)
 			}
 			messages = append(messages, msgs...)
-			if !filter.After.IsZero() {
-				filter.After = latestSnowflake(msgs)
+			if uint(len(msgs)) < f.Limit {
+				break // no more messages
+			}
+
+			if !params.After.IsZero() {
+				params.After = latestSnowflake(msgs)
 			} else {
 				// no snowflake or filter.Before
-				filter.Before = earliestSnowflake(msgs)
+				params.Before = earliestSnowflake(msgs)
 			}
 		}
 	}
@@ -897,12 +1013,30 @@ func (p *DeleteMessagesParams) tooFew(messages int) (err error) {
 	return
 }
 
+// uniqueCount returns the number of unique message IDs, as duplicates are only counted once by Discord
+func (p *DeleteMessagesParams) uniqueCount() int {
+	unique := make(map[Snowflake]struct{}, len(p.Messages))
+	for _, id := range p.Messages {
+		unique[id] = struct{}{}
+	}
+	return len(unique)
+}
+
+func (p *DeleteMessagesParams) contains(id Snowflake) bool {
+	for i := range p.Messages {
+		if p.Messages[i] == id {
+			return true
+		}
+	}
+	return false
+}
+
 // Valid validates the DeleteMessagesParams data
 func (p *DeleteMessagesParams) Valid() (err error) {
 	p.m.RLock()
 	defer p.m.RUnlock()
 
-	messages := len(p.Messages)
+	messages := p.uniqueCount()
 	if err = p.tooMany(messages); err != nil {
 		return
 	}
@@ -910,21 +1044,47 @@ func (p *DeleteMessagesParams) Valid() (err error) {
 	return
 }
 
-// AddMessage Adds a message to be deleted
+// AddMessage Adds a message to be deleted. Messages that are already added are skipped.
 func (p *DeleteMessagesParams) AddMessage(msg *Message) (err error) {
+	return p.AddMessageIDs(msg.ID)
+}
+
+// AddMessageIDs Adds messages to be deleted. IDs that are already added are skipped. If the messages
+// would exceed the limit of 100 messages, none are added.
+func (p *DeleteMessagesParams) AddMessageIDs(ids ...Snowflake) (err error) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	if err = p.tooMany(len(p.Messages) + 1); err != nil {
-		return
+	messages := p.Messages
+	for _, id := range ids {
+		if p.contains(id) {
+			continue
+		}
+		p.Messages = append(p.Messages, id)
 	}
 
-	// TODO: check for duplicates as those are counted only once
-
-	p.Messages = append(p.Messages, msg.ID)
+	if err = p.tooMany(p.uniqueCount()); err != nil {
+		p.Messages = messages
+	}
 	return
 }
 
+// Count returns the number of unique messages to be deleted
+func (p *DeleteMessagesParams) Count() int {
+	p.m.RLock()
+	defer p.m.RUnlock()
+
+	return p.uniqueCount()
+}
+
+// Reset removes all the messages, such that the params can be reused for another batch
+func (p *DeleteMessagesParams) Reset() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.Messages = nil
+}
+
 // DeleteMessages [REST] Delete multiple messages in a single request. This endpoint can only be used on guild
 // Channels and requires the 'MANAGE_MESSAGES' permission. Returns a 204 empty response on success. Fires multiple
 // Message Delete Gateway events.Any message IDs given that do not exist or are invalid will count towards
@@ -958,6 +1118,139 @@ func (c channelQueryBuilder) DeleteMessages(params *DeleteMessagesParams, flags
 	return err
 }
 
+// bulkDeleteMaxAge is the maximum age of a message for it to be deleted using DeleteMessages.
+// A small margin is subtracted to avoid races with the Discord clock.
+const bulkDeleteMaxAge = 14*24*time.Hour - time.Minute
+
+// PruneOptions decides which messages are deleted by PruneMessages. Every option that is set
+// must be met for a message to be deleted.
+type PruneOptions struct {
+	// Limit is the maximum number of messages to delete. Defaults to 100.
+	Limit int
+
+	// AuthorID only deletes messages created by the given user
+	AuthorID Snowflake
+
+	// Before only deletes messages created before the given message ID
+	Before Snowflake
+
+	// OlderThan only deletes messages older than the given duration
+	OlderThan time.Duration
+
+	// ContentContains only deletes messages that holds the given text
+	ContentContains string
+
+	// Filter is a custom check, return true if the message should be deleted
+	Filter func(*Message) bool
+
+	// SkipOldMessages skips messages that are too old to be bulk deleted (14 days), instead
+	// of deleting them one by one.
+	SkipOldMessages bool
+}
+
+func (p *PruneOptions) eligible(msg *Message, now time.Time) bool {
+	if !p.AuthorID.IsZero() && (msg.Author == nil || msg.Author.ID != p.AuthorID) {
+		return false
+	}
+	if p.OlderThan > 0 && now.Sub(msg.ID.Date()) < p.OlderThan {
+		return false
+	}
+	if p.ContentContains != "" && !strings.Contains(msg.Content, p.ContentContains) {
+		return false
+	}
+	if p.Filter != nil && !p.Filter(msg) {
+		return false
+	}
+	return true
+}
+
+// PruneMessages deletes messages in the channel that matches the given options, starting with the most recent
+// ones. Messages are deleted in bulk when possible, which requires the 'MANAGE_MESSAGES' permission. The number
+// of deleted messages is returned, also when an error occurs. Use WithContext to cancel long running prunes.
+func (c channelQueryBuilder) PruneMessages(opts PruneOptions, flags ...Flag) (deleted int, err error) {
+	if c.cid.IsZero() {
+		return 0, errors.New("channelID must be set to prune messages")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	before := opts.Before
+	for deleted < opts.Limit {
+		if err = ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		var msgs []*Message
+		if msgs, err = c.getMessages(&GetMessagesParams{Before: before, Limit: 100}, httd.PriorityLow, flags...); err != nil {
+			return deleted, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		now := time.Now()
+		var bulk, single []Snowflake
+		for _, msg := range msgs {
+			if before.IsZero() || msg.ID < before {
+				before = msg.ID
+			}
+			if len(bulk)+len(single)+deleted >= opts.Limit || !opts.eligible(msg, now) {
+				continue
+			}
+
+			if now.Sub(msg.ID.Date()) < bulkDeleteMaxAge {
+				bulk = append(bulk, msg.ID)
+			} else if !opts.SkipOldMessages {
+				single = append(single, msg.ID)
+			}
+		}
+
+		// bulk delete requires at least two messages
+		if len(bulk) == 1 {
+			single = append(single, bulk[0])
+			bulk = nil
+		}
+		if len(bulk) > 0 {
+			if err = c.DeleteMessages(&DeleteMessagesParams{Messages: bulk}, flags...); err != nil {
+				return deleted, err
+			}
+			deleted += len(bulk)
+		}
+		for _, id := range single {
+			if err = ctx.Err(); err != nil {
+				return deleted, err
+			}
+			if err = c.Message(id).Delete(ctx, flags...); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		if len(msgs) < 100 {
+			break // no more history
+		}
+	}
+
+	return deleted, nil
+}
+
+// PruneMessages deletes messages in a channel that matches the given options. See ChannelQueryBuilder.PruneMessages.
+func (c *Client) PruneMessages(ctx context.Context, channelID Snowflake, opts PruneOptions, flags ...Flag) (deleted int, err error) {
+	return c.Channel(channelID).WithContext(ctx).PruneMessages(opts, flags...)
+}
+
+// The different mention types that can be used in AllowedMentions.Parse
+const (
+	AllowedMentionsEveryone = "everyone"
+	AllowedMentionsUsers    = "users"
+	AllowedMentionsRoles    = "roles"
+)
+
 // AllowedMentions allows finer control over mentions in a message, see
 // https://discord.com/developers/docs/resources/channel#allowed-mentions-object for more info.
 // Any strings in the Parse value must be any from ["everyone", "users", "roles"].
@@ -966,6 +1259,9 @@ type AllowedMentions struct {
 
 	Roles []Snowflake `json:"roles,omitempty"`
 	Users []Snowflake `json:"users,omitempty"`
+
+	// RepliedUser decides if the author of the message being replied to is mentioned
+	RepliedUser bool `json:"replied_user,omitempty"`
 }
 
 // CreateMessageFileParams contains the information needed to upload a file to Discord, it is part of the
@@ -978,21 +1274,56 @@ type CreateMessageFileParams struct {
 	// Current Discord behaviour is that whenever a message with one or more images is marked as
 	// spoiler tag, all the images in that message are blurred out. (independent of msg.Content)
 	SpoilerTag bool `json:"-"`
+
+	// Description is the alt text of the file, used by screen readers.
+	Description string `json:"-"`
+
+	// Progress is called as the file is written to the request body, at most every 100ms and once when done.
+	// total is -1 when the size of the Reader is unknown. The size is known for readers with a Len() int method,
+	// such as *bytes.Reader, and for readers with a Stat method, such as *os.File.
+	Progress func(written, total int64) `json:"-"`
+}
+
+// fileProgressInterval is the minimum time between calls to CreateMessageFileParams.Progress
+const fileProgressInterval = 100 * time.Millisecond
+
+// size returns the number of bytes that can be read from the file, or -1 if unknown
+func (f *CreateMessageFileParams) size() int64 {
+	switch r := f.Reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
 }
 
 // write helper for file uploading in messages
 func (f *CreateMessageFileParams) write(i int, mp *multipart.Writer) error {
-	var filename string
+	return f.writeField("file"+strconv.Itoa(i), mp)
+}
+
+func (f *CreateMessageFileParams) filename() string {
 	if f.SpoilerTag {
-		filename = AttachmentSpoilerPrefix + f.FileName
-	} else {
-		filename = f.FileName
+		return AttachmentSpoilerPrefix + f.FileName
 	}
-	w, err := mp.CreateFormFile("file"+strconv.FormatInt(int64(i), 10), filename)
+	return f.FileName
+}
+
+func (f *CreateMessageFileParams) writeField(field string, mp *multipart.Writer) error {
+	w, err := mp.CreateFormFile(field, f.filename())
 	if err != nil {
 		return err
 	}
 
+	if f.Progress != nil {
+		pw := &progressWriter{w: w, total: f.size(), progress: f.Progress}
+		defer pw.done()
+		w = pw
+	}
+
 	if _, err = io.Copy(w, f.Reader); err != nil {
 		return err
 	}
@@ -1000,9 +1331,32 @@ func (f *CreateMessageFileParams) write(i int, mp *multipart.Writer) error {
 	return nil
 }
 
+// progressWriter counts the written bytes and reports the progress at a bounded frequency
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	last     time.Time
+	progress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (n int, err error) {
+	n, err = p.w.Write(b)
+	p.written += int64(n)
+	if now := time.Now(); now.Sub(p.last) >= fileProgressInterval {
+		p.last = now
+		p.progress(p.written, p.total)
+	}
+	return n, err
+}
+
+func (p *progressWriter) done() {
+	p.progress(p.written, p.total)
+}
+
 // CreateMessageParams JSON params for CreateChannelMessage
 type CreateMessageParams struct {
-	Content string `json:"content"`
+	Content string `json:"content,omitempty"`
 	Nonce   string `json:"nonce,omitempty"` // THIS IS A STRING. NOT A SNOWFLAKE! DONT TOUCH!
 	Tts     bool   `json:"tts,omitempty"`
 	Embed   *Embed `json:"embed,omitempty"` // embedded rich content
@@ -1013,6 +1367,102 @@ type CreateMessageParams struct {
 	SpoilerTagAllAttachments bool `json:"-"`
 
 	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"` // The allowed mentions object for the message.
+
+	// MessageReference makes the message a reply to the referenced message
+	MessageReference *MessageReference `json:"message_reference,omitempty"`
+
+	Flags MessageFlag `json:"flags,omitempty"`
+
+	// Silent sends the message without triggering push and desktop notifications,
+	// see MessageFlagSuppressNotifications.
+	Silent bool `json:"-"`
+
+	// validate is set by NewCreateMessageParams, such that only params from the constructor are validated
+	// when the message is sent
+	validate bool
+}
+
+// MaxMessageContentLength is the maximum number of characters in the content of a message
+const MaxMessageContentLength = 2000
+
+// CreateMessageOption configures the CreateMessageParams created by NewCreateMessageParams
+type CreateMessageOption func(params *CreateMessageParams)
+
+// NewCreateMessageParams creates the params for a new message using the given options, such that common messages
+// can be created in a single expression:
+//
+//	params := disgord.NewCreateMessageParams(disgord.WithContent("hello"), disgord.WithFile("a.txt", r))
+//
+// The params are validated when the message is sent, see CreateMessageParams.Validate. Params that are not
+// created by NewCreateMessageParams are sent as is.
+func NewCreateMessageParams(opts ...CreateMessageOption) *CreateMessageParams {
+	params := &CreateMessageParams{validate: true}
+	for _, opt := range opts {
+		opt(params)
+	}
+	return params
+}
+
+// WithContent sets the message content
+func WithContent(content string) CreateMessageOption {
+	return func(params *CreateMessageParams) {
+		params.Content = content
+	}
+}
+
+// WithEmbed sets the message embed. A message can only hold one embed, so the last one is used.
+func WithEmbed(embed *Embed) CreateMessageOption {
+	return func(params *CreateMessageParams) {
+		params.Embed = embed
+	}
+}
+
+// WithTTS makes the message a text to speech message
+func WithTTS() CreateMessageOption {
+	return func(params *CreateMessageParams) {
+		params.Tts = true
+	}
+}
+
+// WithFile adds a file to the message. Use it several times to upload multiple files.
+func WithFile(name string, r io.Reader) CreateMessageOption {
+	return func(params *CreateMessageParams) {
+		params.Files = append(params.Files, CreateMessageFileParams{Reader: r, FileName: name})
+	}
+}
+
+// WithSpoilerContent marks the message content as a spoiler
+func WithSpoilerContent() CreateMessageOption {
+	return func(params *CreateMessageParams) {
+		params.SpoilerTagContent = true
+	}
+}
+
+// WithAllowedMentions decides who can be mentioned by the message
+func WithAllowedMentions(mentions *AllowedMentions) CreateMessageOption {
+	return func(params *CreateMessageParams) {
+		params.AllowedMentions = mentions
+	}
+}
+
+// Validate checks that the message has content, an embed or files, that the content is not too long
+// and that every file has a name and a reader.
+func (p *CreateMessageParams) Validate() error {
+	if p.Content == "" && p.Embed == nil && len(p.Files) == 0 {
+		return errors.New("message must have content, an embed or files")
+	}
+	if utf8.RuneCountInString(p.Content) > MaxMessageContentLength {
+		return fmt.Errorf("message content can not exceed %d characters", MaxMessageContentLength)
+	}
+	for i := range p.Files {
+		if p.Files[i].Reader == nil {
+			return fmt.Errorf("file %d is missing a reader", i)
+		}
+		if p.Files[i].FileName == "" {
+			return fmt.Errorf("file %d is missing a name", i)
+		}
+	}
+	return nil
 }
 
 func (p *CreateMessageParams) prepare() (postBody interface{}, contentType string, err error) {
@@ -1021,6 +1471,13 @@ func (p *CreateMessageParams) prepare() (postBody interface{}, contentType strin
 		p.Content = "|| " + p.Content + " ||"
 	}
 
+	if p.Silent {
+		// the flag is added to a copy, such that the caller can reuse the params without the flag
+		silent := *p
+		silent.Flags |= MessageFlagSuppressNotifications
+		p = &silent
+	}
+
 	if len(p.Files) == 0 {
 		postBody = p
 		contentType = httd.ContentTypeJSON
@@ -1046,23 +1503,46 @@ func (p *CreateMessageParams) prepare() (postBody interface{}, contentType strin
 		}
 	}
 
+	return multipartBody(p, p.Files)
+}
+
+// multipartBody writes the JSON payload as the payload_json field, followed by every file, into
+// a multipart/form-data body. Used whenever a request must upload one or more files.
+func multipartBody(payload interface{}, files []CreateMessageFileParams) (postBody interface{}, contentType string, err error) {
 	// Set up a new multipart writer, as we'll be using this for the POST body instead
 	buf := new(bytes.Buffer)
 	mp := multipart.NewWriter(buf)
 
 	// Write the existing JSON payload
-	var payload []byte
-	payload, err = json.Marshal(p)
+	var data []byte
+	data, err = json.Marshal(payload)
 	if err != nil {
 		return
 	}
-	if err = mp.WriteField("payload_json", string(payload)); err != nil {
+
+	// attachment metadata, such as descriptions, refer to the files by their index in files[n]
+	withMetadata := false
+	for i := range files {
+		withMetadata = withMetadata || files[i].Description != ""
+	}
+	if withMetadata {
+		if data, err = withAttachmentMetadata(data, files); err != nil {
+			return
+		}
+	}
+
+	if err = mp.WriteField("payload_json", string(data)); err != nil {
 		return
 	}
 
 	// Iterate through all the files and write them to the multipart blob
-	for i, file := range p.Files {
-		if err = file.write(i, mp); err != nil {
+	for i, file := range files {
+		if withMetadata {
+			err = file.writeField("files["+strconv.Itoa(i)+"]", mp)
+		} else {
+			err = file.write(i, mp)
+		}
+		if err != nil {
 			return
 		}
 	}
@@ -1075,6 +1555,37 @@ func (p *CreateMessageParams) prepare() (postBody interface{}, contentType strin
 	return
 }
 
+// attachmentMetadata describes an uploaded file, where ID is the index of the file
+type attachmentMetadata struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	Description string `json:"description,omitempty"`
+}
+
+// withAttachmentMetadata adds the attachments array to a JSON payload
+func withAttachmentMetadata(payload []byte, files []CreateMessageFileParams) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+
+	attachments := make([]attachmentMetadata, 0, len(files))
+	for i := range files {
+		attachments = append(attachments, attachmentMetadata{
+			ID:          i,
+			Filename:    files[i].filename(),
+			Description: files[i].Description,
+		})
+	}
+
+	data, err := json.Marshal(attachments)
+	if err != nil {
+		return nil, err
+	}
+	fields["attachments"] = data
+	return json.Marshal(fields)
+}
+
 // CreateMessage [REST] Post a message to a guild text or DM channel. If operating on a guild channel, this
 // endpoint requires the 'SEND_MESSAGES' permission to be present on the current user. If the tts field is set to true,
 // the SEND_TTS_MESSAGES permission is required for the message to be spoken. Returns a message object. Fires a
@@ -1094,22 +1605,36 @@ func (c channelQueryBuilder) CreateMessage(params *CreateMessageParams, flags ..
 		err = errors.New("message must be set")
 		return nil, err
 	}
+	if params.validate {
+		if err = params.Validate(); err != nil {
+			return nil, err
+		}
+	}
 
 	var (
 		postBody    interface{}
 		contentType string
 	)
 
+	if mergeFlags(flags).VerifyNonce() {
+		return c.createMessageWithNonce(params, flags...)
+	}
+
 	if postBody, contentType, err = params.prepare(); err != nil {
 		return nil, err
 	}
 
+	return c.createMessage(postBody, contentType, flags...)
+}
+
+func (c channelQueryBuilder) createMessage(postBody interface{}, contentType string, flags ...Flag) (*Message, error) {
 	r := c.client.newRESTRequest(&httd.Request{
 		Method:      httd.MethodPost,
 		Ctx:         c.ctx,
 		Endpoint:    "/channels/" + c.cid.String() + "/messages",
 		Body:        postBody,
 		ContentType: contentType,
+		Priority:    httd.PriorityHigh,
 	}, flags)
 	r.pool = c.client.pool.message
 	r.factory = func() interface{} {
@@ -1119,7 +1644,94 @@ func (c channelQueryBuilder) CreateMessage(params *CreateMessageParams, flags ..
 	return getMessage(r.Execute)
 }
 
+const (
+	// nonceLookback is the number of recent channel messages searched for a nonce
+	nonceLookback = 10
+
+	// nonceAttempts is the maximum number of times a message is sent when using VerifyNonce
+	nonceAttempts = 3
+)
+
+// newNonce creates a random nonce that fits within the 25 character limit
+func newNonce() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// createMessageWithNonce avoids duplicate messages when a request fails after Discord might have created the
+// message. On failures that may be retried, the recent channel messages are searched for the nonce before the
+// message is sent again.
+func (c channelQueryBuilder) createMessageWithNonce(params *CreateMessageParams, flags ...Flag) (msg *Message, err error) {
+	p := *params
+	if p.Nonce == "" {
+		if p.Nonce, err = newNonce(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		postBody    interface{}
+		contentType string
+	)
+	if postBody, contentType, err = p.prepare(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		if msg, err = c.createMessage(postBody, contentType, flags...); err == nil || !c.retryable(err) {
+			return msg, err
+		}
+
+		if existing := c.findMessageByNonce(p.Nonce, flags...); existing != nil {
+			return existing, nil
+		}
+
+		// files are streamed and can not be sent again
+		if attempt >= nonceAttempts || len(p.Files) > 0 {
+			return nil, err
+		}
+	}
+}
+
+// retryable checks if a failed request might succeed when sent again
+func (c channelQueryBuilder) retryable(err error) bool {
+	if c.ctx != nil && c.ctx.Err() != nil {
+		return false
+	}
+
+	var restErr *httd.ErrREST
+	if errors.As(err, &restErr) {
+		return restErr.HTTPCode >= http.StatusInternalServerError
+	}
+	return true // transport errors
+}
+
+// findMessageByNonce looks for a recent message, created by the bot, with the given nonce
+func (c channelQueryBuilder) findMessageByNonce(nonce string, flags ...Flag) *Message {
+	msgs, err := c.getMessages(&GetMessagesParams{Limit: nonceLookback}, httd.PriorityHigh, flags...)
+	if err != nil {
+		return nil
+	}
+
+	botID := c.client.myID
+	for _, msg := range msgs {
+		if msg.NonceString() != nonce {
+			continue
+		}
+		if !botID.IsZero() && (msg.Author == nil || msg.Author.ID != botID) {
+			continue
+		}
+		return msg
+	}
+	return nil
+}
+
 // GetPinnedMessages [REST] Returns all pinned messages in the channel as an array of message objects.
+// The messages are sorted by their snowflake, newest message first. Supply OrderAscending for the oldest first.
+// The returned messages are not shared and can be modified.
 //  Method                  GET
 //  Endpoint                /channels/{channel.id}/pins
 //  Discord documentation   https://discord.com/developers/docs/resources/channel#get-pinned-messages
@@ -1135,7 +1747,20 @@ func (c channelQueryBuilder) GetPinnedMessages(flags ...Flag) (ret []*Message, e
 		return &tmp
 	}
 
-	return getMessages(r.Execute)
+	if ret, err = getMessages(r.Execute); err != nil {
+		return nil, err
+	}
+	sortPinnedMessages(ret, mergeFlags(flags))
+	c.client.backfillGuildID(c.cid, ret, mergeFlags(flags))
+	return ret, nil
+}
+
+func sortPinnedMessages(msgs []*Message, flags Flag) {
+	order := OrderDescending
+	if (flags & OrderAscending) > 0 {
+		order = OrderAscending
+	}
+	Sort(msgs, SortByID, order)
 }
 
 // CreateWebhookParams json params for the create webhook rest request avatar string
@@ -3,9 +3,20 @@
 package disgord
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/andersfylling/disgord/httdtesting"
+	"github.com/andersfylling/disgord/internal/httd"
 	"github.com/andersfylling/disgord/json"
 )
 
@@ -92,3 +103,552 @@ func TestChannel_JSONIconNull(t *testing.T) {
 		t.Error(c.Icon, "was not empty")
 	}
 }
+
+func TestFollowNewsChannelParams(t *testing.T) {
+	data, err := json.Marshal(&followNewsChannelParams{WebhookChannelID: 486833611564253186})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"webhook_channel_id":486833611564253186}`
+	if string(data) != expected {
+		t.Errorf("unexpected body. Got %s, wants %s", string(data), expected)
+	}
+}
+
+func TestPruneOptions_eligible(t *testing.T) {
+	now := time.Now()
+	msgAt := func(created time.Time, author Snowflake, content string) *Message {
+		id := Snowflake(uint64(created.UnixNano()/int64(time.Millisecond)-1420070400000) << 22)
+		return &Message{ID: id, Author: &User{ID: author}, Content: content}
+	}
+
+	recent := msgAt(now.Add(-time.Minute), 1, "hello world")
+	old := msgAt(now.Add(-time.Hour), 2, "goodbye")
+
+	table := []struct {
+		name string
+		opts PruneOptions
+		msg  *Message
+		want bool
+	}{
+		{"no options", PruneOptions{}, recent, true},
+		{"author match", PruneOptions{AuthorID: 1}, recent, true},
+		{"author mismatch", PruneOptions{AuthorID: 1}, old, false},
+		{"too recent", PruneOptions{OlderThan: 10 * time.Minute}, recent, false},
+		{"old enough", PruneOptions{OlderThan: 10 * time.Minute}, old, true},
+		{"content match", PruneOptions{ContentContains: "world"}, recent, true},
+		{"content mismatch", PruneOptions{ContentContains: "world"}, old, false},
+		{"filter", PruneOptions{Filter: func(m *Message) bool { return m == old }}, recent, false},
+	}
+
+	for _, test := range table {
+		if got := test.opts.eligible(test.msg, now); got != test.want {
+			t.Errorf("%s: got %t, wants %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestClient_PruneMessages(t *testing.T) {
+	now := time.Now()
+	msgAt := func(created time.Time, author Snowflake) *Message {
+		id := Snowflake(uint64(created.UnixNano()/int64(time.Millisecond)-1420070400000) << 22)
+		return &Message{ID: id, ChannelID: 2, Author: &User{ID: author}}
+	}
+
+	// a full page where every tenth message is from the user being pruned
+	var page1, bulk []*Message
+	for i := 0; i < 100; i++ {
+		msg := msgAt(now.Add(-time.Duration(i+1)*time.Minute), 2)
+		if i%10 == 0 {
+			msg.Author.ID = 1
+			bulk = append(bulk, msg)
+		}
+		page1 = append(page1, msg)
+	}
+	// the end of the channel, where a message that is too old for bulk deletes and a single recent message
+	// must be deleted one by one
+	recent := msgAt(now.Add(-3*time.Hour), 1)
+	old := msgAt(now.Add(-20*24*time.Hour), 1)
+	page2 := []*Message{recent, msgAt(now.Add(-4*time.Hour), 2), old}
+
+	// the pages only hold the message fields that PruneMessages looks at
+	body := func(msgs []*Message) string {
+		items := make([]string, 0, len(msgs))
+		for _, msg := range msgs {
+			items = append(items, `{"id":"`+msg.ID.String()+`","channel_id":"2","author":{"id":"`+msg.Author.ID.String()+`"}}`)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+
+	mock := httdtesting.New(t)
+	mock.Expect("GET", "/channels/2/messages?limit=100").Respond(http.StatusOK, body(page1))
+	mock.Expect("POST", "/channels/2/messages/bulk-delete").Respond(http.StatusNoContent, "").
+		Do(func(ctx context.Context, req *httdtesting.Request) {
+			params := req.Body.(*DeleteMessagesParams)
+			if len(params.Messages) != len(bulk) {
+				t.Fatalf("expected %d messages to be bulk deleted, got %d", len(bulk), len(params.Messages))
+			}
+			for i := range bulk {
+				if params.Messages[i] != bulk[i].ID {
+					t.Errorf("expected message %d to be bulk deleted, got %d", bulk[i].ID, params.Messages[i])
+				}
+			}
+		})
+	mock.Expect("GET", "/channels/2/messages?before="+page1[99].ID.String()+"&limit=100").Respond(http.StatusOK, body(page2))
+	mock.Expect("DELETE", "/channels/2/messages/"+old.ID.String()).Respond(http.StatusNoContent, "")
+	mock.Expect("DELETE", "/channels/2/messages/"+recent.ID.String()).Respond(http.StatusNoContent, "")
+	defer mock.AssertExpectations()
+
+	client := New(Config{BotToken: "testing", RESTRequester: mock, DisableCache: true})
+	deleted, err := client.PruneMessages(context.Background(), 2, PruneOptions{AuthorID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wants := len(bulk) + 2; deleted != wants {
+		t.Errorf("expected %d deleted messages, got %d", wants, deleted)
+	}
+
+	t.Run("limit", func(t *testing.T) {
+		mock := httdtesting.New(t)
+		mock.Expect("GET", "/channels/2/messages?limit=100").Respond(http.StatusOK, body(page1))
+		mock.Expect("POST", "/channels/2/messages/bulk-delete").Respond(http.StatusNoContent, "").
+			Do(func(ctx context.Context, req *httdtesting.Request) {
+				if params := req.Body.(*DeleteMessagesParams); len(params.Messages) != 5 {
+					t.Errorf("expected 5 messages to be bulk deleted, got %d", len(params.Messages))
+				}
+			})
+		defer mock.AssertExpectations()
+
+		client := New(Config{BotToken: "testing", RESTRequester: mock, DisableCache: true})
+		deleted, err := client.PruneMessages(context.Background(), 2, PruneOptions{AuthorID: 1, Limit: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 5 {
+			t.Errorf("expected 5 deleted messages, got %d", deleted)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock := httdtesting.New(t)
+		mock.Expect("GET", "/channels/2/messages?limit=100").Respond(http.StatusOK, body(page2))
+		mock.Expect("DELETE", "/channels/2/messages/"+old.ID.String()).Respond(http.StatusNoContent, "")
+		mock.Expect("DELETE", "/channels/2/messages/"+recent.ID.String()).Respond(http.StatusForbidden, `{"code":50013,"message":"Missing Permissions"}`)
+		defer mock.AssertExpectations()
+
+		client := New(Config{BotToken: "testing", RESTRequester: mock, DisableCache: true})
+		deleted, err := client.PruneMessages(context.Background(), 2, PruneOptions{AuthorID: 1})
+		if err == nil {
+			t.Fatal("expected the prune to fail")
+		}
+		if deleted != 1 {
+			t.Errorf("expected the deleted messages before the error to be counted, got %d", deleted)
+		}
+	})
+}
+
+func TestCreateMessageParams_prepare(t *testing.T) {
+	t.Run("files-only", func(t *testing.T) {
+		params := &CreateMessageParams{
+			Files: []CreateMessageFileParams{
+				{Reader: strings.NewReader("file content"), FileName: "a.txt"},
+			},
+		}
+		body, contentType, err := params.prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, mediaParams, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		form, err := multipart.NewReader(body.(*bytes.Buffer), mediaParams["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload := form.Value["payload_json"]
+		if len(payload) != 1 || payload[0] != "{}" {
+			t.Errorf("expected payload_json to be {}, got %q", payload)
+		}
+	})
+
+	t.Run("silent", func(t *testing.T) {
+		params := &CreateMessageParams{Content: "hi", Silent: true}
+		body, _, err := params.prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent := body.(*CreateMessageParams); sent.Flags != MessageFlagSuppressNotifications {
+			t.Errorf("expected the sent flags to suppress notifications, got %d", sent.Flags)
+		}
+		if params.Flags != 0 {
+			t.Errorf("the flags of the params were modified, got %d", params.Flags)
+		}
+	})
+
+	t.Run("descriptions", func(t *testing.T) {
+		params := &CreateMessageParams{
+			Content: "hello",
+			Files: []CreateMessageFileParams{
+				{Reader: strings.NewReader("a"), FileName: "a.png", Description: "a red dot"},
+				{Reader: strings.NewReader("b"), FileName: "b.png", SpoilerTag: true},
+			},
+		}
+		body, contentType, err := params.prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, mediaParams, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		form, err := multipart.NewReader(body.(*bytes.Buffer), mediaParams["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"attachments":[{"id":0,"filename":"a.png","description":"a red dot"},{"id":1,"filename":"SPOILER_b.png"}],"content":"hello"}`
+		if payload := form.Value["payload_json"]; len(payload) != 1 || payload[0] != expected {
+			t.Errorf("unexpected payload_json: %q", payload)
+		}
+		if files := form.File["files[1]"]; len(files) != 1 || files[0].Filename != "SPOILER_b.png" {
+			t.Errorf("expected files[1] to be SPOILER_b.png, got %+v", files)
+		}
+	})
+
+	t.Run("content", func(t *testing.T) {
+		data, err := json.Marshal(&CreateMessageParams{Content: "hello"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"content":"hello"}` {
+			t.Errorf("unexpected json: %s", string(data))
+		}
+	})
+}
+
+func TestGetMessagesIteratively(t *testing.T) {
+	// a channel with the messages 1 to 1000
+	const channelSize = 1000
+	page := func(params *GetMessagesParams) (msgs []*Message, err error) {
+		if params.Limit > 100 {
+			t.Fatalf("page limit can not exceed 100, got %d", params.Limit)
+		}
+
+		var from, to Snowflake
+		switch {
+		case !params.Around.IsZero():
+			from = params.Around - Snowflake(params.Limit/2)
+			to = from + Snowflake(params.Limit) - 1
+		case !params.After.IsZero():
+			from = params.After + 1
+			to = params.After + Snowflake(params.Limit)
+		default:
+			if params.Before.IsZero() {
+				params.Before = channelSize + 1
+			}
+			from = params.Before - Snowflake(params.Limit)
+			to = params.Before - 1
+		}
+		for id := to; id >= from && id > 0; id-- {
+			if id <= channelSize {
+				msgs = append(msgs, &Message{ID: id})
+			}
+		}
+		return msgs, nil
+	}
+	single := func(id Snowflake) (*Message, error) {
+		return &Message{ID: id}, nil
+	}
+
+	for _, limit := range []uint{100, 101, 151, 250} {
+		filter := &GetMessagesParams{Around: 500, Limit: limit}
+		msgs, err := getMessagesIteratively(filter, page, single)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint(len(msgs)) != limit {
+			t.Errorf("limit %d: got %d messages", limit, len(msgs))
+		}
+		if filter.Limit != limit {
+			t.Errorf("limit %d: the filter was modified", limit)
+		}
+
+		unique := map[Snowflake]bool{}
+		for _, msg := range msgs {
+			if unique[msg.ID] {
+				t.Errorf("limit %d: message %d is duplicated", limit, msg.ID)
+			}
+			unique[msg.ID] = true
+		}
+		if !unique[500] {
+			t.Errorf("limit %d: missing the around message", limit)
+		}
+	}
+
+	t.Run("before", func(t *testing.T) {
+		msgs, err := getMessagesIteratively(&GetMessagesParams{Limit: 250}, page, single)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 250 || msgs[len(msgs)-1].ID != channelSize-249 {
+			t.Errorf("expected the 250 latest messages, got %d", len(msgs))
+		}
+	})
+
+	t.Run("end of channel", func(t *testing.T) {
+		msgs, err := getMessagesIteratively(&GetMessagesParams{After: 900, Limit: 500}, page, single)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 100 {
+			t.Errorf("expected the 100 messages after 900, got %d", len(msgs))
+		}
+	})
+}
+
+func TestGetMessagesIteratively_partialResult(t *testing.T) {
+	single := func(id Snowflake) (*Message, error) {
+		return &Message{ID: id}, nil
+	}
+	// pages of 100 messages before the given snowflake, or after the given snowflake, that fails after n requests
+	failingPage := func(n int) func(*GetMessagesParams) ([]*Message, error) {
+		var requests int
+		return func(params *GetMessagesParams) (msgs []*Message, err error) {
+			requests++
+			if requests > n {
+				return nil, httd.ErrRateLimited
+			}
+
+			for i := uint(1); i <= params.Limit; i++ {
+				if !params.After.IsZero() {
+					msgs = append(msgs, &Message{ID: params.After + Snowflake(i)})
+				} else {
+					before := params.Before
+					if before.IsZero() {
+						before = 10000
+					}
+					msgs = append(msgs, &Message{ID: before - Snowflake(i)})
+				}
+			}
+			return msgs, nil
+		}
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		msgs, err := getMessagesIteratively(&GetMessagesParams{Limit: 500}, failingPage(2), single)
+		var partial *ErrPartialResult
+		if !errors.As(err, &partial) {
+			t.Fatalf("expected a partial result error, got %v", err)
+		}
+		if !errors.Is(err, httd.ErrRateLimited) {
+			t.Error("expected the rate limit error to be wrapped")
+		}
+		if len(msgs) != 200 {
+			t.Errorf("expected the 200 fetched messages, got %d", len(msgs))
+		}
+	})
+
+	t.Run("around", func(t *testing.T) {
+		// the before half takes 2 requests, and the after half fails on the first request
+		msgs, err := getMessagesIteratively(&GetMessagesParams{Around: 5000, Limit: 301}, failingPage(2), single)
+		var partial *ErrPartialResult
+		if !errors.As(err, &partial) {
+			t.Fatalf("expected a partial result error, got %v", err)
+		}
+		if errors.As(partial.Err, &partial) {
+			t.Error("partial result errors should not be nested")
+		}
+		if !errors.Is(err, httd.ErrRateLimited) {
+			t.Error("expected the rate limit error to be wrapped")
+		}
+		if len(msgs) != 150 {
+			t.Errorf("expected the 150 messages before, got %d", len(msgs))
+		}
+	})
+
+	t.Run("first request", func(t *testing.T) {
+		msgs, err := getMessagesIteratively(&GetMessagesParams{Limit: 500}, failingPage(0), single)
+		if err != httd.ErrRateLimited {
+			t.Errorf("expected the error to be returned as is, got %v", err)
+		}
+		if msgs != nil {
+			t.Errorf("expected no messages, got %d", len(msgs))
+		}
+	})
+}
+
+func TestCreateMessageFileParams_Progress(t *testing.T) {
+	upload := func(reader io.Reader) (calls [][2]int64) {
+		file := &CreateMessageFileParams{
+			Reader:   reader,
+			FileName: "video.mp4",
+			Progress: func(written, total int64) {
+				calls = append(calls, [2]int64{written, total})
+			},
+		}
+		mp := multipart.NewWriter(&bytes.Buffer{})
+		if err := file.write(0, mp); err != nil {
+			t.Fatal(err)
+		}
+		return calls
+	}
+
+	content := strings.Repeat("a", 1<<20)
+	calls := upload(strings.NewReader(content))
+	if len(calls) < 2 || len(calls) > 3 {
+		t.Errorf("expected the progress to be reported at a bounded frequency, got %d calls", len(calls))
+	}
+	if last := calls[len(calls)-1]; last != [2]int64{1 << 20, 1 << 20} {
+		t.Errorf("expected the last call to report the whole file, got %v", last)
+	}
+
+	calls = upload(ioutil.NopCloser(strings.NewReader(content)))
+	if last := calls[len(calls)-1]; last != [2]int64{1 << 20, -1} {
+		t.Errorf("expected an unknown total, got %v", last)
+	}
+}
+
+func TestNewCreateMessageParams(t *testing.T) {
+	embed := &Embed{Title: "title"}
+	mentions := &AllowedMentions{Parse: []string{}}
+	params := NewCreateMessageParams(
+		WithContent("hello"),
+		WithEmbed(embed),
+		WithTTS(),
+		WithFile("a.txt", strings.NewReader("a")),
+		WithFile("b.txt", strings.NewReader("b")),
+		WithSpoilerContent(),
+		WithAllowedMentions(mentions),
+	)
+
+	if params.Content != "hello" || params.Embed != embed || !params.Tts || !params.SpoilerTagContent {
+		t.Errorf("options were not applied: %+v", params)
+	}
+	if params.AllowedMentions != mentions {
+		t.Error("allowed mentions were not set")
+	}
+	if len(params.Files) != 2 || params.Files[0].FileName != "a.txt" || params.Files[1].FileName != "b.txt" {
+		t.Errorf("expected both files, got %+v", params.Files)
+	}
+	if err := params.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateMessageParams_Validate(t *testing.T) {
+	table := []struct {
+		name   string
+		params *CreateMessageParams
+		valid  bool
+	}{
+		{"content", NewCreateMessageParams(WithContent("hello")), true},
+		{"embed", NewCreateMessageParams(WithEmbed(&Embed{})), true},
+		{"file", NewCreateMessageParams(WithFile("a.txt", strings.NewReader("a"))), true},
+		{"empty", NewCreateMessageParams(), false},
+		{"max length", NewCreateMessageParams(WithContent(strings.Repeat("ø", MaxMessageContentLength))), true},
+		{"too long", NewCreateMessageParams(WithContent(strings.Repeat("a", MaxMessageContentLength+1))), false},
+		{"missing reader", NewCreateMessageParams(WithFile("a.txt", nil)), false},
+		{"missing file name", NewCreateMessageParams(WithFile("", strings.NewReader("a"))), false},
+	}
+
+	for _, test := range table {
+		if err := test.params.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid to be %t, got error %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestClient_CreateMessage_validation(t *testing.T) {
+	mock := httdtesting.New(t)
+	mock.Expect("POST", "/channels/2/messages").Respond(http.StatusOK, `{"id":"3","channel_id":"2"}`)
+	defer mock.AssertExpectations()
+
+	client := New(Config{BotToken: "testing", RESTRequester: mock, DisableCache: true})
+
+	// params that are not created by NewCreateMessageParams are sent as is, and left to Discord to validate
+	reply := &CreateMessageParams{MessageReference: &MessageReference{MessageID: 1, ChannelID: 2}}
+	if _, err := client.Channel(2).CreateMessage(reply); err != nil {
+		t.Errorf("expected the params to be sent without validation, got %v", err)
+	}
+
+	if _, err := client.Channel(2).CreateMessage(NewCreateMessageParams()); err == nil {
+		t.Error("expected params from NewCreateMessageParams to be validated")
+	}
+}
+
+func TestDeleteMessagesParams(t *testing.T) {
+	params := &DeleteMessagesParams{}
+	if err := params.AddMessage(&Message{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := params.AddMessage(&Message{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if params.Count() != 1 {
+		t.Errorf("expected duplicates to be skipped, got %d messages", params.Count())
+	}
+	if err := params.Valid(); err == nil {
+		t.Error("expected a single message to be invalid")
+	}
+
+	ids := make([]Snowflake, 0, 100)
+	for i := 1; i <= 100; i++ {
+		ids = append(ids, Snowflake(i))
+	}
+	if err := params.AddMessageIDs(ids...); err != nil {
+		t.Fatal(err)
+	}
+	if params.Count() != 100 {
+		t.Errorf("expected 100 messages, got %d", params.Count())
+	}
+	if err := params.Valid(); err != nil {
+		t.Error(err)
+	}
+
+	if err := params.AddMessageIDs(101, 102); err == nil {
+		t.Error("expected an error when exceeding 100 messages")
+	}
+	if params.Count() != 100 {
+		t.Errorf("expected no messages to be added on error, got %d", params.Count())
+	}
+
+	params.Messages = append(params.Messages, 1, 2)
+	if err := params.Valid(); err != nil {
+		t.Errorf("duplicates should only be counted once: %s", err)
+	}
+
+	params.Reset()
+	if params.Count() != 0 {
+		t.Errorf("expected no messages after reset, got %d", params.Count())
+	}
+}
+
+func TestSortPinnedMessages(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/channel/pins.json")
+	check(err, t)
+
+	var pins []*Message
+	if err = json.Unmarshal(data, &pins); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := func(msgs []*Message) (s []string) {
+		for _, msg := range msgs {
+			s = append(s, msg.Content)
+		}
+		return s
+	}
+
+	sortPinnedMessages(pins, 0)
+	if got := strings.Join(contents(pins), ","); got != "fourth,third,second,first" {
+		t.Errorf("expected the newest messages first, got %s", got)
+	}
+
+	sortPinnedMessages(pins, OrderAscending)
+	if got := strings.Join(contents(pins), ","); got != "first,second,third,fourth" {
+		t.Errorf("expected the oldest messages first, got %s", got)
+	}
+}
@@ -3,9 +3,14 @@
 package disgord
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/andersfylling/disgord/httdtesting"
 	"github.com/andersfylling/disgord/internal/logger"
 	"github.com/andersfylling/disgord/json"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -427,3 +432,299 @@ func TestInternalStateHandlers(t *testing.T) {
 		t.Errorf("Removing a connected guild should affect the internal state. Got %d, wants %d", len(c.GetConnectedGuilds()), 0)
 	}
 }
+
+// roundTripperFunc captures REST requests without sending them to Discord
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClient_HeartbeatLatency(t *testing.T) {
+	c := &Client{}
+	if latency := c.HeartbeatLatency(); latency != 0 {
+		t.Errorf("expected no latency before connecting, got %s", latency)
+	}
+
+	latency := averageHeartbeatLatency(map[uint]ShardStatus{
+		0: {HeartbeatLatency: 100 * time.Millisecond},
+		1: {HeartbeatLatency: 300 * time.Millisecond},
+		2: {}, // no heartbeat ACK yet
+	})
+	if latency != 200*time.Millisecond {
+		t.Errorf("expected an average latency of 200ms, got %s", latency)
+	}
+}
+
+func TestClient_SendMsgSilent(t *testing.T) {
+	var payload map[string]interface{}
+	client := New(Config{
+		BotToken: "testing",
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				if err = json.Unmarshal(body, &payload); err != nil {
+					return nil, err
+				}
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":"2","channel_id":"1","content":"hello","flags":4096}`)),
+					Request:    req,
+				}, nil
+			}),
+		},
+	})
+
+	msg, err := client.SendMsg(context.Background(), 1, "hello", Silent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags, ok := payload["flags"].(float64); !ok || MessageFlag(flags) != MessageFlagSuppressNotifications {
+		t.Errorf("expected flags to be %d, got %v", MessageFlagSuppressNotifications, payload["flags"])
+	}
+	if msg.Flags != MessageFlagSuppressNotifications {
+		t.Errorf("expected message flags to be %d, got %d", MessageFlagSuppressNotifications, msg.Flags)
+	}
+
+	payload = nil
+	if _, err = client.SendMsg(context.Background(), 1, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := payload["flags"]; ok {
+		t.Errorf("flags should be omitted when zero, got %v", payload["flags"])
+	}
+}
+
+func TestClient_RESTRequest(t *testing.T) {
+	var endpoint, method string
+	client := New(Config{
+		BotToken: "testing",
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				endpoint, method = req.URL.Path, req.Method
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":"2","channel_id":"1","content":"hello"}`)),
+					Request:    req,
+				}, nil
+			}),
+		},
+	})
+
+	v, err := client.RESTRequest(&RESTRequestConfig{
+		Method:      http.MethodPost,
+		Endpoint:    "/channels/1/messages",
+		Body:        &CreateMessageParams{Content: "hello"},
+		ContentType: ContentTypeJSON,
+	}, func() interface{} {
+		return &Message{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, ok := v.(*Message); !ok || msg.Content != "hello" || msg.ID != 2 {
+		t.Errorf("unexpected response: %+v", v)
+	}
+	if method != http.MethodPost || !strings.HasSuffix(endpoint, "/channels/1/messages") {
+		t.Errorf("unexpected request: %s %s", method, endpoint)
+	}
+
+	v, err = client.RESTRequest(&RESTRequestConfig{Endpoint: "/channels/1/messages/2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := v.(*json.RawMessage); !ok || !strings.Contains(string(*raw), `"content":"hello"`) {
+		t.Errorf("expected the raw response, got %+v", v)
+	}
+
+	// the method can be set from a variable, such as a method read from a config file
+	del := http.MethodDelete
+	if _, err = client.RESTRequest(&RESTRequestConfig{Method: HTTPMethod(del), Endpoint: "/channels/1/messages/2"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodDelete {
+		t.Errorf("expected a DELETE request, got %s", method)
+	}
+
+	if _, err = client.RESTRequest(&RESTRequestConfig{Endpoint: "channels/1"}, nil); err == nil {
+		t.Error("expected an error for an endpoint without a leading /")
+	}
+}
+
+func TestClient_SendDM(t *testing.T) {
+	var dmCreations int
+	var blocked bool
+	client := New(Config{
+		BotToken: "testing",
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				status, body := http.StatusOK, `{"id":"3","channel_id":"2","content":"hello"}`
+				if strings.HasSuffix(req.URL.Path, "/users/@me/channels") {
+					dmCreations++
+					body = `{"id":"2","type":1}`
+				} else if blocked {
+					status, body = http.StatusForbidden, `{"code":50007,"message":"Cannot send messages to this user"}`
+				}
+				return &http.Response{
+					StatusCode: status,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+					Request:    req,
+				}, nil
+			}),
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		msg, err := client.SendDM(context.Background(), 1, "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.ChannelID != 2 {
+			t.Errorf("expected the message to be sent to the DM channel, got %d", msg.ChannelID)
+		}
+	}
+	if dmCreations != 1 {
+		t.Errorf("expected the DM channel to be created once, got %d requests", dmCreations)
+	}
+
+	blocked = true
+	_, err := client.SendDM(context.Background(), 1, "hello")
+	var cannotDM *ErrCannotDM
+	if !errors.As(err, &cannotDM) {
+		t.Fatalf("expected ErrCannotDM, got %v", err)
+	}
+	if cannotDM.UserID != 1 {
+		t.Errorf("expected user ID 1, got %d", cannotDM.UserID)
+	}
+}
+
+// countingCodec counts the calls to the standard codec
+type countingCodec struct {
+	json.StdCodec
+	marshals, unmarshals int
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshals++
+	return c.StdCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return c.StdCodec.Unmarshal(data, v)
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := &countingCodec{}
+	global := &countingCodec{}
+	restore := setCodec(global)
+	defer restore()
+
+	client := New(Config{
+		BotToken:  "testing",
+		RESTCodec: codec,
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":"2","channel_id":"1","content":"hello"}`)),
+					Request:    req,
+				}, nil
+			}),
+		},
+	})
+
+	if _, err := client.SendMsg(context.Background(), 1, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if codec.marshals == 0 || codec.unmarshals == 0 {
+		t.Errorf("expected the request and response to use the codec, got %d marshals and %d unmarshals", codec.marshals, codec.unmarshals)
+	}
+	if global.marshals != 0 || global.unmarshals != 0 {
+		t.Errorf("expected the codec of the json package to be unused, got %d marshals and %d unmarshals", global.marshals, global.unmarshals)
+	}
+
+	t.Run("SetCodec", func(t *testing.T) {
+		codec := &countingCodec{}
+		restore := setCodec(codec)
+		defer restore()
+
+		client := New(Config{BotToken: "testing", HTTPClient: client.httpClient})
+		if _, err := client.SendMsg(context.Background(), 1, "hello"); err != nil {
+			t.Fatal(err)
+		}
+		if codec.marshals == 0 || codec.unmarshals == 0 {
+			t.Errorf("expected clients without a codec to use SetCodec, got %d marshals and %d unmarshals", codec.marshals, codec.unmarshals)
+		}
+	})
+}
+
+// setCodec calls json.SetCodec, and returns a func that restores the variables of the json package
+func setCodec(codec json.Codec) (restore func()) {
+	marshal, unmarshal, encode := json.Marshal, json.Unmarshal, json.Encode
+	json.SetCodec(codec)
+	return func() {
+		json.Marshal, json.Unmarshal, json.Encode = marshal, unmarshal, encode
+	}
+}
+
+func TestClient_RESTErrors(t *testing.T) {
+	client := New(Config{
+		BotToken:    "testing",
+		RetryPolicy: RetryPolicy{MaxAttempts: 1},
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(`{"code":10003,"message":"Unknown Channel"}`)),
+					Request:    req,
+				}, nil
+			}),
+		},
+	})
+
+	_, err := client.Channel(1).WithContext(context.Background()).Get(IgnoreCache)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the error to match ErrNotFound, got %v", err)
+	}
+
+	var restErr *ErrRest
+	if !errors.As(err, &restErr) || restErr.Code != 10003 || restErr.Method != http.MethodGet {
+		t.Errorf("expected the full REST error, got %+v", restErr)
+	}
+}
+
+func TestClient_RESTRequester(t *testing.T) {
+	mock := httdtesting.New(t)
+	mock.Expect("POST", "/users/@me/channels").Respond(http.StatusOK, `{"id":"2","type":1}`)
+	mock.Expect("POST", "/channels/2/messages").Do(func(ctx context.Context, req *httdtesting.Request) {
+		if body, _ := json.Marshal(req.Body); !strings.Contains(string(body), "hello") {
+			t.Errorf("unexpected message body %s", body)
+		}
+	}).Respond(http.StatusOK, `{"id":"3","channel_id":"2","content":"hello"}`)
+	mock.Expect("DELETE", "/channels/2/messages/3").Respond(http.StatusNotFound, `{"code":10008,"message":"Unknown Message"}`)
+	defer mock.AssertExpectations()
+
+	client := New(Config{BotToken: "testing", RESTRequester: mock})
+	msg, err := client.SendDM(context.Background(), 1, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 3 || msg.ChannelID != 2 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	err = client.Channel(2).Message(3).Delete(context.Background())
+	if !IsUnknownMessage(err) {
+		t.Errorf("expected an unknown message error, got %v", err)
+	}
+}
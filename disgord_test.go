@@ -142,6 +142,10 @@ func (g *mockerWSReceiveOnly) Close() (err error) {
 	return
 }
 
+func (g *mockerWSReceiveOnly) CloseResumable() (err error) {
+	return
+}
+
 func (g *mockerWSReceiveOnly) Read(ctx context.Context) (packet []byte, err error) {
 	packet = <-g.reading
 	return
@@ -151,6 +155,14 @@ func (g *mockerWSReceiveOnly) Disconnected() bool {
 	return true
 }
 
+func (g *mockerWSReceiveOnly) Inactive() bool {
+	return false
+}
+
+func (g *mockerWSReceiveOnly) InactiveSince() time.Time {
+	return time.Now()
+}
+
 var _ gateway.Conn = (*mockerWSReceiveOnly)(nil)
 
 var sink1 int = 1
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/andersfylling/disgord/internal/endpoint"
@@ -76,6 +78,59 @@ func (e *Emoji) IDReference() string {
 	return  e.Name + ":" + e.ID.String()
 }
 
+// APIName returns the URL safe emoji reference used by the reaction endpoints. Custom emojis
+// are referenced as name:id, while unicode emojis are escaped.
+func (e *Emoji) APIName() string {
+	return url.PathEscape(e.IDReference())
+}
+
+// Equal checks if the emoji is the same as the given emoji, which can be a *Emoji, Emoji or a string.
+// Strings can either be unicode, :name:, name:id or a custom emoji mention such as <:name:id>.
+// Custom emojis are compared by their ID when both are known, otherwise by name.
+func (e *Emoji) Equal(other interface{}) bool {
+	var o *Emoji
+	switch t := other.(type) {
+	case *Emoji:
+		o = t
+	case Emoji:
+		o = &t
+	case string:
+		o = parseEmoji(t)
+	}
+	if e == nil || o == nil {
+		return false
+	}
+
+	if !e.ID.IsZero() && !o.ID.IsZero() {
+		return e.ID == o.ID
+	}
+	return normalizeEmojiName(e.Name) == normalizeEmojiName(o.Name)
+}
+
+// parseEmoji creates an emoji from a unicode emoji, :name:, name:id or a custom emoji mention.
+func parseEmoji(s string) *Emoji {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && s[0] == '<' && s[len(s)-1] == '>' {
+		// <:name:id> or <a:name:id>
+		s = strings.TrimPrefix(s[1:len(s)-1], "a")
+	}
+	s = unwrapEmoji(s)
+
+	e := &Emoji{Name: s}
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		if id, err := strconv.ParseUint(s[i+1:], 10, 64); err == nil {
+			e.Name = strings.TrimPrefix(s[:i], ":")
+			e.ID = Snowflake(id)
+		}
+	}
+	return e
+}
+
+// normalizeEmojiName removes the variation selector, such that ❤ and ❤️ are considered the same emoji.
+func normalizeEmojiName(name string) string {
+	return strings.ReplaceAll(name, "\ufe0f", "")
+}
+
 // DeepCopy see interface at struct.go#DeepCopier
 func (e *Emoji) DeepCopy() (copy interface{}) {
 	copy = &Emoji{}
@@ -0,0 +1,63 @@
+// +build !integration
+
+package disgord
+
+import "testing"
+
+func TestEmoji_Equal(t *testing.T) {
+	custom := &Emoji{ID: 540519588153262081, Name: "blob"}
+	unicode := &Emoji{Name: "❤\ufe0f"}
+
+	table := []struct {
+		emoji *Emoji
+		other interface{}
+		want  bool
+	}{
+		{custom, &Emoji{ID: 540519588153262081}, true},
+		{custom, Emoji{ID: 540519588153262081, Name: "renamed"}, true},
+		{custom, &Emoji{ID: 1, Name: "blob"}, false},
+		{custom, "blob:540519588153262081", true},
+		{custom, "<:blob:540519588153262081>", true},
+		{custom, "<a:blob:540519588153262081>", true},
+		{custom, ":blob:", true},
+		{custom, "blob:1", false},
+		{unicode, "❤\ufe0f", true},
+		{unicode, "❤", true},
+		{unicode, &Emoji{Name: "❤"}, true},
+		{unicode, "👍", false},
+		{unicode, 42, false},
+		{unicode, nil, false},
+		{nil, "❤", false},
+	}
+
+	for i, test := range table {
+		if got := test.emoji.Equal(test.other); got != test.want {
+			t.Errorf("test %d: %v.Equal(%v) = %t, wants %t", i, test.emoji, test.other, got, test.want)
+		}
+	}
+}
+
+func TestEmoji_APIName(t *testing.T) {
+	table := []struct {
+		emoji *Emoji
+		want  string
+	}{
+		{&Emoji{ID: 540519588153262081, Name: "blob"}, "blob:540519588153262081"},
+		{&Emoji{Name: "👍"}, "%F0%9F%91%8D"},
+	}
+
+	for _, test := range table {
+		if got := test.emoji.APIName(); got != test.want {
+			t.Errorf("got %s, wants %s", got, test.want)
+		}
+	}
+
+	for _, s := range []string{"👍", ":👍:"} {
+		if got, err := emojiReference(s); err != nil || got != "%F0%9F%91%8D" {
+			t.Errorf("emojiReference(%q) = %s, %v", s, got, err)
+		}
+	}
+	if got, _ := emojiReference("<:blob:540519588153262081>"); got != "blob:540519588153262081" {
+		t.Errorf("expected custom emoji mention to become name:id, got %s", got)
+	}
+}
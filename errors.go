@@ -1,10 +1,135 @@
 package disgord
 
 import (
+	"errors"
+
 	"github.com/andersfylling/disgord/internal/disgorderr"
+	"github.com/andersfylling/disgord/internal/httd"
 )
 
 // TODO: go generate from internal/errors/*
 type Err = disgorderr.Err
 type CloseConnectionErr = disgorderr.ClosedConnectionErr
 type HandlerSpecErr = disgorderr.HandlerSpecErr
+
+// Errors returned by REST methods, usually as an ErrRest, can be matched against these using errors.Is.
+// Use errors.As to get the full ErrRest.
+var (
+	ErrBadRequest   = httd.ErrBadRequest
+	ErrUnauthorized = httd.ErrUnauthorized
+	ErrForbidden    = httd.ErrForbidden
+	ErrNotFound     = httd.ErrNotFound
+	ErrRateLimited  = httd.ErrRateLimited
+)
+
+// UnauthorizedError is returned for REST requests rejected with 401 Unauthorized, and explains what might be
+// wrong with the bot token. It matches ErrUnauthorized with errors.Is, and unwraps to an ErrRest.
+type UnauthorizedError = httd.UnauthorizedError
+
+// ErrClientClosed is returned by REST methods called after Client.Disconnect
+var ErrClientClosed = httd.ErrClientClosed
+
+// ErrRESTAcquireUnsupported is returned by Client.RESTAcquire when a custom RESTBucketManager can not hold
+// requests ahead of sending them
+var ErrRESTAcquireUnsupported = httd.ErrAcquireUnsupported
+
+// ErrResponseTooLarge is returned when a REST response body is larger than Config.RESTMaxResponseSize
+type ErrResponseTooLarge = httd.ErrResponseTooLarge
+
+// ErrUnsupportedEncoding is returned when a REST response is compressed with an encoding that can not be
+// decoded, or when Config.RESTAcceptEncodings holds one
+type ErrUnsupportedEncoding = httd.ErrUnsupportedEncoding
+
+// ErrCloudflareBlocked is returned when Cloudflare, in front of the Discord API, blocked a REST request.
+// All REST requests are then rejected locally for a while, see Config.RESTCloudflareCooldown.
+var ErrCloudflareBlocked = httd.ErrCloudflareBlocked
+
+// ErrInvalidRequestLimit is returned when a REST request is rejected locally, because too many invalid
+// requests were sent within 10 minutes, see Config.RESTInvalidRequestStopThreshold.
+var ErrInvalidRequestLimit = httd.ErrInvalidRequestLimit
+
+// RateLimitError matches ErrRateLimited, and tells how long to wait before the request can be sent again.
+// It is returned for 429 responses, and when Config.CancelRequestWhenRateLimited stops a request from
+// waiting on an exhausted bucket.
+type RateLimitError = httd.RateLimitError
+
+//go:generate go run generate/errorcodes/main.go
+
+// JSONErrorCode is the error code Discord puts in the body of a failed REST response, see ErrRest.Code.
+// Suggestion gives a hint on what caused it.
+type JSONErrorCode = httd.JSONErrorCode
+
+// HasJSONErrorCode checks if err is, or wraps, a REST error with the given Discord error code
+func HasJSONErrorCode(err error, code JSONErrorCode) bool {
+	return httd.HasJSONErrorCode(err, code)
+}
+
+// IsUnknownMessage checks if the REST error is caused by a deleted or unknown message
+func IsUnknownMessage(err error) bool {
+	return httd.IsUnknownMessage(err)
+}
+
+// IsUnknownChannel checks if the REST error is caused by a deleted or unknown channel
+func IsUnknownChannel(err error) bool {
+	return httd.IsUnknownChannel(err)
+}
+
+// IsMissingAccess checks if the REST error is caused by the bot not being able to see the resource
+func IsMissingAccess(err error) bool {
+	return httd.IsMissingAccess(err)
+}
+
+// IsMissingPermissions checks if the REST error is caused by the bot lacking a permission
+func IsMissingPermissions(err error) bool {
+	return httd.IsMissingPermissions(err)
+}
+
+// IsInvalidFormBody checks if the REST error is caused by an invalid field in the request
+func IsInvalidFormBody(err error) bool {
+	return httd.IsInvalidFormBody(err)
+}
+
+// ErrMaxPinsReached is returned when a channel can not hold any more pinned messages.
+// See MaxPinnedMessages.
+var ErrMaxPinsReached = errors.New("maximum number of pinned messages reached")
+
+// ErrPartialResult is returned when only some of the requested data could be fetched. The data that was fetched
+// is returned alongside the error, and Err holds the reason why the rest could not be fetched.
+type ErrPartialResult struct {
+	Err error
+}
+
+var _ error = (*ErrPartialResult)(nil)
+
+func (e *ErrPartialResult) Error() string {
+	return "partial result: " + e.Err.Error()
+}
+
+func (e *ErrPartialResult) Unwrap() error {
+	return e.Err
+}
+
+// ErrCannotDM is returned by Client.SendDM when a direct message can not be sent to the user, usually
+// because the user has disabled DMs from server members or blocked the bot. Err holds the REST error.
+type ErrCannotDM struct {
+	UserID Snowflake
+	Err    error
+}
+
+var _ error = (*ErrCannotDM)(nil)
+
+func (e *ErrCannotDM) Error() string {
+	return "cannot send messages to user " + e.UserID.String() + ": " + e.Err.Error()
+}
+
+func (e *ErrCannotDM) Unwrap() error {
+	return e.Err
+}
+
+// dmError wraps the "Cannot send messages to this user" REST error in ErrCannotDM
+func dmError(userID Snowflake, err error) error {
+	if HasJSONErrorCode(err, JSONErrorCodeCannotSendMessagesToUser) {
+		return &ErrCannotDM{UserID: userID, Err: err}
+	}
+	return err
+}
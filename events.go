@@ -177,7 +177,15 @@ func (obj *MessageCreate) UnmarshalJSON(data []byte) error {
 
 // MessageUpdate message was edited
 type MessageUpdate struct {
+	// Message holds the fields sent by Discord. Note that updates can be partial, such as when
+	// embeds are unfurled, in which case fields like the author and content are empty.
 	Message *Message
+
+	// Partial only holds the fields that were sent, and can be used to update an existing
+	// message using Message.ApplyPartial. Message is created from Partial, so the two share
+	// the same users, members and lists.
+	Partial *PartialMessage
+
 	Ctx     context.Context `json:"-"`
 	ShardID uint            `json:"-"`
 }
@@ -188,15 +196,18 @@ func (obj *MessageUpdate) updateInternals() {
 	obj.Message.updateInternals()
 }
 
-// UnmarshalJSON ...
+// UnmarshalJSON decodes the payload once, as a partial message, and applies it to an empty Message
 func (obj *MessageUpdate) UnmarshalJSON(data []byte) error {
-	obj.Message = &Message{}
-	if err := json.Unmarshal(data, obj.Message); err != nil {
+	obj.Partial = &PartialMessage{}
+	if err := json.Unmarshal(data, obj.Partial); err != nil {
 		return err
 	}
-	if obj.Message.Member != nil {
-		obj.Message.Member.GuildID = obj.Message.GuildID
+	if obj.Partial.Member != nil {
+		obj.Partial.Member.GuildID = obj.Partial.GuildID
 	}
+
+	obj.Message = &Message{}
+	obj.Message.ApplyPartial(obj.Partial)
 	return nil
 }
 
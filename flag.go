@@ -11,6 +11,22 @@ func (f Flag) IgnoreEmptyParams() bool {
 	return (f & IgnoreEmptyParams) > 0
 }
 
+func (f Flag) CheckPinLimit() bool {
+	return (f & CheckPinLimit) > 0
+}
+
+func (f Flag) VerifyNonce() bool {
+	return (f & VerifyNonce) > 0
+}
+
+func (f Flag) EscapeContent() bool {
+	return (f & EscapeContent) > 0
+}
+
+func (f Flag) IgnoreGuildIDBackfill() bool {
+	return (f & IgnoreGuildIDBackfill) > 0
+}
+
 func (f Flag) Sort() bool {
 	flags := SortByID | SortByName
 	flags |= OrderAscending | OrderDescending
@@ -32,6 +48,19 @@ const (
 	// ordering
 	OrderAscending // default when sorting
 	OrderDescending
+
+	// CheckPinLimit verifies that a channel has room for another pinned message before pinning it
+	CheckPinLimit
+
+	// VerifyNonce makes sure a message is only created once, even when the request must be sent again.
+	// A nonce is generated unless one is given, and recent channel messages are checked for it on failure.
+	VerifyNonce
+
+	// EscapeContent escapes markdown and mentions in the string arguments given to Client.SendMsg
+	EscapeContent
+
+	// IgnoreGuildIDBackfill skips the channel cache lookup used to set the guild ID of messages fetched over REST
+	IgnoreGuildIDBackfill
 )
 
 func mergeFlags(flags []Flag) (f Flag) {
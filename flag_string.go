@@ -17,17 +17,25 @@ func _() {
 	_ = x[SortByChannelID-64]
 	_ = x[OrderAscending-128]
 	_ = x[OrderDescending-256]
+	_ = x[CheckPinLimit-512]
+	_ = x[VerifyNonce-1024]
+	_ = x[EscapeContent-2048]
+	_ = x[IgnoreGuildIDBackfill-4096]
 }
 
 const (
-	_Flag_name_0 = "IgnoreCacheIgnoreEmptyParams"
-	_Flag_name_1 = "SortByID"
-	_Flag_name_2 = "SortByName"
-	_Flag_name_3 = "SortByHoist"
-	_Flag_name_4 = "SortByGuildID"
-	_Flag_name_5 = "SortByChannelID"
-	_Flag_name_6 = "OrderAscending"
-	_Flag_name_7 = "OrderDescending"
+	_Flag_name_0  = "IgnoreCacheIgnoreEmptyParams"
+	_Flag_name_1  = "SortByID"
+	_Flag_name_2  = "SortByName"
+	_Flag_name_3  = "SortByHoist"
+	_Flag_name_4  = "SortByGuildID"
+	_Flag_name_5  = "SortByChannelID"
+	_Flag_name_6  = "OrderAscending"
+	_Flag_name_7  = "OrderDescending"
+	_Flag_name_8  = "CheckPinLimit"
+	_Flag_name_9  = "VerifyNonce"
+	_Flag_name_10 = "EscapeContent"
+	_Flag_name_11 = "IgnoreGuildIDBackfill"
 )
 
 var (
@@ -53,6 +61,14 @@ func (i Flag) String() string {
 		return _Flag_name_6
 	case i == 256:
 		return _Flag_name_7
+	case i == 512:
+		return _Flag_name_8
+	case i == 1024:
+		return _Flag_name_9
+	case i == 2048:
+		return _Flag_name_10
+	case i == 4096:
+		return _Flag_name_11
 	default:
 		return "Flag(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
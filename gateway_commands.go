@@ -2,6 +2,9 @@ package disgord
 
 import (
 	"errors"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/andersfylling/disgord/internal/gateway"
@@ -55,12 +58,23 @@ func prepareGatewayCommand(payload gatewayCmdPayload) (x gateway.CmdPayload, err
 		if err != nil {
 			return nil, err
 		}
-		x = &gateway.UpdateStatusPayload{
+		if err = t.validate(); err != nil {
+			return nil, err
+		}
+		game := t.Game
+		if game == nil && len(t.Activities) > 0 {
+			game = t.Activities[0]
+		}
+		presence := &gateway.UpdateStatusPayload{
 			Since:  t.Since,
-			Game:   t.Game,
+			Game:   game,
 			Status: status,
 			AFK:    t.AFK,
 		}
+		if len(t.Activities) > 0 {
+			presence.Activities = t.Activities
+		}
+		x = presence
 	default:
 		return nil, errors.New("missing support for payload")
 	}
@@ -127,10 +141,11 @@ func (u *UpdateVoiceStatePayload) isGatewayCmdPayload() bool { return true }
 // #################################################################
 
 const (
-	StatusOnline  = string(gateway.StatusOnline)
-	StatusOffline = string(gateway.StatusOffline)
-	StatusDnd     = string(gateway.StatusDND)
-	StatusIdle    = string(gateway.StatusIdle)
+	StatusOnline    = string(gateway.StatusOnline)
+	StatusOffline   = string(gateway.StatusOffline)
+	StatusDnd       = string(gateway.StatusDND)
+	StatusIdle      = string(gateway.StatusIdle)
+	StatusInvisible = string(gateway.StatusInvisible)
 )
 
 // UpdateStatusPayload payload for socket command UPDATE_STATUS.
@@ -145,6 +160,9 @@ type UpdateStatusPayload struct {
 	// Game null, or the user's new activity
 	Game *Activity
 
+	// Activities the user's new activities. Game defaults to the first one.
+	Activities []*Activity
+
 	// Status the user's new status
 	Status string
 
@@ -155,3 +173,53 @@ type UpdateStatusPayload struct {
 var _ gatewayCmdPayload = (*UpdateStatusPayload)(nil)
 
 func (u *UpdateStatusPayload) isGatewayCmdPayload() bool { return true }
+
+// streamingHosts are the sites Discord accepts for streaming activities
+var streamingHosts = []string{"twitch.tv", "youtube.com"}
+
+// validate checks that streaming activities link to a stream on Twitch or YouTube, as Discord would otherwise
+// show them as playing
+func (u *UpdateStatusPayload) validate() error {
+	activities := u.Activities
+	if u.Game != nil {
+		activities = append([]*Activity{u.Game}, activities...)
+	}
+	for _, activity := range activities {
+		if activity == nil || activity.Type != ActivityTypeStreaming {
+			continue
+		}
+		if !isStreamingURL(activity.URL) {
+			return errors.New("streaming activity requires a twitch.tv or youtube.com url, got " + strconv.Quote(activity.URL))
+		}
+	}
+	return nil
+}
+
+func isStreamingURL(streamURL string) bool {
+	u, err := url.Parse(streamURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, streamingHost := range streamingHosts {
+		if host == streamingHost || strings.HasSuffix(host, "."+streamingHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivityGame is a "Playing name" activity, see Client.SetActivity
+func ActivityGame(name string) *Activity {
+	return &Activity{Name: name, Type: ActivityTypeGame}
+}
+
+// ActivityStreaming is a "Streaming name" activity. The url must link to a Twitch or YouTube stream.
+func ActivityStreaming(name, url string) *Activity {
+	return &Activity{Name: name, Type: ActivityTypeStreaming, URL: url}
+}
+
+// ActivityListening is a "Listening to name" activity
+func ActivityListening(name string) *Activity {
+	return &Activity{Name: name, Type: ActivityTypeListening}
+}
@@ -0,0 +1,49 @@
+// +build !integration
+
+package disgord
+
+import (
+	"testing"
+
+	"github.com/andersfylling/disgord/internal/gateway"
+)
+
+func TestPrepareGatewayCommand_UpdateStatus(t *testing.T) {
+	t.Run("activities", func(t *testing.T) {
+		payload, err := prepareGatewayCommand(&UpdateStatusPayload{
+			Status:     StatusInvisible,
+			Activities: []*Activity{ActivityListening("music"), ActivityGame("with disgord")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		presence := payload.(*gateway.UpdateStatusPayload)
+		if presence.Status != gateway.StatusInvisible {
+			t.Errorf("expected the invisible status, got %s", presence.Status)
+		}
+		if game, ok := presence.Game.(*Activity); !ok || game.Name != "music" {
+			t.Errorf("expected the game to be the first activity, got %+v", presence.Game)
+		}
+		if activities, ok := presence.Activities.([]*Activity); !ok || len(activities) != 2 {
+			t.Errorf("expected both activities, got %+v", presence.Activities)
+		}
+	})
+
+	urls := map[string]bool{
+		"https://www.twitch.tv/disgord":         true,
+		"https://youtube.com/watch?v=abc":       true,
+		"https://twitch.tv.example.com/disgord": false,
+		"twitch.tv/disgord":                     false,
+		"":                                      false,
+	}
+	for streamURL, valid := range urls {
+		_, err := prepareGatewayCommand(&UpdateStatusPayload{
+			Game: ActivityStreaming("disgord", streamURL),
+		})
+		if valid && err != nil {
+			t.Errorf("expected %q to be a valid stream, got %s", streamURL, err)
+		} else if !valid && err == nil {
+			t.Errorf("expected %q to be rejected", streamURL)
+		}
+	}
+}
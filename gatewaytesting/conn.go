@@ -0,0 +1,273 @@
+// Package gatewaytesting checks that a disgord.GatewayConn behaves the way the gateway client expects, such
+// that alternative websocket implementations can be given to disgord.Config.GatewayConn. Run the suite from a
+// test:
+//
+//  func TestMyConn(t *testing.T) {
+//  	gatewaytesting.RunConnTests(t, func() disgord.GatewayConn {
+//  		return NewMyConn()
+//  	})
+//  }
+//
+// Every test opens a new Conn against a local websocket server.
+package gatewaytesting
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/andersfylling/disgord"
+	"github.com/andersfylling/disgord/json"
+)
+
+// timeout is how long a test waits for the Conn or the server before it fails
+const timeout = 5 * time.Second
+
+// RunConnTests runs the conformance tests against the Conns created by newConn
+func RunConnTests(t *testing.T, newConn func() disgord.GatewayConn) {
+	t.Run("write", func(t *testing.T) { testWrite(t, newConn) })
+	t.Run("read", func(t *testing.T) { testRead(t, newConn) })
+	t.Run("read-limit", func(t *testing.T) { testReadLimit(t, newConn) })
+	t.Run("close-frame", func(t *testing.T) { testCloseFrame(t, newConn) })
+	t.Run("cancel-read", func(t *testing.T) { testCancelRead(t, newConn) })
+	t.Run("close", func(t *testing.T) { testClose(t, newConn) })
+	t.Run("close-resumable", func(t *testing.T) { testCloseResumable(t, newConn) })
+}
+
+// serve starts a websocket server that runs handler for every connection. Call the returned function to
+// stop it.
+func serve(t *testing.T, handler func(ctx context.Context, c *websocket.Conn)) (url string, stop func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			t.Errorf("gatewaytesting: unable to accept the connection: %s", err)
+			return
+		}
+		defer c.Close(websocket.StatusInternalError, "")
+		handler(r.Context(), c)
+	}))
+	return "ws" + strings.TrimPrefix(server.URL, "http"), server.Close
+}
+
+func open(t *testing.T, newConn func() disgord.GatewayConn, url string) disgord.GatewayConn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn := newConn()
+	if err := conn.Open(ctx, url, http.Header{}); err != nil {
+		t.Fatalf("unable to open the connection: %s", err)
+	}
+	if conn.Disconnected() {
+		t.Error("expected the opened connection to be connected")
+	}
+	if conn.Inactive() {
+		t.Error("expected the opened connection to be active")
+	}
+	return conn
+}
+
+func read(t *testing.T, conn disgord.GatewayConn) []byte {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	packet, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("unable to read: %s", err)
+	}
+	return packet
+}
+
+func testWrite(t *testing.T, newConn func() disgord.GatewayConn) {
+	received := make(chan []byte, 1)
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		_, data, err := c.Read(ctx)
+		if err == nil {
+			received <- data
+		}
+		_ = c.Close(websocket.StatusNormalClosure, "")
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]int{"op": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-received:
+		var payload struct {
+			Op int `json:"op"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil || payload.Op != 1 {
+			t.Errorf("expected the written JSON, got %q", data)
+		}
+	case <-time.After(timeout):
+		t.Error("the server never received the message")
+	}
+}
+
+func testRead(t *testing.T, newConn func() disgord.GatewayConn) {
+	const payload = `{"op":11,"d":null}`
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		_, _ = w.Write([]byte(payload))
+		_ = w.Close()
+
+		_ = c.Write(ctx, websocket.MessageText, []byte(payload))
+		_ = c.Write(ctx, websocket.MessageBinary, compressed.Bytes())
+		c.CloseRead(ctx)
+		<-ctx.Done()
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	defer conn.Close()
+	opened := conn.InactiveSince()
+
+	if packet := read(t, conn); string(packet) != payload {
+		t.Errorf("expected the text message %s, got %q", payload, packet)
+	}
+	if packet := read(t, conn); string(packet) != payload {
+		t.Errorf("expected the decompressed binary message %s, got %q", payload, packet)
+	}
+	if conn.InactiveSince().Before(opened) {
+		t.Error("expected reading to keep the connection active")
+	}
+	if conn.Inactive() {
+		t.Error("expected the connection to be active after a read")
+	}
+}
+
+func testReadLimit(t *testing.T, newConn func() disgord.GatewayConn) {
+	// large guilds are sent in messages of several megabytes
+	payload := `{"d":"` + strings.Repeat("a", 8<<20) + `"}`
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		_ = c.Write(ctx, websocket.MessageText, []byte(payload))
+		c.CloseRead(ctx)
+		<-ctx.Done()
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	defer conn.Close()
+	if packet := read(t, conn); len(packet) != len(payload) {
+		t.Errorf("expected a message of %d bytes, got %d", len(payload), len(packet))
+	}
+}
+
+func testCloseFrame(t *testing.T, newConn func() disgord.GatewayConn) {
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		_ = c.Close(4004, "Authentication failed.")
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := conn.Read(ctx)
+	var closeErr *disgord.GatewayCloseErr
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("expected a *disgord.GatewayCloseErr, got %v", err)
+	}
+	if closeErr.Code() != 4004 {
+		t.Errorf("expected close code 4004, got %d", closeErr.Code())
+	}
+	if !conn.Disconnected() {
+		t.Error("expected the connection to be disconnected after a close frame")
+	}
+}
+
+func testCancelRead(t *testing.T, newConn func() disgord.GatewayConn) {
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		c.CloseRead(ctx)
+		<-ctx.Done()
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(ctx)
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(timeout):
+		t.Error("Read ignored the cancelled context")
+	}
+}
+
+func testClose(t *testing.T, newConn func() disgord.GatewayConn) {
+	status := make(chan websocket.StatusCode, 1)
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		_, _, err := c.Read(ctx)
+		status <- websocket.CloseStatus(err)
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	if err := conn.Close(); err != nil {
+		t.Errorf("unable to close: %s", err)
+	}
+	if !conn.Disconnected() {
+		t.Error("expected the connection to be disconnected after Close")
+	}
+
+	select {
+	case code := <-status:
+		if code != websocket.StatusNormalClosure {
+			t.Errorf("expected a normal closure, got %d", code)
+		}
+	case <-time.After(timeout):
+		t.Error("the server never received the close frame")
+	}
+}
+
+func testCloseResumable(t *testing.T, newConn func() disgord.GatewayConn) {
+	status := make(chan websocket.StatusCode, 1)
+	url, stop := serve(t, func(ctx context.Context, c *websocket.Conn) {
+		_, _, err := c.Read(ctx)
+		status <- websocket.CloseStatus(err)
+	})
+	defer stop()
+
+	conn := open(t, newConn, url)
+	if err := conn.CloseResumable(); err != nil {
+		t.Errorf("unable to close: %s", err)
+	}
+	if !conn.Disconnected() {
+		t.Error("expected the connection to be disconnected after CloseResumable")
+	}
+
+	select {
+	case code := <-status:
+		// Discord ends the session on these
+		if code == websocket.StatusNormalClosure || code == websocket.StatusGoingAway || code == -1 {
+			t.Errorf("expected a close code that keeps the session, got %d", code)
+		}
+	case <-time.After(timeout):
+		t.Error("the server never received the close frame")
+	}
+}
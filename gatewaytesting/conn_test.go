@@ -0,0 +1,19 @@
+// +build !integration
+
+package gatewaytesting
+
+import (
+	"testing"
+
+	"github.com/andersfylling/disgord"
+)
+
+func TestRunConnTests(t *testing.T) {
+	RunConnTests(t, func() disgord.GatewayConn {
+		conn, err := disgord.NewGatewayConn(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	})
+}
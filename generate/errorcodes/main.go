@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"strings"
+	"text/template"
+)
+
+const prefix = "JSONErrorCode"
+
+type errorCode struct {
+	Const string // eg. JSONErrorCodeUnknownMessage
+	Name  string // eg. UnknownMessage
+	Hint  string
+}
+
+func main() {
+	file, err := parser.ParseFile(token.NewFileSet(), "internal/httd/errorcodes.go", nil, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+
+	// Find the error codes in the order they are declared, where the comment of each code is its hint
+	var codes []errorCode
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			value := spec.(*ast.ValueSpec)
+			name := value.Names[0].Name
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if value.Doc == nil {
+				panic(name + " is missing a comment with the hint")
+			}
+			codes = append(codes, errorCode{
+				Const: name,
+				Name:  strings.TrimPrefix(name, prefix),
+				Hint:  strings.TrimSpace(strings.Replace(value.Doc.Text(), "\n", " ", -1)),
+			})
+		}
+	}
+
+	makeFile(codes, "generate/errorcodes/httd.gohtml", "internal/httd/errorcodes_gen.go")
+	makeFile(codes, "generate/errorcodes/disgord.gohtml", "errorcodes_gen.go")
+}
+
+func makeFile(codes []errorCode, tplFile, target string) {
+	// Open & parse our template
+	tpl := template.Must(template.New(path.Base(tplFile)).ParseFiles(tplFile))
+
+	// Execute the template, inserting the error codes
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, codes); err != nil {
+		panic(err)
+	}
+
+	// Format it according to gofmt standards
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		panic(err)
+	}
+
+	// And write it.
+	if err = ioutil.WriteFile(target, formatted, 0644); err != nil {
+		panic(err)
+	}
+}
@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/andersfylling/snowflake/v4 v4.0.2
+	github.com/andybalholm/brotli v1.0.4
 	github.com/kr/pretty v0.1.0 // indirect
 	go.uber.org/atomic v1.4.0
 	golang.org/x/crypto v0.0.0-20191227163750-53104e6ec876
@@ -30,6 +30,30 @@ func (b PermissionBit) Contains(Bits PermissionBit) bool {
 	return (b & Bits) == Bits
 }
 
+// UnmarshalJSON accepts both numbers and strings, as permissions are serialized as strings from API v8.
+func (b *PermissionBit) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*b = 0
+		return nil
+	}
+	if len(data) > 1 && data[0] == '"' {
+		data = data[1 : len(data)-1]
+	}
+
+	v, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("unable to parse permission bits %s: %w", data, err)
+	}
+	*b = PermissionBit(v)
+	return nil
+}
+
+// MarshalJSON serializes the permissions as a decimal string, such that the bits above 2^53 are not rounded
+// by JSON decoders that read numbers as floats.
+func (b PermissionBit) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strconv.FormatUint(uint64(b), 10) + `"`), nil
+}
+
 // Constants for the different bit offsets of text channel permissions
 const (
 	PermissionReadMessages PermissionBit = 1 << (iota + 10)
@@ -1424,11 +1448,11 @@ func (g guildQueryBuilder) GetMemberPermissions(userID Snowflake, flags ...Flag)
 // CreateGuildRoleParams ...
 // https://discord.com/developers/docs/resources/guild#create-guild-role-json-params
 type CreateGuildRoleParams struct {
-	Name        string `json:"name,omitempty"`
-	Permissions uint64 `json:"permissions,omitempty"`
-	Color       uint   `json:"color,omitempty"`
-	Hoist       bool   `json:"hoist,omitempty"`
-	Mentionable bool   `json:"mentionable,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Permissions PermissionBit `json:"permissions,omitempty"`
+	Color       uint          `json:"color,omitempty"`
+	Hoist       bool          `json:"hoist,omitempty"`
+	Mentionable bool          `json:"mentionable,omitempty"`
 
 	// Reason is a X-Audit-Log-Reason header field that will show up on the audit log for this action.
 	Reason string `json:"-"`
@@ -1525,6 +1549,7 @@ func (g guildQueryBuilder) PruneMembers(days int, reason string, flags ...Flag)
 		Endpoint: endpoint.GuildPrune(g.gid) + params.URLQueryString(),
 		Ctx:      g.ctx,
 		Reason:   reason,
+		Priority: httd.PriorityLow,
 	}, flags)
 
 	_, err = r.Execute()
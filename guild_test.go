@@ -5,6 +5,7 @@ package disgord
 import (
 	"github.com/andersfylling/disgord/json"
 	"io/ioutil"
+	"strconv"
 	"testing"
 )
 
@@ -187,4 +188,63 @@ func TestPermissionBit(t *testing.T) {
 	if !testBits.Contains(PermissionReadMessages) {
 		t.Fatal("does have read messages")
 	}
+	if expected := `"` + strconv.FormatUint(uint64(testBits), 10) + `"`; string(b) != expected {
+		t.Errorf("expected permissions to be marshalled as %s, got %s", expected, string(b))
+	}
+
+	// API v8 and later serialize permissions as strings
+	var role Role
+	if err = json.Unmarshal([]byte(`{"id":"1","permissions":"2048"}`), &role); err != nil {
+		t.Fatal(err)
+	}
+	if role.PermissionBits() != PermissionSendMessages {
+		t.Errorf("expected the string permissions to be parsed, got %d", role.Permissions)
+	}
+	if err = json.Unmarshal([]byte(`{"id":"1","permissions":1024}`), &role); err != nil {
+		t.Fatal(err)
+	}
+	if role.PermissionBits() != PermissionReadMessages {
+		t.Errorf("expected the numeric permissions to be parsed, got %d", role.Permissions)
+	}
+}
+
+func BenchmarkJSONCodec(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/guild/event-d-guild-create1.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	codecs := []struct {
+		name  string
+		codec json.Codec
+	}{
+		{"std", json.StdCodec{}},
+		{"package", json.PackageCodec{}},
+	}
+	for _, c := range codecs {
+		codec := c.codec
+		b.Run(c.name+"/unmarshal", func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				guild := &Guild{}
+				if err := codec.Unmarshal(data, guild); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		guild := &Guild{}
+		if err = codec.Unmarshal(data, guild); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(c.name+"/marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := codec.Marshal(guild); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
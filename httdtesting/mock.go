@@ -0,0 +1,193 @@
+// Package httdtesting provides a scripted REST requester for testing bots without a Discord connection.
+// Give the Mock to disgord.Config.RESTRequester, and script the expected requests in the order they are sent:
+//
+//  mock := httdtesting.New(t)
+//  mock.Expect("GET", "/channels/1/messages/2").Respond(http.StatusOK, `{"id":"2","channel_id":"1"}`)
+//  mock.Expect("DELETE", "/channels/1/messages/2").Respond(http.StatusNoContent, "")
+//  defer mock.AssertExpectations()
+//
+//  client := disgord.New(disgord.Config{BotToken: "testing", RESTRequester: mock})
+//
+// The mock skips rate limiting, retries and caching of responses, such that tests are deterministic.
+package httdtesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/andersfylling/disgord/internal/httd"
+	"github.com/andersfylling/disgord/json"
+)
+
+// TestingT is the part of *testing.T used by the Mock
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Request is the request received by the Mock, see Expectation.Do
+type Request = httd.Request
+
+// Expectation is a scripted request and its response
+type Expectation struct {
+	method   string
+	endpoint string
+
+	status int
+	header http.Header
+	body   []byte
+	err    error
+	do     func(ctx context.Context, req *Request)
+
+	called bool
+}
+
+// Respond sets the status code and body of the response. Bodies of failed responses are decoded into the
+// returned ErrREST, just like Discord errors are, eg. `{"code":10008,"message":"Unknown Message"}`.
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	e.status = status
+	e.body = []byte(body)
+	return e
+}
+
+// RespondJSON works like Respond, but marshals v to be the response body
+func (e *Expectation) RespondJSON(status int, v interface{}) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("httdtesting: unable to marshal the response body: %s", err))
+	}
+	e.status = status
+	e.body = body
+	return e
+}
+
+// WithHeader adds a header field to the response
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// Fail returns err instead of a response, such as a transport error
+func (e *Expectation) Fail(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Do is called with the request before the response is returned, such that the body or the headers can be
+// inspected
+func (e *Expectation) Do(do func(ctx context.Context, req *Request)) *Expectation {
+	e.do = do
+	return e
+}
+
+// Mock is a httd.Requester that expects requests in the order they were scripted with Expect
+type Mock struct {
+	mu           sync.Mutex
+	t            TestingT
+	expectations []*Expectation
+	next         int
+}
+
+var _ httd.Requester = (*Mock)(nil)
+
+// New creates an empty Mock. Unexpected requests are reported to t.
+func New(t TestingT) *Mock {
+	return &Mock{t: t}
+}
+
+// Expect adds a request that must be sent after the previously expected requests. The endpoint is the path
+// after the API version, including the query string, eg. "/channels/1/messages?limit=50". The response
+// defaults to 200 OK with an empty JSON object.
+func (m *Mock) Expect(method, endpoint string) *Expectation {
+	e := &Expectation{
+		method:   method,
+		endpoint: endpoint,
+		status:   http.StatusOK,
+		header:   make(http.Header),
+		body:     []byte(`{}`),
+	}
+
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// Do responds to the request with the next Expectation
+func (m *Mock) Do(ctx context.Context, req *Request) (*http.Response, []byte, error) {
+	method := req.Method.String()
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	m.mu.Lock()
+	if m.next >= len(m.expectations) {
+		m.mu.Unlock()
+		m.t.Helper()
+		m.t.Errorf("httdtesting: unexpected request %s %s", method, req.Endpoint)
+		return nil, nil, errors.New("httdtesting: unexpected request " + method + " " + req.Endpoint)
+	}
+	e := m.expectations[m.next]
+	m.next++
+	e.called = true
+	m.mu.Unlock()
+
+	if e.method != method || e.endpoint != req.Endpoint {
+		m.t.Helper()
+		m.t.Errorf("httdtesting: expected request %s %s, got %s %s", e.method, e.endpoint, method, req.Endpoint)
+		return nil, nil, errors.New("httdtesting: unexpected request " + method + " " + req.Endpoint)
+	}
+
+	if e.do != nil {
+		e.do(ctx, req)
+	}
+	if e.err != nil {
+		return nil, nil, e.err
+	}
+
+	header := e.header.Clone()
+	if header.Get(httd.ContentType) == "" && len(e.body) > 0 {
+		header.Set(httd.ContentType, httd.ContentTypeJSON)
+	}
+	resp := &http.Response{
+		Status:     strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode: e.status,
+		Header:     header,
+		Request:    &http.Request{Method: method},
+	}
+
+	if e.status < 200 || e.status >= 300 {
+		restErr := &httd.ErrREST{
+			Msg:      "response was not within the successful http code range [200, 300). code: " + strconv.Itoa(e.status),
+			HTTPCode: e.status,
+			Attempts: 1,
+			Method:   method,
+			Endpoint: httd.RedactEndpoint(req.Endpoint),
+		}
+		if len(e.body) > 0 {
+			_ = json.Unmarshal(e.body, restErr)
+		}
+		return nil, nil, restErr
+	}
+
+	body := make([]byte, len(e.body))
+	copy(body, e.body)
+	return resp, body, nil
+}
+
+// AssertExpectations reports the expected requests that were never sent
+func (m *Mock) AssertExpectations() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.expectations {
+		if !e.called {
+			m.t.Helper()
+			m.t.Errorf("httdtesting: expected request %s %s was never sent", e.method, e.endpoint)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// +build !integration
+
+package httdtesting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/andersfylling/disgord/internal/httd"
+)
+
+// recorder collects the reported errors, such that failures can be tested
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMock(t *testing.T) {
+	rec := &recorder{}
+	mock := New(rec)
+	mock.Expect("GET", "/channels/1").RespondJSON(http.StatusOK, map[string]string{"id": "1"}).WithHeader("X-Test", "yes")
+	mock.Expect("DELETE", "/channels/1").Respond(http.StatusForbidden, `{"code":50013,"message":"Missing Permissions"}`)
+	mock.Expect("GET", "/gateway").Fail(errors.New("connection reset"))
+	mock.Expect("GET", "/never")
+
+	resp, body, err := mock.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id":"1"}` || resp.Header.Get("X-Test") != "yes" || resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected response %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+
+	_, _, err = mock.Do(context.Background(), &Request{Method: httd.MethodDelete, Endpoint: "/channels/1"})
+	if !errors.Is(err, httd.ErrForbidden) || !httd.IsMissingPermissions(err) {
+		t.Errorf("expected a missing permissions error, got %v", err)
+	}
+
+	if _, _, err = mock.Do(context.Background(), &Request{Endpoint: "/channels/2"}); err == nil {
+		t.Error("expected an error for an unexpected request")
+	}
+	if len(rec.errors) != 1 {
+		t.Errorf("expected the unexpected request to be reported, got %q", rec.errors)
+	}
+
+	mock.AssertExpectations()
+	if len(rec.errors) != 2 {
+		t.Errorf("expected the missing request to be reported, got %q", rec.errors)
+	}
+}
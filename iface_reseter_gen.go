@@ -121,6 +121,9 @@ func (m *Message) Reset() {
 	m.Activity = MessageActivity{}
 	m.Application = MessageApplication{}
 	m.MessageReference = nil
+	if m.ReferencedMessage != nil {
+		m.ReferencedMessage.Reset()
+	}
 	m.Flags = 0
 	m.GuildID = 0
 	m.SpoilerTagContent = false
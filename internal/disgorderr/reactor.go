@@ -11,6 +11,7 @@ const (
 	HandlerSpecErrCodeUnexpectedCtrl
 	HandlerSpecErrCodeNotHandlerCtrlImpl
 	HandlerSpecErrCodeUnknownHandlerSignature
+	HandlerSpecErrCodeEventMismatch
 )
 
 func NewHandlerSpecErr(code uint8, info string) error {
@@ -52,6 +52,11 @@ func ChannelPins(channelID fmt.Stringer) string {
 	return Channel(channelID) + pins
 }
 
+// ChannelFollowers /channels/{channel.id}/followers
+func ChannelFollowers(channelID fmt.Stringer) string {
+	return Channel(channelID) + followers
+}
+
 // ChannelPin ...
 func ChannelPin(channelID, messageID fmt.Stringer) string {
 	return ChannelPins(channelID) + "/" + messageID.String()
@@ -10,6 +10,7 @@ const (
 	bulkDelete   = "/bulk-delete"
 	recipients   = "/recipients"
 	pins         = "/pins"
+	followers    = "/followers"
 	typing       = "/typing"
 	permissions  = "/permissions"
 	invites      = "/invites"
@@ -31,3 +31,8 @@ func ChannelWebhooks(id fmt.Stringer) string {
 func GuildWebhooks(id fmt.Stringer) string {
 	return Guild(id) + webhooks
 }
+
+// WebhookMessage /webhooks/{webhook.id}/{webhook.token}/messages/{message.id}
+func WebhookMessage(id fmt.Stringer, token string, messageID fmt.Stringer) string {
+	return WebhookToken(id, token) + messages + "/" + messageID.String()
+}
@@ -45,13 +45,14 @@ type discordErrListener = func(code int, reason string)
 // newClient ...
 func newClient(shardID uint, conf *config, connect connectSignature) (c *client, err error) {
 	var ws Conn
-	if conf.conn == nil {
-		ws, err = newConn(conf.HTTPClient)
-		if err != nil {
+	if conf.conn != nil {
+		ws = conf.conn
+	} else if conf.newConn != nil {
+		if ws, err = conf.newConn(); err != nil {
 			return nil, err
 		}
-	} else {
-		ws = conf.conn
+	} else if ws, err = NewConn(conf.HTTPClient); err != nil {
+		return nil, err
 	}
 
 	var queueLimit int
@@ -91,6 +92,9 @@ type config struct {
 	// for testing only
 	conn Conn
 
+	// newConn creates the websocket connection when set, instead of NewConn
+	newConn func() (Conn, error)
+
 	// Endpoint for establishing socket connection. Either endpoints, `Gateway` or `Gateway Bot`, is used to retrieve
 	// a valid socket endpoint from Discord
 	Endpoint string
@@ -104,6 +108,12 @@ type config struct {
 	// messageQueueLimit number of outgoing messages that can be queued and sent correctly.
 	messageQueueLimit uint
 
+	// stateChanges receives the connection state, if set
+	stateChanges chan<- *ShardStateChange
+
+	// maxReconnectAttempts is how many times to reconnect before giving up, or 0 to retry forever
+	maxReconnectAttempts uint
+
 	SystemShutdown chan interface{}
 }
 
@@ -121,7 +131,7 @@ type client struct {
 
 	pulsating          uint8
 	pulseMutex         sync.Mutex
-	heartbeatLatency   time.Duration
+	heartbeatLatencies [heartbeatSamples]time.Duration // latest first, see HeartbeatLatency
 	heartbeatInterval  uint
 	lastHeartbeatAck   time.Time
 	lastHeartbeatSent  time.Time
@@ -143,6 +153,9 @@ type client struct {
 	// is returned.
 	connect connectSignature
 
+	// invalidateSession forgets the session, such that the next connection identifies. Optional.
+	invalidateSession func()
+
 	// states
 	isConnected       atomic.Bool
 	haveConnectedOnce atomic.Bool
@@ -269,7 +282,9 @@ func (c *client) IsDisconnected() bool {
 	return !c.isConnected.Load()
 }
 
-func (c *client) disconnect() (err error) {
+// disconnect closes the connection. A resumable disconnect keeps the Discord session, such that it can be
+// resumed by the next connection.
+func (c *client) disconnect(resumable bool) (err error) {
 	c.Lock()
 	defer c.Unlock()
 	alreadyDisconnected := c.conn.Disconnected() || !c.haveConnectedOnce.Load() || c.cancel == nil
@@ -281,7 +296,11 @@ func (c *client) disconnect() (err error) {
 	}
 
 	// use the emitter to dispatch the close message
-	err = c.conn.Close()
+	if resumable {
+		err = c.conn.CloseResumable()
+	} else {
+		err = c.conn.Close()
+	}
 	// a typical err here is that the pipe is closed. Err is returned later
 
 	// c.Emit(event.Close, nil)
@@ -298,7 +317,7 @@ func (c *client) disconnect() (err error) {
 // Disconnect disconnects the socket connection
 func (c *client) Disconnect() (err error) {
 	c.requestedDisconnect.Store(true)
-	return c.disconnect()
+	return c.disconnect(false)
 }
 
 func (c *client) reconnect() (err error) {
@@ -309,7 +328,7 @@ func (c *client) reconnect() (err error) {
 	defer c.isReconnecting.Store(false)
 
 	c.log.Debug(c.getLogPrefix(), "is reconnecting")
-	if err := c.disconnect(); err != nil {
+	if err := c.disconnect(true); err != nil {
 		c.log.Debug(c.getLogPrefix(), "reconnecting failed: ", err.Error())
 		c.RLock()
 		if c.requestedDisconnect.Load() {
@@ -319,13 +338,32 @@ func (c *client) reconnect() (err error) {
 		}
 		c.RUnlock()
 	}
+	c.setState(ShardDisconnected, nil)
 
 	return c.reconnectLoop()
 }
 
+const (
+	reconnectMinDelay = 1 * time.Second
+	reconnectMaxDelay = 2 * time.Minute
+)
+
+// reconnectDelay doubles the delay for every failed attempt, up to reconnectMaxDelay. Up to a quarter of it
+// is random, such that shards that lost their connection at the same time do not reconnect at the same time.
+func reconnectDelay(try uint) time.Duration {
+	delay := reconnectMaxDelay
+	if try < 8 {
+		delay = reconnectMinDelay << try
+	}
+	if delay > reconnectMaxDelay {
+		delay = reconnectMaxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay / 4)))
+	return delay - jitter
+}
+
 func (c *client) reconnectLoop() (err error) {
 	var try uint
-	var delay = 3 * time.Second
 	for {
 		if try == 0 {
 			c.log.Debug(c.getLogPrefix(), "trying to connect")
@@ -337,23 +375,55 @@ func (c *client) reconnectLoop() (err error) {
 			break
 		}
 		c.log.Error(c.getLogPrefix(), "establishing connection failed: ", err)
-		c.log.Info(c.getLogPrefix(), "next connection attempt in ", delay)
 
-		// wait N seconds
+		if max := c.conf.maxReconnectAttempts; max > 0 && try+1 >= max {
+			err = fmt.Errorf("unable to connect after %d attempts: %w", try+1, err)
+			c.setState(ShardDisconnected, err)
+			return err
+		}
+
+		delay := reconnectDelay(try)
+		c.log.Info(c.getLogPrefix(), "next connection attempt in ", delay)
 		select {
 		case <-time.After(delay):
-			delay += (4 + time.Duration(try*2)) * time.Second
 		case <-c.SystemShutdown:
 			c.log.Debug(c.getLogPrefix(), "stopping reconnect attempt", try)
 			return
 		}
+		try++
+	}
+
+	return
+}
+
+// setState notifies the listener of state changes, if any. The state change is dropped if the listener
+// is not keeping up.
+func (c *client) setState(state ShardState, err error) {
+	if c.conf.stateChanges == nil {
+		return
+	}
+
+	select {
+	case c.conf.stateChanges <- &ShardStateChange{ShardID: c.ShardID, State: state, Err: err}:
+	default:
+		c.log.Debug(c.getLogPrefix(), "state change listener is full, dropped state", state)
+	}
+}
 
-		if delay > 5*60*time.Second {
-			delay = 60 * time.Second
+// unrecoverable reports whether the connection was closed for a reason that reconnecting can not fix
+func (c *client) unrecoverable(err *CloseErr) bool {
+	if c.clientType == clientTypeVoice {
+		switch err.code {
+		case 4006: // session no longer valid, a new one must be requested through the event gateway
+		case 4014: // disconnected, either the channel was deleted or you were kicked
+		default:
+			return false
 		}
+		return true
 	}
 
-	return
+	// the shard manager reshards, see ShardConfig.DisableAutoScaling
+	return err.Fatal() && err.code != CloseShardingRequired
 }
 
 //////////////////////////////////////////////////////
@@ -377,21 +447,12 @@ func (c *client) queueRequest(command string, data CmdPayload) (err error) {
 		return errors.New("unsupported command: " + command)
 	}
 
-	p := &clientPacket{
+	// the rate limit is checked once the command is written, see writeQueued
+	return c.messageQueue.Add(&clientPacket{
 		Op:      op,
 		Data:    data,
 		CmdName: command,
-	}
-	if accepted := c.ratelimit.Request(command); !accepted {
-		// we might be rate limited.. but lets see if there is another
-		// presence update in the queue; then it can be overwritten
-		if err := c.messageQueue.AddByOverwrite(p); err != nil {
-			return errors.New("rate limited")
-		} else {
-			return nil
-		}
-	}
-	return c.messageQueue.Add(p)
+	})
 }
 
 func (c *client) emit(command string, data interface{}) (err error) {
@@ -407,6 +468,29 @@ func (c *client) emit(command string, data interface{}) (err error) {
 	return nil
 }
 
+// writeQueued writes the oldest queued command that does not exceed the rate limit. On failure the command
+// is kept in the queue.
+func (c *client) writeQueued(write func(msg *clientPacket) error) error {
+	return c.messageQueue.Try(func(msg *clientPacket) error {
+		if err := c.ratelimit.Request(msg.CmdName); err != nil {
+			return err
+		}
+		return write(msg)
+	})
+}
+
+// writeInternal writes heartbeats, identify, resume and other commands sent by the client itself. They are
+// never delayed by the rate limit, but counted towards it.
+func (c *client) writeInternal(msg *clientPacket, write func(msg *clientPacket) error) error {
+	c.ratelimit.Record(msg.CmdName)
+	return write(msg)
+}
+
+// CommandUsage returns the number of gateway commands sent within the last 60 seconds, see CommandLimit
+func (c *client) CommandUsage() int {
+	return c.ratelimit.Usage()
+}
+
 // emitter holds the actually dispatching logic for sending data to the Discord Gateway.
 // client#Emit depends on this.
 func (c *client) emitter(ctx context.Context) {
@@ -448,12 +532,15 @@ func (c *client) emitter(ctx context.Context) {
 			if !c.messageQueue.IsEmpty() {
 				// try to write the message
 				// on failure the message is put back into the queue
-				err = c.messageQueue.Try(write)
+				err = c.writeQueued(write)
+				if errors.Is(err, errRateLimited) {
+					err = nil // try again later
+				}
 			}
 		case msg, open := <-c.internalEmitChan:
 			if !open {
 				err = errors.New("emitter channel is closed")
-			} else if err = write(msg); err != nil {
+			} else if err = c.writeInternal(msg, write); err != nil {
 				insight = fmt.Sprintf("%v", *msg)
 			}
 		}
@@ -519,15 +606,16 @@ func (c *client) receiver(ctx context.Context) {
 				if c.conf.discordErrListener != nil && closeErr.code >= 4000 && closeErr.code < 5000 {
 					go c.conf.discordErrListener(closeErr.code, closeErr.info)
 				}
-				switch closeErr.code {
-				case 4014:
-					// Disconnected: Either the channel was deleted or you were kicked. Should not reconnect.
-					// https://discord.com/developers/docs/topics/opcodes-and-status-codes#voice-voice-close-event-codes
-					c.log.Debug(c.getLogPrefix(), "discord sent a 4014 websocket code and the bot will now disconnect")
+				// https://discord.com/developers/docs/topics/opcodes-and-status-codes
+				if c.unrecoverable(closeErr) {
+					c.log.Error(c.getLogPrefix(), "discord sent a", closeErr.code, "websocket code and the bot will now disconnect:", closeErr)
 					_ = c.Disconnect()
 					close(c.receiveChan) // notify client
 					reconnect = false
-				default:
+					c.setState(ShardDisconnected, closeErr)
+				} else if !closeErr.Resumable() && c.invalidateSession != nil {
+					// identify right away, instead of having Discord reject the resume
+					c.invalidateSession()
 				}
 			}
 
@@ -618,11 +706,11 @@ func (c *client) prepareHeartbeating(ctx context.Context) {
 }
 
 func (c *client) pulsate(ctx context.Context) {
-	c.RLock()
+	c.Lock()
 	c.lastHeartbeatSent = time.Now()
-	c.lastHeartbeatAck = time.Now()
+	c.lastHeartbeatAck = c.lastHeartbeatSent
 	interval := time.Millisecond * time.Duration(c.heartbeatInterval)
-	c.RUnlock()
+	c.Unlock()
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -635,18 +723,23 @@ func (c *client) pulsate(ctx context.Context) {
 		lastSent = c.lastHeartbeatSent
 		c.RUnlock()
 
-		// make sure that Discord replied to the last heartbeat signal (heartbeat ack)
+		// make sure that Discord replied to the last heartbeat signal (heartbeat ack). Otherwise the
+		// connection is a zombie, and is closed such that the session can be resumed on a new one.
 		if lastSent.After(lastAck) {
 			c.log.Info(c.getLogPrefix(), "heartbeat ACK was not received, forcing reconnect")
 			go c.reconnect()
 			break
+		} else if c.conn.Inactive() {
+			// the Conn can tell that the connection is dead before a heartbeat ACK is missed
+			c.log.Info(c.getLogPrefix(), "nothing received since", c.conn.InactiveSince(), "forcing reconnect")
+			go c.reconnect()
+			break
 		} else {
 			c.log.Debug(c.getLogPrefix(), "heartbeat ACK ok")
 		}
 
-		// update heartbeat latency record & send new heartbeat signal
+		// send new heartbeat signal
 		c.Lock()
-		c.heartbeatLatency = lastAck.Sub(lastSent)
 		c.lastHeartbeatSent = time.Now()
 		c.Unlock()
 		if err := c.behaviors[heartbeating].actions[sendHeartbeat](nil); err != nil {
@@ -675,15 +768,40 @@ func (c *client) pulsate(ctx context.Context) {
 	c.log.Debug(c.getLogPrefix(), "stopping pulse")
 }
 
-// HeartbeatLatency get the time diff between sending a heartbeat and Discord replying with a heartbeat ack
+// heartbeatSamples is the number of heartbeats the latency is averaged over
+const heartbeatSamples = 5
+
+// heartbeatAcked records that Discord replied to a heartbeat, and the latency if it replied to the latest
+// heartbeat sent by pulsate.
+func (c *client) heartbeatAcked() {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if c.lastHeartbeatSent.After(c.lastHeartbeatAck) {
+		copy(c.heartbeatLatencies[1:], c.heartbeatLatencies[:heartbeatSamples-1])
+		c.heartbeatLatencies[0] = now.Sub(c.lastHeartbeatSent)
+	}
+	c.lastHeartbeatAck = now
+}
+
+// HeartbeatLatency get the time diff between sending a heartbeat and Discord replying with a heartbeat ack,
+// averaged over the last few heartbeats
 func (c *client) HeartbeatLatency() (duration time.Duration, err error) {
 	c.RLock()
 	defer c.RUnlock()
 
-	duration = c.heartbeatLatency
-	if duration == 0 {
-		err = errors.New("latency not determined yet")
+	var samples time.Duration
+	for _, latency := range c.heartbeatLatencies {
+		if latency == 0 {
+			break
+		}
+		duration += latency
+		samples++
+	}
+	if samples == 0 {
+		return 0, errors.New("latency not determined yet")
 	}
 
-	return
+	return duration / samples, nil
 }
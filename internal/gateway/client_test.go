@@ -0,0 +1,285 @@
+// +build !integration
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/internal/logger"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	var previous time.Duration
+	for try := uint(0); try < 20; try++ {
+		delay := reconnectDelay(try)
+		if delay > reconnectMaxDelay {
+			t.Fatalf("attempt %d: expected the delay to be capped at %s, got %s", try, reconnectMaxDelay, delay)
+		}
+		if delay < reconnectMinDelay*3/4 {
+			t.Fatalf("attempt %d: expected a delay of at least %s, got %s", try, reconnectMinDelay*3/4, delay)
+		}
+		if try > 0 && try < 7 && delay <= previous {
+			t.Errorf("attempt %d: expected the delay to grow, got %s after %s", try, delay, previous)
+		}
+		previous = delay
+	}
+}
+
+func TestClient_unrecoverable(t *testing.T) {
+	evt := &client{clientType: clientTypeEvent}
+	voice := &client{clientType: clientTypeVoice}
+
+	for _, code := range []int{4004, 4010, 4012, 4013, 4014} {
+		if !evt.unrecoverable(&CloseErr{code: code}) {
+			t.Errorf("expected close code %d to stop the event client", code)
+		}
+	}
+	for _, code := range []int{4000, 4007, 4008, 4009, 4011} {
+		if evt.unrecoverable(&CloseErr{code: code}) {
+			t.Errorf("expected the event client to reconnect after close code %d", code)
+		}
+	}
+	if !voice.unrecoverable(&CloseErr{code: 4006}) || !voice.unrecoverable(&CloseErr{code: 4014}) ||
+		voice.unrecoverable(&CloseErr{code: 4009}) {
+		t.Error("unexpected voice close codes")
+	}
+}
+
+func TestClient_reconnectLoop_maxAttempts(t *testing.T) {
+	states := make(chan *ShardStateChange, 1)
+	var attempts uint
+	c, err := newClient(3, &config{
+		Logger:               &logger.Empty{},
+		conn:                 &testWS{},
+		stateChanges:         states,
+		maxReconnectAttempts: 2,
+	}, func() (interface{}, error) {
+		attempts++
+		return nil, errors.New("bad gateway")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.reconnectLoop(); err == nil {
+		t.Fatal("expected reconnecting to give up")
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+
+	select {
+	case state := <-states:
+		if state.ShardID != 3 || state.State != ShardDisconnected || state.Err == nil {
+			t.Errorf("expected shard 3 to be disconnected for good, got %+v", state)
+		}
+	default:
+		t.Error("expected a state change")
+	}
+}
+
+// silentConn is a connection to a gateway that never replies, and records how it was closed
+type silentConn struct {
+	closed   chan string
+	inactive bool
+}
+
+func (g *silentConn) Open(ctx context.Context, endpoint string, requestHeader http.Header) error {
+	return nil
+}
+
+func (g *silentConn) WriteJSON(v interface{}) error {
+	return nil
+}
+
+func (g *silentConn) Close() error {
+	g.closed <- "close"
+	return nil
+}
+
+func (g *silentConn) CloseResumable() error {
+	g.closed <- "resumable"
+	return nil
+}
+
+func (g *silentConn) Read(ctx context.Context) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (g *silentConn) Disconnected() bool {
+	return false
+}
+
+func (g *silentConn) Inactive() bool {
+	return g.inactive
+}
+
+func (g *silentConn) InactiveSince() time.Time {
+	return time.Now()
+}
+
+var _ Conn = (*silentConn)(nil)
+
+func TestClient_pulsate_zombie(t *testing.T) {
+	conn := &silentConn{closed: make(chan string, 1)}
+	reconnected := make(chan struct{}, 1)
+	c, err := newClient(0, &config{
+		Logger: &logger.Empty{},
+		conn:   conn,
+	}, func() (interface{}, error) {
+		reconnected <- struct{}{}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.haveConnectedOnce.Store(true)
+	c.isConnected.Store(true)
+
+	heartbeats := make(chan struct{}, 10)
+	c.addBehavior(&behavior{
+		addresses: heartbeating,
+		actions: behaviorActions{
+			sendHeartbeat: func(interface{}) error {
+				heartbeats <- struct{}{} // the ACK is withheld
+				return nil
+			},
+		},
+	})
+	c.heartbeatInterval = 20
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.pulsate(ctx)
+
+	select {
+	case how := <-conn.closed:
+		if how != "resumable" {
+			t.Errorf("expected the zombie connection to be closed such that the session can be resumed, got %s", how)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the zombie connection was never closed")
+	}
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reconnect")
+	}
+	if len(heartbeats) != 1 {
+		t.Errorf("expected a single unanswered heartbeat, got %d", len(heartbeats))
+	}
+}
+
+func TestClient_pulsate_inactive(t *testing.T) {
+	conn := &silentConn{closed: make(chan string, 1), inactive: true}
+	reconnected := make(chan struct{}, 1)
+	c, err := newClient(0, &config{
+		Logger: &logger.Empty{},
+		conn:   conn,
+	}, func() (interface{}, error) {
+		reconnected <- struct{}{}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.haveConnectedOnce.Store(true)
+	c.isConnected.Store(true)
+
+	heartbeats := make(chan struct{}, 10)
+	c.addBehavior(&behavior{
+		addresses: heartbeating,
+		actions: behaviorActions{
+			sendHeartbeat: func(interface{}) error {
+				heartbeats <- struct{}{}
+				c.heartbeatAcked()
+				return nil
+			},
+		},
+	})
+	c.heartbeatInterval = 20
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go c.pulsate(ctx)
+
+	select {
+	case how := <-conn.closed:
+		if how != "resumable" {
+			t.Errorf("expected the inactive connection to be closed such that the session can be resumed, got %s", how)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the inactive connection was never closed")
+	}
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reconnect")
+	}
+	if len(heartbeats) != 0 {
+		t.Errorf("expected no heartbeats on an inactive connection, got %d", len(heartbeats))
+	}
+}
+
+func TestNewClient_newConn(t *testing.T) {
+	conn := &silentConn{}
+	c, err := newClient(0, &config{
+		Logger: &logger.Empty{},
+		newConn: func() (Conn, error) {
+			return conn, nil
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.conn != conn {
+		t.Error("expected the connection of newConn")
+	}
+
+	if _, err = newClient(0, &config{
+		Logger: &logger.Empty{},
+		newConn: func() (Conn, error) {
+			return nil, errors.New("unsupported")
+		},
+	}, nil); err == nil {
+		t.Error("expected the error of newConn")
+	}
+}
+
+func TestClient_HeartbeatLatency(t *testing.T) {
+	c := &client{}
+	if _, err := c.HeartbeatLatency(); err == nil {
+		t.Error("expected no latency before the first heartbeat ACK")
+	}
+
+	ack := func(latency time.Duration) {
+		c.lastHeartbeatAck = time.Now().Add(-time.Minute)
+		c.lastHeartbeatSent = time.Now().Add(-latency)
+		c.heartbeatAcked()
+	}
+	ack(100 * time.Millisecond)
+	ack(300 * time.Millisecond)
+
+	// an ACK to a heartbeat requested by Discord is not a new sample
+	c.heartbeatAcked()
+
+	latency, err := c.HeartbeatLatency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latency < 200*time.Millisecond || latency > 250*time.Millisecond {
+		t.Errorf("expected an average latency of about 200ms, got %s", latency)
+	}
+
+	for i := 0; i < heartbeatSamples; i++ {
+		ack(50 * time.Millisecond)
+	}
+	if latency, _ = c.HeartbeatLatency(); latency > 100*time.Millisecond {
+		t.Errorf("expected the old samples to be dropped, got %s", latency)
+	}
+}
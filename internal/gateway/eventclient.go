@@ -43,14 +43,19 @@ func NewEventClient(shardID uint, conf *EvtConfig) (client *EvtClient, err error
 		DiscordPktPool:    conf.DiscordPktPool,
 		HTTPClient:        conf.HTTPClient,
 		conn:              conf.conn,
+		newConn:           conf.NewConn,
 		messageQueueLimit: conf.MessageQueueLimit,
 
+		stateChanges:         conf.StateChanges,
+		maxReconnectAttempts: conf.MaxReconnectAttempts,
+
 		SystemShutdown: conf.SystemShutdown,
 	}, client.internalConnect)
 	if err != nil {
 		return nil, err
 	}
 	client.setupBehaviors()
+	client.invalidateSession = client.resetSession
 
 	client.identity = &evtIdentity{
 		Token: conf.BotToken,
@@ -69,10 +74,21 @@ func NewEventClient(shardID uint, conf *EvtConfig) (client *EvtClient, err error
 			return nil, err
 		}
 	}
+	if conf.Session != nil {
+		client.sessionID = conf.Session.ID
+		client.sequenceNumber.Store(conf.Session.Sequence)
+	}
 
 	return
 }
 
+// Session is the gateway session of a shard. It can be resumed by another process, as long as Discord has
+// not invalidated it, such that the events missed during a restart are replayed instead of lost.
+type Session struct {
+	ID       string `json:"session_id"`
+	Sequence uint32 `json:"seq"`
+}
+
 // Event is dispatched by the socket layer after parsing and extracting Discord data from a incoming packet.
 // This is the data structure used by Disgord for triggering handlers and channels with an event.
 type Event struct {
@@ -90,6 +106,9 @@ type EvtConfig struct {
 	// for testing only
 	conn Conn
 
+	// NewConn creates the websocket connection, defaults to NewConn
+	NewConn func() (Conn, error)
+
 	// IgnoreEvents holds a list of predetermined events that should be ignored.
 	IgnoreEvents []string
 
@@ -103,8 +122,18 @@ type EvtConfig struct {
 
 	discordErrListener discordErrListener
 
+	// StateChanges receives the connection state, see ShardConfig.StateChanges
+	StateChanges chan<- *ShardStateChange
+
+	// MaxReconnectAttempts is how many times to reconnect before giving up, or 0 to retry forever
+	MaxReconnectAttempts uint
+
 	Presence *UpdateStatusPayload
 
+	// Session is resumed on the first connect instead of identifying. When Discord no longer knows the session,
+	// a new one is identified.
+	Session *Session
+
 	// Endpoint for establishing socket connection. Either endpoints, `Gateway` or `Gateway Bot`, is used to retrieve
 	// a valid socket endpoint from Discord
 	Endpoint string
@@ -156,6 +185,7 @@ type EvtClient struct {
 	rdyPool *sync.Pool
 
 	identity *evtIdentity
+	presence CmdPayload // sent again after a resume, as identify packets hold it otherwise
 	idMu     sync.RWMutex
 }
 
@@ -167,11 +197,22 @@ func (c *EvtClient) SetPresence(data interface{}) (err error) {
 	}
 	c.idMu.Lock()
 	c.identity.Presence = presence
+	c.presence, _ = data.(CmdPayload)
 	c.idMu.Unlock()
 
 	return nil
 }
 
+// Session returns the current session, such that it can be stored and resumed later with EvtConfig.Session.
+// The ID is empty until the shard is ready.
+func (c *EvtClient) Session() Session {
+	c.RLock()
+	id := c.sessionID
+	c.RUnlock()
+
+	return Session{ID: id, Sequence: c.sequenceNumber.Load()}
+}
+
 func (c *EvtClient) Emit(command string, data CmdPayload) (err error) {
 	if command == cmd.UpdateStatus {
 		if err = c.SetPresence(data); err != nil {
@@ -240,6 +281,14 @@ func (c *EvtClient) synchronizeSnr(p *DiscordPacket) (err error) {
 	return nil
 }
 
+// resetSession forgets the session, such that the next hello is answered with an identify
+func (c *EvtClient) resetSession() {
+	c.Lock()
+	c.sessionID = ""
+	c.Unlock()
+	c.sequenceNumber.Store(0)
+}
+
 func (c *EvtClient) virginConnection() bool {
 	return c.sessionID == "" && c.sequenceNumber.Load() == 0
 }
@@ -276,6 +325,10 @@ func (c *EvtClient) onDiscordEvent(v interface{}) (err error) {
 		if err = c.onReady(p); err != nil {
 			return err
 		}
+		c.setState(ShardReady, nil)
+	} else if p.EventName == event.Resumed {
+		c.resendPresence()
+		c.setState(ShardReady, nil)
 	}
 	//} else if p.EventName == event.Resumed {
 	//	if ch := c.onceChannels.Acquire(opcode.EventReadyResumed); ch != nil {
@@ -299,15 +352,26 @@ func (c *EvtClient) onDiscordEvent(v interface{}) (err error) {
 	return nil
 } // end onDiscordEvent
 
+// resendPresence queues the last presence update, such that it survives a resumed session
+func (c *EvtClient) resendPresence() {
+	c.idMu.RLock()
+	presence := c.presence
+	c.idMu.RUnlock()
+	if presence == nil {
+		return
+	}
+
+	if err := c.client.queueRequest(cmd.UpdateStatus, presence); err != nil {
+		c.log.Error(c.getLogPrefix(), "unable to restore the presence after resuming: ", err)
+	}
+}
+
 func (c *EvtClient) onHeartbeatRequest(v interface{}) error {
 	return c.sendHeartbeat(v)
 }
 
 func (c *EvtClient) onHeartbeatAck(v interface{}) error {
-	c.Lock()
-	c.lastHeartbeatAck = time.Now()
-	c.Unlock()
-
+	c.heartbeatAcked()
 	return nil
 }
 
@@ -335,11 +399,21 @@ func (c *EvtClient) onHello(v interface{}) error {
 }
 
 func (c *EvtClient) onSessionInvalidated(v interface{}) error {
-	// invalid session. Must respond with a identify packet
-	c.log.Info(c.getLogPrefix(), "Discord invalidated session")
+	p := v.(*DiscordPacket)
 
-	// session is invalidated, reset the sequence number
-	c.sequenceNumber.Store(0)
+	// the data tells whether the session can be resumed
+	var resumable bool
+	_ = json.Unmarshal(p.Data, &resumable)
+
+	if resumable {
+		c.log.Info(c.getLogPrefix(), "Discord invalidated session, resuming")
+	} else {
+		// invalid session. Must respond with a identify packet
+		c.log.Info(c.getLogPrefix(), "Discord invalidated session")
+
+		// session is invalidated, reset the session
+		c.resetSession()
+	}
 
 	rand.Seed(time.Now().UnixNano())
 	delay := rand.Intn(4) + 1
@@ -356,6 +430,9 @@ func (c *EvtClient) onSessionInvalidated(v interface{}) error {
 		return errors.New("system is shutting down")
 	}
 
+	if resumable {
+		return c.sendResumePacket()
+	}
 	return sendIdentityPacket(true, c)
 }
 
@@ -366,9 +443,11 @@ func (c *EvtClient) onSessionInvalidated(v interface{}) error {
 //////////////////////////////////////////////////////
 
 func (c *EvtClient) sendHeartbeat(i interface{}) error {
-	c.RLock()
-	snr := c.sequenceNumber
-	c.RUnlock()
+	// the sequence number is null until the first dispatch was received
+	var snr *uint32
+	if sequence := c.sequenceNumber.Load(); sequence > 0 {
+		snr = &sequence
+	}
 
 	return c.emit(event.Heartbeat, snr)
 }
@@ -460,15 +539,19 @@ func (c *EvtClient) eventOfInterest(name string) bool {
 	return true
 }
 
-func (c *EvtClient) sendHelloPacket() {
+func (c *EvtClient) sendResumePacket() error {
 	c.RLock()
 	token := c.evtConf.BotToken
 	session := c.sessionID
 	c.RUnlock()
 	sequence := c.sequenceNumber.Load()
 
-	err := c.emit(event.Resume, &evtResume{token, session, sequence})
-	if err != nil {
+	c.setState(ShardResuming, nil)
+	return c.emit(event.Resume, &evtResume{token, session, sequence})
+}
+
+func (c *EvtClient) sendHelloPacket() {
+	if err := c.sendResumePacket(); err != nil {
 		c.log.Error(c.getLogPrefix(), err)
 	}
 
@@ -46,6 +46,10 @@ func (g *testWS) Close() (err error) {
 	return
 }
 
+func (g *testWS) CloseResumable() (err error) {
+	return g.Close()
+}
+
 func (g *testWS) Read(ctx context.Context) (packet []byte, err error) {
 loop:
 	for {
@@ -71,6 +75,14 @@ func (g *testWS) Disconnected() bool {
 	return !g.isConnected.Load()
 }
 
+func (g *testWS) Inactive() bool {
+	return false
+}
+
+func (g *testWS) InactiveSince() time.Time {
+	return time.Now()
+}
+
 var _ Conn = (*testWS)(nil)
 
 func TestEvtIdentify(t *testing.T) {
@@ -295,3 +307,126 @@ func TestEvtClient_communication(t *testing.T) {
 
 	<-time.After(10 * time.Millisecond)
 }
+
+func newTestEvtClient(t *testing.T, session *Session) *EvtClient {
+	c, err := NewEventClient(0, &EvtConfig{
+		BotToken:       "testing",
+		Logger:         &logger.FmtPrinter{},
+		EventChan:      make(chan *Event),
+		SystemShutdown: make(chan interface{}),
+		Session:        session,
+		conn:           &testWS{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.timeoutMultiplier = 0
+	c.haveConnectedOnce.Store(true)
+	return c
+}
+
+func emitted(t *testing.T, c *EvtClient) string {
+	t.Helper()
+	select {
+	case p := <-c.internalEmitChan:
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	default:
+		t.Fatal("nothing was emitted")
+		return ""
+	}
+}
+
+func TestEvtClient_sendHeartbeat(t *testing.T) {
+	c := newTestEvtClient(t, nil)
+
+	if err := c.sendHeartbeat(nil); err != nil {
+		t.Fatal(err)
+	}
+	if packet := emitted(t, c); packet != `{"op":1,"d":null}` {
+		t.Errorf("expected a null sequence before the first dispatch, got %s", packet)
+	}
+
+	c.sequenceNumber.Store(42)
+	if err := c.sendHeartbeat(nil); err != nil {
+		t.Fatal(err)
+	}
+	if packet := emitted(t, c); packet != `{"op":1,"d":42}` {
+		t.Errorf("expected the last sequence number, got %s", packet)
+	}
+}
+
+func TestEvtClient_Session(t *testing.T) {
+	c := newTestEvtClient(t, &Session{ID: "abc", Sequence: 7})
+	if session := c.Session(); session.ID != "abc" || session.Sequence != 7 {
+		t.Errorf("expected the configured session, got %+v", session)
+	}
+	if c.virginConnection() {
+		t.Error("expected the configured session to be resumed")
+	}
+}
+
+func TestEvtClient_onSessionInvalidated(t *testing.T) {
+	t.Run("resumable", func(t *testing.T) {
+		c := newTestEvtClient(t, &Session{ID: "abc", Sequence: 7})
+		if err := c.onSessionInvalidated(&DiscordPacket{Data: []byte(`true`)}); err != nil {
+			t.Fatal(err)
+		}
+		if packet := emitted(t, c); packet != `{"op":6,"d":{"token":"testing","session_id":"abc","seq":7}}` {
+			t.Errorf("expected a resume, got %s", packet)
+		}
+	})
+	t.Run("not resumable", func(t *testing.T) {
+		c := newTestEvtClient(t, &Session{ID: "abc", Sequence: 7})
+		if err := c.onSessionInvalidated(&DiscordPacket{Data: []byte(`false`)}); err != nil {
+			t.Fatal(err)
+		}
+		var packet clientPacket
+		if err := json.Unmarshal([]byte(emitted(t, c)), &packet); err != nil || packet.Op != opcode.EventIdentify {
+			t.Errorf("expected an identify, got %+v", packet)
+		}
+		if session := c.Session(); session != (Session{}) {
+			t.Errorf("expected the session to be reset, got %+v", session)
+		}
+	})
+}
+
+func TestEvtClient_resendPresence(t *testing.T) {
+	c := newTestEvtClient(t, nil)
+	c.resendPresence()
+	if !c.messageQueue.IsEmpty() {
+		t.Fatal("expected nothing to be sent without a presence")
+	}
+
+	if err := c.SetPresence(&UpdateStatusPayload{Status: StatusIdle}); err != nil {
+		t.Fatal(err)
+	}
+	c.resendPresence()
+	if c.messageQueue.IsEmpty() {
+		t.Error("expected the presence to be sent again")
+	}
+}
+
+func TestEvtClient_stateChanges(t *testing.T) {
+	c := newTestEvtClient(t, &Session{ID: "abc", Sequence: 5})
+	states := make(chan *ShardStateChange, 2)
+	c.conf.stateChanges = states
+
+	if err := c.sendResumePacket(); err != nil {
+		t.Fatal(err)
+	}
+	_ = emitted(t, c)
+	if state := <-states; state.State != ShardResuming {
+		t.Errorf("expected the shard to be resuming, got %s", state.State)
+	}
+
+	c.setState(ShardReady, nil)
+	c.setState(ShardReady, nil)
+	c.setState(ShardReady, nil) // dropped, the listener is full
+	if len(states) != 2 {
+		t.Errorf("expected the state changes to fill the listener, got %d", len(states))
+	}
+}
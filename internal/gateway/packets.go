@@ -165,7 +165,7 @@ const (
 
 func StringToStatusType(status string) (updateStatusPayloadStatus, error) {
 	switch updateStatusPayloadStatus(status) {
-	case StatusOnline, StatusIdle, StatusOffline, StatusDND:
+	case StatusOnline, StatusIdle, StatusOffline, StatusDND, StatusInvisible:
 		return updateStatusPayloadStatus(status), nil
 	case "": // default value
 		return StatusOnline, nil
@@ -181,6 +181,9 @@ type UpdateStatusPayload struct {
 	// Game null, or the user's new activity
 	Game interface{} `json:"game"`
 
+	// Activities the user's new activities
+	Activities interface{} `json:"activities,omitempty"`
+
 	// Status the user's new status
 	Status updateStatusPayloadStatus `json:"status"`
 
@@ -208,9 +211,10 @@ type GatewayBot struct {
 	Gateway
 	Shards            uint `json:"shards"`
 	SessionStartLimit struct {
-		Total      uint `json:"total"`
-		Remaining  uint `json:"remaining"`
-		ResetAfter uint `json:"reset_after"`
+		Total          uint `json:"total"`
+		Remaining      uint `json:"remaining"`
+		ResetAfter     uint `json:"reset_after"`
+		MaxConcurrency uint `json:"max_concurrency"`
 	} `json:"session_start_limit"`
 }
 
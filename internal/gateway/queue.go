@@ -60,24 +60,30 @@ func (c *clientPktQueue) Add(msg *clientPacket) error {
 	return nil
 }
 
-func (c *clientPktQueue) Try(cb func(msg *clientPacket) error) error {
+// Try passes the oldest entry to cb, and removes it unless cb fails. When cb returns errCommandRateLimited the
+// entry is kept and the next one is tried instead, such that a rate limited command does not hold back the
+// commands behind it. Entries of the same command are still written in order.
+func (c *clientPktQueue) Try(cb func(msg *clientPacket) error) (err error) {
 	c.Lock()
 	defer c.Unlock()
 	if len(c.messages) == 0 {
 		return nil // nothing to try, this avoid a potential race as well
 	}
 
-	next := c.messages[0]
-	if err := cb(next); err != nil {
-		return err
-	}
+	for i := range c.messages {
+		if err = cb(c.messages[i]); errors.Is(err, errCommandRateLimited) {
+			continue
+		} else if err != nil {
+			return err
+		}
 
-	// shift to avoid re-allocations
-	for i := 0; i < len(c.messages)-1; i++ {
-		c.messages[i] = c.messages[i+1]
+		// shift to avoid re-allocations
+		copy(c.messages[i:], c.messages[i+1:])
+		c.messages[len(c.messages)-1] = nil
+		c.messages = c.messages[:len(c.messages)-1]
+		return nil
 	}
-	c.messages = c.messages[:len(c.messages)-1]
-	return nil
+	return err
 }
 
 func (c *clientPktQueue) Steal() (m []*clientPacket) {
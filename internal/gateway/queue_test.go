@@ -126,4 +126,29 @@ func TestClientPktQueue_Try(t *testing.T) {
 	if len(q.messages) != 2 {
 		t.Error("the number of entries in the queue should reduce after Try execution")
 	}
+
+	// rate limited commands are skipped
+	q.Steal()
+	_ = q.Add(&clientPacket{CmdName: "a"})
+	_ = q.Add(&clientPacket{CmdName: "b"})
+	_ = q.Add(&clientPacket{CmdName: "c"})
+	skipA := func(msg *clientPacket) error {
+		if msg.CmdName == "a" {
+			return errCommandRateLimited
+		}
+		return nil
+	}
+	if err := q.Try(skipA); err != nil {
+		t.Error("Try should not have failed", err)
+	}
+	if len(q.messages) != 2 || q.messages[0].CmdName != "a" || q.messages[1].CmdName != "c" {
+		t.Error("expected the entry after the rate limited one to be removed")
+	}
+	_ = q.Try(skipA)
+	if err := q.Try(skipA); err != errCommandRateLimited {
+		t.Error("expected Try to fail when every entry is rate limited", err)
+	}
+	if len(q.messages) != 1 {
+		t.Error("the rate limited entry should be kept")
+	}
 }
@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -57,10 +59,26 @@ func (s *UniqueStringSlice) Remove(id string) {
 	}
 }
 
+const (
+	// CommandLimit is how many gateway commands, heartbeats included, Discord accepts per 60 seconds before
+	// the connection is closed with close code 4008
+	CommandLimit = 120
+
+	// reservedCommands are kept free of queued commands, such that heartbeats, identify and resume can
+	// always be sent without exceeding CommandLimit
+	reservedCommands = 10
+)
+
+var errRateLimited = errors.New("rate limited")
+
+// errCommandRateLimited is returned when only the bucket of the command is limited, such that other commands
+// can still be written
+var errCommandRateLimited = fmt.Errorf("command %w", errRateLimited)
+
 func newRatelimiter() ratelimiter {
 	rl := ratelimiter{
 		buckets: map[string]rlBucket{},
-		global:  newRatelimitBucket(120, 60),
+		global:  newRatelimitBucket(CommandLimit, 60),
 	}
 	rl.buckets[cmd.UpdateStatus] = newRatelimitBucket(5, 60)
 
@@ -80,17 +98,32 @@ func newRatelimitBucketNano(requests, nano int64) rlBucket {
 	return rlBucket{
 		entries:  make([]rlEntry, requests),
 		duration: (time.Duration(nano) * time.Nanosecond).Nanoseconds(),
+		now:      time.Now,
 	}
 }
 
+// rlBucket is a sliding window of the latest requests, newest first
 type rlBucket struct {
 	entries  []rlEntry
 	duration int64
+	now      func() time.Time
 }
 
 func (b *rlBucket) Blocked() bool {
 	last := b.entries[len(b.entries)-1]
-	return time.Now().UnixNano()-last.unix <= b.duration
+	return b.now().UnixNano()-last.unix <= b.duration
+}
+
+// Count returns the number of requests within the window
+func (b *rlBucket) Count() (n int) {
+	now := b.now().UnixNano()
+	for i := range b.entries {
+		if now-b.entries[i].unix > b.duration {
+			break
+		}
+		n++
+	}
+	return n
 }
 
 func (b *rlBucket) Insert(cmd string) {
@@ -100,34 +133,54 @@ func (b *rlBucket) Insert(cmd string) {
 		b.entries[i] = b.entries[i-1]
 	}
 	b.entries[0] = rlEntry{
-		unix: time.Now().UnixNano(),
+		unix: b.now().UnixNano(),
 		cmd:  cmd,
 	}
 }
 
+// ratelimiter keeps the gateway commands written to a connection below the limits of Discord
 type ratelimiter struct {
 	sync.RWMutex
 	buckets map[string]rlBucket
 	global  rlBucket
 }
 
-func (rl *ratelimiter) Request(command string) (accepted bool) {
+// Request counts a queued command if it can be written now. Otherwise errRateLimited is returned when the
+// global limit is reached, and errCommandRateLimited when the bucket of the command is. The last
+// reservedCommands of the global limit are left for Record.
+func (rl *ratelimiter) Request(command string) error {
 	rl.Lock()
 	defer rl.Unlock()
 
 	// global
-	if rl.global.Blocked() {
-		return false
+	if rl.global.Count() >= len(rl.global.entries)-reservedCommands {
+		return errRateLimited
 	}
-	rl.global.Insert(command)
 
 	// bucket specific
 	if bucket, exists := rl.buckets[command]; exists {
 		if bucket.Blocked() {
-			return false
+			return errCommandRateLimited
 		}
 		bucket.Insert(command)
 	}
 
-	return true
+	rl.global.Insert(command)
+	return nil
+}
+
+// Record counts a command that is written regardless of the limits, such as heartbeats.
+func (rl *ratelimiter) Record(command string) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	rl.global.Insert(command)
+}
+
+// Usage returns the number of commands written within the last 60 seconds
+func (rl *ratelimiter) Usage() int {
+	rl.RLock()
+	defer rl.RUnlock()
+
+	return rl.global.Count()
 }
@@ -3,8 +3,12 @@
 package gateway
 
 import (
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/andersfylling/disgord/internal/gateway/cmd"
+	"github.com/andersfylling/disgord/internal/gateway/event"
 )
 
 func TestRlBucket(t *testing.T) {
@@ -58,3 +62,162 @@ func TestRlBucket(t *testing.T) {
 
 	})
 }
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// useClock makes every bucket of rl read the time from clock
+func useClock(rl *ratelimiter, clock *fakeClock) {
+	rl.global.now = clock.Now
+	for name, bucket := range rl.buckets {
+		bucket.now = clock.Now
+		rl.buckets[name] = bucket
+	}
+}
+
+func TestRatelimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	rl := newRatelimiter()
+	useClock(&rl, clock)
+
+	for i := 0; i < CommandLimit-reservedCommands; i++ {
+		if err := rl.Request(cmd.RequestGuildMembers); err != nil {
+			t.Fatalf("expected command %d to be accepted", i+1)
+		}
+		clock.Add(100 * time.Millisecond)
+	}
+	if err := rl.Request(cmd.RequestGuildMembers); err != errRateLimited {
+		t.Errorf("expected the reserved commands to be kept free, got %v", err)
+	}
+
+	rl.Record(event.Heartbeat)
+	if usage := rl.Usage(); usage != CommandLimit-reservedCommands+1 {
+		t.Errorf("expected a usage of %d, got %d", CommandLimit-reservedCommands+1, usage)
+	}
+
+	// the window slides past the first two commands, one of which made room for the heartbeat
+	clock.Add(60*time.Second - 11*time.Second + 101*time.Millisecond)
+	if err := rl.Request(cmd.RequestGuildMembers); err != nil {
+		t.Error("expected a command to be accepted once the oldest command left the window")
+	}
+
+	clock.Add(time.Minute + time.Millisecond)
+	if usage := rl.Usage(); usage != 0 {
+		t.Errorf("expected no usage after a minute, got %d", usage)
+	}
+	for i := 0; i < 5; i++ {
+		if err := rl.Request(cmd.UpdateStatus); err != nil {
+			t.Fatalf("expected status update %d to be accepted", i+1)
+		}
+	}
+	if err := rl.Request(cmd.UpdateStatus); err != errCommandRateLimited {
+		t.Errorf("expected the status update bucket to be limited, got %v", err)
+	}
+}
+
+func TestClient_heartbeatWhenRateLimited(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	c := newTestEvtClient(t, nil)
+	c.messageQueue = newClientPktQueue(CommandLimit * 2)
+	useClock(&c.ratelimit, clock)
+
+	var written []string
+	write := func(msg *clientPacket) error {
+		written = append(written, msg.CmdName)
+		return nil
+	}
+
+	// saturate the queue
+	for i := 0; i < CommandLimit+10; i++ {
+		if err := c.Emit(cmd.RequestGuildMembers, &RequestGuildMembersPayload{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < CommandLimit; i++ {
+		if err := c.writeQueued(write); err != nil {
+			if err != errRateLimited {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	if len(written) != CommandLimit-reservedCommands {
+		t.Fatalf("expected %d commands to be written, got %d", CommandLimit-reservedCommands, len(written))
+	}
+	if c.messageQueue.IsEmpty() {
+		t.Fatal("expected the rate limited commands to stay in the queue")
+	}
+
+	for i := 0; i < reservedCommands; i++ {
+		if err := c.writeInternal(&clientPacket{CmdName: event.Heartbeat}, write); err != nil {
+			t.Fatal(err)
+		}
+		if last := written[len(written)-1]; last != event.Heartbeat {
+			t.Fatalf("expected the heartbeat to be written, got %s", last)
+		}
+	}
+	if usage := c.CommandUsage(); usage != CommandLimit {
+		t.Errorf("expected a usage of %d, got %d", CommandLimit, usage)
+	}
+
+	clock.Add(time.Minute + time.Millisecond)
+	if err := c.writeQueued(write); err != nil {
+		t.Errorf("expected the queue to continue once the window passed, got %s", err)
+	}
+}
+
+func TestClient_writeQueued_commandRateLimited(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	c := newTestEvtClient(t, nil)
+	useClock(&c.ratelimit, clock)
+
+	var written []string
+	write := func(msg *clientPacket) error {
+		written = append(written, msg.CmdName)
+		return nil
+	}
+
+	// use up the status update bucket
+	for i := 0; i < 5; i++ {
+		if err := c.ratelimit.Request(cmd.UpdateStatus); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Emit(cmd.UpdateStatus, &UpdateStatusPayload{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Emit(cmd.RequestGuildMembers, &RequestGuildMembersPayload{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.writeQueued(write); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(written) != 2 || written[0] != cmd.RequestGuildMembers || written[1] != cmd.RequestGuildMembers {
+		t.Fatalf("expected the commands behind the status update to be written, got %v", written)
+	}
+	if err := c.writeQueued(write); !errors.Is(err, errRateLimited) {
+		t.Errorf("expected the status update to wait for its bucket, got %v", err)
+	}
+
+	clock.Add(time.Minute + time.Millisecond)
+	if err := c.writeQueued(write); err != nil {
+		t.Fatal(err)
+	}
+	if last := written[len(written)-1]; last != cmd.UpdateStatus || !c.messageQueue.IsEmpty() {
+		t.Errorf("expected the status update to be written once the window passed, got %v", written)
+	}
+}
@@ -10,14 +10,26 @@ import (
 const DefaultIdentifyRateLimit = 1000
 
 func newShardSync(conf *ShardConfig, l logger.Logger, lPrefix string, shutdownChan chan interface{}) *shardSync {
+	maxConcurrency := conf.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = 1
+	}
+	queues := make([]chan *shardSyncQueueItem, maxConcurrency)
+	for i := range queues {
+		queues[i] = make(chan *shardSyncQueueItem, 100) // it's just pointers anyways
+	}
+
 	return &shardSync{
 		identifiesPer24H: conf.IdentifiesPer24H,
 		timeout:          conf.ShardRateLimit,
-		queue:            make(chan *shardSyncQueueItem, 100), // it's just pointers anyways
+		queues:           queues,
 		logger:           l,
 		lpre:             lPrefix,
 		shutdownChan:     shutdownChan,
 		metric:           &IdentifyMetric{},
+
+		identifiesRemaining: conf.identifiesRemaining,
+		identifiesResetAt:   conf.identifiesResetAt,
 	}
 }
 
@@ -32,11 +44,39 @@ type shardSync struct {
 
 	identifiesPer24H uint
 	timeout          time.Duration
-	queue            chan *shardSyncQueueItem
+	queues           []chan *shardSyncQueueItem // one per rate limit bucket, see ShardConfig.MaxConcurrency
 	logger           logger.Logger
 	lpre             string
 	shutdownChan     chan interface{}
 	metric           *IdentifyMetric
+
+	// the session start limit reported by Discord on startup. It includes the identifies of earlier runs,
+	// which the metric does not know about, and is used until it resets.
+	identifiesRemaining uint
+	identifiesResetAt   time.Time
+}
+
+// sessionStartPenalty returns how long to wait before the next identify, such that a bot that restarts in
+// a loop does not use up the identifies that Discord allows per 24 hours.
+func (s *shardSync) sessionStartPenalty() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+
+	untilReset := time.Until(s.identifiesResetAt)
+	if untilReset <= 0 || s.identifiesRemaining > 0 {
+		return 0
+	}
+	return untilReset
+}
+
+// sessionStarted counts an identify towards the session start limit
+func (s *shardSync) sessionStarted() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.identifiesRemaining > 0 {
+		s.identifiesRemaining--
+	}
 }
 
 func (s *shardSync) queueShard(shardID uint, cb func() error) (err error) {
@@ -48,7 +88,7 @@ func (s *shardSync) queueShard(shardID uint, cb func() error) (err error) {
 	start := time.Now()
 
 	s.logger.Debug(s.lpre, "shard", shardID, "is waiting to identify")
-	s.queue <- &shardSyncQueueItem{
+	s.queues[shardID%uint(len(s.queues))] <- &shardSyncQueueItem{
 		ShardID: shardID,
 		run:     cb,
 		errChan: errChan,
@@ -63,7 +103,20 @@ func (s *shardSync) queueShard(shardID uint, cb func() error) (err error) {
 	return err
 }
 
+// process identifies the queued shards of every rate limit bucket, until shutdown
 func (s *shardSync) process() {
+	wg := sync.WaitGroup{}
+	for _, queue := range s.queues {
+		wg.Add(1)
+		go func(queue chan *shardSyncQueueItem) {
+			defer wg.Done()
+			s.processQueue(queue)
+		}(queue)
+	}
+	wg.Wait()
+}
+
+func (s *shardSync) processQueue(queue chan *shardSyncQueueItem) {
 	for {
 		var item *shardSyncQueueItem
 		var open bool
@@ -73,7 +126,7 @@ func (s *shardSync) process() {
 		case <-s.shutdownChan:
 			s.logger.Debug(s.lpre, "shard identify-rate-limiter got shutdown signal")
 			return
-		case item, open = <-s.queue:
+		case item, open = <-queue:
 			if !open {
 				s.logger.Error(s.lpre, "queue unexpectly closed - shards can no longer identify")
 				return
@@ -83,6 +136,17 @@ func (s *shardSync) process() {
 			continue
 		}
 
+		if penalty = s.sessionStartPenalty(); penalty > 0 {
+			s.logger.Info(s.lpre, "no identifies remain of the session start limit and connections are halted for", penalty)
+			select {
+			case <-s.shutdownChan:
+				s.logger.Debug(s.lpre, "shard identify-rate-limiter got shutdown signal")
+				return
+			case <-time.After(penalty):
+			}
+			penalty = 0
+		}
+
 		err := item.run()
 		item.errChan <- err // panics if shutdown is triggered as errChan is then closed
 		if err != nil {
@@ -92,6 +156,7 @@ func (s *shardSync) process() {
 		s.metric.Lock()
 		s.metric.Reconnects = append(s.metric.Reconnects, time.Now())
 		s.metric.Unlock()
+		s.sessionStarted()
 
 		// 1000 identify / 24 hours rate limit check
 		if s.metric.ReconnectsSince(24*time.Hour) > (s.identifiesPer24H - 1) {
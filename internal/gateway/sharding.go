@@ -15,7 +15,7 @@ import (
 )
 
 const defaultShardRateLimit time.Duration = 5*time.Second + 100*time.Millisecond
-const discordErrShardScalingRequired = 4011
+const discordErrShardScalingRequired = CloseShardingRequired
 
 type shardID = uint
 
@@ -46,10 +46,25 @@ func ConfigureShardConfig(ctx context.Context, client GatewayBotGetter, conf *Sh
 		conf.URL = data.URL
 	}
 
+	if conf.IdentifiesPer24H == 0 {
+		conf.IdentifiesPer24H = data.SessionStartLimit.Total
+	}
+	if data.SessionStartLimit.Total > 0 {
+		// identifies by earlier runs of the bot count as well
+		conf.identifiesRemaining = data.SessionStartLimit.Remaining
+		conf.identifiesResetAt = time.Now().Add(time.Duration(data.SessionStartLimit.ResetAfter) * time.Millisecond)
+	}
 	if conf.IdentifiesPer24H == 0 {
 		conf.IdentifiesPer24H = DefaultIdentifyRateLimit
 	}
 
+	if conf.MaxConcurrency == 0 {
+		conf.MaxConcurrency = data.SessionStartLimit.MaxConcurrency
+	}
+	if conf.MaxConcurrency == 0 {
+		conf.MaxConcurrency = 1
+	}
+
 	if len(conf.ShardIDs) == 0 {
 		conf.ShardCount = data.Shards
 		for i := uint(0); i < data.Shards; i++ {
@@ -124,6 +139,58 @@ type ShardManager interface {
 	ShardIDs() (shardIDs []uint)
 	GetShard(shardID shardID) (shard *EvtClient, err error)
 	HeartbeatLatencies() (latencies map[shardID]time.Duration, err error)
+	Sessions() map[shardID]Session
+	Reconnect(shardID shardID) error
+	Status() map[shardID]ShardStatus
+}
+
+// ShardStatus describes the connection of a shard, see ShardManager.Status
+type ShardStatus struct {
+	Connected        bool
+	HeartbeatLatency time.Duration
+	Session          Session
+
+	// Ready is how many times the shard has identified
+	Ready uint
+
+	// Commands is how many gateway commands the shard sent within the last 60 seconds. Discord disconnects
+	// shards that exceed CommandLimit.
+	Commands int
+}
+
+// ShardState is the state of the connection of a shard, see ShardConfig.StateChanges
+type ShardState int
+
+const (
+	// ShardDisconnected is sent when the connection is lost. The shard reconnects, unless Err is set.
+	ShardDisconnected ShardState = iota
+	// ShardResuming is sent when the shard resumes its session, such that missed events are replayed
+	ShardResuming
+	// ShardReady is sent once the shard receives events again, after identifying or resuming
+	ShardReady
+)
+
+func (s ShardState) String() string {
+	switch s {
+	case ShardDisconnected:
+		return "disconnected"
+	case ShardResuming:
+		return "resuming"
+	case ShardReady:
+		return "ready"
+	default:
+		return "unknown"
+	}
+}
+
+// ShardStateChange is sent when the connection of a shard changes state
+type ShardStateChange struct {
+	ShardID uint
+	State   ShardState
+
+	// Err is set when the shard stopped reconnecting, eg. because the bot token is invalid or
+	// ShardConfig.MaxReconnectAttempts was reached. The shard must be reconnected manually.
+	Err error
 }
 
 type ShardConfig struct {
@@ -150,6 +217,12 @@ type ShardConfig struct {
 	// Large bots only. If Discord did not give you a custom rate limit, do not touch this.
 	ShardRateLimit time.Duration
 
+	// MaxConcurrency is how many shards can identify at the same time, once per ShardRateLimit. Shards share
+	// a rate limit bucket when their ID modulo MaxConcurrency is the same.
+	//
+	// Defaults to the max_concurrency that Discord reports for the bot, which is 1 unless the bot is large.
+	MaxConcurrency uint
+
 	// ConnectQueue is used to control how often shards can connect by sending an identify command.
 	// For distributed systems, this must be overwritten as, by default, you can only send one identify
 	// every five seconds. The default implementation can be found in shard_sync.go.
@@ -187,8 +260,28 @@ type ShardConfig struct {
 	// Setting it to 0 will default it to 1000.
 	IdentifiesPer24H uint
 
+	// Sessions are resumed by the shards with the same ID on the first connect, such that a restarted bot
+	// receives the events it missed instead of identifying again. Store them before shutting down, see
+	// Client.GatewaySessions. Shards without a session identify as usual.
+	Sessions map[uint]Session
+
+	// StateChanges receives the state of every shard when it disconnects, resumes or becomes ready, such that
+	// outgoing work can be paused while a shard is reconnecting. Use a buffered channel, state changes are
+	// dropped when it is full.
+	StateChanges chan<- *ShardStateChange
+
+	// MaxReconnectAttempts is how many times a shard tries to reconnect before giving up. The delay between
+	// attempts doubles from one second up to two minutes.
+	//
+	// Setting it to 0 retries forever.
+	MaxReconnectAttempts uint
+
 	// URL is fetched from the gateway before initialising a connection
 	URL string
+
+	// the session start limit reported by Discord, see shardSync
+	identifiesRemaining uint
+	identifiesResetAt   time.Time
 }
 
 // ShardManagerConfig all fields, except proxy.Dialer, is required
@@ -201,6 +294,9 @@ type ShardManagerConfig struct {
 	ShutdownChan chan interface{}
 	conn         Conn
 
+	// NewConn creates the websocket connection of every shard, defaults to NewConn
+	NewConn func() (Conn, error)
+
 	// ...
 	IgnoreEvents []string
 	Intents      Intent
@@ -251,6 +347,9 @@ func (s *shardMngr) initShards() error {
 		EventChan:    s.conf.EventChan,
 		connectQueue: s.connectQueue,
 
+		StateChanges:         s.conf.StateChanges,
+		MaxReconnectAttempts: s.conf.MaxReconnectAttempts,
+
 		// user settings
 		BotToken:   s.conf.BotToken,
 		HTTPClient: s.conf.HTTPClient,
@@ -287,7 +386,8 @@ func (s *shardMngr) initShards() error {
 				s.conf.Logger.Info("scaling", "connected")
 			}
 		},
-		conn: s.conf.conn,
+		conn:    s.conf.conn,
+		NewConn: s.conf.NewConn,
 	}
 
 	for _, id := range s.conf.ShardIDs {
@@ -297,6 +397,9 @@ func (s *shardMngr) initShards() error {
 		}
 
 		uniqueConfig := baseConfig // create copy, review requirement
+		if session, ok := s.conf.Sessions[id]; ok {
+			uniqueConfig.Session = &session
+		}
 		shard, err := NewEventClient(id, &uniqueConfig)
 		if err != nil {
 			return err
@@ -304,6 +407,9 @@ func (s *shardMngr) initShards() error {
 
 		s.shards[id] = shard
 	}
+
+	// sessions are only resumed once, shards added by scaling identify
+	s.conf.Sessions = nil
 	return nil
 }
 
@@ -445,6 +551,49 @@ func (s *shardMngr) HeartbeatLatencies() (latencies map[shardID]time.Duration, e
 	return
 }
 
+// Reconnect restarts the connection of a single shard, without affecting the others. The session is resumed
+// when Discord still knows it.
+func (s *shardMngr) Reconnect(shardID shardID) error {
+	shard, err := s.GetShard(shardID)
+	if err != nil {
+		return err
+	}
+	return shard.reconnect()
+}
+
+// Status describes the connection of every local shard
+func (s *shardMngr) Status() map[shardID]ShardStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := make(map[shardID]ShardStatus, len(s.shards))
+	for id, shard := range s.shards {
+		latency, _ := shard.HeartbeatLatency()
+		shard.RLock()
+		ready := shard.ReadyCounter
+		shard.RUnlock()
+		status[id] = ShardStatus{
+			Connected:        shard.isConnected.Load(),
+			HeartbeatLatency: latency,
+			Session:          shard.Session(),
+			Ready:            ready,
+			Commands:         shard.CommandUsage(),
+		}
+	}
+	return status
+}
+
+func (s *shardMngr) Sessions() map[shardID]Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make(map[shardID]Session, len(s.shards))
+	for id, shard := range s.shards {
+		sessions[id] = shard.Session()
+	}
+	return sessions
+}
+
 func (s *shardMngr) scale(code int, reason string) {
 	if s.conf.DisableAutoScaling {
 		s.conf.Logger.Debug("discord require websocket shards to scale up but auto scaling is disabled - did not handle scaling internally")
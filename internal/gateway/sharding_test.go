@@ -237,7 +237,7 @@ func TestIdentifyRateLimiting(t *testing.T) {
 		t.Fatal("should not be able to connect")
 	case <-time.After(100 * time.Millisecond): // TODO: remove timeout, just don't know how yet
 		select {
-		case item, ok := <-mngr.sync.queue:
+		case item, ok := <-mngr.sync.queues[0]:
 			if !ok {
 				t.Fatal("queue was closed somehow")
 			}
@@ -248,3 +248,139 @@ func TestIdentifyRateLimiting(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigureShardConfig_SessionStartLimit(t *testing.T) {
+	mock := &GatewayBotGetterMock{
+		get: func() (gateway *GatewayBot, err error) {
+			bot := &GatewayBot{Shards: 16}
+			bot.SessionStartLimit.Total = 2000
+			bot.SessionStartLimit.Remaining = 3
+			bot.SessionStartLimit.ResetAfter = 60000
+			bot.SessionStartLimit.MaxConcurrency = 4
+			return bot, nil
+		},
+	}
+
+	conf := ShardConfig{}
+	if err := ConfigureShardConfig(context.Background(), mock, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.MaxConcurrency != 4 {
+		t.Errorf("expected the max concurrency of Discord, got %d", conf.MaxConcurrency)
+	}
+	if conf.IdentifiesPer24H != 2000 {
+		t.Errorf("expected the session start limit of Discord, got %d", conf.IdentifiesPer24H)
+	}
+	if conf.identifiesRemaining != 3 {
+		t.Errorf("expected the remaining identifies of Discord, got %d", conf.identifiesRemaining)
+	}
+	if reset := time.Until(conf.identifiesResetAt); reset <= 59*time.Second || reset > time.Minute {
+		t.Errorf("expected the session start limit to reset in a minute, got %s", reset)
+	}
+
+	conf = ShardConfig{MaxConcurrency: 1}
+	if err := ConfigureShardConfig(context.Background(), mock, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.MaxConcurrency != 1 {
+		t.Errorf("expected the configured max concurrency to be kept, got %d", conf.MaxConcurrency)
+	}
+}
+
+func TestShardSync_MaxConcurrency(t *testing.T) {
+	shutdown := make(chan interface{})
+	defer close(shutdown)
+	s := newShardSync(&ShardConfig{
+		IdentifiesPer24H: DefaultIdentifyRateLimit,
+		ShardRateLimit:   200 * time.Millisecond,
+		MaxConcurrency:   2,
+	}, &logger.Empty{}, "", shutdown)
+	go s.process()
+
+	start := time.Now()
+	identified := make(chan uint, 4)
+	for id := uint(0); id < 4; id++ {
+		go s.queueShard(id, func(id uint) func() error {
+			return func() error {
+				identified <- id
+				return nil
+			}
+		}(id))
+	}
+
+	// shard 0 and 2 share a bucket, as do shard 1 and 3
+	for i := 0; i < 2; i++ {
+		<-identified
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("expected two shards to identify at once, took %s", elapsed)
+	}
+	for i := 0; i < 2; i++ {
+		<-identified
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the other shards to wait for the rate limit, took %s", elapsed)
+	}
+}
+
+func TestShardSync_SessionStartLimit(t *testing.T) {
+	shutdown := make(chan interface{})
+	defer close(shutdown)
+	s := newShardSync(&ShardConfig{
+		IdentifiesPer24H:    DefaultIdentifyRateLimit,
+		ShardRateLimit:      time.Millisecond,
+		identifiesRemaining: 1,
+		identifiesResetAt:   time.Now().Add(300 * time.Millisecond),
+	}, &logger.Empty{}, "", shutdown)
+	go s.process()
+
+	start := time.Now()
+	identify := func() error { return nil }
+	if err := s.queueShard(0, identify); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("expected the remaining identify to be used right away, took %s", elapsed)
+	}
+
+	if err := s.queueShard(1, identify); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected the next identify to wait for the session start limit to reset, took %s", elapsed)
+	}
+}
+
+func TestShardMngr_Status(t *testing.T) {
+	config := ShardManagerConfig{
+		ShardConfig: ShardConfig{
+			ShardIDs:       []uint{0, 1},
+			ShardCount:     2,
+			ConnectQueue:   func(shardID uint, cb func() error) error { return cb() },
+			Sessions:       map[uint]Session{1: {ID: "abc", Sequence: 3}},
+			ShardRateLimit: time.Millisecond,
+		},
+		BotToken:     "test",
+		ShutdownChan: make(chan interface{}),
+		EventChan:    make(chan *Event),
+		Logger:       &logger.Empty{},
+	}
+	mngr := NewShardMngr(config)
+	if err := mngr.initShards(); err != nil {
+		t.Fatal(err)
+	}
+
+	status := mngr.Status()
+	if len(status) != 2 {
+		t.Fatalf("expected the status of both shards, got %d", len(status))
+	}
+	if status[0].Connected || status[0].Session != (Session{}) {
+		t.Errorf("expected shard 0 to be disconnected without a session, got %+v", status[0])
+	}
+	if status[1].Session != (Session{ID: "abc", Sequence: 3}) {
+		t.Errorf("expected shard 1 to resume the configured session, got %+v", status[1])
+	}
+	if err := mngr.Reconnect(5); err == nil {
+		t.Error("expected an error for an unknown shard")
+	}
+}
@@ -39,6 +39,10 @@ type VoiceConfig struct {
 
 	Logger logger.Logger
 
+	// CloseListener is called when Discord closes the voice websocket with a 4xxx close code, eg. 4014 when the
+	// bot was disconnected from the channel or the voice server changed.
+	CloseListener func(code int, reason string)
+
 	SystemShutdown chan interface{}
 }
 
@@ -73,8 +77,9 @@ func NewVoiceClient(conf *VoiceConfig) (client *VoiceClient, err error) {
 				return &DiscordPacket{}
 			},
 		},
-		messageQueueLimit: conf.MessageQueueLimit,
-		SystemShutdown:    conf.SystemShutdown,
+		messageQueueLimit:  conf.MessageQueueLimit,
+		discordErrListener: conf.CloseListener,
+		SystemShutdown:     conf.SystemShutdown,
 	}, client.internalConnect)
 	if err != nil {
 		return nil, err
@@ -164,10 +169,7 @@ func (c *VoiceClient) onHeartbeatRequest(v interface{}) error {
 
 func (c *VoiceClient) onHeartbeatAck(v interface{}) error {
 	// heartbeat received
-	c.Lock()
-	c.lastHeartbeatAck = time.Now()
-	c.Unlock()
-
+	c.heartbeatAcked()
 	return nil
 }
 
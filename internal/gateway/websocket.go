@@ -3,28 +3,107 @@ package gateway
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/andersfylling/disgord/internal/util"
 )
 
 type Snowflake = util.Snowflake
 
+// Conn is a websocket connection to the Discord gateway. NewConn returns the default implementation, and
+// alternatives can be checked with the gatewaytesting package in the root of the module.
 type Conn interface {
+	// Close closes the connection with the normal closure code 1000, which ends the Discord session.
 	Close() error
+
+	// CloseResumable closes the connection with a code other than 1000 and 1001, such that the Discord
+	// session can be resumed on a new connection.
+	CloseResumable() error
+
 	Open(ctx context.Context, endpoint string, requestHeader http.Header) error
 	WriteJSON(v interface{}) error
+
+	// Read returns the next message, decompressed. A close frame from the peer is returned as a *CloseErr,
+	// and a cancelled ctx returns the ctx error.
 	Read(ctx context.Context) (packet []byte, err error)
 
 	Disconnected() bool
+
+	// Inactive is true when nothing, not even a pong, has been received for a while, such that the
+	// connection is most likely dead even though it was never closed.
+	Inactive() bool
+
+	// InactiveSince is when the last message or pong was received
+	InactiveSince() time.Time
 }
 
+// Close codes of the event gateway, see
+// https://discord.com/developers/docs/topics/opcodes-and-status-codes#gateway-gateway-close-event-codes
+const (
+	CloseUnknownError         = 4000
+	CloseUnknownOpcode        = 4001
+	CloseDecodeError          = 4002
+	CloseNotAuthenticated     = 4003
+	CloseAuthenticationFailed = 4004
+	CloseAlreadyAuthenticated = 4005
+	CloseInvalidSeq           = 4007
+	CloseRateLimited          = 4008
+	CloseSessionTimedOut      = 4009
+	CloseInvalidShard         = 4010
+	CloseShardingRequired     = 4011
+	CloseInvalidAPIVersion    = 4012
+	CloseInvalidIntents       = 4013
+	CloseDisallowedIntents    = 4014
+)
+
+// CloseErr is the close frame sent by the peer
 type CloseErr struct {
 	code int
 	info string
 }
 
 func (e *CloseErr) Error() string {
-	return e.info
+	switch e.code {
+	case CloseAuthenticationFailed:
+		return e.info + ": the bot token is invalid"
+	case CloseInvalidIntents:
+		return e.info + ": the intents are invalid, only combine the Intent constants"
+	case CloseDisallowedIntents:
+		return e.info + ": the intents include privileged intents that are not enabled for the bot. Enable " +
+			"them in the Discord developer portal, or remove IntentGuildMembers and IntentGuildPresences from " +
+			"the intents"
+	default:
+		return e.info
+	}
+}
+
+// Code is the close code, eg. 4004 when the bot token is invalid
+func (e *CloseErr) Code() int {
+	return e.code
+}
+
+// Resumable is true when the session can be resumed on a new connection, such that no events are missed.
+func (e *CloseErr) Resumable() bool {
+	switch e.code {
+	case CloseUnknownError, CloseUnknownOpcode, CloseDecodeError, CloseNotAuthenticated,
+		CloseAlreadyAuthenticated, CloseRateLimited:
+		return true
+	default:
+		// 4007 and 4009 must identify a new session, and websocket errors below 4000 have not closed the
+		// session either
+		return e.code < 4000
+	}
+}
+
+// Fatal is true when reconnecting does not help, as the bot must be reconfigured first.
+func (e *CloseErr) Fatal() bool {
+	switch e.code {
+	case CloseAuthenticationFailed, CloseInvalidShard, CloseShardingRequired, CloseInvalidAPIVersion,
+		CloseInvalidIntents, CloseDisallowedIntents:
+		return true
+	default:
+		return false
+	}
 }
 
 // WebsocketErr is used internally when the websocket package returns an error. It does not represent a Discord error!
@@ -8,15 +8,31 @@ import (
 	"github.com/andersfylling/disgord/json"
 	"io"
 	"net/http"
+	"time"
 
 	"go.uber.org/atomic"
 
 	"nhooyr.io/websocket"
 )
 
-func newConn(httpClient *http.Client) (Conn, error) {
+// readLimit is the largest message that is read. Guild creates of large guilds are several megabytes.
+const readLimit = 32768 * 10000 // discord.. Can we add stream support?
+
+// closeResumable is the close code of CloseResumable. Discord ends the session on 1000 and 1001 only.
+const closeResumable websocket.StatusCode = 4000
+
+// DefaultPingInterval is how often the peer is pinged, see NewConn
+const DefaultPingInterval = 30 * time.Second
+
+var errNotConnected = errors.New("websocket connection was never opened")
+
+// NewConn creates the default Conn. Once open, the peer is pinged every DefaultPingInterval, and the
+// connection is Inactive when neither a message nor a pong was received for two intervals. A pong that
+// takes longer than an interval closes the connection, which makes Read fail.
+func NewConn(httpClient *http.Client) (Conn, error) {
 	return &nhooyr{
-		httpClient: httpClient,
+		httpClient:   httpClient,
+		pingInterval: DefaultPingInterval,
 	}, nil
 }
 
@@ -24,9 +40,15 @@ type nhooyr struct {
 	c           *websocket.Conn
 	httpClient  *http.Client
 	isConnected atomic.Bool
+
+	pingInterval  time.Duration
+	stopKeepalive context.CancelFunc
+	lastActive    atomic.Int64 // unix nano
 }
 
 func (g *nhooyr) Open(ctx context.Context, endpoint string, requestHeader http.Header) (err error) {
+	g.stop()
+
 	// establish ws connection
 	g.c, _, err = websocket.Dial(ctx, endpoint, &websocket.DialOptions{
 		HTTPClient: g.httpClient,
@@ -39,12 +61,54 @@ func (g *nhooyr) Open(ctx context.Context, endpoint string, requestHeader http.H
 		return err
 	}
 	g.isConnected.Store(true)
+	g.active()
+
+	g.c.SetReadLimit(readLimit)
 
-	g.c.SetReadLimit(32768 * 10000) // discord.. Can we add stream support?
+	keepaliveCtx, cancel := context.WithCancel(context.Background())
+	g.stopKeepalive = cancel
+	go g.keepalive(keepaliveCtx, g.c)
 	return
 }
 
+// keepalive pings the peer until ctx is cancelled. The pongs are read by Read, which must be called
+// concurrently.
+func (g *nhooyr) keepalive(ctx context.Context, c *websocket.Conn) {
+	ticker := time.NewTicker(g.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, g.pingInterval)
+		err := c.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			return
+		}
+		g.active()
+	}
+}
+
+func (g *nhooyr) stop() {
+	if g.stopKeepalive != nil {
+		g.stopKeepalive()
+		g.stopKeepalive = nil
+	}
+}
+
+func (g *nhooyr) active() {
+	g.lastActive.Store(time.Now().UnixNano())
+}
+
 func (g *nhooyr) WriteJSON(v interface{}) (err error) {
+	if g.c == nil {
+		return errNotConnected
+	}
+
 	// TODO: move unmarshalling out of here?
 	var w io.WriteCloser
 	w, err = g.c.Writer(context.Background(), websocket.MessageText)
@@ -62,7 +126,20 @@ func (g *nhooyr) WriteJSON(v interface{}) (err error) {
 }
 
 func (g *nhooyr) Close() (err error) {
-	err = g.c.Close(websocket.StatusNormalClosure, "Bot is shutting down")
+	return g.close(websocket.StatusNormalClosure, "Bot is shutting down")
+}
+
+func (g *nhooyr) CloseResumable() (err error) {
+	return g.close(closeResumable, "Reconnecting")
+}
+
+func (g *nhooyr) close(code websocket.StatusCode, reason string) (err error) {
+	g.stop()
+	if g.c == nil {
+		return nil
+	}
+
+	err = g.c.Close(code, reason)
 	if !g.isConnected.Load() {
 		err = nil // discard error if we're already closed, should be a noop anyways
 	}
@@ -71,18 +148,23 @@ func (g *nhooyr) Close() (err error) {
 }
 
 func (g *nhooyr) Read(ctx context.Context) (packet []byte, err error) {
+	if g.c == nil {
+		return nil, errNotConnected
+	}
+
 	var messageType websocket.MessageType
 	messageType, packet, err = g.c.Read(ctx)
 	if err != nil {
+		// the connection can not be used after a failed read
+		g.isConnected.Store(false)
+
 		// Cancelling Read by ctx results in closed WS, see issue
 		// https://github.com/nhooyr/websocket/issues/242
-		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
-			g.isConnected.Store(false)
-			return nil, context.Canceled
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
 		var closeErr websocket.CloseError
 		if errors.As(err, &closeErr) {
-			g.isConnected.Store(false)
 			err = &CloseErr{
 				code: int(closeErr.Code),
 				info: closeErr.Error(),
@@ -90,15 +172,28 @@ func (g *nhooyr) Read(ctx context.Context) (packet []byte, err error) {
 		}
 		return nil, err
 	}
+	g.active()
 
 	if messageType == websocket.MessageBinary {
 		packet, err = decompressBytes(packet)
 	}
-	return packet, nil
+	return packet, err
 }
 
 func (g *nhooyr) Disconnected() bool {
 	return !g.isConnected.Load()
 }
 
+func (g *nhooyr) Inactive() bool {
+	return time.Since(g.InactiveSince()) > 2*g.pingInterval
+}
+
+func (g *nhooyr) InactiveSince() time.Time {
+	lastActive := g.lastActive.Load()
+	if lastActive == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, lastActive)
+}
+
 var _ Conn = (*nhooyr)(nil)
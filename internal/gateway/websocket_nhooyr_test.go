@@ -0,0 +1,90 @@
+// +build !integration
+
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func serveWebsocket(handler func(ctx context.Context, c *websocket.Conn)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close(websocket.StatusInternalError, "")
+		handler(r.Context(), c)
+	}))
+}
+
+func TestNhooyr_Keepalive(t *testing.T) {
+	server := serveWebsocket(func(ctx context.Context, c *websocket.Conn) {
+		// answers pings
+		c.CloseRead(ctx)
+		<-ctx.Done()
+	})
+	defer server.Close()
+
+	conn := &nhooyr{pingInterval: 20 * time.Millisecond}
+	if err := conn.Open(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	opened := conn.InactiveSince()
+
+	// pongs are read by Read
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go conn.Read(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+	if !conn.InactiveSince().After(opened) {
+		t.Error("expected the pongs to keep the connection active")
+	}
+	if conn.Inactive() || conn.Disconnected() {
+		t.Error("expected the connection to be open and active")
+	}
+}
+
+func TestNhooyr_KeepaliveDeadPeer(t *testing.T) {
+	server := serveWebsocket(func(ctx context.Context, c *websocket.Conn) {
+		// never reads, so pings are not answered
+		<-ctx.Done()
+	})
+	defer server.Close()
+
+	conn := &nhooyr{pingInterval: 20 * time.Millisecond}
+	if err := conn.Open(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := conn.Read(ctx); err == nil || ctx.Err() != nil {
+		t.Fatalf("expected the unanswered ping to fail the read, got %v", err)
+	}
+	if !conn.Disconnected() {
+		t.Error("expected the connection to be disconnected")
+	}
+}
+
+func TestNhooyr_NotOpened(t *testing.T) {
+	conn, _ := NewConn(nil)
+	if _, err := conn.Read(context.Background()); err == nil {
+		t.Error("expected reading an unopened connection to fail")
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("expected closing an unopened connection to be a noop, got %s", err)
+	}
+	if !conn.Inactive() || !conn.InactiveSince().IsZero() {
+		t.Error("expected an unopened connection to be inactive")
+	}
+}
@@ -0,0 +1,48 @@
+// +build !integration
+
+package gateway
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCloseErr(t *testing.T) {
+	testCases := []struct {
+		code      int
+		resumable bool
+		fatal     bool
+	}{
+		{1006, true, false}, // abnormal closure, eg. a network error
+		{CloseUnknownError, true, false},
+		{CloseUnknownOpcode, true, false},
+		{CloseDecodeError, true, false},
+		{CloseNotAuthenticated, true, false},
+		{CloseAuthenticationFailed, false, true},
+		{CloseAlreadyAuthenticated, true, false},
+		{CloseInvalidSeq, false, false},
+		{CloseRateLimited, true, false},
+		{CloseSessionTimedOut, false, false},
+		{CloseInvalidShard, false, true},
+		{CloseShardingRequired, false, true},
+		{CloseInvalidAPIVersion, false, true},
+		{CloseInvalidIntents, false, true},
+		{CloseDisallowedIntents, false, true},
+	}
+	for _, tc := range testCases {
+		err := &CloseErr{code: tc.code, info: "closed"}
+		if err.Resumable() != tc.resumable {
+			t.Errorf("close code %d: expected Resumable to be %t", tc.code, tc.resumable)
+		}
+		if err.Fatal() != tc.fatal {
+			t.Errorf("close code %d: expected Fatal to be %t", tc.code, tc.fatal)
+		}
+	}
+
+	for _, code := range []int{CloseInvalidIntents, CloseDisallowedIntents} {
+		err := &CloseErr{code: code, info: "closed"}
+		if !strings.Contains(err.Error(), "intents") {
+			t.Errorf("close code %d: expected the error to explain the intents, got %q", code, err.Error())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrAcquireUnsupported is returned by Client.Acquire when the RESTBucketManager can not hold requests ahead of
+// sending them
+var ErrAcquireUnsupported = errors.New("the RESTBucketManager does not support Acquire")
+
+// Acquire waits for the ltBucket of the local endpoint hash, and holds a request of it, see
+// AcquirableBucket.Acquire. Endpoints are linked to the discord ltBucket hash once release is called with the
+// response, like requests sent by Bucket.
+func (r *Manager) Acquire(ctx context.Context, localHash string) (release func(resp *http.Response), err error) {
+	pID, bucket := r.bucket(localHash)
+	if release, err = bucket.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	return r.consolidateOnRelease(localHash, pID, bucket, release), nil
+}
+
+// TryAcquire works like Acquire, but never waits, see AcquirableBucket.TryAcquire
+func (r *Manager) TryAcquire(localHash string) (release func(resp *http.Response), wait time.Duration, ok bool) {
+	pID, bucket := r.bucket(localHash)
+	if release, wait, ok = bucket.TryAcquire(); !ok {
+		return nil, wait, false
+	}
+	return r.consolidateOnRelease(localHash, pID, bucket, release), 0, true
+}
+
+func (r *Manager) consolidateOnRelease(id, pID string, bucket *ltBucket, release func(resp *http.Response)) func(resp *http.Response) {
+	return func(resp *http.Response) {
+		release(resp)
+		bucket.mu.RLock()
+		hash := bucket.hash
+		bucket.mu.RUnlock()
+		r.consolidate(id, pID, bucket, hash)
+	}
+}
+
+// acquirer is implemented by a RESTBucketManager that can hold requests ahead of sending them, such as Manager
+type acquirer interface {
+	Acquire(ctx context.Context, localHash string) (release func(resp *http.Response), err error)
+	TryAcquire(localHash string) (release func(resp *http.Response), wait time.Duration, ok bool)
+}
+
+var _ acquirer = (*Manager)(nil)
+
+// Acquire waits for the rate limit bucket of an endpoint and the global throttle, and holds a request of the
+// bucket, for requests that are not sent by Do. release must be called with the response as Discord sent it,
+// or with nil when the request was not sent. It fails with ErrAcquireUnsupported when the RESTBucketManager
+// does not support it.
+func (c *Client) Acquire(ctx context.Context, method, endpoint string) (release func(resp *http.Response), err error) {
+	manager, ok := c.buckets.(acquirer)
+	if !ok {
+		return nil, ErrAcquireUnsupported
+	}
+	// the global throttle is waited for first, such that the bucket is not held while waiting, see Do
+	if err = c.throttle.wait(ctx); err != nil {
+		return nil, err
+	}
+	req := &Request{Method: HTTPMethod(method), Endpoint: endpoint}
+	if release, err = manager.Acquire(ctx, req.HashEndpoint()); err != nil {
+		c.throttle.refund()
+		return nil, err
+	}
+	return c.normalizeOnRelease(release), nil
+}
+
+// TryAcquire works like Acquire, but never waits. When ok is false, wait is how long until the bucket or the
+// global throttle allows a request, or zero when the bucket is only busy. wait is WaitTimeUnknown when the
+// RESTBucketManager does not support it.
+func (c *Client) TryAcquire(method, endpoint string) (release func(resp *http.Response), wait time.Duration, ok bool) {
+	manager, supported := c.buckets.(acquirer)
+	if !supported {
+		return nil, WaitTimeUnknown, false
+	}
+	if wait, ok = c.throttle.take(); !ok {
+		return nil, wait, false
+	}
+	req := &Request{Method: HTTPMethod(method), Endpoint: endpoint}
+	if release, wait, ok = manager.TryAcquire(req.HashEndpoint()); !ok {
+		c.throttle.refund()
+		return nil, wait, false
+	}
+	return c.normalizeOnRelease(release), 0, true
+}
+
+// normalizeOnRelease normalizes the header of the response before the bucket is updated, see
+// NormalizeDiscordHeader. The global throttle token is given back when the request was not sent.
+func (c *Client) normalizeOnRelease(release func(resp *http.Response)) func(resp *http.Response) {
+	return func(resp *http.Response) {
+		if resp == nil {
+			c.throttle.refund()
+		} else if resp.Header.Get(DisgordNormalizedHeader) == "" {
+			header, err := NormalizeDiscordHeader(c.apiVersion, resp.StatusCode, resp.Header, nil)
+			if err != nil {
+				resp = nil
+			} else {
+				normalized := *resp
+				normalized.Header = header
+				resp = &normalized
+			}
+		}
+		release(resp)
+	}
+}
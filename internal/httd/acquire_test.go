@@ -0,0 +1,110 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestManager_Acquire(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "POST:/channels/1/messages"
+
+	release, err := mngr.Acquire(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, wait, ok := mngr.TryAcquire(id); ok || wait != 0 {
+		t.Errorf("expected the held bucket to be busy, got ok=%t wait=%s", ok, wait)
+	}
+
+	reset := time.Now().Add(time.Minute)
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+	resp.Header.Set(XRateLimitRemaining, "0")
+	resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.UnixNano()/int64(time.Millisecond), 10))
+	resp.Header.Set(XRateLimitBucket, "abcd1234")
+	resp.Header.Set(DisgordNormalizedHeader, "true")
+	release(resp)
+
+	// the endpoint is linked to the discord hash, like requests sent through Bucket
+	if pID := mngr.ProxyID(id); pID != "abcd1234" {
+		t.Errorf("expected the discord hash to be known, got %s", pID)
+	}
+	if _, wait, ok := mngr.TryAcquire(id); ok || wait < 50*time.Second {
+		t.Errorf("expected to wait for the reset, got ok=%t wait=%s", ok, wait)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var rateLimitErr *RateLimitError
+	if _, err = mngr.Acquire(ctx, id); !errors.As(err, &rateLimitErr) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+}
+
+func TestClient_Acquire(t *testing.T) {
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := "/channels/486833611564253186/messages"
+	release, err := client.Acquire(context.Background(), http.MethodPost, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the header is released as Discord sent it, in seconds
+	reset := time.Now().Add(time.Minute)
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+	resp.Header.Set(XRateLimitRemaining, "0")
+	resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+	release(resp)
+	if resp.Header.Get(DisgordNormalizedHeader) != "" {
+		t.Error("the header of the caller was modified")
+	}
+
+	if _, wait, ok := client.TryAcquire(http.MethodPost, endpoint); ok || wait < 50*time.Second || wait > time.Minute {
+		t.Errorf("expected to wait for the reset, got ok=%t wait=%s", ok, wait)
+	}
+
+	// a request that is not sent is given back
+	release, _, ok := client.TryAcquire(http.MethodGet, endpoint)
+	if !ok {
+		t.Fatal("expected another endpoint to be free")
+	}
+	release(nil)
+	if _, _, ok = client.TryAcquire(http.MethodGet, endpoint); !ok {
+		t.Error("expected the bucket to be released")
+	}
+}
+
+func TestClient_AcquireUnsupported(t *testing.T) {
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		DisableRateLimiter: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Acquire(context.Background(), http.MethodGet, "/gateway"); !errors.Is(err, ErrAcquireUnsupported) {
+		t.Errorf("expected acquire to be unsupported, got %v", err)
+	}
+	if _, wait, ok := client.TryAcquire(http.MethodGet, "/gateway"); ok || wait != WaitTimeUnknown {
+		t.Errorf("expected acquire to be unsupported, got ok=%t wait=%s", ok, wait)
+	}
+}
@@ -0,0 +1,86 @@
+package httd
+
+import "fmt"
+
+// SupportedAPIVersions lists the Discord API versions that requests can be sent to. Version 6 is the
+// default, the others are experimental until the payload differences are handled everywhere.
+var SupportedAPIVersions = []int{6, 7, 8, 9, 10}
+
+// APIVersion describes the known differences between Discord API versions, such that code which depends
+// on them can ask about the behaviour instead of comparing version numbers.
+type APIVersion interface {
+	// Version is the number used in the request URL, eg. 6 for "/api/v6"
+	Version() int
+
+	// Deprecated is true when Discord has announced that the version will be removed
+	Deprecated() bool
+
+	// RateLimitPrecisionHeader is true when the X-RateLimit-Precision header is needed to receive
+	// rate limit resets with millisecond precision. Later versions always use milliseconds.
+	RateLimitPrecisionHeader() bool
+
+	// RetryAfterInSeconds is true when the retry_after field of a rate limit response body is in seconds,
+	// with millisecond fractions. Earlier versions use whole milliseconds.
+	RetryAfterInSeconds() bool
+}
+
+// NewAPIVersion returns the APIVersion of a supported Discord API version
+func NewAPIVersion(version int) (APIVersion, error) {
+	if !SupportsDiscordAPIVersion(version) {
+		return nil, fmt.Errorf("Discord API version %d is not supported", version)
+	}
+	return apiVersion(version), nil
+}
+
+type apiVersion int
+
+var _ APIVersion = apiVersion(0)
+
+func (v apiVersion) Version() int {
+	return int(v)
+}
+
+func (v apiVersion) Deprecated() bool {
+	return v < 9
+}
+
+func (v apiVersion) RateLimitPrecisionHeader() bool {
+	return v < 8
+}
+
+func (v apiVersion) RetryAfterInSeconds() bool {
+	return v >= 8
+}
+
+// RateLimitPrecision is the precision of the rate limit resets Discord responds with, see
+// Config.RateLimitPrecision
+type RateLimitPrecision string
+
+const (
+	// RateLimitPrecisionMillisecond sends resets in seconds with millisecond fractions. It is the default,
+	// and the only precision of API version 8 and later.
+	RateLimitPrecisionMillisecond RateLimitPrecision = "millisecond"
+
+	// RateLimitPrecisionSecond sends resets in whole seconds, rounded up. Only API versions 6 and 7.
+	RateLimitPrecisionSecond RateLimitPrecision = "second"
+)
+
+// precisionHeader returns the X-RateLimit-Precision header value to send, or an empty string when the
+// version does not support the header
+func precisionHeader(version APIVersion, precision RateLimitPrecision) (string, error) {
+	switch precision {
+	case "":
+		precision = RateLimitPrecisionMillisecond
+	case RateLimitPrecisionMillisecond, RateLimitPrecisionSecond:
+	default:
+		return "", fmt.Errorf("unknown rate limit precision %q", precision)
+	}
+
+	if !version.RateLimitPrecisionHeader() {
+		if precision != RateLimitPrecisionMillisecond {
+			return "", fmt.Errorf("Discord API version %d only supports the %s rate limit precision", version.Version(), RateLimitPrecisionMillisecond)
+		}
+		return "", nil
+	}
+	return string(precision), nil
+}
@@ -0,0 +1,89 @@
+package httd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/andersfylling/disgord/json"
+)
+
+// maxPooledBodySize keeps unusually large buffers, such as a message with many embeds, out of the pool
+const maxPooledBodySize = 64 * 1024
+
+var bodyBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// pooledBody is a JSON request body encoded into a pooled buffer. The transport may close a request body
+// after the response was returned, so the buffer is only recycled once the request is done and every
+// reader of it is closed. Retries read the body again through reader, see http.Request.GetBody.
+type pooledBody struct {
+	mu   sync.Mutex
+	buf  *bytes.Buffer
+	size int64
+	refs int
+}
+
+func newPooledBody(codec json.Codec, v interface{}) (*pooledBody, error) {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := encode(codec, buf, v); err != nil {
+		bodyBufferPool.Put(buf)
+		return nil, err
+	}
+	return &pooledBody{buf: buf, size: int64(buf.Len()), refs: 1}, nil
+}
+
+// encode writes the JSON of v to buf. The package codec streams it through json.Encode, while other codecs
+// can only marshal it.
+func encode(codec json.Codec, buf *bytes.Buffer, v interface{}) error {
+	if _, ok := codec.(json.PackageCodec); ok {
+		return json.Encode(buf, v)
+	}
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = buf.Write(data)
+	return err
+}
+
+// reader returns a new reader of the body, which must be closed
+func (b *pooledBody) reader() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return nil, errors.New("the request body was already recycled")
+	}
+	b.refs++
+	return &pooledBodyReader{Reader: bytes.NewReader(b.buf.Bytes()), body: b}, nil
+}
+
+// release drops a reference to the buffer, and recycles it when it was the last one
+func (b *pooledBody) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refs--
+	if b.refs > 0 || b.buf == nil {
+		return
+	}
+	if b.buf.Cap() <= maxPooledBodySize {
+		bodyBufferPool.Put(b.buf)
+	}
+	b.buf = nil
+}
+
+type pooledBodyReader struct {
+	*bytes.Reader
+	body *pooledBody
+	once sync.Once
+}
+
+func (r *pooledBodyReader) Close() error {
+	r.once.Do(r.body.release)
+	return nil
+}
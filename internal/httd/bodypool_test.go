@@ -0,0 +1,110 @@
+// +build !integration
+
+package httd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/disgord/json"
+)
+
+func TestPooledBody(t *testing.T) {
+	body, err := newPooledBody(json.PackageCodec{}, map[string]string{"content": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `{"content":"hello"}` + "\n"
+	if body.size != int64(len(expected)) {
+		t.Errorf("expected a size of %d, got %d", len(expected), body.size)
+	}
+
+	// a retry reads the body again after the first reader was closed
+	for attempt := 1; attempt <= 2; attempt++ {
+		reader, err := body.reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(reader)
+		if string(data) != expected {
+			t.Errorf("attempt %d: expected body %q, got %q", attempt, expected, string(data))
+		}
+		_ = reader.Close()
+		_ = reader.Close()
+	}
+
+	// the transport may close the body after the request is done
+	reader, _ := body.reader()
+	body.release()
+	if body.buf == nil {
+		t.Fatal("the buffer was recycled while a reader was open")
+	}
+	_ = reader.Close()
+	if body.buf != nil {
+		t.Error("the buffer was not recycled after the last reader was closed")
+	}
+	if _, err = body.reader(); err == nil {
+		t.Error("expected an error when reading a recycled body")
+	}
+}
+
+// createMessagePayload is about the size of a CreateMessageParams with a full message and an embed
+type createMessagePayload struct {
+	Content string `json:"content"`
+	Nonce   string `json:"nonce"`
+	TTS     bool   `json:"tts"`
+	Embed   struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Fields      []struct {
+			Name   string `json:"name"`
+			Value  string `json:"value"`
+			Inline bool   `json:"inline"`
+		} `json:"fields"`
+	} `json:"embed"`
+}
+
+func BenchmarkJSONRequestBody(b *testing.B) {
+	payload := &createMessagePayload{
+		Content: strings.Repeat("a", 2000),
+		Nonce:   "34d5f3a2c0b1e6f7a8d9c0b1",
+	}
+	payload.Embed.Title = strings.Repeat("t", 256)
+	payload.Embed.Description = strings.Repeat("d", 2048)
+	payload.Embed.Fields = make([]struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}, 5)
+	for i := range payload.Embed.Fields {
+		payload.Embed.Fields[i].Name = strings.Repeat("n", 64)
+		payload.Embed.Fields[i].Value = strings.Repeat("v", 256)
+	}
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			data, err := json.Marshal(payload)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_, _ = io.Copy(ioutil.Discard, bytes.NewReader(data))
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			body, err := newPooledBody(json.PackageCodec{}, payload)
+			if err != nil {
+				b.Fatal(err)
+			}
+			reader, _ := body.reader()
+			_, _ = io.Copy(ioutil.Discard, reader)
+			_ = reader.Close()
+			body.release()
+		}
+	})
+}
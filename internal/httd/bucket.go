@@ -3,9 +3,10 @@ package httd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/andersfylling/disgord/internal/util"
@@ -16,6 +17,10 @@ func newLeakyBucket(global *ltBucket) (b *ltBucket) {
 		remaining: -1,
 		resetTime: time.Now(),
 		global:    global,
+		lastUsed:  time.Now().UnixNano(),
+	}
+	if global != nil {
+		b.drift = global.drift
 	}
 
 	return b
@@ -23,6 +28,33 @@ func newLeakyBucket(global *ltBucket) (b *ltBucket) {
 
 type bucketTransaction = func() (resp *http.Response, body []byte, err error)
 
+// maxRateLimitDelayKey holds how long a request may wait for an exhausted bucket to reset, see
+// Config.MaxRateLimitDelay
+type maxRateLimitDelayKey struct{}
+
+// WithMaxRateLimitDelay returns a context that makes requests wait at most delay for an exhausted rate limit
+// bucket, instead of Config.MaxRateLimitDelay. Requests are never delayed when delay is negative.
+func WithMaxRateLimitDelay(ctx context.Context, delay time.Duration) context.Context {
+	return context.WithValue(ctx, maxRateLimitDelayKey{}, delay)
+}
+
+// maxRateLimitDelay returns how long the request may wait for an exhausted bucket, and false when it may wait
+// as long as the context allows
+func maxRateLimitDelay(ctx context.Context) (within time.Duration, limited bool) {
+	if within, limited = ctx.Value(maxRateLimitDelayKey{}).(time.Duration); within < 0 {
+		within = 0
+	}
+	return within, limited
+}
+
+// priorityKey holds the Priority of the request waiting for the bucket
+type priorityKey struct{}
+
+func requestPriority(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
 // ltBucket combines leaky and token buckets to allow time aware of the REST requests while they're in queue.
 type ltBucket struct {
 	mu         sync.RWMutex
@@ -40,9 +72,51 @@ type ltBucket struct {
 	// this bucket is global if this.global is nil or this == this.global
 	global      *ltBucket
 	usingGlobal bool
+
+	// lastUsed is the unix nano time of the latest transaction, see Manager.EvictIdleBuckets
+	lastUsed int64
+
+	// limit is the number of requests per reset given by discord, or zero when not known yet
+	limit int
+
+	// waited is the total time in nanoseconds requests have spent waiting for the ltBucket, see Manager.Stats
+	waited int64
+
+	// rateLimits counts how often the ltBucket ran out of requests before the reset, see Manager.Stats
+	rateLimits int64
+
+	// drift is shared by the buckets of a Manager, or nil for a standalone ltBucket
+	drift *clockDrift
+
+	// seed paces the requests until discord has reported the rate limit, see Manager.SeedRouteLimit
+	seed *RouteLimit
+
+	// window is the length of the observed rate limit windows, see simulateReset
+	window resetWindow
+
+	// pacing spreads the remaining requests until the reset, when nextGrant is when the next one may be sent
+	pacing    Pacing
+	nextGrant time.Time
+
+	// queueLimits bound the waiting requests, and shedRequests counts the requests that were rejected by them
+	queueLimits  QueueLimits
+	shedRequests int64
+
+	// reserved are the remaining requests that are held by reservations, see Manager.Reserve
+	reserved int
+
+	// globalActive is set on the global ltBucket by a global 429 response, and cleared once the reset and the
+	// globalCooldown have passed. onGlobal is told about both, see Manager.OnGlobalRateLimit.
+	globalActive bool
+	onGlobal     func(active bool, wait time.Duration)
 }
 
-var _ RESTBucket = (*ltBucket)(nil)
+// globalCooldown is how long requests keep waiting for the global ltBucket after the global rate limit has
+// reset, such that a global rate limit that comes right back does not make every request flap between the
+// two.
+const globalCooldown = 500 * time.Millisecond
+
+var _ AcquirableBucket = (*ltBucket)(nil)
 
 func (b *ltBucket) AcquireLock() (locked bool) {
 	if locked = b.atomicLock.AcquireLock(); !locked {
@@ -58,11 +132,15 @@ func (b *ltBucket) AcquireLock() (locked bool) {
 }
 
 func (b *ltBucket) SelectiveGlobalLock() (locked bool, err error) {
-	if b != b.global {
+	if b.global != nil && b != b.global {
 		// peek global ltBucket
 		b.global.mu.RLock()
 		globalLock := b.global.active()
+		expired := !globalLock && b.global.globalActive
 		b.global.mu.RUnlock()
+		if expired {
+			b.global.expireGlobal(time.Now())
+		}
 		// TODO: can this cause http 429?
 		if globalLock {
 			// so check if the globalLock has changed since the read
@@ -85,33 +163,60 @@ func (b *ltBucket) SelectiveGlobalLock() (locked bool, err error) {
 }
 
 func (b *ltBucket) Transaction(ctx context.Context, do bucketTransaction) (resp *http.Response, body []byte, err error) {
-	// wait until you are next in line and you can acquire a lock
-	// this is to support timeout/cancellation for stacked requests
 	// TODO: on success, every request with same endpoint or a valid subset can be fulfilled locally
 	// reqA = /guilds/1/members?limit=100
 	// reqB = /guilds/1/members?limit=10
 	// reqB is a subset of A, and therefore reqA can create a response for reqB locally (must be deep copy - djp)
-	token := b.queue.NewTicket()
-	for {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// send request
+	resp, body, err = do()
+	if err != nil {
+		release(nil)
+		return nil, nil, err
+	}
+	release(resp)
+	return resp, body, nil
+}
+
+// Acquire waits until the ltBucket can send a request, and reserves it. The ltBucket is held until release
+// is called with the response, which updates the ltBucket, or with nil when the request was not sent, which
+// gives the reserved request back. Cancelling ctx while waiting gives up the place in the queue and reserves
+// nothing. It fails right away when the queue is beyond its limits, see Manager.SetQueueLimits.
+func (b *ltBucket) Acquire(ctx context.Context) (release func(resp *http.Response), err error) {
+	// wait until you are next in line and you can acquire a lock
+	// this is to support timeout/cancellation for stacked requests
+	started := time.Now()
+	atomic.StoreInt64(&b.lastUsed, started.UnixNano())
+	res := reservationFrom(ctx)
+	if res == nil || res.bucket != b {
+		if err = b.shed(started); err != nil {
+			return nil, err
+		}
+	}
+	token := b.queue.NewPriorityTicket(int(requestPriority(ctx)))
+	ready := b.queue.Ready(token)
+	poll := time.NewTimer(time.Hour)
+	defer poll.Stop()
+	for !b.queue.Next(token, b.AcquireLock) {
+		// the request in front is woken when the ltBucket is released. It also polls, as the global ltBucket
+		// is released by other buckets.
+		var retry <-chan time.Time
+		if b.queue.IsNext(token) {
+			poll.Reset(10 * time.Millisecond)
+			retry = poll.C
+		}
+
 		select {
 		case <-ctx.Done():
 			b.queue.Delete(token)
-			return nil, nil, errors.New("time out")
-		case <-time.After(10 * time.Millisecond):
-			// TODO-perf: this wastes a lot of CPU usage
+			return nil, fmt.Errorf("time out: %w", ctx.Err())
+		case <-ready:
+		case <-retry:
 		}
-
-		if !b.queue.Next(token, b.AcquireLock) {
-			continue
-		}
-		break
-	}
-	defer b.atomicLock.Unlock()
-	if b.usingGlobal {
-		defer b.global.atomicLock.Unlock()
-		defer func() {
-			b.usingGlobal = false
-		}()
 	}
 
 	// set active ltBucket
@@ -122,34 +227,164 @@ func (b *ltBucket) Transaction(ctx context.Context, do bucketTransaction) (resp
 		bucket = b
 	}
 
-	// check if rate limited and try to wait it out
-	var wait time.Duration
+	// check if rate limited and try to wait it out, unless a reserved request is used
 	now := time.Now()
-	if bucket.resetTime.After(now) && bucket.remaining == 0 {
-		wait = bucket.resetTime.Sub(now)
+	bucket.mu.RLock()
+	reserved := res.holds(bucket, now)
+	bucket.mu.RUnlock()
+	if wait := bucket.exhaustedFor(now); wait > 0 && !reserved {
+		deadline, ok := ctx.Deadline()
+		within, limited := maxRateLimitDelay(ctx)
+		if (limited && wait > within) || (ok && deadline.Before(now.Add(wait))) {
+			err = &RateLimitError{RetryAfter: wait, Global: b.usingGlobal, Bucket: bucket.hash}
+			b.unlock()
+			return nil, err
+		}
+
+		notifyRateLimited(ctx, wait, b.usingGlobal, bucket.hash)
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			b.unlock()
+			return nil, fmt.Errorf("time out: %w", ctx.Err())
+		case <-timer.C:
+		}
 	}
-	if deadline, ok := ctx.Deadline(); ok && deadline.Before(time.Now().Add(wait)) {
-		return nil, nil, errors.New("time out, bucket resets in " + wait.String())
+	waited := int64(time.Since(started))
+	atomic.AddInt64(&b.waited, waited)
+	if b.usingGlobal {
+		atomic.AddInt64(&b.global.waited, waited)
 	}
-	select {
-	case <-ctx.Done():
-		return nil, nil, errors.New("time out")
-	case <-time.After(wait):
+
+	if reserved {
+		return b.reserve(bucket, res), nil
 	}
+	return b.reserve(bucket, nil), nil
+}
 
-	// send request
-	resp, body, err = do()
-	if err != nil {
-		return nil, nil, err
+// TryAcquire works like Acquire, but never waits. When the ltBucket is held by another request or has no
+// remaining requests, ok is false and wait is how long until the ltBucket resets, or zero when it is only busy.
+func (b *ltBucket) TryAcquire() (release func(resp *http.Response), wait time.Duration, ok bool) {
+	now := time.Now()
+	atomic.StoreInt64(&b.lastUsed, now.UnixNano())
+	wait = b.exhaustedFor(now)
+	if b.global != nil && b != b.global {
+		if globalWait := b.global.exhaustedFor(now); globalWait > wait {
+			wait = globalWait
+		}
+	}
+
+	// queued requests go first
+	if wait > 0 || b.queue.Len() > 0 || !b.AcquireLock() {
+		return nil, wait, false
+	}
+	bucket := b
+	if b.usingGlobal {
+		bucket = b.global
 	}
+	if wait = bucket.exhaustedFor(time.Now()); wait > 0 {
+		b.unlock()
+		return nil, wait, false
+	}
+	return b.reserve(bucket, nil), 0, true
+}
 
-	// update ltBucket info
-	// reduce remaining if needed
-	if !b.updateAfterRequest(resp.Header, resp.StatusCode) && bucket.remaining > 0 {
+// exhaustedFor returns how long until the ltBucket resets, when it has no remaining requests
+func (b *ltBucket) exhaustedFor(now time.Time) time.Duration {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.resetTime.After(now) && b.remaining == 0 {
+		return b.resetTime.Sub(now)
+	}
+	if b.pacing == PacingSpread && b.nextGrant.After(now) {
+		return b.nextGrant.Sub(now)
+	}
+	return 0
+}
+
+// reserve takes a request from the active ltBucket, or from the reservation when it still holds one, which is
+// given back when release is called without a response, unless the ltBucket has been reset in the meantime.
+// release unlocks the ltBucket, and only the first call has an effect.
+func (b *ltBucket) reserve(bucket *ltBucket, res *Reservation) (release func(resp *http.Response)) {
+	bucket.mu.Lock()
+	now := time.Now()
+	bucket.simulateReset(now)
+	if !res.holds(bucket, now) {
+		res = nil
+	}
+	reserved := res == nil && bucket.remaining > 0
+	if res != nil {
+		res.used++
+		bucket.reserved--
+	} else if reserved {
+		if bucket.pacing == PacingSpread {
+			bucket.nextGrant = now.Add(pacedDelay(now, bucket.resetTime, bucket.remaining))
+		}
 		bucket.remaining--
 	}
+	reset := bucket.discordResetTime
+	bucket.mu.Unlock()
+
+	var once sync.Once
+	return func(resp *http.Response) {
+		once.Do(func() {
+			defer b.unlock()
+			if resp != nil {
+				// the headers replace the reserved request, unless they are older than the ltBucket
+				b.updateAfterRequest(resp.Header, resp.StatusCode)
+				return
+			}
 
-	return resp, body, nil
+			bucket.mu.Lock()
+			if res != nil && bucket.discordResetTime.Equal(reset) {
+				res.used--
+				bucket.reserved++
+			} else if reserved && bucket.discordResetTime.Equal(reset) {
+				bucket.remaining++
+			}
+			bucket.mu.Unlock()
+		})
+	}
+}
+
+// simulatedWindow is the window that is started when the reset has passed before a response tells the next
+// one: the seeded interval until discord has reported the rate limit, and the observed window after. It is
+// zero when the ltBucket is not limited until the next response. The ltBucket must be locked.
+func (b *ltBucket) simulatedWindow() (limit int, window time.Duration) {
+	if b.discordResetTime.IsZero() {
+		if b.seed != nil {
+			return b.seed.Limit, b.seed.Interval
+		}
+		return 0, 0
+	}
+	if b.limit <= 0 {
+		return 0, 0
+	}
+	return b.limit, b.window.estimate()
+}
+
+// simulateReset starts a new window when the reset has passed, such that the requests sent before the next
+// response are paced. The next response replaces the simulated window. The ltBucket must be locked.
+func (b *ltBucket) simulateReset(now time.Time) {
+	if b.resetTime.After(now) {
+		return
+	}
+	if limit, window := b.simulatedWindow(); window > 0 {
+		b.remaining = limit
+		b.resetTime = now.Add(window)
+		b.reserved = 0
+	}
+}
+
+// unlock releases the locks taken by AcquireLock, and wakes the next request in line
+func (b *ltBucket) unlock() {
+	if b.usingGlobal {
+		b.usingGlobal = false
+		b.global.atomicLock.Unlock()
+	}
+	b.atomicLock.Unlock()
+	b.queue.Notify()
 }
 
 // updateAfterRequests updates the bucket with the latest rate limit info from http responses.
@@ -160,24 +395,38 @@ func (b *ltBucket) updateAfterRequest(header http.Header, statusCode int) (adjus
 		panic("headers were not normalized to use milliseconds")
 	}
 
+	// hooks are called once the buckets are unlocked
+	var notify func()
+	defer func() {
+		if notify != nil {
+			notify()
+		}
+	}()
+
+	// the ltBucket is left untouched when the essential fields are malformed, instead of being half updated.
+	// The client reports the malformed fields.
+	parsed := parseRateLimitHeader(header, statusCode)
+	if !parsed.valid() {
+		return false
+	}
+
 	// to synchronize the timestamp between the bot and the discord server
-	// we assume the current time is equal the header date
+	// we assume the current time is equal the header date, smoothed over the previous responses
+	localTime := time.Now()
+	var diff time.Duration
 	discordTime, err := HeaderToTime(header)
 	if err != nil {
-		discordTime = time.Now()
+		discordTime = localTime
+		diff = b.drift.estimate()
+	} else {
+		diff = b.drift.observe(localTime.Sub(discordTime))
 	}
 
-	localTime := time.Now()
-	diff := localTime.Sub(discordTime)
-
-	var isGlobal bool
-	bucketHash := header.Get(XRateLimitBucket)
-	if _, ok := header[XRateLimitBucket]; ok && bucketHash == "" {
-		isGlobal = true
-	}
-	isGlobal = isGlobal || header.Get(XRateLimitGlobal) == "true"
+	isGlobal := parsed.global
+	bucketHash := parsed.bucket
 
 	// if this is not a 429 error we can determine if the local ltBucket is a global one or not
+	b.mu.Lock()
 	if statusCode != http.StatusTooManyRequests && b.hash == "" {
 		if isGlobal {
 			b.hash = GlobalHash
@@ -185,23 +434,17 @@ func (b *ltBucket) updateAfterRequest(header http.Header, statusCode int) (adjus
 			b.hash = bucketHash
 		}
 	}
+	b.mu.Unlock()
 
 	var reset time.Time
 	var discordReset time.Time
-	var remaining int = -1
-	if resetStr := header.Get(XRateLimitReset); resetStr != "" {
-		epoch, _ := strconv.ParseInt(resetStr, 10, 64)
-		epoch *= int64(time.Millisecond) // ms => nano
+	if parsed.reset > 0 {
+		epoch := parsed.reset * int64(time.Millisecond) // ms => nano
 		reset = time.Unix(0, epoch+diff.Nanoseconds())
 		discordReset = time.Unix(0, epoch)
 	}
-
-	if remainingStr := header.Get(XRateLimitRemaining); remainingStr != "" {
-		remainingInt64, _ := strconv.ParseInt(remainingStr, 10, 64)
-		if remainingInt64 >= 0 {
-			remaining = int(remainingInt64)
-		}
-	}
+	remaining := parsed.remaining
+	limit := parsed.limit
 
 	// update ltBucket reference to whatever the header regards
 	var bucket *ltBucket
@@ -214,25 +457,50 @@ func (b *ltBucket) updateAfterRequest(header http.Header, statusCode int) (adjus
 		bucket.mu.Lock()
 		defer bucket.mu.Unlock()
 	} else {
-		bucket = b // no need to lock normal buckets
+		// normal buckets are locked as well, as they can be merged with another ltBucket, see Manager
+		bucket = b
+		bucket.mu.Lock()
+		defer bucket.mu.Unlock()
 		if !(b.global == nil || b == b.global) && bucketHash != "" {
 			b.hash = bucketHash
 		}
 	}
 
+	if limit > 0 {
+		bucket.limit = limit
+	}
 	if discordReset.Before(time.Unix(0, int64(time.Hour))) {
 		return false
 	}
 
+	wasLimited := bucket.remaining == 0 && bucket.discordResetTime.Equal(discordReset)
+	defer func() {
+		if adjustedRemaining && bucket.remaining == 0 && !wasLimited {
+			atomic.AddInt64(&bucket.rateLimits, 1)
+		}
+	}()
+
 	// TODO: this can be simpler
 	// use discord reset time, as the local reset can be different in ms or s per request.
 	if discordReset.After(bucket.discordResetTime) {
+		// the first request of a window tells how long the windows of the ltBucket last
+		if bucket.limit > 0 && remaining == bucket.limit-1 {
+			bucket.window.observe(reset.Sub(localTime))
+		}
 		bucket.resetTime = reset
 		bucket.discordResetTime = discordReset
 		bucket.remaining = remaining
 		bucket.updatedAt = discordTime
+		bucket.reserved = 0 // the reservations of the previous window expire
 		adjustedRemaining = true
 	} else if bucket.discordResetTime == discordReset {
+		// the reserved requests have not been sent, and are part of the remaining requests discord reports
+		if remaining >= 0 {
+			if bucket.reserved > remaining {
+				bucket.reserved = remaining
+			}
+			remaining -= bucket.reserved
+		}
 		if bucket.remaining == -1 || bucket.remaining > remaining {
 			bucket.remaining = remaining
 			bucket.updatedAt = discordTime
@@ -240,10 +508,86 @@ func (b *ltBucket) updateAfterRequest(header http.Header, statusCode int) (adjus
 			adjustedRemaining = true
 		}
 	}
+	if isGlobal && statusCode == http.StatusTooManyRequests {
+		notify = bucket.activateGlobal(localTime)
+	}
 
 	return adjustedRemaining
 }
 
+// merge copies the rate limit state of another ltBucket with the same discord hash, when it is more recent
+// or has fewer remaining requests. The buckets are locked one at a time.
+func (b *ltBucket) merge(other *ltBucket) {
+	other.mu.RLock()
+	remaining := other.remaining
+	reset := other.resetTime
+	discordReset := other.discordResetTime
+	updatedAt := other.updatedAt
+	limit := other.limit
+	other.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if limit > 0 {
+		b.limit = limit
+	}
+	if discordReset.After(b.discordResetTime) {
+		b.resetTime = reset
+		b.discordResetTime = discordReset
+		b.remaining = remaining
+		b.updatedAt = updatedAt
+	} else if discordReset.Equal(b.discordResetTime) && remaining >= 0 && (b.remaining == -1 || remaining < b.remaining) {
+		b.remaining = remaining
+		b.updatedAt = updatedAt
+	}
+}
+
+// idle is true when no request has used the ltBucket since the given time, and the rate limit has reset such
+// that a new ltBucket would behave the same
+func (b *ltBucket) idle(since time.Time) bool {
+	if atomic.LoadInt64(&b.lastUsed) > since.UnixNano() || b.atomicLock.IsLocked() || b.queue.Len() > 0 {
+		return false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.resetTime.Before(time.Now())
+}
+
 func (b *ltBucket) active() bool {
-	return b.remaining >= 0 && !time.Now().After(b.resetTime)
+	now := time.Now()
+	if b.globalActive && now.Before(b.resetTime.Add(globalCooldown)) {
+		return true
+	}
+	return b.remaining >= 0 && !now.After(b.resetTime)
+}
+
+// activateGlobal starts the global rate limit after a global 429 response, after which every request waits
+// for the global ltBucket. It returns the hook to call once the ltBucket is unlocked. The ltBucket must be
+// locked.
+func (b *ltBucket) activateGlobal(now time.Time) (notify func()) {
+	if b.globalActive || b.remaining != 0 || !b.resetTime.After(now) {
+		return nil
+	}
+	b.globalActive = true
+	if hook, wait := b.onGlobal, b.resetTime.Sub(now); hook != nil {
+		return func() { hook(true, wait) }
+	}
+	return nil
+}
+
+// expireGlobal ends the global rate limit once its reset and the cooldown have passed, such that requests no
+// longer wait for the global ltBucket
+func (b *ltBucket) expireGlobal(now time.Time) {
+	b.mu.Lock()
+	expired := b.globalActive && !now.Before(b.resetTime.Add(globalCooldown))
+	if expired {
+		b.globalActive = false
+	}
+	hook := b.onGlobal
+	b.mu.Unlock()
+
+	if expired && hook != nil {
+		hook(false, 0)
+	}
 }
@@ -1,11 +1,49 @@
 package httd
 
 import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 const GlobalHash = "global"
 
+// DefaultBucketTTL is how long an unused ltBucket is kept, see Manager.StartEviction
+const DefaultBucketTTL = time.Hour
+
+// RouteLimit is a rate limit that is known before Discord reports it, see Manager.SeedRouteLimit
+type RouteLimit struct {
+	Limit    int
+	Interval time.Duration
+}
+
+// ReactionsGroup shares a ltBucket per channel between adding and removing reactions. Discord allows about
+// one reaction per 250ms, which the headers of the first response do not tell.
+const ReactionsGroup = "reactions"
+
+var reactionsLimit = RouteLimit{Limit: 1, Interval: 250 * time.Millisecond}
+
+var reactionsRoutes = []string{
+	"PUT:/channels/{major}/messages/{id}/reactions/{emoji}/@me",
+	"DELETE:/channels/{major}/messages/{id}/reactions/{emoji}/@me",
+	"DELETE:/channels/{major}/messages/{id}/reactions/{emoji}/{id}",
+	"DELETE:/channels/{major}/messages/{id}/reactions/{emoji}",
+	"DELETE:/channels/{major}/messages/{id}/reactions",
+}
+
+// EmojisGroup shares a ltBucket per guild between creating, updating and deleting emojis. Their resets take
+// several seconds, and are kept apart from the other guild routes.
+const EmojisGroup = "emojis"
+
+var emojisRoutes = []string{
+	"POST:/guilds/{major}/emojis",
+	"PATCH:/guilds/{major}/emojis/{id}",
+	"DELETE:/guilds/{major}/emojis/{id}",
+}
+
 func relationsByBucketID(relations map[string]string) map[string][]string {
 	byHash := make(map[string][]string)
 	for id, hash := range relations {
@@ -22,12 +60,20 @@ func relationsByBucketID(relations map[string]string) map[string][]string {
 func NewManager(defaultRelations map[string]string) *Manager {
 	global := newLeakyBucket(nil)
 	global.hash = GlobalHash
+	global.drift = &clockDrift{}
 
 	m := &Manager{
-		proxy:   make(map[string]string),
-		buckets: make(map[string]*ltBucket),
-		global:  global,
+		shardCount: managerShards,
+		routes:     make(map[string]string),
+		limits:     make(map[string]RouteLimit),
+		global:     global,
 	}
+	for i := range m.shards {
+		m.shards[i].proxy = make(map[string]string)
+		m.shards[i].buckets = make(map[string]*ltBucket)
+	}
+	m.SeedRouteLimit(ReactionsGroup, reactionsLimit, reactionsRoutes...)
+	m.SeedRouteLimit(EmojisGroup, RouteLimit{}, emojisRoutes...)
 
 	hashRelations := relationsByBucketID(defaultRelations)
 	for hash, ids := range hashRelations {
@@ -39,90 +85,470 @@ func NewManager(defaultRelations map[string]string) *Manager {
 		}
 
 		for i := range ids {
-			m.buckets[ids[i]] = bucket
+			m.shards[m.bucketShard(ids[i], ids[i])].buckets[ids[i]] = bucket
 		}
 	}
 
 	return m
 }
 
-type Manager struct {
+// managerShards is the number of shards the endpoints of a Manager are spread over, see managerShard
+const managerShards = 32
+
+// managerShard holds the endpoints of the major parameters that hash to it, such that requests to different
+// channels and guilds do not contend for the same lock. Endpoints that share an ltBucket have the same major
+// parameter, so their ltBucket is in the same shard, except for the ltBucket of the global hash, which is
+// always in the first shard. Shards are locked after Manager.mu, and in the order of their index.
+type managerShard struct {
 	mu sync.RWMutex
 
 	// proxy links a local endpoint hash to a discord defined rate limit ltBucket hash
 	// when no discord ltBucket hash is known, the value and the key are the same
 	proxy   map[string]string
 	buckets map[string]*ltBucket
+}
+
+type Manager struct {
+	shards [managerShards]managerShard
+
+	// shardCount is the number of shards in use, which divides managerShards. It is only lowered to compare
+	// the sharding with a single lock, see BenchmarkManager_Bucket.
+	shardCount int
+
+	// mu guards the fields below
+	mu sync.RWMutex
+
+	// routes links a local endpoint hash without the major parameter to a discord ltBucket hash, such that
+	// the ltBucket of a new major parameter is known before the first response
+	routes map[string]string
+
+	// limits are the seeded rate limits of a group of routes, see SeedRouteLimit
+	limits map[string]RouteLimit
+
+	pacing      Pacing
+	queueLimits QueueLimits
 
 	global *ltBucket
+
+	// stop ends the eviction started by StartEviction
+	stop chan struct{}
+}
+
+// majorParameter splits the major parameter, the guild, channel, webhook or interaction ID, from a local
+// endpoint hash.
+// Discord limits every major parameter separately, even when the ltBucket hash is the same.
+// "GET:/channels/1/messages/{id}" => "GET:/channels/{major}/messages/{id}", "channels/1"
+func majorParameter(id string) (route, major string) {
+	start, end := majorIndex(id)
+	if start < 0 {
+		return id, ""
+	}
+	major = id[start:end]
+	kind := major[:strings.IndexByte(major, '/')]
+	return id[:start] + kind + "/{major}" + id[end:], major
+}
+
+// BucketRoute is the route of a bucket hash as given to Metrics, such that requests can be grouped without a
+// group per guild, channel, webhook or interaction. Hashed endpoints have their major parameter replaced, and
+// their webhook and interaction tokens are already redacted, see Request.HashEndpoint. Discord bucket hashes
+// are returned as they are.
+// "POST:/interactions/1/{token}/callback" => "POST:/interactions/{major}/{token}/callback"
+func BucketRoute(bucket string) string {
+	route, _ := majorParameter(bucket)
+	return route
+}
+
+// majorIndex is where the major parameter is in a local endpoint hash, or -1 when it has none. It does not
+// allocate, as it is used for every lookup, see Manager.proxyShard.
+func majorIndex(id string) (start, end int) {
+	i := strings.Index(id, ":/")
+	if i < 0 {
+		return -1, -1
+	}
+	start = i + 2
+	slash := strings.IndexByte(id[start:], '/')
+	if slash < 0 {
+		return -1, -1
+	}
+	switch id[start : start+slash] {
+	case "guilds", "channels", "webhooks", "interactions":
+	default:
+		return -1, -1
+	}
+
+	end = start + slash + 1
+	if next := strings.IndexByte(id[end:], '/'); next >= 0 {
+		end += next
+	} else {
+		end = len(id)
+	}
+	if param := id[start+slash+1 : end]; param == "" || param == "{id}" {
+		return -1, -1
+	}
+	return start, end
+}
+
+// bucketKey is where the ltBucket of a local endpoint hash is stored. Endpoints with the same discord
+// ltBucket hash and major parameter share an ltBucket.
+func bucketKey(id, pID string) string {
+	if pID == id || pID == GlobalHash {
+		return pID
+	}
+	if _, major := majorParameter(id); major != "" {
+		return pID + ":" + major
+	}
+	return pID
 }
 
 var _ RESTBucketManager = (*Manager)(nil)
 
-func (r *Manager) BucketGrouping() (group map[string][]string) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// shard is the index of the shard of a major parameter
+func shard(major string) int {
+	// FNV-1a
+	hash := uint32(2166136261)
+	for i := 0; i < len(major); i++ {
+		hash ^= uint32(major[i])
+		hash *= 16777619
+	}
+	return int(hash % managerShards)
+}
+
+// proxyShard is the index of the shard that holds the discord hash of a local endpoint hash
+func (r *Manager) proxyShard(id string) int {
+	if start, end := majorIndex(id); start >= 0 {
+		return shard(id[start:end]) % r.shardCount
+	}
+	return shard("") % r.shardCount
+}
 
+// bucketShard is the index of the shard that holds the ltBucket of a local endpoint hash
+func (r *Manager) bucketShard(id, pID string) int {
+	if pID == GlobalHash {
+		return 0
+	}
+	return r.proxyShard(id)
+}
+
+// lockShards write locks the shards in the order of their index, and returns a function to unlock them
+func (r *Manager) lockShards(indexes ...int) (unlock func()) {
+	sort.Ints(indexes)
+	locked := make([]*managerShard, 0, len(indexes))
+	for i, index := range indexes {
+		if i > 0 && index == indexes[i-1] {
+			continue
+		}
+		r.shards[index].mu.Lock()
+		locked = append(locked, &r.shards[index])
+	}
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			locked[i].mu.Unlock()
+		}
+	}
+}
+
+// lockAllShards write locks every shard, see lockShards
+func (r *Manager) lockAllShards() (unlock func()) {
+	indexes := make([]int, managerShards)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return r.lockShards(indexes...)
+}
+
+// lookupBucket returns the ltBucket under the key of a local endpoint hash, or nil
+func (r *Manager) lookupBucket(id, pID string) *ltBucket {
+	s := &r.shards[r.bucketShard(id, pID)]
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.buckets[bucketKey(id, pID)]
+}
+
+func (r *Manager) BucketGrouping() (group map[string][]string) {
 	group = make(map[string][]string)
-	for k, v := range r.proxy {
-		group[v] = append(group[v], k)
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		for k, v := range s.proxy {
+			group[v] = append(group[v], k)
+		}
+		s.mu.RUnlock()
 	}
 	return group
 }
 
+// ProxyID returns the discord ltBucket hash of a local endpoint hash, or the local endpoint hash when it is
+// not known yet. New endpoints of a known route, such as a different channel, use the hash of the route.
 func (r *Manager) ProxyID(id string) (pID string) {
 	// only do a write lock if we need to create a new proxy
-	r.mu.RLock()
-	pID, ok := r.proxy[id]
-	r.mu.RUnlock()
+	s := &r.shards[r.proxyShard(id)]
+	s.mu.RLock()
+	pID, ok := s.proxy[id]
+	s.mu.RUnlock()
 	if !ok {
-		r.mu.Lock()
-		if _, ok = r.proxy[id]; !ok {
-			r.proxy[id] = id
+		route, _ := majorParameter(id)
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		s.mu.Lock()
+		if _, ok = s.proxy[id]; !ok {
+			if hash, known := r.routes[route]; known {
+				s.proxy[id] = hash
+			} else {
+				s.proxy[id] = id
+			}
 		}
-		pID = r.proxy[id]
-		r.mu.Unlock()
+		pID = s.proxy[id]
+		s.mu.Unlock()
 	}
 
 	return pID
 }
 
+// BucketKey identifies the ltBucket of a local endpoint hash: the discord ltBucket hash followed by the major
+// parameter, or the local endpoint hash while the discord hash is not known. It is the same in every process,
+// such that it can be used to share the rate limits, see the redisratelimit package.
+func (r *Manager) BucketKey(id string) string {
+	return bucketKey(id, r.ProxyID(id))
+}
+
+// SeedRouteLimit makes the routes share a ltBucket per major parameter, which is limited to limit.Limit
+// requests per limit.Interval until Discord responds with rate limit headers. Routes are local endpoint hashes
+// with the major parameter replaced, eg. "PUT:/channels/{major}/messages/{id}/reactions/{emoji}/@me".
+// Endpoints that already have a ltBucket are not affected.
+func (r *Manager) SeedRouteLimit(group string, limit RouteLimit, routes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits[group] = limit
+	for _, route := range routes {
+		r.routes[route] = group
+	}
+}
+
+// UpdateProxyID links the local endpoint hash to the discord ltBucket hash, and merges its ltBucket with the
+// ltBucket of other endpoints that discord says are the same.
 func (r *Manager) UpdateProxyID(id, pID, bucketHash string) {
-	if bucketHash == "" || bucketHash == pID {
+	if bucket := r.lookupBucket(id, pID); bucket != nil {
+		r.consolidate(id, pID, bucket, bucketHash)
+	}
+}
+
+// consolidate moves the endpoint to the ltBucket of the discord hash. bucket is the ltBucket the last request
+// used, which might have been replaced while the request was in flight, and its rate limit state is merged
+// into the shared ltBucket such that no update from a response is lost.
+func (r *Manager) consolidate(id, pID string, bucket *ltBucket, bucketHash string) {
+	if bucketHash == "" {
+		return
+	}
+	key := bucketKey(id, bucketHash)
+	if pID == bucketHash && r.lookupBucket(id, bucketHash) == bucket {
+		// usually nothing has changed since the previous request
 		return
 	}
 
 	r.mu.Lock()
-	if _, exists := r.buckets[bucketHash]; !exists {
-		r.buckets[bucketHash] = r.buckets[r.proxy[id]]
+	defer r.mu.Unlock()
+	if bucketHash != GlobalHash {
+		route, _ := majorParameter(id)
+		r.routes[route] = bucketHash
 	}
-	r.proxy[id] = bucketHash
-	r.mu.Unlock()
+
+	// the ltBucket of the global hash is in another shard than the endpoint
+	proxies := &r.shards[r.proxyShard(id)]
+	previous := &r.shards[r.bucketShard(id, pID)]
+	current := &r.shards[r.bucketShard(id, bucketHash)]
+	defer r.lockShards(r.proxyShard(id), r.bucketShard(id, pID), r.bucketShard(id, bucketHash))()
+
+	shared, exists := current.buckets[key]
+	if !exists {
+		current.buckets[key] = bucket
+		shared = bucket
+	} else if shared != bucket {
+		// buckets are only locked one at a time, and always after the manager, so merging can not deadlock
+		shared.merge(bucket)
+	}
+
+	// requests that looked up the old key before the update must find the shared ltBucket as well
+	if old := bucketKey(id, pID); old != key {
+		previous.buckets[old] = shared
+	}
+	proxies.proxy[id] = bucketHash
 }
 
-func (r *Manager) Bucket(id string, cb func(bucket RESTBucket)) {
-	pID := r.ProxyID(id)
+// bucket returns the ltBucket of the local endpoint hash, and creates it when there is none. It is only created
+// while the endpoint still links to the discord hash it was looked up with, otherwise a request that raced a
+// consolidation would register an ltBucket under a key no other request uses, and bypass the shared one.
+func (r *Manager) bucket(id string) (pID string, bucket *ltBucket) {
+	for {
+		pID = r.ProxyID(id)
+		if bucket = r.lookupBucket(id, pID); bucket != nil {
+			return pID, bucket
+		}
 
-	// only do a write lock if we need to create a new ltBucket
-	r.mu.RLock()
-	bucket, ok := r.buckets[pID]
-	r.mu.RUnlock()
-	if !ok {
-		r.mu.Lock()
-		if _, ok = r.buckets[pID]; !ok {
-			r.buckets[pID] = newLeakyBucket(r.global)
+		// only do a write lock if we need to create a new ltBucket
+		key := bucketKey(id, pID)
+		proxies := &r.shards[r.proxyShard(id)]
+		s := &r.shards[r.bucketShard(id, pID)]
+		r.mu.RLock()
+		unlock := r.lockShards(r.proxyShard(id), r.bucketShard(id, pID))
+		if current, linked := proxies.proxy[id]; linked && current == pID {
+			if bucket = s.buckets[key]; bucket == nil {
+				bucket = newLeakyBucket(r.global)
+				bucket.pacing = r.pacing
+				bucket.queueLimits = r.queueLimits
+				if limit, seeded := r.limits[pID]; seeded {
+					bucket.seed = &limit
+				}
+				s.buckets[key] = bucket
+			}
+		}
+		unlock()
+		r.mu.RUnlock()
+
+		if bucket != nil {
+			return pID, bucket
 		}
-		bucket = r.buckets[pID]
-		r.mu.Unlock()
 	}
+}
 
+// Bucket calls cb with the ltBucket of the local endpoint hash
+func (r *Manager) Bucket(id string, cb func(bucket RESTBucket)) {
+	pID, bucket := r.bucket(id)
 	cb(bucket)
 	bucket.mu.RLock()
 	hash := bucket.hash
 	bucket.mu.RUnlock()
-	r.UpdateProxyID(id, pID, hash)
+	r.consolidate(id, pID, bucket, hash)
 }
 
+// Consolidate removes the ltBuckets of local endpoint hashes that have been merged into the ltBucket of their
+// discord hash. Requests that are still waiting in a removed ltBucket are merged when they complete.
 func (r *Manager) Consolidate() {
+	defer r.lockAllShards()()
+
+	// endpoints can use an ltBucket of another shard, see managerShard
+	var used [managerShards]map[string]bool
+	for i := range used {
+		used[i] = make(map[string]bool)
+	}
+	for i := range r.shards {
+		for id, pID := range r.shards[i].proxy {
+			used[r.bucketShard(id, pID)][bucketKey(id, pID)] = true
+		}
+	}
+	for i := range r.shards {
+		s := &r.shards[i]
+		for key := range s.buckets {
+			if _, isLocal := s.proxy[key]; isLocal && !used[i][key] {
+				delete(s.buckets, key)
+			}
+		}
+	}
+}
 
+// EvictIdleBuckets removes the ltBuckets that have not been used within ttl, and whose rate limit has reset,
+// together with the endpoints that point to them. Otherwise every channel and guild the bot has ever sent a
+// request to keeps an ltBucket. It returns the number of ltBuckets removed.
+//
+// A request that looked up an ltBucket right before it was removed finishes in it, as the ltBucket holds no
+// rate limit state worth keeping. The next request creates a new ltBucket.
+func (r *Manager) EvictIdleBuckets(ttl time.Duration) (evicted int) {
+	since := time.Now().Add(-ttl)
+	defer r.lockAllShards()()
+
+	idle := make(map[*ltBucket]bool)
+	for i := range r.shards {
+		for key, bucket := range r.shards[i].buckets {
+			if bucket == r.global {
+				continue
+			}
+			isIdle, checked := idle[bucket]
+			if !checked {
+				isIdle = bucket.idle(since)
+				idle[bucket] = isIdle
+				if isIdle {
+					evicted++
+				}
+			}
+			if isIdle {
+				delete(r.shards[i].buckets, key)
+			}
+		}
+	}
+	for i := range r.shards {
+		for id, pID := range r.shards[i].proxy {
+			if _, exists := r.shards[r.bucketShard(id, pID)].buckets[bucketKey(id, pID)]; !exists {
+				delete(r.shards[i].proxy, id)
+			}
+		}
+	}
+	return evicted
+}
+
+// StartEviction calls EvictIdleBuckets in the background every ttl/2, until Stop is called. A ttl of zero uses
+// DefaultBucketTTL. Calling it again while the eviction is running does nothing.
+func (r *Manager) StartEviction(ttl time.Duration) {
+	if ttl == 0 {
+		ttl = DefaultBucketTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(ttl / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				r.EvictIdleBuckets(ttl)
+			}
+		}
+	}()
+}
+
+// Stop ends the eviction started by StartEviction
+func (r *Manager) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// NewNopManager creates a RESTBucketManager that does not rate limit requests locally. Use it when the
+// requests go through a proxy that handles the rate limits, see Config.DisableRateLimiter.
+func NewNopManager() *NopManager {
+	return &NopManager{}
+}
+
+// NopManager sends every request right away, see NewNopManager
+type NopManager struct{}
+
+var _ RESTBucketManager = (*NopManager)(nil)
+
+func (NopManager) Bucket(_ string, cb func(bucket RESTBucket)) {
+	cb(nopBucket{})
+}
+
+func (NopManager) BucketGrouping() (group map[string][]string) {
+	return map[string][]string{}
+}
+
+type nopBucket struct{}
+
+var _ RESTBucket = (*nopBucket)(nil)
+
+func (nopBucket) Transaction(ctx context.Context, do bucketTransaction) (*http.Response, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return do()
 }
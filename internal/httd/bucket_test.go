@@ -6,12 +6,28 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
+// managerSize counts the buckets and endpoints in the shards of the manager
+func managerSize(mngr *Manager) (buckets, endpoints int) {
+	for i := range mngr.shards {
+		s := &mngr.shards[i]
+		s.mu.RLock()
+		buckets += len(s.buckets)
+		endpoints += len(s.proxy)
+		s.mu.RUnlock()
+	}
+	return buckets, endpoints
+}
+
 func TestLtBucket_AcquireLock(t *testing.T) {
 	t.Run("already-locked", func(t *testing.T) {
 		global := newLeakyBucket(nil)
@@ -80,7 +96,7 @@ func TestLtBucket_updateAfterResponse(t *testing.T) {
 		resp.Header.Set(XRateLimitReset, strconv.FormatFloat(float64(reset.UnixNano())/float64(time.Second), 'f', 5, 64))
 		resp.Header.Set("date", time.Now().Format(time.RFC1123))
 
-		header, err := NormalizeDiscordHeader(resp.StatusCode, resp.Header, nil)
+		header, err := NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -97,6 +113,48 @@ func TestLtBucket_updateAfterResponse(t *testing.T) {
 			t.Errorf("reset did not update. Got %s, wants %s", bucket.discordResetTime.String(), reset.String())
 		}
 	})
+
+	t.Run("malformed-fields", func(t *testing.T) {
+		reset := time.Now().Add(time.Minute)
+		newReset := strconv.FormatInt(reset.Add(time.Minute).UnixNano()/int64(time.Millisecond), 10)
+		tests := []struct {
+			name      string
+			fields    map[string]string
+			remaining int
+			limit     int
+			updated   bool
+		}{
+			{"reset", map[string]string{XRateLimitReset: "soon", XRateLimitRemaining: "0", XRateLimitLimit: "5"}, 3, 10, false},
+			{"remaining", map[string]string{XRateLimitReset: newReset, XRateLimitRemaining: "none", XRateLimitLimit: "5"}, 3, 10, false},
+			{"limit", map[string]string{XRateLimitReset: newReset, XRateLimitRemaining: "1", XRateLimitLimit: "five"}, 1, 10, true},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				bucket := newLeakyBucket(newLeakyBucket(nil))
+				bucket.hash = "a"
+				bucket.limit = 10
+				bucket.remaining = 3
+				bucket.discordResetTime = reset
+				bucket.resetTime = reset
+
+				header := http.Header{}
+				header.Set(XRateLimitBucket, "b")
+				for field, value := range test.fields {
+					header.Set(field, value)
+				}
+				header.Set(DisgordNormalizedHeader, "true")
+				if updated := bucket.updateAfterRequest(header, http.StatusOK); updated != test.updated {
+					t.Errorf("expected the update to be %t", test.updated)
+				}
+				if bucket.remaining != test.remaining || bucket.limit != test.limit {
+					t.Errorf("expected %d/%d remaining, got %d/%d", test.remaining, test.limit, bucket.remaining, bucket.limit)
+				}
+				if !test.updated && (bucket.hash != "a" || !bucket.discordResetTime.Equal(reset)) {
+					t.Errorf("the bucket was partially updated to %s, reset %s", bucket.hash, bucket.discordResetTime)
+				}
+			})
+		}
+	})
 }
 
 func TestLtBucket_RespectRateLimit(t *testing.T) {
@@ -121,7 +179,7 @@ func TestLtBucket_RespectRateLimit(t *testing.T) {
 			resp.Header.Set(XRateLimitReset, strconv.FormatFloat(float64(reset.UnixNano())/float64(time.Second), 'f', 4, 64))
 			resp.Header.Set("date", time.Now().Format(time.RFC1123))
 
-			resp.Header, _ = NormalizeDiscordHeader(resp.StatusCode, resp.Header, nil)
+			resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
 
 			return resp, nil, nil
 		})
@@ -134,7 +192,7 @@ func TestLtBucket_RespectRateLimit(t *testing.T) {
 			return nil, nil, nil
 		})
 
-		if err == nil || !strings.Contains(err.Error(), "time out") {
+		if !errors.Is(err, ErrRateLimited) {
 			t.Error("should have been rate limited")
 		}
 	})
@@ -157,3 +215,832 @@ func TestLtBucket_RespectRateLimit(t *testing.T) {
 	})
 
 }
+
+func TestLtBucket_CancelRateLimitWait(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "cancelled-wait"
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.remaining = 0
+		b.resetTime = time.Now().Add(time.Hour)
+	})
+
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-time.After(50 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		_, _, err := bucket.Transaction(ctx, func() (*http.Response, []byte, error) {
+			return nil, nil, errors.New("should not send the request")
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the wait to be cancelled, got %v", err)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Error("the rate limit wait did not abort on cancellation")
+		}
+
+		// the bucket must be released again
+		b := bucket.(*ltBucket)
+		b.resetTime = time.Now()
+		_, _, err = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			return nil, nil, errors.New("sent")
+		})
+		if err == nil || err.Error() != "sent" {
+			t.Errorf("expected the request to be sent, got %v", err)
+		}
+	})
+}
+
+func TestLtBucket_CancelWhenRateLimited(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "cancel-when-rate-limited"
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.hash = "abcd1234"
+		b.remaining = 0
+		b.resetTime = time.Now().Add(time.Hour)
+	})
+
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		ctx := WithMaxRateLimitDelay(context.Background(), -1)
+		var sent bool
+		_, _, err := bucket.Transaction(ctx, func() (*http.Response, []byte, error) {
+			sent = true
+			return nil, nil, errors.New("should not send the request")
+		})
+		if sent {
+			t.Error("the request was sent while the bucket was exhausted")
+		}
+
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) || !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected a rate limit error, got %v", err)
+		}
+		if rateLimitErr.Bucket != "abcd1234" || rateLimitErr.Global {
+			t.Errorf("unexpected bucket details: %+v", rateLimitErr)
+		}
+		if rateLimitErr.RetryAfter < 59*time.Minute || rateLimitErr.RetryAfter > time.Hour {
+			t.Errorf("expected to retry after about an hour, got %s", rateLimitErr.RetryAfter)
+		}
+	})
+}
+
+func TestLtBucket_Acquire(t *testing.T) {
+	b := newLeakyBucket(nil)
+	b.remaining = 5
+	b.resetTime = time.Now().Add(time.Hour)
+	b.discordResetTime = b.resetTime
+
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.remaining != 4 {
+		t.Errorf("expected a reserved request, got %d remaining", b.remaining)
+	}
+
+	// requests cancelled while another request holds the bucket must not take a request
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := b.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to be cancelled, got %v", err)
+	}
+
+	// the request was not sent, so it is given back
+	release(nil)
+	release(nil)
+	if b.remaining != 5 {
+		t.Errorf("expected the reserved request to be given back, got %d remaining", b.remaining)
+	}
+
+	release, err = b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(&http.Response{StatusCode: http.StatusOK, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}})
+	if b.remaining != 4 {
+		t.Errorf("expected the sent request to be counted, got %d remaining", b.remaining)
+	}
+}
+
+func TestLtBucket_AcquirePastExhaustion(t *testing.T) {
+	b := newLeakyBucket(nil)
+	b.remaining = 1
+	b.resetTime = time.Now().Add(time.Hour)
+
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(&http.Response{StatusCode: http.StatusOK, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}})
+
+	// rejected requests must not take a request from the next window
+	ctx := WithMaxRateLimitDelay(context.Background(), 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, err := b.Acquire(ctx); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected the exhausted bucket to reject the request, got %v", err)
+		}
+	}
+	if b.remaining != 0 {
+		t.Errorf("expected no remaining requests, got %d", b.remaining)
+	}
+	if _, wait, ok := b.TryAcquire(); ok || wait < 59*time.Minute {
+		t.Errorf("expected the bucket to stay limited, got ok=%t wait=%s", ok, wait)
+	}
+}
+
+func TestLtBucket_TryAcquire(t *testing.T) {
+	b := newLeakyBucket(nil)
+	b.remaining = 2
+	b.resetTime = time.Now().Add(time.Hour)
+
+	release, _, ok := b.TryAcquire()
+	if !ok {
+		t.Fatal("expected to acquire the bucket")
+	}
+	if _, wait, ok := b.TryAcquire(); ok || wait != 0 {
+		t.Errorf("expected the held bucket to be busy, got ok=%t wait=%s", ok, wait)
+	}
+	release(&http.Response{StatusCode: http.StatusOK, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}})
+	if release, _, ok = b.TryAcquire(); !ok {
+		t.Fatal("expected to acquire the last request")
+	}
+	release(&http.Response{StatusCode: http.StatusOK, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}})
+
+	_, wait, ok := b.TryAcquire()
+	if ok {
+		t.Fatal("acquired an exhausted bucket")
+	}
+	if wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("expected to wait about an hour, got %s", wait)
+	}
+}
+
+func TestLtBucket_GlobalRateLimit(t *testing.T) {
+	mngr := NewManager(nil)
+	mngr.Bucket("/channels/1/messages", func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			body := []byte(`{"message":"You are being rate limited.","retry_after":60000,"global":true}`)
+			resp := &http.Response{
+				Header:     make(http.Header),
+				StatusCode: http.StatusTooManyRequests,
+			}
+			resp.Header.Set(XRateLimitGlobal, "true")
+			resp.Header.Set("date", time.Now().Format(time.RFC1123))
+
+			var err error
+			resp.Header, err = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, body)
+			return resp, body, err
+		})
+	})
+
+	// a different bucket must wait for the global reset
+	mngr.Bucket("/guilds/1/members", func(bucket RESTBucket) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var sent bool
+		_, _, err := bucket.Transaction(ctx, func() (*http.Response, []byte, error) {
+			sent = true
+			return nil, nil, errors.New("should not send the request")
+		})
+		if sent {
+			t.Fatal("the request was sent while the global bucket was exhausted")
+		}
+
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("expected a rate limit error, got %v", err)
+		}
+		if !rateLimitErr.Global || rateLimitErr.RetryAfter < 50*time.Second {
+			t.Errorf("expected to wait about a minute on the global bucket, got %+v", rateLimitErr)
+		}
+	})
+
+	// and requests are sent again once the global bucket has reset
+	mngr.global.mu.Lock()
+	mngr.global.resetTime = time.Now().Add(-time.Second)
+	mngr.global.mu.Unlock()
+	mngr.Bucket("/guilds/1/members", func(bucket RESTBucket) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, _, err := bucket.Transaction(ctx, func() (*http.Response, []byte, error) {
+			return nil, nil, errors.New("sent")
+		})
+		if err == nil || err.Error() != "sent" {
+			t.Errorf("expected the request to be sent after the global reset, got %v", err)
+		}
+	})
+}
+
+func TestLtBucket_Priority(t *testing.T) {
+	const window = 100 * time.Millisecond
+	b := newLeakyBucket(nil)
+	b.remaining = 0
+	b.resetTime = time.Now().Add(window)
+
+	var mu sync.Mutex
+	var order []string
+	send := func(name string, priority Priority, wg *sync.WaitGroup) {
+		defer wg.Done()
+		ctx := context.Background()
+		if priority != PriorityNormal {
+			ctx = context.WithValue(ctx, priorityKey{}, priority)
+		}
+		_, _, _ = b.Transaction(ctx, func() (*http.Response, []byte, error) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil, nil, errors.New("sent")
+		})
+	}
+
+	// the first request holds the bucket until the window resets, while the others queue up behind it
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go send("first", PriorityLow, &wg)
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go send("low", PriorityLow, &wg)
+	}
+	time.Sleep(20 * time.Millisecond)
+	wg.Add(1)
+	go send("high", PriorityHigh, &wg)
+	wg.Wait()
+
+	if len(order) != 12 {
+		t.Fatalf("expected 12 requests to be sent, got %d", len(order))
+	}
+	if order[0] != "first" || order[1] != "high" {
+		t.Errorf("expected the high priority request to be sent right after the first window, got %v", order)
+	}
+}
+
+func TestLtBucket_FIFO(t *testing.T) {
+	b := newLeakyBucket(nil)
+
+	// the first request holds the bucket while the others queue up behind it
+	held, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, _, _ = b.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			close(held)
+			<-release
+			return nil, nil, errors.New("sent")
+		})
+	}()
+	<-held
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, _ = b.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				return nil, nil, errors.New("sent")
+			})
+		}(i)
+
+		// wait for the request to be queued, such that the order is known
+		for b.queue.Len() <= i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	for i := range order {
+		if order[i] != i {
+			t.Fatalf("expected the requests to be sent in the order they were queued, got %v", order)
+		}
+	}
+}
+
+func BenchmarkLtBucket_ConcurrentSenders(b *testing.B) {
+	const senders = 100
+	bucket := newLeakyBucket(nil)
+	latencies := make([]time.Duration, 0, b.N*senders)
+	var mu sync.Mutex
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var wg sync.WaitGroup
+		for i := 0; i < senders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+					return nil, nil, errors.New("sent")
+				})
+				mu.Lock()
+				latencies = append(latencies, time.Since(start))
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	p99 := latencies[len(latencies)*99/100]
+	b.ReportMetric(float64(p99)/float64(time.Millisecond), "p99-ms")
+}
+
+// BenchmarkManager_Bucket looks up the buckets of 500 channels from 1000 goroutines, with the endpoints spread
+// over the shards, and in a single shard as a baseline
+func BenchmarkManager_Bucket(b *testing.B) {
+	b.Run("sharded", func(b *testing.B) {
+		benchmarkManagerBucket(b, managerShards)
+	})
+	b.Run("single-shard", func(b *testing.B) {
+		benchmarkManagerBucket(b, 1)
+	})
+}
+
+func benchmarkManagerBucket(b *testing.B, shards int) {
+	const senders = 1000
+	mngr := NewManager(nil)
+	mngr.shardCount = shards
+	routes := []string{"POST:/channels/%d/messages", "GET:/channels/%d/messages/{id}", "POST:/channels/%d/typing"}
+	ids := make([]string, 0, 500*len(routes))
+	for channel := 1; channel <= 500; channel++ {
+		for _, route := range routes {
+			ids = append(ids, strings.Replace(route, "%d", strconv.Itoa(channel), 1))
+		}
+	}
+	for i, id := range ids {
+		hash := routes[i%len(routes)]
+		mngr.Bucket(id, func(bucket RESTBucket) {
+			bucket.(*ltBucket).hash = hash
+		})
+	}
+
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for n := 0; n < b.N/senders+1; n++ {
+				mngr.Bucket(ids[(offset+n*senders)%len(ids)], func(RESTBucket) {})
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestManager_MergeBucketsByHash(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+	respond := func(hash string, remaining int) bucketTransaction {
+		return func() (*http.Response, []byte, error) {
+			resp := &http.Response{Header: make(http.Header), StatusCode: http.StatusOK}
+			resp.Header.Set(XRateLimitBucket, hash)
+			resp.Header.Set(XRateLimitRemaining, strconv.Itoa(remaining))
+			resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+			resp.Header.Set("date", time.Now().UTC().Format(time.RFC1123))
+
+			var err error
+			resp.Header, err = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+			return resp, nil, err
+		}
+	}
+	send := func(mngr *Manager, id string, tx bucketTransaction) (used *ltBucket) {
+		mngr.Bucket(id, func(bucket RESTBucket) {
+			used = bucket.(*ltBucket)
+			_, _, _ = bucket.Transaction(context.Background(), tx)
+		})
+		return used
+	}
+
+	const messages = "GET:/channels/1/messages"
+	const message = "DELETE:/channels/1/messages/{id}"
+
+	t.Run("converge", func(t *testing.T) {
+		mngr := NewManager(nil)
+		first := send(mngr, messages, respond("abcd1234", 4))
+		second := send(mngr, message, respond("abcd1234", 3))
+		if first == second {
+			t.Fatal("the local keys should start out with their own bucket")
+		}
+
+		shared := send(mngr, message, respond("abcd1234", 2))
+		if again := send(mngr, messages, respond("abcd1234", 1)); again != shared || shared != first {
+			t.Fatal("expected both local keys to use the bucket of the discord hash")
+		}
+		if shared.remaining != 1 {
+			t.Errorf("expected 1 remaining request, got %d", shared.remaining)
+		}
+		if ids := mngr.BucketGrouping()["abcd1234"]; len(ids) != 2 {
+			t.Errorf("expected both local keys to be grouped by the hash, got %v", ids)
+		}
+
+		// another channel shares the hash, but is limited separately
+		if mngr.ProxyID("GET:/channels/2/messages") != "abcd1234" {
+			t.Error("expected the hash of the route to be known for a new channel")
+		}
+		if other := send(mngr, "GET:/channels/2/messages", respond("abcd1234", 4)); other == shared {
+			t.Error("channels with the same hash must not share a bucket")
+		}
+
+		mngr.Consolidate()
+		if mngr.lookupBucket(message, message) != nil {
+			t.Error("expected the merged local bucket to be removed")
+		}
+	})
+
+	t.Run("in flight", func(t *testing.T) {
+		mngr := NewManager(nil)
+		send(mngr, messages, respond("abcd1234", 4))
+
+		// a request holds the local bucket while the other local key learns the hash
+		started, release := make(chan struct{}), make(chan struct{})
+		done := make(chan *ltBucket)
+		go func() {
+			done <- send(mngr, message, func() (*http.Response, []byte, error) {
+				close(started)
+				<-release
+				return respond("abcd1234", 0)()
+			})
+		}()
+		<-started
+		shared := send(mngr, messages, respond("abcd1234", 3))
+		close(release)
+		local := <-done
+
+		if local == shared {
+			t.Fatal("expected the request to be in flight in the local bucket")
+		}
+		if shared.remaining != 0 {
+			t.Errorf("the update of the request in flight was lost, %d remaining", shared.remaining)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		mngr := NewManager(nil)
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id := "GET:/channels/1/messages/" + strconv.Itoa(i%4) + "/reactions"
+				send(mngr, id, respond("abcd1234", 50-i))
+			}(i)
+		}
+		wg.Wait()
+
+		var shared *ltBucket
+		for i := 0; i < 4; i++ {
+			bucket := send(mngr, "GET:/channels/1/messages/"+strconv.Itoa(i)+"/reactions", respond("abcd1234", 5))
+			if shared != nil && bucket != shared {
+				t.Fatal("expected every local key to converge on one bucket")
+			}
+			shared = bucket
+		}
+	})
+}
+
+func TestManager_ConcurrentConsolidation(t *testing.T) {
+	mngr := NewManager(nil)
+	reset := time.Now().Add(time.Minute)
+	respond := func(hash string) bucketTransaction {
+		return func() (*http.Response, []byte, error) {
+			resp := &http.Response{Header: make(http.Header), StatusCode: http.StatusOK}
+			resp.Header.Set(XRateLimitBucket, hash)
+			resp.Header.Set(XRateLimitRemaining, "100")
+			resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+			resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+			return resp, nil, nil
+		}
+	}
+
+	// the channels are spread over the shards, while the ltBucket of the global hash is shared across them
+	routes := map[string]string{
+		"GET:/channels/%d/messages":         "messages",
+		"GET:/channels/%d/messages/{id}":    "messages",
+		"POST:/channels/%d/typing":          "typing",
+		"GET:/channels/%d/pins":             GlobalHash,
+		"PUT:/channels/%d/permissions/{id}": GlobalHash,
+	}
+	endpoint := func(route string, channel int) string {
+		return strings.Replace(route, "%d", strconv.Itoa(channel), 1)
+	}
+
+	stop := make(chan struct{})
+	maintained := make(chan struct{})
+	go func() {
+		defer close(maintained)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			mngr.Consolidate()
+			mngr.EvictIdleBuckets(time.Hour)
+			mngr.Stats()
+			mngr.BucketGrouping()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for channel := 1; channel <= 64; channel++ {
+				for route, hash := range routes {
+					id := endpoint(route, channel)
+					if hash == GlobalHash {
+						mngr.Bucket(id, func(RESTBucket) {})
+						mngr.UpdateProxyID(id, mngr.ProxyID(id), GlobalHash)
+						continue
+					}
+					mngr.Bucket(id, func(bucket RESTBucket) {
+						_, _, _ = bucket.Transaction(context.Background(), respond(hash))
+					})
+					mngr.WaitTime(id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-maintained
+
+	bucketOf := func(id string) (bucket RESTBucket) {
+		mngr.Bucket(id, func(b RESTBucket) {
+			bucket = b
+		})
+		return bucket
+	}
+	global := bucketOf(endpoint("GET:/channels/%d/pins", 1))
+	for channel := 1; channel <= 64; channel++ {
+		messages := bucketOf(endpoint("GET:/channels/%d/messages", channel))
+		if messages != bucketOf(endpoint("GET:/channels/%d/messages/{id}", channel)) {
+			t.Errorf("expected the message endpoints of channel %d to share a bucket", channel)
+		}
+		if messages == bucketOf(endpoint("POST:/channels/%d/typing", channel)) {
+			t.Errorf("expected the typing endpoint of channel %d to have its own bucket", channel)
+		}
+		if bucketOf(endpoint("PUT:/channels/%d/permissions/{id}", channel)) != global {
+			t.Errorf("expected the endpoints of channel %d with the global hash to share a bucket across shards", channel)
+		}
+	}
+}
+
+func TestManager_ConcurrentBucketCreation(t *testing.T) {
+	mngr := NewManager(nil)
+	reset := time.Now().Add(time.Minute)
+	const id = "GET:/channels/1/messages"
+	const limit = 1000
+
+	// discord counts the requests of the window, whichever ltBucket they were sent from
+	var sent int32
+	workers := 8 * runtime.GOMAXPROCS(0)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			mngr.Bucket(id, func(bucket RESTBucket) {
+				_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+					remaining := limit - int(atomic.AddInt32(&sent, 1))
+					resp := &http.Response{Header: make(http.Header), StatusCode: http.StatusOK}
+					resp.Header.Set(XRateLimitBucket, "messages")
+					resp.Header.Set(XRateLimitLimit, strconv.Itoa(limit))
+					resp.Header.Set(XRateLimitRemaining, strconv.Itoa(remaining))
+					resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+					resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+					return resp, nil, nil
+				})
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+	mngr.Consolidate()
+
+	if buckets, endpoints := managerSize(mngr); buckets != 1 || endpoints != 1 {
+		t.Errorf("expected a single ltBucket and endpoint, got %d buckets and %d endpoints", buckets, endpoints)
+	}
+	var bucket *ltBucket
+	mngr.Bucket(id, func(b RESTBucket) {
+		bucket = b.(*ltBucket)
+	})
+	bucket.mu.RLock()
+	defer bucket.mu.RUnlock()
+	if wants := limit - workers; bucket.remaining != wants {
+		t.Errorf("expected %d remaining after every request, got %d", wants, bucket.remaining)
+	}
+	if bucket.hash != "messages" || bucket.limit != limit {
+		t.Errorf("expected the rate limit of the responses, got hash %q and limit %d", bucket.hash, bucket.limit)
+	}
+}
+
+func TestManager_EvictIdleBuckets(t *testing.T) {
+	mngr := NewManager(nil)
+	for i := 0; i < 10000; i++ {
+		mngr.Bucket("GET:/channels/"+strconv.Itoa(i)+"/messages", func(RESTBucket) {})
+	}
+
+	// one bucket is still rate limited, and another is in use
+	mngr.Bucket("GET:/channels/1/messages", func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.mu.Lock()
+		b.remaining = 0
+		b.resetTime = time.Now().Add(time.Hour)
+		b.mu.Unlock()
+	})
+	mngr.Bucket("GET:/channels/2/messages", func(bucket RESTBucket) {
+		bucket.(*ltBucket).atomicLock.AcquireLock()
+	})
+
+	if evicted := mngr.EvictIdleBuckets(time.Hour); evicted != 0 {
+		t.Errorf("buckets used within the ttl were evicted: %d", evicted)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if evicted := mngr.EvictIdleBuckets(time.Millisecond); evicted != 9998 {
+		t.Errorf("expected 9998 buckets to be evicted, got %d", evicted)
+	}
+	if buckets, endpoints := managerSize(mngr); buckets != 2 || endpoints != 2 {
+		t.Errorf("expected the maps to shrink to 2 entries, got %d buckets and %d endpoints", buckets, endpoints)
+	}
+
+	// an evicted endpoint gets a new bucket
+	mngr.Bucket("GET:/channels/3/messages", func(bucket RESTBucket) {
+		if bucket == nil {
+			t.Error("expected a new bucket")
+		}
+	})
+}
+
+func TestManager_StartEviction(t *testing.T) {
+	mngr := NewManager(nil)
+	mngr.Bucket("GET:/channels/1/messages", func(RESTBucket) {})
+	mngr.StartEviction(20 * time.Millisecond)
+	mngr.StartEviction(20 * time.Millisecond)
+	defer mngr.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if remaining, _ := managerSize(mngr); remaining != 0 {
+		t.Errorf("expected the idle bucket to be evicted in the background, %d buckets remain", remaining)
+	}
+
+	mngr.Stop()
+	mngr.Stop()
+}
+
+func TestManager_SeedRouteLimit(t *testing.T) {
+	mngr := NewManager(nil)
+	endpoints := []string{
+		"PUT:/channels/1/messages/{id}/reactions/{emoji}/@me",
+		"PUT:/channels/1/messages/{id}/reactions/{emoji}/@me",
+		"DELETE:/channels/1/messages/{id}/reactions/{emoji}/@me",
+		"DELETE:/channels/1/messages/{id}/reactions/{emoji}/{id}",
+		"DELETE:/channels/1/messages/{id}/reactions",
+	}
+
+	noContent := func() (*http.Response, []byte, error) {
+		resp := &http.Response{StatusCode: http.StatusNoContent, Header: make(http.Header)}
+		resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+		return resp, nil, nil
+	}
+
+	var sent []time.Time
+	for _, endpoint := range endpoints {
+		mngr.Bucket(endpoint, func(bucket RESTBucket) {
+			_, _, err := bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+				sent = append(sent, time.Now())
+				return noContent()
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < reactionsLimit.Interval {
+			t.Errorf("reaction %d was sent %s after the previous one", i, gap)
+		}
+	}
+
+	// reactions of other channels are not paced together
+	start := time.Now()
+	mngr.Bucket("PUT:/channels/2/messages/{id}/reactions/{emoji}/@me", func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(context.Background(), noContent)
+	})
+	if elapsed := time.Since(start); elapsed >= reactionsLimit.Interval {
+		t.Errorf("a reaction in another channel waited %s", elapsed)
+	}
+
+	// the rate limit headers replace the seed
+	mngr.Bucket(endpoints[0], func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			resp := &http.Response{StatusCode: http.StatusNoContent, Header: make(http.Header)}
+			resp.Header.Set(XRateLimitRemaining, "4")
+			resp.Header.Set(XRateLimitReset, strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+			resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+			return resp, nil, nil
+		})
+	})
+	start = time.Now()
+	for _, endpoint := range endpoints[1:] {
+		mngr.Bucket(endpoint, func(bucket RESTBucket) {
+			_, _, _ = bucket.Transaction(context.Background(), noContent)
+		})
+	}
+	if elapsed := time.Since(start); elapsed >= reactionsLimit.Interval {
+		t.Errorf("expected the reported rate limit to be used, the reactions took %s", elapsed)
+	}
+}
+
+func TestManager_EmojisGroup(t *testing.T) {
+	mngr := NewManager(nil)
+	bucketOf := func(id string) (b *ltBucket) {
+		mngr.Bucket(id, func(bucket RESTBucket) {
+			b = bucket.(*ltBucket)
+		})
+		return b
+	}
+
+	create := bucketOf("POST:/guilds/1/emojis")
+	if update := bucketOf("PATCH:/guilds/1/emojis/{id}"); update != create {
+		t.Error("expected the emoji routes of a guild to share a ltBucket")
+	}
+	if other := bucketOf("POST:/guilds/2/emojis"); other == create {
+		t.Error("expected the emoji routes of other guilds to have their own ltBucket")
+	}
+	if roles := bucketOf("POST:/guilds/1/roles"); roles == create {
+		t.Error("expected the other guild routes to have their own ltBucket")
+	}
+}
+
+func TestManager_MajorParameterBuckets(t *testing.T) {
+	mngr := NewManager(nil)
+	bucketOf := func(method HTTPMethod, endpoint string) (bucket RESTBucket) {
+		req := &Request{Method: method, Endpoint: endpoint}
+		mngr.Bucket(req.HashEndpoint(), func(b RESTBucket) {
+			bucket = b
+		})
+		return bucket
+	}
+
+	tests := []struct {
+		name     string
+		a, b     string
+		separate bool
+	}{
+		{"same channel", "/channels/486833611564253186/messages/540519319814275089", "/channels/486833611564253186/messages/540519296640614416", false},
+		{"other channel", "/channels/486833611564253186/messages/540519319814275089", "/channels/540519296640614416/messages/540519319814275089", true},
+		{"same guild", "/guilds/486833611564253186/members/540519319814275089", "/guilds/486833611564253186/members/540519296640614416", false},
+		{"other guild", "/guilds/486833611564253186/members/540519319814275089", "/guilds/540519296640614416/members/540519319814275089", true},
+		{"same webhook", "/webhooks/486833611564253186/tokenA", "/webhooks/486833611564253186/tokenB", false},
+		{"other webhook", "/webhooks/486833611564253186/tokenA", "/webhooks/540519296640614416/tokenA", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := bucketOf(MethodDelete, test.a), bucketOf(MethodDelete, test.b)
+			if separate := a != b; separate != test.separate {
+				t.Errorf("expected separate buckets to be %t for %s and %s", test.separate, test.a, test.b)
+			}
+		})
+	}
+}
+
+func TestBucketRoute(t *testing.T) {
+	tests := []struct {
+		method   HTTPMethod
+		a, b     string
+		expected string
+	}{
+		{MethodGet, "/channels/486833611564253186/messages/540519319814275089", "/channels/540519296640614416/messages/486833611564253186", "GET:/channels/{major}/messages/{id}"},
+		{MethodPatch, "/guilds/486833611564253186/members/540519319814275089", "/guilds/540519296640614416/members/486833611564253186", "PATCH:/guilds/{major}/members/{id}"},
+		{MethodPost, "/webhooks/486833611564253186/tokenA", "/webhooks/540519296640614416/tokenB", "POST:/webhooks/{major}/{token}"},
+		{MethodPost, "/interactions/486833611564253186/tokenA/callback", "/interactions/540519296640614416/tokenB/callback", "POST:/interactions/{major}/{token}/callback"},
+		{MethodGet, "/invites/abc", "/invites/def", "GET:/invites/{code}"},
+	}
+	for _, test := range tests {
+		a := BucketRoute((&Request{Method: test.method, Endpoint: test.a}).HashEndpoint())
+		b := BucketRoute((&Request{Method: test.method, Endpoint: test.b}).HashEndpoint())
+		if a != test.expected || b != test.expected {
+			t.Errorf("expected both routes to be %s, got %s and %s", test.expected, a, b)
+		}
+	}
+
+	if hash := BucketRoute("abcd1234"); hash != "abcd1234" {
+		t.Errorf("expected a discord bucket hash to be kept, got %s", hash)
+	}
+}
@@ -4,13 +4,22 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/andybalholm/brotli"
+
+	"github.com/andersfylling/disgord/internal/logger"
 	"github.com/andersfylling/disgord/json"
 )
 
@@ -24,13 +33,23 @@ const (
 	RegexpReactionPrefix = `\/channels\/([0-9]+)\/messages\/\{id\}\/reactions\/`
 
 	// Header
+	Authorization       = "Authorization"
 	AuthorizationFormat = "Bot %s"
 	UserAgentFormat     = "DiscordBot (%s, %s) %s"
 
-	ContentEncoding = "Content-Encoding"
-	ContentType     = "Content-Type"
-	ContentTypeJSON = "application/json"
-	GZIPCompression = "gzip"
+	ContentEncoding  = "Content-Encoding"
+	ContentType      = "Content-Type"
+	ContentTypeJSON  = "application/json"
+	GZIPCompression  = "gzip"
+	BrotliEncoding   = "br"
+	IdentityEncoding = "identity"
+
+	// DefaultMaxRateLimitRetries is how many times a request is sent again after a 429 response, unless
+	// Config.MaxRateLimitRetries says otherwise
+	DefaultMaxRateLimitRetries = 1
+
+	// DefaultMaxResponseSize is the largest response body that is read, unless Config.MaxResponseSize says otherwise
+	DefaultMaxResponseSize = 16 << 20
 )
 
 // Requester holds all the sub-request interface for Discord interaction
@@ -38,6 +57,13 @@ type Requester interface {
 	Do(ctx context.Context, req *Request) (resp *http.Response, body []byte, err error)
 }
 
+// Doer sends a single http request, such as *http.Client. See Config.Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+var _ Doer = (*http.Client)(nil)
+
 // TODO: should RESTBucket and RESTBucketManager be merged?
 
 // RESTBucket is a REST bucket for one endpoint or several endpoints. This includes the global bucket.
@@ -49,6 +75,21 @@ type RESTBucket interface {
 	Transaction(context.Context, func() (*http.Response, []byte, error)) (*http.Response, []byte, error)
 }
 
+// AcquirableBucket is a RESTBucket where a request can be held ahead of sending it, for requests that are
+// not sent within a Transaction, such as by a queue of the caller. The buckets of Manager implement it.
+type AcquirableBucket interface {
+	RESTBucket
+
+	// Acquire waits until the bucket can send a request, and holds it. release must be called with the
+	// normalized response, which updates the bucket, or with nil when the request was not sent, which gives
+	// the request back. Cancelling ctx while waiting holds nothing.
+	Acquire(ctx context.Context) (release func(resp *http.Response), err error)
+
+	// TryAcquire works like Acquire, but never waits. When ok is false, wait is how long until the bucket
+	// resets, or zero when it is only busy.
+	TryAcquire() (release func(resp *http.Response), wait time.Duration, ok bool)
+}
+
 // RESTBucketManager manages the buckets and the global bucket.
 type RESTBucketManager interface {
 	// Bucket returns the bucket for a given local hash. Note that a local hash simply means
@@ -66,41 +107,102 @@ type RESTBucketManager interface {
 }
 
 type ErrREST struct {
-	Code           int      `json:"code"`
-	Msg            string   `json:"message"`
-	Suggestion     string   `json:"-"`
-	HTTPCode       int      `json:"-"`
-	Bucket         []string `json:"-"`
-	HashedEndpoint string   `json:"-"`
+	Code           JSONErrorCode `json:"code"`
+	Msg            string        `json:"message"`
+	HTTPCode       int           `json:"-"`
+	Bucket         []string      `json:"-"`
+	HashedEndpoint string        `json:"-"`
+
+	// Suggestion is the hint of the error code, see JSONErrorCode.Suggestion. It holds the response body
+	// when the code is unknown, or the body is not a Discord error.
+	Suggestion string `json:"-"`
+
+	// Attempts is how many times the request was sent, see RetryPolicy and Config.MaxRateLimitRetries
+	Attempts int `json:"-"`
+
+	// Method and Endpoint of the failed request. Webhook tokens are redacted from the endpoint.
+	Method   string `json:"-"`
+	Endpoint string `json:"-"`
+
+	// Errors holds the rejected fields of an Invalid Form Body error, see FieldError
+	Errors []FieldError `json:"-"`
+
+	// RequestID is the ID given to the hooks, see Config.Hooks. Zero when the error was not created by a Client.
+	RequestID uint64 `json:"-"`
 }
 
 var _ error = (*ErrREST)(nil)
 
 func (e *ErrREST) Error() string {
-	return fmt.Sprintf("%s\n%s\n%s => %+v", e.Msg, e.Suggestion, e.HashedEndpoint, e.Bucket)
+	msg := fmt.Sprintf("%s\n%s\n%s %s: %s => %+v", e.Msg, e.Suggestion, e.Method, e.Endpoint, e.HashedEndpoint, e.Bucket)
+	if e.RequestID != 0 {
+		msg += " (request " + formatRequestID(e.RequestID) + ")"
+	}
+	for i := range e.Errors {
+		msg += "\n" + e.Errors[i].String()
+	}
+	return msg
+}
+
+// Is makes errors.Is match the error with ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrNotFound or
+// ErrRateLimited, depending on the http status code.
+func (e *ErrREST) Is(target error) bool {
+	err, ok := errorsByStatusCode[e.HTTPCode]
+	return ok && err == target
 }
 
 // Client for handling Discord REST requests
 type Client struct {
 	url                          string // base url with API version
+	apiVersion                   APIVersion
 	reqHeader                    http.Header
-	httpClient                   *http.Client
+	httpClient                   Doer
+	requestTimeout               time.Duration
 	cancelRequestWhenRateLimited bool
+	maxRateLimitDelay            time.Duration
+	maxRateLimitRetries          int
+	retryPolicy                  RetryPolicy
+	metrics                      Metrics
+	etags                        *etagCache
 	buckets                      RESTBucketManager
+	log                          logger.Logger
+	debug                        *debugger
+	cloudflare                   *circuitBreaker
+	invalidRequests              *invalidRequestCounter
+	inflight                     *inflightCalls
+	maxResponseSize              int64
+	lifecycle                    lifecycle
+	hooks                        RequestHooks
+	onRateLimit                  func(event RateLimitEvent)
+	requestIDHeader              string
+	throttle                     *globalThrottle
+	ownedManager                 *Manager // stopped by Shutdown
+	codec                        json.Codec
 }
 
 func (c *Client) BucketGrouping() (group map[string][]string) {
 	return c.buckets.BucketGrouping()
 }
 
+// InvalidRequests returns the number of 401, 403, 404 and 429 responses within InvalidRequestWindow
+func (c *Client) InvalidRequests() int {
+	return c.invalidRequests.count()
+}
+
+// APIVersion returns the Discord API version that requests are sent to
+func (c *Client) APIVersion() APIVersion {
+	return c.apiVersion
+}
+
+// Codec returns the JSON codec of the requests, see Config.Codec
+func (c *Client) Codec() json.Codec {
+	return c.codec
+}
+
 // SupportsDiscordAPIVersion check if a given discord api version is supported by this package.
 func SupportsDiscordAPIVersion(version int) bool {
-	supports := []int{
-		6,
-	}
-
 	var supported bool
-	for _, supportedVersion := range supports {
+	for _, supportedVersion := range SupportedAPIVersions {
 		if supportedVersion == version {
 			supported = true
 			break
@@ -122,8 +224,9 @@ func copyHeader(h http.Header) http.Header {
 
 // NewClient ...
 func NewClient(conf *Config) (*Client, error) {
-	if !SupportsDiscordAPIVersion(conf.APIVersion) {
-		return nil, errors.New(fmt.Sprintf("Discord API version %d is not supported", conf.APIVersion))
+	version, err := NewAPIVersion(conf.APIVersion)
+	if err != nil {
+		return nil, err
 	}
 
 	if conf.BotToken == "" {
@@ -131,54 +234,312 @@ func NewClient(conf *Config) (*Client, error) {
 	}
 
 	// if no http client was provided, create a new one
+	if conf.HTTPClient != nil && (conf.Proxy != "" || conf.TLSConfig != nil) {
+		return nil, errors.New("the proxy and TLS config can not be used with a custom HTTPClient, configure its transport instead")
+	}
 	if conf.HTTPClient == nil {
 		// no need for a timeout, everything uses context.Context now
 		conf.HTTPClient = &http.Client{}
+		if conf.Proxy != "" || conf.TLSConfig != nil {
+			transport, err := NewTransport(conf.Proxy, conf.TLSConfig)
+			if err != nil {
+				return nil, err
+			}
+			conf.HTTPClient.Transport = transport
+		}
+	}
+	if conf.Doer == nil {
+		conf.Doer = conf.HTTPClient
+	}
+	if conf.RequestTimeout < 0 {
+		return nil, errors.New("the request timeout can not be negative")
 	}
 
+	if conf.DisableRateLimiter {
+		if conf.RESTBucketManager != nil {
+			return nil, errors.New("a RESTBucketManager can not be used when the rate limiter is disabled")
+		}
+		conf.RESTBucketManager = NewNopManager()
+		conf.GlobalRateLimit = -1
+	}
+	var ownedManager *Manager
 	if conf.RESTBucketManager == nil {
-		conf.RESTBucketManager = NewManager(nil)
+		ownedManager = NewManager(nil)
+		ownedManager.SetPacing(conf.Pacing)
+		ownedManager.SetQueueLimits(conf.QueueLimits)
+		if conf.OnRateLimit != nil {
+			ownedManager.OnGlobalRateLimit(globalRateLimitEvents(conf.OnRateLimit))
+		}
+		if conf.BucketTTL >= 0 {
+			ownedManager.StartEviction(conf.BucketTTL)
+		}
+		conf.RESTBucketManager = ownedManager
+	}
+	if conf.GlobalRateLimit == 0 {
+		conf.GlobalRateLimit = DefaultGlobalRateLimit
+	}
+
+	if conf.MaxRateLimitRetries == 0 {
+		conf.MaxRateLimitRetries = DefaultMaxRateLimitRetries
+	} else if conf.MaxRateLimitRetries < 0 {
+		conf.MaxRateLimitRetries = 0
+	}
+
+	if conf.RetryPolicy.MaxAttempts == 0 {
+		conf.RetryPolicy = DefaultRetryPolicy
+	}
+
+	if conf.Logger == nil {
+		conf.Logger = logger.Empty{}
+	}
+	if problem := diagnoseToken(conf.BotToken); problem != "" {
+		conf.Logger.Error("httd: " + problem + ", requests will most likely fail with 401 Unauthorized")
+	}
+
+	if conf.Metrics == nil {
+		conf.Metrics = NopMetrics{}
+	}
+
+	if conf.Codec == nil {
+		conf.Codec = json.PackageCodec{}
+	}
+
+	if conf.MaxResponseSize == 0 {
+		conf.MaxResponseSize = DefaultMaxResponseSize
+	}
+
+	if conf.CloudflareCooldown == 0 {
+		conf.CloudflareCooldown = DefaultCloudflareCooldown
+	}
+	if conf.InvalidRequestWarningThreshold == 0 {
+		conf.InvalidRequestWarningThreshold = DefaultInvalidRequestWarningThreshold
 	}
 
 	// Clients using the HTTP API must provide a valid User Agent which specifies
 	// information about the client library and version in the following format:
 	//	User-Agent: DiscordBot ($url, $versionNumber)
-	if conf.UserAgentSourceURL == "" || conf.UserAgentVersion == "" {
-		return nil, errors.New("both a source(url) and a version must be present for sending requests to the Discord REST API")
+	userAgent, err := buildUserAgent(conf)
+	if err != nil {
+		return nil, err
 	}
 
 	// setup the required http request header fields
 	authorization := fmt.Sprintf(AuthorizationFormat, conf.BotToken)
-	userAgent := fmt.Sprintf(UserAgentFormat, conf.UserAgentSourceURL, conf.UserAgentVersion, conf.UserAgentExtra)
+	accept, err := acceptEncoding(conf.AcceptEncodings)
+	if err != nil {
+		return nil, err
+	}
 	header := map[string][]string{
-		XRateLimitPrecision: {"millisecond"},
-		"Authorization":     {authorization},
-		"User-Agent":        {userAgent},
-		"Accept-Encoding":   {"gzip"},
+		Authorization:     {authorization},
+		"User-Agent":      {userAgent},
+		"Accept-Encoding": {accept},
+	}
+	precision, err := precisionHeader(version, conf.RateLimitPrecision)
+	if err != nil {
+		return nil, err
+	}
+	if precision != "" {
+		header[XRateLimitPrecision] = []string{precision}
+	}
+
+	var inflight *inflightCalls
+	if conf.CoalesceGETRequests {
+		inflight = &inflightCalls{calls: make(map[string]*inflightCall)}
+	}
+
+	var etags *etagCache
+	if conf.ResponseCacheSize > 0 {
+		etags = newETagCache(conf.ResponseCacheSize, conf.ResponseCacheTTL)
 	}
 
 	return &Client{
-		url:        BaseURL + "/v" + strconv.Itoa(conf.APIVersion),
-		reqHeader:  header,
-		httpClient: conf.HTTPClient,
-		buckets:    conf.RESTBucketManager,
+		url:                          apiURL(conf.APIBaseURL, conf.APIVersion),
+		apiVersion:                   version,
+		reqHeader:                    header,
+		httpClient:                   conf.Doer,
+		requestTimeout:               conf.RequestTimeout,
+		cancelRequestWhenRateLimited: conf.CancelRequestWhenRateLimited,
+		maxRateLimitDelay:            conf.MaxRateLimitDelay,
+		maxRateLimitRetries:          conf.MaxRateLimitRetries,
+		retryPolicy:                  conf.RetryPolicy,
+		metrics:                      conf.Metrics,
+		etags:                        etags,
+		buckets:                      conf.RESTBucketManager,
+		log:                          conf.Logger,
+		debug:                        newDebugger(conf.Debug, conf.DebugBodyLimit),
+		cloudflare:                   &circuitBreaker{cooldown: conf.CloudflareCooldown},
+		inflight:                     inflight,
+		maxResponseSize:              conf.MaxResponseSize,
+		hooks:                        conf.Hooks,
+		onRateLimit:                  conf.OnRateLimit,
+		requestIDHeader:              conf.RequestIDHeader,
+		throttle:                     newGlobalThrottle(conf.GlobalRateLimit),
+		ownedManager:                 ownedManager,
+		codec:                        conf.Codec,
+		invalidRequests: &invalidRequestCounter{
+			threshold:     conf.InvalidRequestWarningThreshold,
+			warn:          conf.InvalidRequestWarning,
+			stopThreshold: conf.InvalidRequestStopThreshold,
+		},
 	}, nil
 }
 
+var regexpAPIVersionSuffix = regexp.MustCompile(`/v[0-9]+$`)
+
+// apiURL combines the base URL and the API version, see Config.APIBaseURL
+func apiURL(baseURL string, version int) string {
+	if baseURL == "" {
+		baseURL = BaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if regexpAPIVersionSuffix.MatchString(baseURL) {
+		return baseURL
+	}
+	return baseURL + "/v" + strconv.Itoa(version)
+}
+
 // Config is the configuration options for the httd.Client structure. Essentially the behaviour of all requests
 // sent to Discord.
 type Config struct {
 	APIVersion int
 	BotToken   string
 
+	// RateLimitPrecision is sent in the X-RateLimit-Precision header on API versions 6 and 7, which need it
+	// to respond with millisecond precision. Later versions always use milliseconds and never get the header.
+	// Defaults to RateLimitPrecisionMillisecond.
+	RateLimitPrecision RateLimitPrecision
+
 	HTTPClient *http.Client
 
+	// Proxy is a http, https, socks5 or socks5h URL that requests are sent through, see NewTransport.
+	// TLSConfig configures the https connections. Neither can be used together with HTTPClient.
+	Proxy     string
+	TLSConfig *tls.Config
+
+	// AcceptEncodings are the content encodings advertised to Discord, in order of preference. Supported are
+	// "gzip", "br" and "identity". Defaults to DefaultAcceptEncodings.
+	AcceptEncodings []string
+
+	// Doer sends the http requests in place of HTTPClient, such as a scripted Doer that tests the rate
+	// limiting and retries without opening connections
+	Doer Doer
+
+	// RequestTimeout limits how long a request may take, including the time spent waiting for rate limits and
+	// retries, unless the context of the request has an earlier deadline. Zero means no timeout. Use this
+	// rather than http.Client.Timeout, which is unknown to the rate limiter and does not apply to a Doer.
+	RequestTimeout time.Duration
+
 	CancelRequestWhenRateLimited bool
 
+	// MaxRateLimitDelay is how long a request may wait for an exhausted rate limit bucket, or for the reset
+	// of a 429 response, before a RateLimitError is returned instead. Zero waits as long as the context
+	// allows, or not at all when CancelRequestWhenRateLimited is set. A negative value never waits. See
+	// Request.MaxRateLimitDelay and WithMaxRateLimitDelay for single requests.
+	MaxRateLimitDelay time.Duration
+
+	// MaxRateLimitRetries is how many times a request is sent again when Discord responds with 429 Too Many
+	// Requests. Zero uses DefaultMaxRateLimitRetries, and a negative value disables the retries. Requests are
+	// never retried when CancelRequestWhenRateLimited is set, or when the body can not be read again.
+	MaxRateLimitRetries int
+
+	// RetryPolicy decides how requests are retried on server errors and temporary transport errors.
+	// The zero value uses DefaultRetryPolicy.
+	RetryPolicy RetryPolicy
+
+	// Logger reports every retry, see MaxRateLimitRetries and RetryPolicy
+	Logger logger.Logger
+
+	// Codec marshals the JSON request bodies, and unmarshals the error responses. Defaults to json.PackageCodec,
+	// which uses the variables of the json package.
+	Codec json.Codec
+
+	// Metrics receives request counts, latencies and rate limit statistics. Defaults to NopMetrics.
+	Metrics Metrics
+
+	// ResponseCacheSize is the number of GET responses that are kept together with their ETag. Requests for a
+	// cached endpoint are sent with If-None-Match, and a 304 Not Modified response is answered with the cached
+	// body. Zero disables the cache.
+	ResponseCacheSize int
+
+	// ResponseCacheTTL is how long a cached response can be used. Zero keeps responses until they are evicted.
+	ResponseCacheTTL time.Duration
+
 	// RESTBucketManager stores all rate limit buckets and dictates the behaviour of how rate limiting is respected
 	RESTBucketManager RESTBucketManager
 
-	// Header field: `User-Agent: DiscordBot ({Source}, {Version}) {Extra}`
+	// APIBaseURL replaces BaseURL, such that requests can be sent to a proxy or a mock server. The API
+	// version is appended unless the URL already ends with one, such as "http://localhost:8080/api/v6".
+	APIBaseURL string
+
+	// BucketTTL is how long the rate limit bucket of an endpoint is kept after its last request, when the
+	// RESTBucketManager is created by the client. Defaults to DefaultBucketTTL, a negative value keeps the
+	// buckets forever. See Manager.StartEviction.
+	BucketTTL time.Duration
+
+	// Pacing decides whether the remaining requests of a bucket are sent right away, or spread evenly until
+	// the reset, when the RESTBucketManager is created by the client. Defaults to PacingBurst.
+	Pacing Pacing
+
+	// QueueLimits bounds the requests waiting for a bucket, when the RESTBucketManager is created by the
+	// client. Requests beyond them fail right away with a RateLimitError. Defaults to no limits.
+	QueueLimits QueueLimits
+
+	// GlobalRateLimit is the number of requests per second that are sent across every route, such that the
+	// global rate limit is not hit. Defaults to DefaultGlobalRateLimit, raise it for bots with a higher limit.
+	// A negative value disables the throttle, as does DisableRateLimiter.
+	GlobalRateLimit int
+
+	// DisableRateLimiter sends requests without waiting for the local rate limit buckets, for when a proxy
+	// handles the rate limits. Can not be combined with RESTBucketManager.
+	DisableRateLimiter bool
+
+	// Debug receives a dump of every request attempt and response, including retries and the time spent
+	// waiting for the rate limit. The bot token and webhook tokens are replaced by [REDACTED].
+	Debug io.Writer
+
+	// DebugBodyLimit caps how many bytes of each body are written to Debug. Defaults to DefaultDebugBodyLimit.
+	// A negative value only writes the body sizes. Multipart bodies are always summarized.
+	DebugBodyLimit int
+
+	// MaxResponseSize is the largest response body that is read into memory, both before and after it is
+	// decompressed. Larger bodies fail with ErrResponseTooLarge. Defaults to DefaultMaxResponseSize, and a
+	// negative value removes the limit. Bodies read with DoStream are not limited.
+	MaxResponseSize int64
+
+	// CoalesceGETRequests lets concurrent GET requests to the same endpoint share a single request. Every
+	// caller receives its own copy of the response, or the error.
+	CoalesceGETRequests bool
+
+	// CloudflareCooldown is how long all requests are rejected with ErrCloudflareBlocked after Cloudflare
+	// blocked a request, or longer if the response says so. Defaults to DefaultCloudflareCooldown.
+	CloudflareCooldown time.Duration
+
+	// InvalidRequestWarning is called when the number of invalid requests within InvalidRequestWindow
+	// reaches InvalidRequestWarningThreshold, such that the bot can back off before the IP address is banned
+	// at InvalidRequestLimit. Defaults to DefaultInvalidRequestWarningThreshold.
+	InvalidRequestWarning          func(count int)
+	InvalidRequestWarningThreshold int
+
+	// InvalidRequestStopThreshold rejects every request with ErrInvalidRequestLimit once the number of invalid
+	// requests within InvalidRequestWindow reaches it, until enough of them have left the window. Zero never
+	// rejects requests.
+	InvalidRequestStopThreshold int
+
+	// Hooks are called around every attempt of a request, with the request ID attached to ErrREST
+	Hooks RequestHooks
+
+	// OnRateLimit is called when a request is delayed or rejected because of a rate limit, and when Discord
+	// responds with 429 Too Many Requests. It is called before the request waits, on the goroutine of the
+	// request, so it must be fast or hand the event off to another goroutine. When the RESTBucketManager is
+	// created by the client, it is also called when the global rate limit starts and ends.
+	OnRateLimit func(event RateLimitEvent)
+
+	// RequestIDHeader sends the request ID in the given header field, such as "X-Request-ID", for proxies
+	// that log it. Discord ignores the header field, so it is not sent by default.
+	RequestIDHeader string
+
+	// Header field: `User-Agent: DiscordBot ({Source}, {Version}) {Extra}`. The source and version default
+	// to the disgord module found in the build info.
 	UserAgentVersion   string
 	UserAgentSourceURL string
 	UserAgentExtra     string
@@ -192,92 +553,366 @@ type Details struct {
 	SuccessHTTPCode int
 }
 
+// maxPooledBufferSize stops unusually large responses from being kept alive by the buffer pool
+const maxPooledBufferSize = 1 << 20
+
+var decodeBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+var gzipReaders sync.Pool
+
+func getGzipReader(r io.Reader) (*gzip.Reader, error) {
+	if zr, ok := gzipReaders.Get().(*gzip.Reader); ok {
+		if err := zr.Reset(r); err != nil {
+			gzipReaders.Put(zr)
+			return nil, err
+		}
+		return zr, nil
+	}
+	return gzip.NewReader(r)
+}
+
+// decodeResponseBody reads, and decompresses if needed, the response body. The returned slice is owned
+// by the caller.
 func (c *Client) decodeResponseBody(resp *http.Response) (body []byte, err error) {
-	buffer, err := ioutil.ReadAll(resp.Body)
+	encoding, err := contentEncoding(resp.Header)
 	if err != nil {
 		return nil, err
 	}
-
-	switch resp.Header.Get(ContentEncoding) {
+	compressed := encoding != IdentityEncoding
+	var r io.Reader = resp.Body
+	if c.maxResponseSize > 0 {
+		r = &limitedReader{r: r, limit: c.maxResponseSize, compressed: compressed}
+	}
+	switch encoding {
 	case GZIPCompression:
-		b := bytes.NewBuffer(buffer)
-		r, err := gzip.NewReader(b)
+		zr, err := getGzipReader(r)
 		if err != nil {
 			return nil, err
 		}
-		defer r.Close()
+		defer func() {
+			_ = zr.Close()
+			gzipReaders.Put(zr)
+		}()
+		r = zr
+	case BrotliEncoding:
+		r = brotli.NewReader(r)
+	}
 
-		var resB bytes.Buffer
-		_, err = resB.ReadFrom(r)
+	// a small compressed body can decompress into a huge one
+	if compressed && c.maxResponseSize > 0 {
+		r = &limitedReader{r: r, limit: c.maxResponseSize}
+	}
+
+	buffer := decodeBuffers.Get().(*bytes.Buffer)
+	buffer.Reset()
+	defer func() {
+		if buffer.Cap() <= maxPooledBufferSize {
+			decodeBuffers.Put(buffer)
+		}
+	}()
+
+	if _, err = buffer.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	// the buffer is reused, so the caller gets a copy
+	body = make([]byte, buffer.Len())
+	copy(body, buffer.Bytes())
+	return body, nil
+}
+
+// limitedReader fails with ErrResponseTooLarge once more than limit bytes are read, see Config.MaxResponseSize
+type limitedReader struct {
+	r          io.Reader
+	limit      int64
+	read       int64
+	compressed bool
+}
+
+func (l *limitedReader) Read(p []byte) (n int, err error) {
+	n, err = l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, &ErrResponseTooLarge{Limit: l.limit, Read: l.read, Compressed: l.compressed}
+	}
+	return n, err
+}
+
+// streamBody decompresses a gzip or brotli response body while it is read
+type streamBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func newStreamBody(resp *http.Response) (io.ReadCloser, error) {
+	encoding, err := contentEncoding(resp.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoding {
+	case GZIPCompression:
+		zr, err := gzip.NewReader(resp.Body)
 		if err != nil {
 			return nil, err
 		}
+		return &streamBody{Reader: zr, closers: []io.Closer{zr, resp.Body}}, nil
+	case BrotliEncoding:
+		return &streamBody{Reader: brotli.NewReader(resp.Body), closers: []io.Closer{resp.Body}}, nil
+	}
+	return resp.Body, nil
+}
 
-		body = resB.Bytes()
-	default:
-		body = buffer
+func (s *streamBody) Close() (err error) {
+	for _, closer := range s.closers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
 	}
+	return err
+}
 
-	return body, nil
+// redactURLError removes webhook tokens from the URL of errors returned by the http client
+func redactURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return &url.Error{Op: urlErr.Op, URL: RedactEndpoint(urlErr.URL), Err: urlErr.Err}
+	}
+	return err
 }
 
 func (c *Client) Do(ctx context.Context, r *Request) (resp *http.Response, body []byte, err error) {
+	if err = c.begin(); err != nil {
+		return nil, nil, err
+	}
+	defer c.end()
+
+	if c.inflight != nil && (r.Method == "" || r.Method == MethodGet) {
+		return c.doCoalesced(ctx, r)
+	}
+	resp, body, _, err = c.do(ctx, r, false)
+	return resp, body, err
+}
+
+// DoStream works like Do, but a successful response body is returned as a stream instead of being read into
+// memory, which suits large payloads. Gzipped bodies are decompressed while reading. The rate limit headers
+// are processed before DoStream returns, so the stream does not hold up the bucket. The caller must close
+// the body.
+func (c *Client) DoStream(ctx context.Context, r *Request) (resp *http.Response, body io.ReadCloser, err error) {
+	if err = c.begin(); err != nil {
+		return nil, nil, err
+	}
+	if resp, _, body, err = c.do(ctx, r, true); err != nil {
+		c.end()
+		return resp, body, err
+	}
+	return resp, &endOnClose{ReadCloser: body, end: c.end}, nil
+}
+
+func (c *Client) do(ctx context.Context, r *Request, streaming bool) (resp *http.Response, body []byte, stream io.ReadCloser, err error) {
 	r.PopulateMissing()
-	if r.Body != nil && r.bodyReader == nil {
+	if ctx == nil {
+		ctx = r.Ctx
+	}
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer func() {
+			// a stream is still being read, and is cancelled when it is closed
+			if stream != nil {
+				stream = &endOnClose{ReadCloser: stream, end: cancel}
+			} else {
+				cancel()
+			}
+		}()
+	}
+	if err = c.cloudflare.allow(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err = c.invalidRequests.allow(); err != nil {
+		return nil, nil, nil, err
+	}
+	var pooled *pooledBody
+	if r.BodyFactory != nil {
+		if r.bodyReader, err = r.BodyFactory(); err != nil {
+			return nil, nil, nil, err
+		}
+	} else if r.Body != nil && r.bodyReader == nil {
 		switch b := r.Body.(type) { // Determine the type of the passed body so we can treat it differently
 		case io.Reader:
 			r.bodyReader = b
 		default:
 			// If the type is unknown, possibly Marshal it as JSON
 			if r.ContentType != ContentTypeJSON {
-				return nil, nil, errors.New("unknown request body types and only be used in conjunction with httd.ContentTypeJSON")
+				return nil, nil, nil, errors.New("unknown request body types and only be used in conjunction with httd.ContentTypeJSON")
 			}
 
-			if r.bodyReader, err = convertStructToIOReader(json.Marshal, r.Body); err != nil {
-				return nil, nil, err
+			if pooled, err = newPooledBody(c.codec, r.Body); err != nil {
+				return nil, nil, nil, err
 			}
+			defer pooled.release()
 		}
 	}
 
 	// create http request
-	req, err := http.NewRequestWithContext(ctx, r.Method.String(), c.url+r.Endpoint, r.bodyReader)
+	reqBody := r.bodyReader
+	if pooled != nil {
+		if reqBody, err = pooled.reader(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method.String(), c.url+r.Endpoint, reqBody)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	if pooled != nil {
+		req.ContentLength = pooled.size
+		req.GetBody = pooled.reader
+	} else if r.BodyFactory != nil {
+		req.GetBody = r.readCloserFactory
 	}
 
+	// the base header is shared by concurrent requests, so request specific fields are set on a copy
 	header := copyHeader(c.reqHeader)
 	header.Set(ContentType, r.ContentType)
+	if r.SkipAuthorization {
+		header.Del(Authorization)
+	}
 	if r.Reason != "" {
-		header.Add(XAuditLogReason, r.Reason)
-	} else {
-		// the header is a map, so it's a shared memory resource
-		req.Header.Del(XAuditLogReason)
+		header.Set(XAuditLogReason, r.Reason)
+	}
+	id := nextRequestID()
+	if c.requestIDHeader != "" {
+		header.Set(c.requestIDHeader, formatRequestID(id))
+	}
+	var cached *etagEntry
+	if !streaming {
+		cached = c.etagRequest(r, header)
 	}
 	req.Header = header
 
-	// queue & send request
-	c.buckets.Bucket(r.hashedEndpoint, func(bucket RESTBucket) {
-		resp, body, err = bucket.Transaction(ctx, func() (*http.Response, []byte, error) {
-			resp, err := c.httpClient.Do(req)
-			if err != nil {
-				return nil, nil, err
+	// queue & send request, and send it again when rate limited or when it failed temporarily.
+	// Every attempt goes through the bucket, such that retries respect the rate limits as well.
+	bucketCtx := ctx
+	maxDelay, limitDelay := c.requestMaxRateLimitDelay(ctx, r)
+	if limitDelay {
+		bucketCtx = WithMaxRateLimitDelay(ctx, maxDelay)
+	}
+	if r.Priority != PriorityNormal {
+		bucketCtx = context.WithValue(bucketCtx, priorityKey{}, r.Priority)
+	}
+	bucketCtx = c.withRateLimitEvents(bucketCtx, r)
+	policy := c.requestRetryPolicy(ctx, r)
+	var attempts, rateLimited int
+	var waited time.Duration
+	var sentResp *http.Response // the response of the last attempt, as Discord sent it
+	for {
+		attempts++
+		var sent, received time.Time
+		sentResp = nil
+		queued := time.Now()
+		// the global throttle is waited for before the bucket is locked, such that the queue of the bucket is
+		// not held up while the throttle is saturated
+		if err = c.throttle.wait(bucketCtx); err != nil {
+			resp, body = nil, nil
+		} else {
+			c.buckets.Bucket(r.hashedEndpoint, func(bucket RESTBucket) {
+				resp, body, err = bucket.Transaction(bucketCtx, func() (*http.Response, []byte, error) {
+					sent = time.Now()
+					c.debug.request(req, id, attempts, sent.Sub(queued))
+					c.hooks.before(id, req, attempts)
+					resp, err := c.httpClient.Do(req)
+					if err != nil {
+						return nil, nil, redactURLError(err)
+					}
+					received, sentResp = time.Now(), resp
+					c.invalidRequests.observe(resp.StatusCode)
+
+					// only successful bodies are streamed, errors are small and decoded below
+					if streaming && 200 <= resp.StatusCode && resp.StatusCode < 300 {
+						if stream, err = newStreamBody(resp); err != nil {
+							_ = resp.Body.Close()
+							return nil, nil, err
+						}
+						c.debug.response(req, resp, nil, true, received.Sub(sent))
+						normalized, err := c.normalizeResponse(req, resp, nil)
+						return normalized, nil, err
+					}
+
+					// decode body
+					body, err := c.decodeResponseBody(resp)
+					_ = resp.Body.Close()
+					if err != nil {
+						return nil, nil, err
+					}
+					c.debug.response(req, resp, body, false, received.Sub(sent))
+					if isCloudflareBlock(resp, body) {
+						return nil, nil, c.cloudflare.trip(resp)
+					}
+
+					// normalize Discord header fields
+					normalized, err := c.normalizeResponse(req, resp, body)
+					return normalized, body, err
+				})
+			})
+			if sent.IsZero() {
+				// the request was not sent, the token is left for the next one
+				c.throttle.refund()
 			}
+		}
+		observed := resp
+		if observed == nil {
+			observed = sentResp
+		}
+		c.observe(r, observed, queued, sent, received)
+		if !sent.IsZero() {
+			waited += sent.Sub(queued)
+			c.hooks.after(id, req, sentResp, err)
+		}
+		if err != nil {
+			if stream != nil {
+				_ = stream.Close()
+				stream = nil
+			}
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) {
+				if rateLimitErr.Bucket == "" {
+					rateLimitErr.Bucket = r.hashedEndpoint
+				}
+				c.rateLimitRejected(r, rateLimitErr)
+			}
+			if c.backoff(ctx, &policy, r, req, 0, err, attempts) {
+				continue
+			}
+			if attempts > 1 {
+				err = &RetryError{Attempts: attempts, Err: err}
+			}
+			return nil, nil, nil, err
+		}
 
-			// decode body
-			body, err := c.decodeResponseBody(resp)
-			_ = resp.Body.Close()
-			if err != nil {
-				return nil, nil, err
+		if resp.StatusCode == http.StatusTooManyRequests {
+			c.tooManyRequests(r, resp)
+			rateLimited++
+			if c.waitForRetry(ctx, r, req, resp, rateLimited) {
+				continue
 			}
+		} else if c.backoff(ctx, &policy, r, req, resp.StatusCode, nil, attempts) {
+			continue
+		}
+		break
+	}
 
-			// normalize Discord header fields
-			resp.Header, err = NormalizeDiscordHeader(resp.StatusCode, resp.Header, body)
-			return resp, body, err
-		})
-	})
-	if err != nil {
-		return nil, nil, err
+	if stream != nil {
+		if info := rateLimitInfoFrom(ctx); info != nil {
+			*info = newRateLimitInfo(r, resp.Header, waited)
+		}
+		resp.Header = sentResp.Header
+		return resp, nil, stream, nil
 	}
+	body = c.etagResponse(r, resp, body, cached)
 
 	// check if request was successful
 	noDiff := resp.StatusCode == http.StatusNotModified
@@ -287,31 +922,209 @@ func (c *Client) Do(ctx context.Context, r *Request) (resp *http.Response, body
 		msg := "response was not within the successful http code range [200, 300). code: "
 		msg += strconv.Itoa(resp.StatusCode)
 
-		err = &ErrREST{
+		restErr := &ErrREST{
 			Msg:            msg,
 			Suggestion:     string(body),
 			HTTPCode:       resp.StatusCode,
 			Bucket:         c.buckets.BucketGrouping()[r.hashedEndpoint],
 			HashedEndpoint: r.hashedEndpoint,
+			Attempts:       attempts,
+			Method:         r.Method.String(),
+			Endpoint:       RedactEndpoint(r.Endpoint),
+			RequestID:      id,
 		}
 
-		// store the Discord error if it exists
-		if len(body) > 0 {
-			_ = json.Unmarshal(body, err)
+		// store the Discord error if it exists, and replace the raw body with the hint of its code
+		if len(body) > 0 && c.codec.Unmarshal(body, restErr) == nil {
+			if suggestion := restErr.Code.Suggestion(); restErr.Code != JSONErrorCodeGeneral && suggestion != "" {
+				restErr.Suggestion = suggestion
+			}
+			restErr.Errors = parseFieldErrors(c.codec, body)
 		}
-		return nil, nil, err
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			return nil, nil, nil, rateLimitError(r, resp.Header, restErr)
+		case http.StatusUnauthorized:
+			token := strings.TrimPrefix(c.reqHeader.Get(Authorization), "Bot ")
+			return nil, nil, nil, unauthorizedError(req.Header, token, restErr)
+		}
+		return nil, nil, nil, restErr
+	}
+
+	if info := rateLimitInfoFrom(ctx); info != nil {
+		*info = newRateLimitInfo(r, resp.Header, waited)
 	}
+	// the caller sees the header as Discord sent it
+	resp.Header = sentResp.Header
+	return resp, body, nil, nil
+}
 
-	return resp, body, nil
+// requestMaxRateLimitDelay picks how long the request may wait for a rate limit from the request, the context or the
+// client, in that order. It returns false when the request may wait as long as the context allows.
+func (c *Client) requestMaxRateLimitDelay(ctx context.Context, r *Request) (maxDelay time.Duration, limited bool) {
+	switch {
+	case r.MaxRateLimitDelay != 0:
+		maxDelay, limited = r.MaxRateLimitDelay, true
+	case ctx.Value(maxRateLimitDelayKey{}) != nil:
+		maxDelay, limited = maxRateLimitDelay(ctx)
+	case c.maxRateLimitDelay != 0:
+		maxDelay, limited = c.maxRateLimitDelay, true
+	case c.cancelRequestWhenRateLimited:
+		limited = true
+	}
+	if maxDelay < 0 {
+		maxDelay = 0
+	}
+	return maxDelay, limited
 }
 
-// helper functions
-func convertStructToIOReader(marshal func(v interface{}) ([]byte, error), v interface{}) (io.Reader, error) {
-	jsonParamsBytes, err := marshal(v)
+// normalizeResponse returns a copy of the response with the rate limit fields normalized for the buckets, and
+// reports the fields that are malformed, such as by a proxy. The header of resp is left as Discord sent it,
+// for the hooks and the caller. Buckets ignore the malformed fields, see parseRateLimitHeader.
+func (c *Client) normalizeResponse(req *http.Request, resp *http.Response, body []byte) (*http.Response, error) {
+	header, err := NormalizeDiscordHeader(c.apiVersion, resp.StatusCode, resp.Header, body)
 	if err != nil {
 		return nil, err
 	}
-	jsonParamsReader := bytes.NewReader(jsonParamsBytes)
+	if malformed := parseRateLimitHeader(header, resp.StatusCode).err(); malformed != nil {
+		c.log.Error(fmt.Sprintf("httd: %s %s: %s", req.Method, redactURL(req.URL.String()), malformed))
+	}
 
-	return jsonParamsReader, nil
+	normalized := *resp
+	normalized.Header = header
+	return &normalized, nil
 }
+
+// waitForRetry waits out the rate limit of a 429 response and prepares the request to be sent again.
+// It returns false when the request should not be retried.
+func (c *Client) waitForRetry(ctx context.Context, r *Request, req *http.Request, resp *http.Response, attempt int) bool {
+	maxDelay, limitDelay := c.requestMaxRateLimitDelay(ctx, r)
+	if (limitDelay && maxDelay == 0) || attempt > c.maxRateLimitRetries {
+		return false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// the body was consumed and can not be replayed, such as a streamed upload
+		return false
+	}
+
+	delay := rateLimitDelay(resp.Header)
+	if limitDelay && delay > maxDelay {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(time.Now().Add(delay)) {
+		return false
+	}
+
+	scope := "bucket"
+	if resp.Header.Get(XRateLimitGlobal) == "true" {
+		scope = "global"
+	}
+	c.log.Info(fmt.Sprintf("httd: %s rate limited on %s %s, retry %d/%d in %s",
+		scope, r.Method, RedactEndpoint(r.Endpoint), attempt, c.maxRateLimitRetries, delay))
+	c.debug.retry(req, scope+" rate limited", delay)
+
+	return sleep(ctx, delay) && rewindBody(req)
+}
+
+// backoff waits before a failed request is sent again, as decided by the policy of the request. It returns
+// false when the request should not be retried.
+func (c *Client) backoff(ctx context.Context, policy *RetryPolicy, r *Request, req *http.Request, statusCode int, err error, attempt int) bool {
+	if attempt >= policy.MaxAttempts || !r.idempotent() || ctx.Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if !policy.shouldRetry(statusCode, err) {
+		return false
+	}
+
+	delay := policy.delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(time.Now().Add(delay)) {
+		return false
+	}
+
+	reason := http.StatusText(statusCode)
+	if err != nil {
+		reason = err.Error()
+	}
+	c.log.Info(fmt.Sprintf("httd: %s %s failed (%s), attempt %d/%d in %s",
+		r.Method, RedactEndpoint(r.Endpoint), reason, attempt+1, policy.MaxAttempts, delay))
+	c.debug.retry(req, reason, delay)
+
+	return sleep(ctx, delay) && rewindBody(req)
+}
+
+// retryStatusCodes are the server errors that usually go away when the request is sent again
+var retryStatusCodes = map[int]bool{
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// temporary checks if a transport error, such as a timeout or a reset connection, might go away
+func temporary(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && (netErr.Timeout() || netErr.Temporary())
+}
+
+// sleep waits for the given duration, and returns false if the context is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// rewindBody replaces the consumed request body with a new copy, such that the request can be sent again
+func rewindBody(req *http.Request) bool {
+	if req.GetBody == nil {
+		return true
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+// rateLimitDelay is the time left until the rate limit of a normalized 429 response resets
+func rateLimitDelay(header http.Header) time.Duration {
+	epoch, err := strconv.ParseInt(header.Get(XRateLimitReset), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	now, err := HeaderToTime(header)
+	if err != nil {
+		now = time.Now()
+	}
+
+	delay := time.Unix(0, epoch*int64(time.Millisecond)).Sub(now)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// rateLimitError describes a 429 response that was not retried
+func rateLimitError(r *Request, header http.Header, err *ErrREST) *RateLimitError {
+	bucket := header.Get(XRateLimitBucket)
+	if bucket == "" {
+		bucket = r.hashedEndpoint
+	}
+	return &RateLimitError{
+		RetryAfter: rateLimitDelay(header),
+		Global:     header.Get(XRateLimitGlobal) == "true",
+		Bucket:     bucket,
+		Err:        err,
+	}
+}
+
+
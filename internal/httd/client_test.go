@@ -5,9 +5,18 @@ package httd
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func missingImplError(t *testing.T, interfaceName string) {
@@ -77,3 +86,890 @@ func TestDecodingResponseBodyWithGZIP(t *testing.T) {
 		t.Errorf("decoding failed. Got %s, wants %s", string(body), expected)
 	}
 }
+
+func TestClient_DoSkipAuthorization(t *testing.T) {
+	var authorization []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get(Authorization))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.url = srv.URL
+
+	for _, skip := range []bool{false, true} {
+		req := &Request{Method: MethodDelete, Endpoint: "/webhooks/1/token/messages/2", SkipAuthorization: skip}
+		if _, _, err = client.Do(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if authorization[0] != "Bot sdfgsdfg" {
+		t.Errorf("expected bot token to be set. Got %q", authorization[0])
+	}
+	if authorization[1] != "" {
+		t.Errorf("expected bot token to be omitted. Got %q", authorization[1])
+	}
+}
+
+func TestClient_DoRedactsWebhookToken(t *testing.T) {
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         &http.Client{},
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.url = "http://127.0.0.1:0"
+
+	req := &Request{Method: MethodPost, Endpoint: "/webhooks/1/s3cr3t"}
+	if _, _, err = client.Do(context.Background(), req); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("the webhook token was part of the error: %s", err)
+	}
+}
+
+func TestClient_DoWithoutContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.url = srv.URL
+
+	req := &Request{Endpoint: "/users/@me"}
+	if _, _, err = client.Do(nil, req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Ctx == nil {
+		t.Error("expected the request context to default to context.Background")
+	}
+}
+
+// onlyReader hides the type of the underlying reader, such that the body can not be replayed
+type onlyReader struct {
+	io.Reader
+}
+
+func TestClient_DoRetryRateLimited(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies)%2 == 1 {
+			w.Header().Set(RateLimitRetryAfter, "10")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":10,"global":false}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	newClient := func(retries int) *Client {
+		client, err := NewClient(&Config{
+			APIVersion:          6,
+			BotToken:            "sdfgsdfg",
+			HTTPClient:          srv.Client(),
+			UserAgentSourceURL:  "test",
+			UserAgentVersion:    "test",
+			MaxRateLimitRetries: retries,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.url = srv.URL
+		return client
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		bodies = nil
+		req := &Request{Method: MethodPost, Endpoint: "/channels/1/messages", Body: map[string]string{"content": "hi"}, ContentType: ContentTypeJSON}
+		if _, _, err := newClient(0).Do(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		if len(bodies) != 2 {
+			t.Fatalf("expected the request to be sent twice, got %d", len(bodies))
+		}
+		if bodies[0] != bodies[1] || !strings.Contains(bodies[1], `"hi"`) {
+			t.Errorf("the body was not replayed, got %q", bodies)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		bodies = nil
+		_, _, err := newClient(-1).Do(context.Background(), &Request{Endpoint: "/channels/1"})
+		var restErr *ErrREST
+		if !errors.As(err, &restErr) || restErr.HTTPCode != http.StatusTooManyRequests {
+			t.Errorf("expected a 429 error, got %v", err)
+		}
+		if len(bodies) != 1 {
+			t.Errorf("expected one request, got %d", len(bodies))
+		}
+
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) || !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected a rate limit error, got %v", err)
+		}
+		if rateLimitErr.RetryAfter <= 0 || rateLimitErr.Global || rateLimitErr.Bucket == "" {
+			t.Errorf("unexpected rate limit details: %+v", rateLimitErr)
+		}
+	})
+
+	t.Run("not replayable", func(t *testing.T) {
+		bodies = nil
+		req := &Request{Method: MethodPost, Endpoint: "/channels/2/messages", Body: onlyReader{strings.NewReader("data")}}
+		_, _, err := newClient(0).Do(context.Background(), req)
+		var restErr *ErrREST
+		if !errors.As(err, &restErr) || restErr.HTTPCode != http.StatusTooManyRequests {
+			t.Errorf("expected a 429 error, got %v", err)
+		}
+		if len(bodies) != 1 {
+			t.Errorf("expected one request, got %d", len(bodies))
+		}
+	})
+
+	t.Run("body factory", func(t *testing.T) {
+		bodies = nil
+		var created int
+		req := &Request{Method: MethodPost, Endpoint: "/channels/3/messages", BodyFactory: func() (io.Reader, error) {
+			created++
+			return onlyReader{strings.NewReader("data")}, nil
+		}}
+		if _, _, err := newClient(0).Do(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		if len(bodies) != 2 || bodies[0] != "data" || bodies[1] != "data" {
+			t.Errorf("expected the body to be sent twice, got %q", bodies)
+		}
+		if created != 2 {
+			t.Errorf("expected a body per attempt, got %d", created)
+		}
+	})
+}
+
+func TestClient_DoMaxRateLimitDelay(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		reset := float64(time.Now().Add(300*time.Millisecond).UnixNano()) / float64(time.Second)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(XRateLimitBucket, "abcd1234")
+		w.Header().Set(XRateLimitRemaining, "0")
+		w.Header().Set(XRateLimitReset, strconv.FormatFloat(reset, 'f', 3, 64))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		MaxRateLimitDelay:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first request exhausts the bucket
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("config", func(t *testing.T) {
+		_, _, err := client.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("expected a rate limit error, got %v", err)
+		}
+		if rateLimitErr.RetryAfter <= 50*time.Millisecond {
+			t.Errorf("expected the time until the reset, got %s", rateLimitErr.RetryAfter)
+		}
+		if requests != 1 {
+			t.Errorf("the request was sent while the bucket was exhausted")
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx := WithMaxRateLimitDelay(context.Background(), -1)
+		if _, _, err := client.Do(ctx, &Request{Endpoint: "/channels/1"}); !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected a rate limit error, got %v", err)
+		}
+	})
+
+	t.Run("request", func(t *testing.T) {
+		ctx := WithMaxRateLimitDelay(context.Background(), -1)
+		if _, _, err := client.Do(ctx, &Request{Endpoint: "/channels/1", MaxRateLimitDelay: 3 * time.Second}); err != nil {
+			t.Fatalf("expected the request to wait for the reset, got %v", err)
+		}
+		if requests != 2 {
+			t.Errorf("expected 2 requests, got %d", requests)
+		}
+	})
+}
+
+func TestClient_DoRetryServerErrors(t *testing.T) {
+	var requests int
+	var failures int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests <= failures {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		RetryPolicy:        RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.url = srv.URL
+
+	table := []struct {
+		name     string
+		req      *Request
+		failures int
+		requests int
+		success  bool
+	}{
+		{"get recovers", &Request{Endpoint: "/channels/1"}, 2, 3, true},
+		{"get gives up", &Request{Endpoint: "/channels/1"}, 5, 3, false},
+		{"post is not retried", &Request{Method: MethodPost, Endpoint: "/channels/1/messages"}, 1, 1, false},
+		{"idempotent post", &Request{Method: MethodPost, Endpoint: "/channels/1/messages", Idempotent: true}, 1, 2, true},
+		{"request policy", &Request{Endpoint: "/channels/1", RetryPolicy: &RetryPolicy{MaxAttempts: 5}}, 4, 5, true},
+		{"request policy gives up", &Request{Endpoint: "/channels/1", RetryPolicy: &RetryPolicy{
+			ShouldRetry: func(status int, err error) bool { return status != http.StatusBadGateway },
+		}}, 1, 1, false},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			requests, failures = 0, test.failures
+			_, _, err := client.Do(context.Background(), test.req)
+			if requests != test.requests {
+				t.Errorf("expected %d requests, got %d", test.requests, requests)
+			}
+			if test.success {
+				if err != nil {
+					t.Errorf("expected success, got %v", err)
+				}
+				return
+			}
+
+			restErr, ok := err.(*ErrREST)
+			if !ok || restErr.HTTPCode != http.StatusBadGateway {
+				t.Fatalf("expected a 502 error, got %v", err)
+			}
+			if restErr.Attempts != test.requests {
+				t.Errorf("expected %d attempts on the error, got %d", test.requests, restErr.Attempts)
+			}
+		})
+	}
+}
+
+func TestClient_DoWithRetryPolicy(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		RetryPolicy:        RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var delays []int
+	ctx := WithRetryPolicy(context.Background(), RetryPolicy{
+		MaxAttempts: 4,
+		Backoff: func(attempt int) time.Duration {
+			delays = append(delays, attempt)
+			return time.Millisecond
+		},
+	})
+	if _, _, err = client.Do(ctx, &Request{Endpoint: "/channels/1"}); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if requests != 4 || fmt.Sprint(delays) != "[1 2 3]" {
+		t.Errorf("expected 4 requests and 3 backoffs, got %d requests and backoffs %v", requests, delays)
+	}
+
+	// the request policy wins over the context
+	requests = 0
+	_, _, _ = client.Do(ctx, &Request{Endpoint: "/channels/1", RetryPolicy: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}})
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	wants := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, want := range wants {
+		if got := policy.delay(i + 1); got != want {
+			t.Errorf("attempt %d: got %s, wants %s", i+1, got, want)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("jitter moved the delay out of range: %s", got)
+		}
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	table := map[string]string{
+		"":                           BaseURL + "/v6",
+		"http://localhost:8080":      "http://localhost:8080/v6",
+		"http://localhost:8080/api/": "http://localhost:8080/api/v6",
+		"http://localhost/api/v8":    "http://localhost/api/v8",
+		"http://localhost/api/v8/":   "http://localhost/api/v8",
+	}
+
+	for base, wants := range table {
+		if got := apiURL(base, 6); got != wants {
+			t.Errorf("apiURL(%q) = %q, wants %q", base, got, wants)
+		}
+	}
+}
+
+func TestClient_DoAPIBaseURL(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	conf := &Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL + "/api",
+		DisableRateLimiter: true,
+	}
+	client, err := NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conf.RESTBucketManager.(*NopManager); !ok {
+		t.Errorf("expected the rate limiter to be disabled, got %T", conf.RESTBucketManager)
+	}
+
+	if _, _, err = client.Do(context.Background(), &Request{Method: MethodDelete, Endpoint: "/channels/1"}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/api/v6/channels/1" {
+		t.Errorf("unexpected request path %q", path)
+	}
+
+	conf.RESTBucketManager = NewManager(nil)
+	if _, err = NewClient(conf); err == nil {
+		t.Error("expected an error when combining a bucket manager with a disabled rate limiter")
+	}
+}
+
+type observation struct {
+	bucket string
+	status int
+}
+
+// recordingMetrics remembers the observed requests
+type recordingMetrics struct {
+	NopMetrics
+	requests    []observation
+	rateLimited int
+}
+
+func (m *recordingMetrics) ObserveRequest(bucket string, _ string, status int, _, _ time.Duration) {
+	m.requests = append(m.requests, observation{bucket, status})
+}
+
+func (m *recordingMetrics) IncRateLimited(bool) {
+	m.rateLimited++
+}
+
+func TestClient_DoMetrics(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(XRateLimitBucket, "abcd1234")
+		if requests == 1 {
+			w.Header().Set(RateLimitRetryAfter, "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":1,"global":false}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	metrics := &recordingMetrics{}
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		Metrics:            metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []observation{{"abcd1234", http.StatusTooManyRequests}, {"abcd1234", http.StatusOK}}
+	if len(metrics.requests) != len(wants) {
+		t.Fatalf("expected %d observations, got %+v", len(wants), metrics.requests)
+	}
+	for i := range wants {
+		if metrics.requests[i] != wants[i] {
+			t.Errorf("observation %d: got %+v, wants %+v", i, metrics.requests[i], wants[i])
+		}
+	}
+	if metrics.rateLimited != 1 {
+		t.Errorf("expected one rate limited request, got %d", metrics.rateLimited)
+	}
+}
+
+func TestClient_DoConcurrentHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the endpoint holds the expected header values
+		segments := strings.Split(r.URL.Path, "/")
+		wantsType, wantsReason := segments[len(segments)-2], segments[len(segments)-1]
+		if got := r.Header.Get(ContentType); got != strings.Replace(wantsType, "_", "/", 1) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got := r.Header.Get(XAuditLogReason); got != wantsReason && !(got == "" && wantsReason == "none") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		DisableRateLimiter: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentTypes := []string{"application_json", "multipart_form-data", "text_plain"}
+	reasons := []string{"none", "cleanup", "spam"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 30; i++ {
+		contentType, reason := contentTypes[i%len(contentTypes)], reasons[(i/3)%len(reasons)]
+		req := &Request{
+			Method:      MethodPost,
+			Endpoint:    "/test/" + contentType + "/" + reason,
+			ContentType: strings.Replace(contentType, "_", "/", 1),
+			Body:        strings.NewReader("{}"),
+		}
+		if reason != "none" {
+			req.Reason = reason
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := client.Do(context.Background(), req); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func BenchmarkDecodingResponseBody(b *testing.B) {
+	guild, err := ioutil.ReadFile("../../testdata/guild/event-d-guild-create1.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// roughly 100 KB, the size of a guild with a few hundred members
+	payload := bytes.Repeat(guild, 100*1024/len(guild)+1)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err = gz.Write(payload); err != nil {
+		b.Fatal(err)
+	}
+	_ = gz.Close()
+
+	client := &Client{}
+	b.Run("plain", func(b *testing.B) {
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			resp := &http.Response{Body: ioutil.NopCloser(bytes.NewReader(payload))}
+			if _, err := client.decodeResponseBody(resp); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("gzip", func(b *testing.B) {
+		header := http.Header{ContentEncoding: []string{GZIPCompression}}
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			resp := &http.Response{Body: ioutil.NopCloser(bytes.NewReader(compressed.Bytes())), Header: header}
+			if _, err := client.decodeResponseBody(resp); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestClient_DoStream(t *testing.T) {
+	payload := strings.Repeat(`{"id":"1"},`, 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(XRateLimitBucket, "streamed")
+		w.Header().Set(XRateLimitRemaining, "4")
+		w.Header().Set(XRateLimitResetAfter, "1")
+		if r.URL.Path == "/v6/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":10003,"message":"Unknown Channel"}`))
+			return
+		}
+
+		w.Header().Set(ContentEncoding, GZIPCompression)
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(payload))
+		_ = gz.Close()
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         &http.Client{Transport: &http.Transport{DisableCompression: true}},
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, body, err := client.DoStream(context.Background(), &Request{Endpoint: "/channels/1/messages"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.BucketGrouping()["streamed"]; !ok {
+		t.Errorf("expected the rate limit headers to be processed before the body is read, got %+v", client.BucketGrouping())
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = body.Close(); err != nil {
+		t.Error(err)
+	}
+	if string(data) != payload {
+		t.Errorf("unexpected body, got %d bytes", len(data))
+	}
+
+	_, body, err = client.DoStream(context.Background(), &Request{Endpoint: "/missing"})
+	if restErr, ok := err.(*ErrREST); !ok || restErr.Code != 10003 || body != nil {
+		t.Errorf("expected the error to be decoded, got %v", err)
+	}
+}
+
+func TestErrREST_Is(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"code":50013,"message":"Missing Permissions"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = client.Do(context.Background(), &Request{Method: MethodDelete, Endpoint: "/webhooks/1/s3cr3t"})
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected the error to match ErrForbidden, got %v", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("did not expect the error to match ErrNotFound")
+	}
+
+	var restErr *ErrREST
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &restErr) {
+		t.Fatal("expected the wrapped error to be an ErrREST")
+	}
+	if restErr.Code != 50013 || restErr.Method != "DELETE" || restErr.Endpoint != "/webhooks/1/{token}" {
+		t.Errorf("unexpected error details: %+v", restErr)
+	}
+	if strings.Contains(restErr.Error(), "s3cr3t") {
+		t.Error("the webhook token is part of the error message")
+	}
+
+	table := map[int]error{
+		http.StatusBadRequest:      ErrBadRequest,
+		http.StatusUnauthorized:    ErrUnauthorized,
+		http.StatusNotFound:        ErrNotFound,
+		http.StatusTooManyRequests: ErrRateLimited,
+	}
+	for code, target := range table {
+		if !errors.Is(&ErrREST{HTTPCode: code}, target) {
+			t.Errorf("expected status %d to match %v", code, target)
+		}
+	}
+}
+
+func TestJSONErrorCode(t *testing.T) {
+	table := []struct {
+		status    int
+		body      string
+		code      JSONErrorCode
+		predicate func(error) bool
+	}{
+		{http.StatusNotFound, `{"code":10008,"message":"Unknown Message"}`, JSONErrorCodeUnknownMessage, IsUnknownMessage},
+		{http.StatusNotFound, `{"code":10003,"message":"Unknown Channel"}`, JSONErrorCodeUnknownChannel, IsUnknownChannel},
+		{http.StatusForbidden, `{"code":50001,"message":"Missing Access"}`, JSONErrorCodeMissingAccess, IsMissingAccess},
+		{http.StatusForbidden, `{"code":50013,"message":"Missing Permissions"}`, JSONErrorCodeMissingPermissions, IsMissingPermissions},
+		{http.StatusBadRequest, `{"code":50035,"errors":{"content":{"_errors":[{"code":"BASE_TYPE_MAX_LENGTH","message":"Must be 2000 or fewer in length."}]}},"message":"Invalid Form Body"}`, JSONErrorCodeInvalidFormBody, IsInvalidFormBody},
+	}
+
+	for _, test := range table {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(test.status)
+			_, _ = w.Write([]byte(test.body))
+		}))
+
+		client, err := NewClient(&Config{
+			APIVersion:         6,
+			BotToken:           "sdfgsdfg",
+			HTTPClient:         srv.Client(),
+			UserAgentSourceURL: "test",
+			UserAgentVersion:   "test",
+			APIBaseURL:         srv.URL,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = client.Do(context.Background(), &Request{Method: MethodGet, Endpoint: "/channels/1/messages/2"})
+		srv.Close()
+
+		err = fmt.Errorf("wrapped: %w", err)
+		if !HasJSONErrorCode(err, test.code) {
+			t.Errorf("code %d: expected the error to have the code, got %v", test.code, err)
+		}
+		if !test.predicate(err) {
+			t.Errorf("code %d: predicate did not match %v", test.code, err)
+		}
+		if IsUnknownMessage(err) != (test.code == JSONErrorCodeUnknownMessage) {
+			t.Errorf("code %d: IsUnknownMessage matched the wrong code", test.code)
+		}
+		if test.code.Suggestion() == "" {
+			t.Errorf("code %d: missing suggestion", test.code)
+		}
+		var restErr *ErrREST
+		if errors.As(err, &restErr) && restErr.Suggestion != test.code.Suggestion() {
+			t.Errorf("code %d: expected the suggestion of the code, got %q", test.code, restErr.Suggestion)
+		}
+	}
+
+	if name := JSONErrorCodeUnknownMessage.String(); name != "UnknownMessage" {
+		t.Errorf("expected the name UnknownMessage, got %s", name)
+	}
+	if name := JSONErrorCode(1).String(); name != "1" {
+		t.Errorf("expected unknown codes to be named by their number, got %s", name)
+	}
+
+	if HasJSONErrorCode(nil, JSONErrorCodeGeneral) || HasJSONErrorCode(errors.New("oops"), JSONErrorCodeGeneral) {
+		t.Error("errors that are not REST errors should never have a code")
+	}
+}
+
+func TestNewClient_APIVersion(t *testing.T) {
+	for _, version := range []int{6, 8, 10} {
+		var path, precision string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			precision = r.Header.Get(XRateLimitPrecision)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		client, err := NewClient(&Config{
+			APIVersion:         version,
+			BotToken:           "sdfgsdfg",
+			HTTPClient:         srv.Client(),
+			UserAgentSourceURL: "test",
+			UserAgentVersion:   "test",
+			APIBaseURL:         srv.URL,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.APIVersion().Version() != version {
+			t.Errorf("expected version %d, got %d", version, client.APIVersion().Version())
+		}
+
+		_, _, err = client.Do(context.Background(), &Request{Method: MethodGet, Endpoint: "/gateway"})
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if wants := "/v" + strconv.Itoa(version) + "/gateway"; path != wants {
+			t.Errorf("expected the path %s, got %s", wants, path)
+		}
+		if (precision != "") != client.APIVersion().RateLimitPrecisionHeader() {
+			t.Errorf("v%d: unexpected %s header %q", version, XRateLimitPrecision, precision)
+		}
+	}
+
+	if _, err := NewClient(&Config{APIVersion: 5, BotToken: "sdfgsdfg"}); err == nil {
+		t.Error("expected an error for an unsupported API version")
+	}
+}
+
+func TestClient_DoMaxResponseSize(t *testing.T) {
+	// 1MB of zeros compresses into about a kilobyte
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, _ = gz.Write(make([]byte, 1<<20))
+	_ = gz.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(ContentEncoding, GZIPCompression)
+		_, _ = w.Write(gzipped.Bytes())
+	}))
+	defer srv.Close()
+
+	newClient := func(limit int64) *Client {
+		client, err := NewClient(&Config{
+			APIVersion:         6,
+			BotToken:           "sdfgsdfg",
+			HTTPClient:         srv.Client(),
+			UserAgentSourceURL: "test",
+			UserAgentVersion:   "test",
+			APIBaseURL:         srv.URL,
+			MaxResponseSize:    limit,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	table := []struct {
+		name  string
+		limit int64
+	}{
+		{"gzip bomb", 64 << 10},
+		{"small limit", 100},
+	}
+	for _, test := range table {
+		_, _, err := newClient(test.limit).Do(context.Background(), &Request{Endpoint: "/channels/1"})
+		var tooLarge *ErrResponseTooLarge
+		if !errors.As(err, &tooLarge) {
+			t.Fatalf("%s: expected the response to be too large, got %v", test.name, err)
+		}
+		if tooLarge.Limit != test.limit || tooLarge.Read <= test.limit {
+			t.Errorf("%s: unexpected error details %+v", test.name, tooLarge)
+		}
+	}
+
+	_, body, err := newClient(-1).Do(context.Background(), &Request{Endpoint: "/channels/1"})
+	if err != nil || len(body) != 1<<20 {
+		t.Errorf("expected the full body without a limit, got %d bytes: %v", len(body), err)
+	}
+
+	_, stream, err := newClient(100).DoStream(context.Background(), &Request{Endpoint: "/channels/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if n, _ := io.Copy(ioutil.Discard, stream); n != 1<<20 {
+		t.Errorf("expected streamed bodies to not be limited, got %d bytes", n)
+	}
+}
@@ -0,0 +1,167 @@
+package httd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discord bans the IP address at Cloudflare when too many invalid requests are sent within 10 minutes.
+// Invalid requests are responses with the status code 401, 403, 404 or 429.
+const (
+	InvalidRequestLimit  = 10000
+	InvalidRequestWindow = 10 * time.Minute
+
+	// DefaultInvalidRequestWarningThreshold is when Config.InvalidRequestWarning is called
+	DefaultInvalidRequestWarningThreshold = 5000
+
+	// invalidRequestSlot is the time covered by every slot of the invalidRequestCounter
+	invalidRequestSlot = 10 * time.Second
+
+	// DefaultCloudflareCooldown is how long requests are rejected locally after Cloudflare blocked a request
+	DefaultCloudflareCooldown = 10 * time.Minute
+)
+
+// ErrCloudflareBlocked is matched with errors.Is when Cloudflare, rather than Discord, rejected a request.
+// Further requests are rejected locally for a while, see Config.CloudflareCooldown.
+var ErrCloudflareBlocked error = &Error{"blocked by cloudflare", time.Unix(0, 0)}
+
+// ErrInvalidRequestLimit is matched with errors.Is when a request is rejected locally, because the number of
+// invalid requests within InvalidRequestWindow reached Config.InvalidRequestStopThreshold.
+var ErrInvalidRequestLimit error = &Error{"too many invalid requests", time.Unix(0, 0)}
+
+// cloudflareErrorPageMarkers are found in the HTML error pages served by Cloudflare
+var cloudflareErrorPageMarkers = [][]byte{
+	[]byte("cf-error-details"),
+	[]byte("Cloudflare Ray ID"),
+}
+
+// isCloudflareBlock checks if a 403 or 429 response comes from Cloudflare, which responds with HTML instead
+// of the JSON error Discord sends. Every Discord response passes through Cloudflare, so the Cloudflare
+// headers only count when the body is not JSON.
+func isCloudflareBlock(resp *http.Response, body []byte) bool {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if strings.HasPrefix(resp.Header.Get(ContentType), ContentTypeJSON) {
+		return false
+	}
+	if strings.EqualFold(resp.Header.Get("Server"), "cloudflare") || resp.Header.Get("CF-RAY") != "" {
+		return true
+	}
+	for _, marker := range cloudflareErrorPageMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// circuitBreaker rejects all requests until the cooldown after a Cloudflare block has passed
+type circuitBreaker struct {
+	sync.RWMutex
+	cooldown time.Duration
+	until    time.Time
+}
+
+// trip starts the cooldown, which lasts at least as long as the Retry-After header of the response
+func (b *circuitBreaker) trip(resp *http.Response) error {
+	cooldown := b.cooldown
+	if seconds, err := strconv.Atoi(resp.Header.Get(RateLimitRetryAfter)); err == nil {
+		if retryAfter := time.Duration(seconds) * time.Second; retryAfter > cooldown {
+			cooldown = retryAfter
+		}
+	}
+
+	b.Lock()
+	b.until = time.Now().Add(cooldown)
+	b.Unlock()
+	return fmt.Errorf("%w: http %d, requests are paused for %s", ErrCloudflareBlocked, resp.StatusCode, cooldown)
+}
+
+// allow returns an error while the cooldown is active
+func (b *circuitBreaker) allow() error {
+	b.RLock()
+	remaining := time.Until(b.until)
+	b.RUnlock()
+	if remaining > 0 {
+		return fmt.Errorf("%w: requests are paused for another %s", ErrCloudflareBlocked, remaining.Round(time.Second))
+	}
+	return nil
+}
+
+// invalidRequestCounter counts the invalid requests in the last InvalidRequestWindow, using a slot per
+// invalidRequestSlot, such that the memory use does not grow with the number of requests
+type invalidRequestCounter struct {
+	sync.Mutex
+	slots     [InvalidRequestWindow / invalidRequestSlot]int
+	periods   [InvalidRequestWindow / invalidRequestSlot]int64
+	threshold int
+	warn      func(count int)
+
+	// stopThreshold rejects requests when reached, unless it is zero
+	stopThreshold int
+}
+
+func isInvalidRequest(statusCode int) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+func (c *invalidRequestCounter) observe(statusCode int) {
+	if !isInvalidRequest(statusCode) {
+		return
+	}
+
+	period := currentPeriod()
+	i := period % int64(len(c.slots))
+	c.Lock()
+	if c.periods[i] != period {
+		c.periods[i], c.slots[i] = period, 0
+	}
+	previous := c.countLocked(period)
+	c.slots[i]++
+	c.Unlock()
+
+	// warn once when the threshold is crossed
+	if c.warn != nil && previous < c.threshold && previous+1 >= c.threshold {
+		c.warn(previous + 1)
+	}
+}
+
+func (c *invalidRequestCounter) count() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.countLocked(currentPeriod())
+}
+
+func (c *invalidRequestCounter) countLocked(period int64) (count int) {
+	for i := range c.slots {
+		if period-c.periods[i] < int64(len(c.slots)) {
+			count += c.slots[i]
+		}
+	}
+	return count
+}
+
+// allow returns an error once the invalid requests reach the stop threshold
+func (c *invalidRequestCounter) allow() error {
+	if c.stopThreshold <= 0 {
+		return nil
+	}
+	if count := c.count(); count >= c.stopThreshold {
+		return fmt.Errorf("%w: %d invalid requests within %s, requests are paused", ErrInvalidRequestLimit, count, InvalidRequestWindow)
+	}
+	return nil
+}
+
+func currentPeriod() int64 {
+	return time.Now().UnixNano() / int64(invalidRequestSlot)
+}
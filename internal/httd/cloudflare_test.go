@@ -0,0 +1,191 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_DoCloudflareBlocked(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Server", "cloudflare")
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`<!DOCTYPE html><html><head><title>Access denied | discord.com used Cloudflare to restrict access</title></head><body>Error 1015</body></html>`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		CloudflareCooldown: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+	if !errors.Is(err, ErrCloudflareBlocked) {
+		t.Fatalf("expected a cloudflare error, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the block to not be retried, got %d requests", requests)
+	}
+
+	// the circuit breaker rejects further requests locally
+	_, _, err = client.Do(context.Background(), &Request{Endpoint: "/guilds/1"})
+	if !errors.Is(err, ErrCloudflareBlocked) {
+		t.Errorf("expected the request to be rejected locally, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected no request to be sent during the cooldown, got %d requests", requests)
+	}
+
+	client.cloudflare.until = time.Now()
+	_, _, _ = client.Do(context.Background(), &Request{Endpoint: "/guilds/1"})
+	if requests != 2 {
+		t.Errorf("expected requests to be sent after the cooldown, got %d requests", requests)
+	}
+}
+
+func TestIsCloudflareBlock(t *testing.T) {
+	table := []struct {
+		name   string
+		status int
+		header map[string]string
+		body   string
+		block  bool
+	}{
+		{"discord rate limit", http.StatusTooManyRequests, map[string]string{"Server": "cloudflare", "CF-RAY": "1-AMS", "Content-Type": "application/json"}, `{"global":false}`, false},
+		{"cloudflare rate limit", http.StatusTooManyRequests, map[string]string{"Server": "cloudflare", "Content-Type": "text/html"}, "", true},
+		{"cloudflare ray", http.StatusForbidden, map[string]string{"CF-RAY": "1-AMS", "Content-Type": "text/html"}, "", true},
+		{"cloudflare error page", http.StatusForbidden, map[string]string{"Content-Type": "text/html"}, `<div id="cf-error-details">`, true},
+		{"proxy", http.StatusForbidden, map[string]string{"Via": "1.1 google", "Content-Type": "text/html"}, "<h1>Forbidden</h1>", false},
+		{"not found", http.StatusNotFound, map[string]string{"Server": "cloudflare", "Content-Type": "text/html"}, "", false},
+		{"unknown origin", http.StatusForbidden, map[string]string{"Content-Type": "text/plain"}, "", false},
+	}
+
+	for _, test := range table {
+		resp := &http.Response{StatusCode: test.status, Header: make(http.Header)}
+		for k, v := range test.header {
+			resp.Header.Set(k, v)
+		}
+		if isCloudflareBlock(resp, []byte(test.body)) != test.block {
+			t.Errorf("%s: expected %t", test.name, test.block)
+		}
+	}
+}
+
+func TestInvalidRequestCounter(t *testing.T) {
+	var warnings []int
+	counter := &invalidRequestCounter{threshold: 3, warn: func(count int) {
+		warnings = append(warnings, count)
+	}}
+
+	for _, status := range []int{200, 401, 403, 204, 404, 429, 429} {
+		counter.observe(status)
+	}
+	if count := counter.count(); count != 5 {
+		t.Errorf("expected 5 invalid requests, got %d", count)
+	}
+	if len(warnings) != 1 || warnings[0] != 3 {
+		t.Errorf("expected a single warning at 3 invalid requests, got %v", warnings)
+	}
+
+	// requests older than the window are forgotten
+	for i := range counter.periods {
+		counter.periods[i] -= int64(len(counter.periods))
+	}
+	if count := counter.count(); count != 0 {
+		t.Errorf("expected the old requests to be dropped, got %d", count)
+	}
+}
+
+func TestInvalidRequestCounter_Burst(t *testing.T) {
+	var warnings []int
+	counter := &invalidRequestCounter{threshold: 100, stopThreshold: 200, warn: func(count int) {
+		warnings = append(warnings, count)
+	}}
+
+	for i := 0; i < 199; i++ {
+		counter.observe(http.StatusTooManyRequests)
+	}
+	if len(warnings) != 1 || warnings[0] != 100 {
+		t.Errorf("expected a single warning at 100 invalid requests, got %v", warnings)
+	}
+	if err := counter.allow(); err != nil {
+		t.Errorf("expected requests below the stop threshold, got %v", err)
+	}
+
+	counter.observe(http.StatusUnauthorized)
+	if err := counter.allow(); !errors.Is(err, ErrInvalidRequestLimit) {
+		t.Errorf("expected the requests to be stopped, got %v", err)
+	}
+
+	// the burst leaves the window when its slot is the oldest
+	oldest := currentPeriod() - int64(len(counter.periods)) + 1
+	for i := range counter.periods {
+		if counter.slots[i] > 0 {
+			counter.periods[i] = oldest
+		}
+	}
+	if err := counter.allow(); !errors.Is(err, ErrInvalidRequestLimit) {
+		t.Errorf("expected the burst to be within the window, got %v", err)
+	}
+	for i := range counter.periods {
+		counter.periods[i]--
+	}
+	if err := counter.allow(); err != nil {
+		t.Errorf("expected the requests to resume, got %v", err)
+	}
+}
+
+func TestClient_DoInvalidRequestStopThreshold(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Missing Access","code":50001}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:                  6,
+		BotToken:                    "sdfgsdfg",
+		HTTPClient:                  srv.Client(),
+		UserAgentSourceURL:          "test",
+		UserAgentVersion:            "test",
+		APIBaseURL:                  srv.URL,
+		InvalidRequestStopThreshold: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+	}
+	if !errors.Is(err, ErrInvalidRequestLimit) {
+		t.Errorf("expected the requests to be stopped, got %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests to be sent, got %d", requests)
+	}
+
+	stats := client.RateLimitStats()
+	if stats.InvalidRequests != 3 || stats.InvalidRequestWindow != InvalidRequestWindow {
+		t.Errorf("unexpected invalid request stats: %d within %s", stats.InvalidRequests, stats.InvalidRequestWindow)
+	}
+}
@@ -0,0 +1,97 @@
+package httd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// inflightCall is a GET request that concurrent identical requests wait for, see Config.CoalesceGETRequests
+type inflightCall struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	err  error
+
+	rateLimit RateLimitInfo
+}
+
+type inflightCalls struct {
+	sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// doCoalesced sends the request, unless an identical GET request is already in flight. Then the response of
+// that request is shared. Every caller receives its own copy of the response, such that they can be modified.
+func (c *Client) doCoalesced(ctx context.Context, r *Request) (resp *http.Response, body []byte, err error) {
+	if ctx == nil {
+		ctx = r.Ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	key := r.Endpoint
+	if r.SkipAuthorization {
+		key = "unauthorized " + key
+	}
+
+	c.inflight.Lock()
+	if call, ok := c.inflight.calls[key]; ok {
+		c.inflight.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("time out: %w", ctx.Err())
+		}
+
+		// the first caller gave up, which does not mean that this caller wants to
+		if call.err != nil && ctx.Err() == nil && (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) {
+			resp, body, _, err = c.do(ctx, r, false)
+			return resp, body, err
+		}
+		if info := rateLimitInfoFrom(ctx); info != nil && call.err == nil {
+			*info = call.rateLimit
+		}
+		return copyResponse(call.resp), copyBody(call.body), call.err
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	c.inflight.calls[key] = call
+	c.inflight.Unlock()
+
+	// the rate limit info is shared with the waiting callers
+	info := rateLimitInfoFrom(ctx)
+	if info == nil {
+		info = &RateLimitInfo{}
+		ctx = WithRateLimitInfo(ctx, info)
+	}
+	call.resp, call.body, _, call.err = c.do(ctx, r, false)
+	call.rateLimit = *info
+
+	c.inflight.Lock()
+	delete(c.inflight.calls, key)
+	c.inflight.Unlock()
+	close(call.done)
+
+	return copyResponse(call.resp), copyBody(call.body), call.err
+}
+
+func copyResponse(resp *http.Response) *http.Response {
+	if resp == nil {
+		return nil
+	}
+	cp := *resp
+	cp.Header = resp.Header.Clone()
+	return &cp
+}
+
+func copyBody(body []byte) []byte {
+	if body == nil {
+		return nil
+	}
+	cp := make([]byte, len(body))
+	copy(cp, body)
+	return cp
+}
@@ -0,0 +1,101 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_DoCoalesceGETRequests(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v6/channels/404" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":10003,"message":"Unknown Channel"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:          6,
+		BotToken:            "sdfgsdfg",
+		HTTPClient:          srv.Client(),
+		UserAgentSourceURL:  "test",
+		UserAgentVersion:    "test",
+		APIBaseURL:          srv.URL,
+		CoalesceGETRequests: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const callers = 10
+	do := func(endpoint string) (bodies [][]byte, errs []error) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, body, err := client.Do(context.Background(), &Request{Endpoint: endpoint})
+				mu.Lock()
+				bodies, errs = append(bodies, body), append(errs, err)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		return bodies, errs
+	}
+
+	bodies, errs := do("/channels/1")
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a single request, got %d", n)
+	}
+	for i := range bodies {
+		if errs[i] != nil || string(bodies[i]) != `{"id":"1"}` {
+			t.Fatalf("unexpected response %s: %v", bodies[i], errs[i])
+		}
+	}
+	bodies[0][0] = 'x'
+	if bodies[1][0] != '{' {
+		t.Error("the callers share the same body")
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	_, errs = do("/channels/404")
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a single request, got %d", n)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected every caller to get the error, got %v", err)
+		}
+	}
+
+	// other methods are never coalesced
+	atomic.StoreInt32(&requests, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = client.Do(context.Background(), &Request{Method: MethodDelete, Endpoint: "/channels/1"})
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("expected every DELETE request to be sent, got %d", n)
+	}
+}
@@ -0,0 +1,153 @@
+package httd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDebugBodyLimit is how many bytes of a request or response body are written to Config.Debug
+const DefaultDebugBodyLimit = 4 * 1024
+
+const redacted = "[REDACTED]"
+
+// debugger writes requests and responses to Config.Debug, without the bot token and webhook tokens
+type debugger struct {
+	sync.Mutex
+	w         io.Writer
+	bodyLimit int
+}
+
+func newDebugger(w io.Writer, bodyLimit int) *debugger {
+	if w == nil {
+		return nil
+	}
+	if bodyLimit == 0 {
+		bodyLimit = DefaultDebugBodyLimit
+	}
+	return &debugger{w: w, bodyLimit: bodyLimit}
+}
+
+func (d *debugger) write(s string) {
+	d.Lock()
+	defer d.Unlock()
+	_, _ = io.WriteString(d.w, s)
+}
+
+// request dumps the request right before it is sent. wait is the time spent waiting for the rate limit bucket.
+func (d *debugger) request(req *http.Request, id uint64, attempt int, wait time.Duration) {
+	if d == nil {
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--> %s %s (attempt %d, rate limit wait %s, request %d)\n", req.Method, redactURL(req.URL.String()), attempt, wait, id)
+	header := copyHeader(req.Header)
+	if header.Get(Authorization) != "" {
+		header.Set(Authorization, redacted)
+	}
+	_ = header.Write(&sb)
+	sb.WriteString("\n")
+
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody == nil:
+		sb.WriteString("[body can not be replayed]\n")
+	default:
+		body, err := req.GetBody()
+		if err != nil {
+			fmt.Fprintf(&sb, "[unable to read body: %s]\n", err)
+			break
+		}
+		data, err := ioutil.ReadAll(body)
+		_ = body.Close()
+		if err != nil {
+			fmt.Fprintf(&sb, "[unable to read body: %s]\n", err)
+			break
+		}
+		d.body(&sb, req.Header.Get(ContentType), data)
+	}
+
+	d.write(sb.String())
+}
+
+// response dumps a received response. Streamed bodies are not read.
+func (d *debugger) response(req *http.Request, resp *http.Response, body []byte, streaming bool, latency time.Duration) {
+	if d == nil {
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<-- %s %s %s (%s)\n", resp.Status, req.Method, redactURL(req.URL.String()), latency)
+	_ = resp.Header.Write(&sb)
+	sb.WriteString("\n")
+	if streaming {
+		sb.WriteString("[streamed body]\n")
+	} else {
+		d.body(&sb, resp.Header.Get(ContentType), body)
+	}
+
+	d.write(sb.String())
+}
+
+// retry notes why, and for how long, the client waits before the request is sent again
+func (d *debugger) retry(req *http.Request, reason string, delay time.Duration) {
+	if d == nil {
+		return
+	}
+	d.write(fmt.Sprintf("--- %s %s: %s, retrying in %s\n\n", req.Method, redactURL(req.URL.String()), reason, delay))
+}
+
+// body writes at most bodyLimit bytes of the body. Multipart bodies are summarized by their part names and sizes.
+func (d *debugger) body(sb *strings.Builder, contentType string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if d.bodyLimit < 0 {
+		fmt.Fprintf(sb, "[%d bytes]\n\n", len(data))
+		return
+	}
+
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		summarizeMultipart(sb, data, params["boundary"])
+		sb.WriteString("\n")
+		return
+	}
+
+	if len(data) > d.bodyLimit {
+		sb.Write(data[:d.bodyLimit])
+		fmt.Fprintf(sb, "\n[%d more bytes]\n\n", len(data)-d.bodyLimit)
+		return
+	}
+	sb.Write(data)
+	sb.WriteString("\n\n")
+}
+
+func summarizeMultipart(sb *strings.Builder, data []byte, boundary string) {
+	reader := multipart.NewReader(bytes.NewReader(data), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(sb, "[unable to read multipart body: %s]\n", err)
+			return
+		}
+
+		size, _ := io.Copy(ioutil.Discard, part)
+		sb.WriteString("part " + strconv.Quote(part.FormName()))
+		if name := part.FileName(); name != "" {
+			sb.WriteString(" (" + name + ")")
+		}
+		sb.WriteString(": " + strconv.FormatInt(size, 10) + " bytes\n")
+	}
+}
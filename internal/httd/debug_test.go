@@ -0,0 +1,118 @@
+// +build !integration
+
+package httd
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newDebugTestClient(t *testing.T, srv *httptest.Server, debug *bytes.Buffer, bodyLimit int) *Client {
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		RetryPolicy:        RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		Debug:              debug,
+		DebugBodyLimit:     bodyLimit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClient_DoDebug(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1","content":"a reply that is longer than the limit"}`))
+	}))
+	defer srv.Close()
+
+	var debug bytes.Buffer
+	client := newDebugTestClient(t, srv, &debug, 32)
+	_, _, err := client.Do(context.Background(), &Request{
+		Method:      MethodPost,
+		Endpoint:    "/webhooks/1/s3cr3t",
+		Body:        map[string]string{"content": "hello"},
+		ContentType: ContentTypeJSON,
+		Idempotent:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := debug.String()
+	for _, secret := range []string{"sdfgsdfg", "s3cr3t"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("the debug output contains %q:\n%s", secret, output)
+		}
+	}
+	expects := []string{
+		"--> POST " + srv.URL + "/v6/webhooks/1/[REDACTED] (attempt 1",
+		"--> POST " + srv.URL + "/v6/webhooks/1/[REDACTED] (attempt 2",
+		"Authorization: [REDACTED]",
+		`{"content":"hello"}`,
+		"<-- 502 Bad Gateway POST",
+		"Bad Gateway, retrying in 1ms",
+		"<-- 200 OK POST",
+		`{"id":"1","content":"a reply tha`,
+		"[28 more bytes]",
+	}
+	for _, expect := range expects {
+		if !strings.Contains(output, expect) {
+			t.Errorf("the debug output is missing %q:\n%s", expect, output)
+		}
+	}
+}
+
+func TestClient_DoDebugMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var body bytes.Buffer
+	mp := multipart.NewWriter(&body)
+	_ = mp.WriteField("payload_json", `{"content":"hi"}`)
+	file, _ := mp.CreateFormFile("file", "image.png")
+	_, _ = file.Write([]byte("raw file content"))
+	_ = mp.Close()
+
+	var debug bytes.Buffer
+	client := newDebugTestClient(t, srv, &debug, 0)
+	_, _, err := client.Do(context.Background(), &Request{
+		Method:      MethodPost,
+		Endpoint:    "/channels/1/messages",
+		Body:        &body,
+		ContentType: mp.FormDataContentType(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := debug.String()
+	if strings.Contains(output, "raw file content") {
+		t.Errorf("the multipart body was dumped raw:\n%s", output)
+	}
+	for _, expect := range []string{`part "payload_json": 16 bytes`, `part "file" (image.png): 16 bytes`} {
+		if !strings.Contains(output, expect) {
+			t.Errorf("the debug output is missing %q:\n%s", expect, output)
+		}
+	}
+}
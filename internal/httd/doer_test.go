@@ -0,0 +1,132 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedDoer responds with the scripted responses in order, without opening connections
+type scriptedDoer struct {
+	mu        sync.Mutex
+	responses []func(req *http.Request) *http.Response
+	requests  []*http.Request
+}
+
+func (d *scriptedDoer) Do(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.requests) >= len(d.responses) {
+		return nil, errors.New("unexpected request " + req.Method + " " + req.URL.String())
+	}
+	respond := d.responses[len(d.requests)]
+	d.requests = append(d.requests, req)
+	return respond(req), nil
+}
+
+func scriptedResponse(status int, header map[string]string, body string) func(req *http.Request) *http.Response {
+	return func(req *http.Request) *http.Response {
+		resp := &http.Response{
+			Status:     http.StatusText(status),
+			StatusCode: status,
+			Header:     http.Header{ContentType: []string{ContentTypeJSON}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+		for key, value := range header {
+			resp.Header.Set(key, value)
+		}
+		return resp
+	}
+}
+
+func newScriptedClient(t *testing.T, doer Doer, timeout time.Duration) *Client {
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		Doer:               doer,
+		RequestTimeout:     timeout,
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClient_DoWithDoer(t *testing.T) {
+	t.Run("rate limited", func(t *testing.T) {
+		doer := &scriptedDoer{responses: []func(req *http.Request) *http.Response{
+			scriptedResponse(http.StatusTooManyRequests, map[string]string{
+				XRateLimitBucket:     "abcd1234",
+				XRateLimitRemaining:  "0",
+				XRateLimitResetAfter: "0.05",
+			}, `{"message":"You are being rate limited.","retry_after":50,"global":false}`),
+			scriptedResponse(http.StatusOK, map[string]string{
+				XRateLimitBucket:     "abcd1234",
+				XRateLimitRemaining:  "4",
+				XRateLimitResetAfter: "1",
+			}, `{"id":"1"}`),
+		}}
+		client := newScriptedClient(t, doer, 0)
+
+		_, body, err := client.Do(context.Background(), &Request{Method: MethodPost, Endpoint: "/channels/1/messages", Body: map[string]string{"content": "hi"}, ContentType: ContentTypeJSON})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != `{"id":"1"}` {
+			t.Errorf("unexpected body %q", string(body))
+		}
+		if len(doer.requests) != 2 {
+			t.Fatalf("expected the request to be sent twice, got %d", len(doer.requests))
+		}
+		retried, _ := ioutil.ReadAll(doer.requests[1].Body)
+		if string(retried) != `{"content":"hi"}`+"\n" {
+			t.Errorf("the retry was sent without the body, got %q", string(retried))
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		doer := &scriptedDoer{responses: []func(req *http.Request) *http.Response{
+			scriptedResponse(http.StatusBadGateway, nil, ""),
+			scriptedResponse(http.StatusOK, nil, `{"id":"1"}`),
+		}}
+		client := newScriptedClient(t, doer, 0)
+		client.retryPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+		if _, _, err := client.Do(context.Background(), &Request{Endpoint: "/channels/1"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(doer.requests) != 2 {
+			t.Errorf("expected the request to be retried once, got %d requests", len(doer.requests))
+		}
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		doer := &scriptedDoer{responses: []func(req *http.Request) *http.Response{
+			scriptedResponse(http.StatusTooManyRequests, map[string]string{
+				XRateLimitBucket:     "abcd1234",
+				XRateLimitRemaining:  "0",
+				XRateLimitResetAfter: "60",
+			}, `{"message":"You are being rate limited.","retry_after":60000,"global":false}`),
+		}}
+		client := newScriptedClient(t, doer, 100*time.Millisecond)
+
+		start := time.Now()
+		_, _, err := client.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("expected a rate limit error, as the reset is after the request timeout, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("the request timeout was ignored, the request took %s", time.Since(start))
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package httd
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// driftSmoothing is the weight of a new sample in the moving average of the clock drift
+	driftSmoothing = 0.1
+
+	// maxDriftDeviation is how far a sample may be from the average before it is clamped, such that a
+	// response delayed by the network does not shift every ltBucket reset
+	maxDriftDeviation = 2 * time.Second
+)
+
+// clockDrift estimates how far the local clock is ahead of the discord clock, from the Date header of the
+// responses. The header only has second granularity, so single samples are off by up to a second and are
+// smoothed by an exponentially weighted moving average. It is shared by the buckets of a Manager.
+type clockDrift struct {
+	mu      sync.Mutex
+	average time.Duration
+	samples int
+}
+
+// observe adds a sample and returns the new estimate. A nil clockDrift returns the sample as is.
+func (d *clockDrift) observe(sample time.Duration) time.Duration {
+	if d == nil {
+		return sample
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.samples == 0 {
+		d.average = sample
+	} else {
+		deviation := sample - d.average
+		if deviation > maxDriftDeviation {
+			deviation = maxDriftDeviation
+		} else if deviation < -maxDriftDeviation {
+			deviation = -maxDriftDeviation
+		}
+		d.average += time.Duration(float64(deviation) * driftSmoothing)
+	}
+	d.samples++
+	return d.average
+}
+
+func (d *clockDrift) estimate() time.Duration {
+	if d == nil {
+		return 0
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.average
+}
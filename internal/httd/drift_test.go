@@ -0,0 +1,54 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClockDrift(t *testing.T) {
+	var d *clockDrift
+	if estimate := d.observe(time.Second); estimate != time.Second {
+		t.Errorf("a nil clockDrift must use the sample, got %s", estimate)
+	}
+
+	// the rounded Date header makes the samples jump between 0 and 1 second
+	d = &clockDrift{}
+	for i := 0; i < 100; i++ {
+		d.observe(time.Duration(i%2) * time.Second)
+	}
+	for i := 0; i < 10; i++ {
+		estimate := d.observe(time.Duration(i%2) * time.Second)
+		if estimate < 300*time.Millisecond || estimate > 700*time.Millisecond {
+			t.Fatalf("expected the estimate to settle around half a second, got %s", estimate)
+		}
+	}
+
+	// a response delayed by the network only moves the estimate a little
+	before := d.estimate()
+	after := d.observe(time.Minute)
+	if after-before > time.Duration(float64(maxDriftDeviation)*driftSmoothing)+time.Millisecond {
+		t.Errorf("expected the outlier to be clamped, the estimate moved from %s to %s", before, after)
+	}
+}
+
+func TestManager_ClockDrift(t *testing.T) {
+	mngr := NewManager(nil)
+	mngr.Bucket("/channels/1/messages", func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			resp := &http.Response{Header: make(http.Header), StatusCode: http.StatusOK}
+			// the discord clock is a minute behind
+			resp.Header.Set("date", time.Now().Add(-time.Minute).UTC().Format(time.RFC1123))
+			resp.Header.Set(DisgordNormalizedHeader, "true")
+			return resp, nil, nil
+		})
+	})
+
+	drift := mngr.Stats().ClockDrift
+	if drift < time.Minute || drift > time.Minute+time.Second {
+		t.Errorf("expected a drift of about a minute, got %s", drift)
+	}
+}
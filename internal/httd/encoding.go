@@ -0,0 +1,41 @@
+package httd
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultAcceptEncodings are the content encodings advertised unless Config.AcceptEncodings is set
+var DefaultAcceptEncodings = []string{GZIPCompression}
+
+// supportedEncodings can be decoded by the client
+var supportedEncodings = map[string]bool{
+	GZIPCompression:  true,
+	BrotliEncoding:   true,
+	IdentityEncoding: true,
+}
+
+// contentEncoding returns the encoding of a response body, which is IdentityEncoding for uncompressed bodies
+func contentEncoding(header http.Header) (string, error) {
+	encoding := strings.ToLower(strings.TrimSpace(header.Get(ContentEncoding)))
+	if encoding == "" {
+		return IdentityEncoding, nil
+	}
+	if !supportedEncodings[encoding] {
+		return "", &ErrUnsupportedEncoding{Encoding: encoding}
+	}
+	return encoding, nil
+}
+
+// acceptEncoding creates the Accept-Encoding header field, and rejects encodings that can not be decoded
+func acceptEncoding(encodings []string) (string, error) {
+	if len(encodings) == 0 {
+		encodings = DefaultAcceptEncodings
+	}
+	for _, encoding := range encodings {
+		if !supportedEncodings[encoding] {
+			return "", &ErrUnsupportedEncoding{Encoding: encoding}
+		}
+	}
+	return strings.Join(encodings, ", "), nil
+}
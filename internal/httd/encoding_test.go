@@ -0,0 +1,96 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_DoContentEncoding(t *testing.T) {
+	message, err := ioutil.ReadFile("testdata/encoding/message.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := ioutil.ReadFile("testdata/encoding/message.json.br")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/v6/br":
+			w.Header().Set(ContentEncoding, BrotliEncoding)
+			_, _ = w.Write(compressed)
+		case "/v6/identity":
+			w.Header().Set(ContentEncoding, IdentityEncoding)
+			_, _ = w.Write(message)
+		case "/v6/zstd":
+			w.Header().Set(ContentEncoding, "zstd")
+			_, _ = w.Write(message)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         &http.Client{Transport: &http.Transport{DisableCompression: true}},
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		AcceptEncodings:    []string{BrotliEncoding, GZIPCompression},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, endpoint := range []string{"/br", "/identity"} {
+		_, body, err := client.Do(context.Background(), &Request{Endpoint: endpoint})
+		if err != nil {
+			t.Fatal(endpoint, err)
+		}
+		if string(body) != string(message) {
+			t.Errorf("%s: unexpected body %q", endpoint, body)
+		}
+	}
+	if acceptEncoding != "br, gzip" {
+		t.Errorf("expected the configured encodings to be advertised, got %q", acceptEncoding)
+	}
+
+	_, stream, err := client.DoStream(context.Background(), &Request{Endpoint: "/br"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(stream)
+	_ = stream.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(message) {
+		t.Errorf("unexpected streamed body %q", data)
+	}
+
+	var unsupported *ErrUnsupportedEncoding
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/zstd"}); !errors.As(err, &unsupported) || unsupported.Encoding != "zstd" {
+		t.Errorf("expected an ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestNewClient_AcceptEncodings(t *testing.T) {
+	_, err := NewClient(&Config{
+		APIVersion:      6,
+		BotToken:        "sdfgsdfg",
+		AcceptEncodings: []string{GZIPCompression, "deflate"},
+	})
+	var unsupported *ErrUnsupportedEncoding
+	if !errors.As(err, &unsupported) || unsupported.Encoding != "deflate" {
+		t.Errorf("expected an ErrUnsupportedEncoding, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package httd
+
+import (
+	"errors"
+	"strconv"
+)
+
+// JSONErrorCode is the error code Discord puts in the body of a failed response.
+// See https://discord.com/developers/docs/topics/opcodes-and-status-codes#json
+type JSONErrorCode int
+
+// The known error codes. The comment of each code is its hint, see JSONErrorCode.Suggestion. The table of names
+// and hints, and the aliases in the disgord package, are generated from these by generate/errorcodes.
+const (
+	// general error, see the message for details
+	JSONErrorCodeGeneral JSONErrorCode = 0
+
+	// the account does not exist
+	JSONErrorCodeUnknownAccount JSONErrorCode = 10001
+
+	// the application does not exist
+	JSONErrorCodeUnknownApplication JSONErrorCode = 10002
+
+	// the channel was deleted, or the ID is wrong
+	JSONErrorCodeUnknownChannel JSONErrorCode = 10003
+
+	// the bot is not in the guild, or the ID is wrong
+	JSONErrorCodeUnknownGuild JSONErrorCode = 10004
+
+	// the integration does not exist
+	JSONErrorCodeUnknownIntegration JSONErrorCode = 10005
+
+	// the invite expired or was deleted
+	JSONErrorCodeUnknownInvite JSONErrorCode = 10006
+
+	// the user is not a member of the guild
+	JSONErrorCodeUnknownMember JSONErrorCode = 10007
+
+	// the message was deleted, or it is in a different channel
+	JSONErrorCodeUnknownMessage JSONErrorCode = 10008
+
+	// the permission overwrite does not exist
+	JSONErrorCodeUnknownOverwrite JSONErrorCode = 10009
+
+	// the role was deleted, or it belongs to a different guild
+	JSONErrorCodeUnknownRole JSONErrorCode = 10011
+
+	// the token is not valid
+	JSONErrorCodeUnknownToken JSONErrorCode = 10012
+
+	// the user does not exist
+	JSONErrorCodeUnknownUser JSONErrorCode = 10013
+
+	// the emoji was deleted, or the bot can not use it
+	JSONErrorCodeUnknownEmoji JSONErrorCode = 10014
+
+	// the webhook was deleted, or the token is wrong
+	JSONErrorCodeUnknownWebhook JSONErrorCode = 10015
+
+	// the user is not banned
+	JSONErrorCodeUnknownBan JSONErrorCode = 10026
+
+	// the endpoint is only available to user accounts
+	JSONErrorCodeBotsCannotUseEndpoint JSONErrorCode = 20001
+
+	// the endpoint is only available to bot accounts
+	JSONErrorCodeOnlyBotsCanUseEndpoint JSONErrorCode = 20002
+
+	// leave a guild before joining or creating another
+	JSONErrorCodeMaxGuildsReached JSONErrorCode = 30001
+
+	// unpin a message before pinning another, a channel holds 50 pins
+	JSONErrorCodeMaxPinsReached JSONErrorCode = 30003
+
+	// delete a role before creating another, a guild holds 250 roles
+	JSONErrorCodeMaxRolesReached JSONErrorCode = 30005
+
+	// delete a webhook in the channel before creating another
+	JSONErrorCodeMaxWebhooksReached JSONErrorCode = 30007
+
+	// a message holds at most 20 different reactions
+	JSONErrorCodeMaxReactionsReached JSONErrorCode = 30010
+
+	// delete a channel before creating another, a guild holds 500 channels
+	JSONErrorCodeMaxChannelsReached JSONErrorCode = 30013
+
+	// send at most 10 attachments per message
+	JSONErrorCodeMaxAttachmentsReached JSONErrorCode = 30015
+
+	// delete old invites before creating more
+	JSONErrorCodeMaxInvitesReached JSONErrorCode = 30016
+
+	// the bot token is missing or invalid
+	JSONErrorCodeUnauthorized JSONErrorCode = 40001
+
+	// the request body is too large, such as a file upload above the limit
+	JSONErrorCodeRequestTooLarge JSONErrorCode = 40005
+
+	// the bot can not see the channel or guild, check the VIEW_CHANNEL permission
+	JSONErrorCodeMissingAccess JSONErrorCode = 50001
+
+	// the account type can not use the endpoint
+	JSONErrorCodeInvalidAccountType JSONErrorCode = 50002
+
+	// the action is not available in DM channels
+	JSONErrorCodeCannotExecuteOnDM JSONErrorCode = 50003
+
+	// only the author can edit a message
+	JSONErrorCodeCannotEditOthersMessage JSONErrorCode = 50005
+
+	// add content, an embed or a file to the message
+	JSONErrorCodeCannotSendEmptyMessage JSONErrorCode = 50006
+
+	// the user does not accept direct messages from the bot
+	JSONErrorCodeCannotSendMessagesToUser JSONErrorCode = 50007
+
+	// messages can not be sent to voice channels
+	JSONErrorCodeCannotSendInVoiceChannel JSONErrorCode = 50008
+
+	// the bot lacks a permission for the action, or the target has a higher role
+	JSONErrorCodeMissingPermissions JSONErrorCode = 50013
+
+	// the authentication token is invalid
+	JSONErrorCodeInvalidAuthenticationToken JSONErrorCode = 50014
+
+	// the note is too long
+	JSONErrorCodeNoteTooLong JSONErrorCode = 50015
+
+	// bulk delete between 2 and 100 messages
+	JSONErrorCodeInvalidBulkDeleteCount JSONErrorCode = 50016
+
+	// the action is not available for system messages
+	JSONErrorCodeCannotExecuteOnSystemMsg JSONErrorCode = 50021
+
+	// messages older than two weeks must be deleted one at a time
+	JSONErrorCodeMessageTooOldToBulkDelete JSONErrorCode = 50034
+
+	// a field in the request is invalid, see the errors in the response body
+	JSONErrorCodeInvalidFormBody JSONErrorCode = 50035
+
+	// the invite was accepted to a guild the bot is not in
+	JSONErrorCodeInviteAcceptedToMissingBot JSONErrorCode = 50036
+
+	// the user blocked the reaction
+	JSONErrorCodeReactionBlocked JSONErrorCode = 90001
+)
+
+// jsonErrorCode is the name and the hint of a known error code
+type jsonErrorCode struct {
+	name string
+	hint string
+}
+
+// Suggestion gives a hint on what caused the error. It is empty for unknown error codes.
+func (c JSONErrorCode) Suggestion() string {
+	return jsonErrorCodes[c].hint
+}
+
+// String returns the name of the error code, such as UnknownMessage, or the number of unknown error codes
+func (c JSONErrorCode) String() string {
+	if code, ok := jsonErrorCodes[c]; ok {
+		return code.name
+	}
+	return strconv.Itoa(int(c))
+}
+
+// HasJSONErrorCode checks if err is, or wraps, an ErrREST with the given Discord error code
+func HasJSONErrorCode(err error, code JSONErrorCode) bool {
+	var restErr *ErrREST
+	return errors.As(err, &restErr) && restErr.HTTPCode >= 400 && restErr.Code == code
+}
+
+// IsUnknownMessage checks if the error is caused by a deleted or unknown message
+func IsUnknownMessage(err error) bool {
+	return HasJSONErrorCode(err, JSONErrorCodeUnknownMessage)
+}
+
+// IsUnknownChannel checks if the error is caused by a deleted or unknown channel
+func IsUnknownChannel(err error) bool {
+	return HasJSONErrorCode(err, JSONErrorCodeUnknownChannel)
+}
+
+// IsMissingAccess checks if the error is caused by the bot not being able to see the resource
+func IsMissingAccess(err error) bool {
+	return HasJSONErrorCode(err, JSONErrorCodeMissingAccess)
+}
+
+// IsMissingPermissions checks if the error is caused by the bot lacking a permission
+func IsMissingPermissions(err error) bool {
+	return HasJSONErrorCode(err, JSONErrorCodeMissingPermissions)
+}
+
+// IsInvalidFormBody checks if the error is caused by an invalid field in the request
+func IsInvalidFormBody(err error) bool {
+	return HasJSONErrorCode(err, JSONErrorCodeInvalidFormBody)
+}
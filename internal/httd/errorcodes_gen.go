@@ -0,0 +1,52 @@
+package httd
+
+// Code generated - This file has been automatically generated by generate/errorcodes/main.go - DO NOT EDIT.
+// Warning: This file is overwritten at "go generate", instead adapt internal/httd/errorcodes.go and run go generate
+
+// jsonErrorCodes holds the name and the hint of each known error code
+var jsonErrorCodes = map[JSONErrorCode]jsonErrorCode{
+	JSONErrorCodeGeneral:                    {name: "General", hint: "general error, see the message for details"},
+	JSONErrorCodeUnknownAccount:             {name: "UnknownAccount", hint: "the account does not exist"},
+	JSONErrorCodeUnknownApplication:         {name: "UnknownApplication", hint: "the application does not exist"},
+	JSONErrorCodeUnknownChannel:             {name: "UnknownChannel", hint: "the channel was deleted, or the ID is wrong"},
+	JSONErrorCodeUnknownGuild:               {name: "UnknownGuild", hint: "the bot is not in the guild, or the ID is wrong"},
+	JSONErrorCodeUnknownIntegration:         {name: "UnknownIntegration", hint: "the integration does not exist"},
+	JSONErrorCodeUnknownInvite:              {name: "UnknownInvite", hint: "the invite expired or was deleted"},
+	JSONErrorCodeUnknownMember:              {name: "UnknownMember", hint: "the user is not a member of the guild"},
+	JSONErrorCodeUnknownMessage:             {name: "UnknownMessage", hint: "the message was deleted, or it is in a different channel"},
+	JSONErrorCodeUnknownOverwrite:           {name: "UnknownOverwrite", hint: "the permission overwrite does not exist"},
+	JSONErrorCodeUnknownRole:                {name: "UnknownRole", hint: "the role was deleted, or it belongs to a different guild"},
+	JSONErrorCodeUnknownToken:               {name: "UnknownToken", hint: "the token is not valid"},
+	JSONErrorCodeUnknownUser:                {name: "UnknownUser", hint: "the user does not exist"},
+	JSONErrorCodeUnknownEmoji:               {name: "UnknownEmoji", hint: "the emoji was deleted, or the bot can not use it"},
+	JSONErrorCodeUnknownWebhook:             {name: "UnknownWebhook", hint: "the webhook was deleted, or the token is wrong"},
+	JSONErrorCodeUnknownBan:                 {name: "UnknownBan", hint: "the user is not banned"},
+	JSONErrorCodeBotsCannotUseEndpoint:      {name: "BotsCannotUseEndpoint", hint: "the endpoint is only available to user accounts"},
+	JSONErrorCodeOnlyBotsCanUseEndpoint:     {name: "OnlyBotsCanUseEndpoint", hint: "the endpoint is only available to bot accounts"},
+	JSONErrorCodeMaxGuildsReached:           {name: "MaxGuildsReached", hint: "leave a guild before joining or creating another"},
+	JSONErrorCodeMaxPinsReached:             {name: "MaxPinsReached", hint: "unpin a message before pinning another, a channel holds 50 pins"},
+	JSONErrorCodeMaxRolesReached:            {name: "MaxRolesReached", hint: "delete a role before creating another, a guild holds 250 roles"},
+	JSONErrorCodeMaxWebhooksReached:         {name: "MaxWebhooksReached", hint: "delete a webhook in the channel before creating another"},
+	JSONErrorCodeMaxReactionsReached:        {name: "MaxReactionsReached", hint: "a message holds at most 20 different reactions"},
+	JSONErrorCodeMaxChannelsReached:         {name: "MaxChannelsReached", hint: "delete a channel before creating another, a guild holds 500 channels"},
+	JSONErrorCodeMaxAttachmentsReached:      {name: "MaxAttachmentsReached", hint: "send at most 10 attachments per message"},
+	JSONErrorCodeMaxInvitesReached:          {name: "MaxInvitesReached", hint: "delete old invites before creating more"},
+	JSONErrorCodeUnauthorized:               {name: "Unauthorized", hint: "the bot token is missing or invalid"},
+	JSONErrorCodeRequestTooLarge:            {name: "RequestTooLarge", hint: "the request body is too large, such as a file upload above the limit"},
+	JSONErrorCodeMissingAccess:              {name: "MissingAccess", hint: "the bot can not see the channel or guild, check the VIEW_CHANNEL permission"},
+	JSONErrorCodeInvalidAccountType:         {name: "InvalidAccountType", hint: "the account type can not use the endpoint"},
+	JSONErrorCodeCannotExecuteOnDM:          {name: "CannotExecuteOnDM", hint: "the action is not available in DM channels"},
+	JSONErrorCodeCannotEditOthersMessage:    {name: "CannotEditOthersMessage", hint: "only the author can edit a message"},
+	JSONErrorCodeCannotSendEmptyMessage:     {name: "CannotSendEmptyMessage", hint: "add content, an embed or a file to the message"},
+	JSONErrorCodeCannotSendMessagesToUser:   {name: "CannotSendMessagesToUser", hint: "the user does not accept direct messages from the bot"},
+	JSONErrorCodeCannotSendInVoiceChannel:   {name: "CannotSendInVoiceChannel", hint: "messages can not be sent to voice channels"},
+	JSONErrorCodeMissingPermissions:         {name: "MissingPermissions", hint: "the bot lacks a permission for the action, or the target has a higher role"},
+	JSONErrorCodeInvalidAuthenticationToken: {name: "InvalidAuthenticationToken", hint: "the authentication token is invalid"},
+	JSONErrorCodeNoteTooLong:                {name: "NoteTooLong", hint: "the note is too long"},
+	JSONErrorCodeInvalidBulkDeleteCount:     {name: "InvalidBulkDeleteCount", hint: "bulk delete between 2 and 100 messages"},
+	JSONErrorCodeCannotExecuteOnSystemMsg:   {name: "CannotExecuteOnSystemMsg", hint: "the action is not available for system messages"},
+	JSONErrorCodeMessageTooOldToBulkDelete:  {name: "MessageTooOldToBulkDelete", hint: "messages older than two weeks must be deleted one at a time"},
+	JSONErrorCodeInvalidFormBody:            {name: "InvalidFormBody", hint: "a field in the request is invalid, see the errors in the response body"},
+	JSONErrorCodeInviteAcceptedToMissingBot: {name: "InviteAcceptedToMissingBot", hint: "the invite was accepted to a guild the bot is not in"},
+	JSONErrorCodeReactionBlocked:            {name: "ReactionBlocked", hint: "the user blocked the reaction"},
+}
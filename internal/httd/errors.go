@@ -1,6 +1,10 @@
 package httd
 
-import "time"
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
 
 type Error struct {
 	message string
@@ -13,6 +17,143 @@ func (e *Error) Error() string {
 	return e.message
 }
 
+// ErrREST matches these with errors.Is, based on the http status code of the response
 var (
-	ErrRateLimited error = &Error{"rate limited", time.Unix(0, 0)}
+	ErrRateLimited  error = &Error{"rate limited", time.Unix(0, 0)}
+	ErrBadRequest   error = &Error{"bad request", time.Unix(0, 0)}
+	ErrUnauthorized error = &Error{"unauthorized", time.Unix(0, 0)}
+	ErrForbidden    error = &Error{"forbidden", time.Unix(0, 0)}
+	ErrNotFound     error = &Error{"not found", time.Unix(0, 0)}
 )
+
+var errorsByStatusCode = map[int]error{
+	http.StatusTooManyRequests: ErrRateLimited,
+	http.StatusBadRequest:      ErrBadRequest,
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrForbidden,
+	http.StatusNotFound:        ErrNotFound,
+}
+
+// RateLimitError is returned when a request is rejected because of a rate limit, either by Discord with
+// 429 Too Many Requests or locally when the request can not wait for the bucket to reset, such as when
+// CancelRequestWhenRateLimited is set. It matches ErrRateLimited with errors.Is, and a 429 response
+// still unwraps to the ErrREST.
+type RateLimitError struct {
+	// RetryAfter is how long to wait before the bucket resets
+	RetryAfter time.Duration
+	Global     bool
+
+	// Bucket is the Discord bucket hash when known, otherwise the hashed endpoint
+	Bucket string
+
+	Err error
+}
+
+var _ error = (*RateLimitError)(nil)
+
+func (e *RateLimitError) Error() string {
+	scope := "bucket " + e.Bucket
+	if e.Global {
+		scope = "global bucket"
+	}
+	msg := "rate limited by " + scope + ", retry after " + e.RetryAfter.String()
+	if restErr, ok := e.Err.(*ErrREST); ok && restErr.RequestID != 0 {
+		msg += " (request " + formatRequestID(restErr.RequestID) + ")"
+	}
+	return msg
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// ErrResponseTooLarge is returned when a response body is larger than Config.MaxResponseSize. Read is how
+// many bytes were read before giving up. Compressed is true when the gzipped body was too large, rather
+// than the decompressed body.
+type ErrResponseTooLarge struct {
+	Limit      int64
+	Read       int64
+	Compressed bool
+}
+
+var _ error = (*ErrResponseTooLarge)(nil)
+
+func (e *ErrResponseTooLarge) Error() string {
+	body := "response body"
+	if e.Compressed {
+		body = "compressed response body"
+	}
+	return body + " exceeds the limit of " + strconv.FormatInt(e.Limit, 10) + " bytes, read " + strconv.FormatInt(e.Read, 10) + " bytes"
+}
+
+// RetryError is returned when a request failed with a transport error after it was sent more than once.
+// Failed responses are returned as ErrREST, which holds the number of attempts as well.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+var _ error = (*RetryError)(nil)
+
+func (e *RetryError) Error() string {
+	return e.Err.Error() + " (after " + strconv.Itoa(e.Attempts) + " attempts)"
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// UnauthorizedError is returned for 401 Unauthorized responses, with a hint on what is wrong with the bot
+// token. It matches ErrUnauthorized with errors.Is, and unwraps to the ErrREST.
+type UnauthorizedError struct {
+	// AuthorizationSent is false for requests sent without the bot token, such as webhook token endpoints
+	AuthorizationSent bool
+
+	// TokenProblem describes why the bot token does not look like one. Empty when it looks valid, in which case
+	// it was most likely regenerated or revoked.
+	TokenProblem string
+
+	Err error
+}
+
+var _ error = (*UnauthorizedError)(nil)
+
+func (e *UnauthorizedError) Error() string {
+	msg := "unauthorized: "
+	switch {
+	case !e.AuthorizationSent:
+		msg += "the request was sent without the bot token, check the token in the URL"
+	case e.TokenProblem != "":
+		msg += e.TokenProblem + ". Copy the token from the Bot tab at " + TokenResetURL
+	default:
+		msg += "the bot token was rejected, it might have been regenerated. Reset it under the Bot tab at " + TokenResetURL
+	}
+	if e.Err != nil {
+		msg += " (" + e.Err.Error() + ")"
+	}
+	return msg
+}
+
+func (e *UnauthorizedError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+func (e *UnauthorizedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnsupportedEncoding is returned when a response body uses a content encoding that can not be decoded,
+// or when Config.AcceptEncodings holds one
+type ErrUnsupportedEncoding struct {
+	Encoding string
+}
+
+var _ error = (*ErrUnsupportedEncoding)(nil)
+
+func (e *ErrUnsupportedEncoding) Error() string {
+	return "unsupported content encoding " + strconv.Quote(e.Encoding) + ", supported are gzip, br and identity"
+}
@@ -0,0 +1,128 @@
+package httd
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ETag        = "ETag"
+	IfNoneMatch = "If-None-Match"
+)
+
+type etagEntry struct {
+	endpoint string
+	etag     string
+	body     []byte
+	storedAt time.Time
+}
+
+// etagCache keeps the ETag and body of recent GET responses, such that a 304 Not Modified response can be
+// served from memory. The least recently used entries are evicted once the cache is full.
+type etagCache struct {
+	sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // front is the most recently used
+}
+
+func newETagCache(size int, ttl time.Duration) *etagCache {
+	return &etagCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *etagCache) get(endpoint string) (entry *etagEntry, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	elem, exists := c.entries[endpoint]
+	if !exists {
+		return nil, false
+	}
+	entry = elem.Value.(*etagEntry)
+	if c.ttl > 0 && time.Since(entry.storedAt) > c.ttl {
+		c.order.Remove(elem)
+		delete(c.entries, endpoint)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *etagCache) set(endpoint, etag string, body []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry := &etagEntry{endpoint: endpoint, etag: etag, body: body, storedAt: time.Now()}
+	if elem, exists := c.entries[endpoint]; exists {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[endpoint] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*etagEntry).endpoint)
+	}
+}
+
+// invalidate removes the cached responses of an endpoint, regardless of the query parameters used
+func (c *etagCache) invalidate(endpoint string) {
+	path := strings.Split(endpoint, "?")[0]
+
+	c.Lock()
+	defer c.Unlock()
+
+	for key, elem := range c.entries {
+		if strings.Split(key, "?")[0] == path {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// etagRequest adds If-None-Match to GET requests with a cached response
+func (c *Client) etagRequest(r *Request, header http.Header) (cached *etagEntry) {
+	if c.etags == nil || r.Method != MethodGet {
+		return nil
+	}
+
+	if cached, ok := c.etags.get(r.Endpoint); ok {
+		header.Set(IfNoneMatch, cached.etag)
+		return cached
+	}
+	return nil
+}
+
+// etagResponse stores the response of a GET request, or replaces the empty body of a 304 response with the
+// cached body. Successful requests with other methods clear the cached responses of the endpoint, as they
+// might have modified the resource.
+func (c *Client) etagResponse(r *Request, resp *http.Response, body []byte, cached *etagEntry) []byte {
+	if c.etags == nil {
+		return body
+	}
+
+	switch {
+	case r.Method != MethodGet:
+		if resp.StatusCode < 300 {
+			c.etags.invalidate(r.Endpoint)
+		}
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+		return cached.body
+	case resp.StatusCode == http.StatusOK && resp.Header.Get(ETag) != "":
+		c.etags.set(r.Endpoint, resp.Header.Get(ETag), body)
+	}
+	return body
+}
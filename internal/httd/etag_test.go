@@ -0,0 +1,99 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_DoETag(t *testing.T) {
+	var ifNoneMatch []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get(IfNoneMatch))
+		if r.Header.Get(IfNoneMatch) == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(ETag, `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		ResponseCacheSize:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() {
+		resp, body, err := client.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(body) != `{"id":"1"}` {
+			t.Errorf("unexpected response: %d %s", resp.StatusCode, body)
+		}
+	}
+
+	get()
+	get()
+	if _, _, err = client.Do(context.Background(), &Request{Method: MethodPatch, Endpoint: "/channels/1"}); err != nil {
+		t.Fatal(err)
+	}
+	get()
+
+	wants := []string{"", `"v1"`, ""}
+	if len(ifNoneMatch) != len(wants) {
+		t.Fatalf("expected %d GET requests, got %d", len(wants), len(ifNoneMatch))
+	}
+	for i := range wants {
+		if ifNoneMatch[i] != wants[i] {
+			t.Errorf("request %d: expected If-None-Match %q, got %q", i, wants[i], ifNoneMatch[i])
+		}
+	}
+}
+
+func TestETagCache(t *testing.T) {
+	cache := newETagCache(2, time.Hour)
+	cache.set("/a", "1", nil)
+	cache.set("/b", "2", nil)
+	cache.get("/a")
+	cache.set("/c", "3", nil)
+
+	if _, ok := cache.get("/b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if _, ok := cache.get("/a"); !ok {
+		t.Error("expected /a to be cached")
+	}
+
+	cache.set("/c?limit=1", "4", nil)
+	cache.invalidate("/c")
+	if _, ok := cache.get("/c?limit=1"); ok {
+		t.Error("expected every query of /c to be invalidated")
+	}
+
+	cache = newETagCache(2, time.Nanosecond)
+	cache.set("/a", "1", nil)
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.get("/a"); ok {
+		t.Error("expected the entry to expire")
+	}
+}
@@ -0,0 +1,87 @@
+package httd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andersfylling/disgord/json"
+)
+
+// FieldError describes why a single field of the request payload was rejected, as found in the "errors"
+// object of an Invalid Form Body (50035) response.
+type FieldError struct {
+	// Path to the field, such as "embed.fields[3].value". Empty when the error applies to the whole payload.
+	Path    string
+	Code    string
+	Message string
+}
+
+func (e FieldError) String() string {
+	if e.Path == "" {
+		return e.Code + ": " + e.Message
+	}
+	return e.Path + ": " + e.Code + ": " + e.Message
+}
+
+// parseFieldErrors flattens the nested "errors" object of a Discord error response. Objects are nested by
+// field name, arrays by their index as a string key, and the leafs are held by an "_errors" key.
+func parseFieldErrors(codec json.Codec, body []byte) []FieldError {
+	var data struct {
+		Errors map[string]interface{} `json:"errors"`
+	}
+	if err := codec.Unmarshal(body, &data); err != nil || len(data.Errors) == 0 {
+		return nil
+	}
+
+	var errs []FieldError
+	flattenFieldErrors(&errs, "", data.Errors)
+	return errs
+}
+
+func flattenFieldErrors(errs *[]FieldError, path string, node map[string]interface{}) {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		if key == "_errors" {
+			leafs, _ := node[key].([]interface{})
+			for i := range leafs {
+				leaf, ok := leafs[i].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				code, _ := leaf["code"].(string)
+				message, _ := leaf["message"].(string)
+				*errs = append(*errs, FieldError{Path: path, Code: code, Message: message})
+			}
+			continue
+		}
+
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		flattenFieldErrors(errs, fieldErrorPath(path, key), child)
+	}
+}
+
+func fieldErrorPath(path, key string) string {
+	if key != "" && strings.Trim(key, "0123456789") == "" {
+		return path + "[" + key + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,100 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/disgord/json"
+)
+
+func TestParseFieldErrors(t *testing.T) {
+	table := []struct {
+		name string
+		body string
+		want []FieldError
+	}{
+		{
+			name: "embed field",
+			body: `{"code": 50035, "errors": {"embed": {"fields": {"3": {"value": {"_errors": [{"code": "BASE_TYPE_MAX_LENGTH", "message": "Must be 1024 or fewer in length."}]}}}}}, "message": "Invalid Form Body"}`,
+			want: []FieldError{
+				{Path: "embed.fields[3].value", Code: "BASE_TYPE_MAX_LENGTH", Message: "Must be 1024 or fewer in length."},
+			},
+		},
+		{
+			name: "multiple fields",
+			body: `{"code": 50035, "errors": {"content": {"_errors": [{"code": "BASE_TYPE_MAX_LENGTH", "message": "Must be 2000 or fewer in length."}]}, "embed": {"fields": {"10": {"name": {"_errors": [{"code": "BASE_TYPE_REQUIRED", "message": "This field is required"}]}}, "2": {"name": {"_errors": [{"code": "BASE_TYPE_REQUIRED", "message": "This field is required"}]}}}, "color": {"_errors": [{"code": "NUMBER_TYPE_COERCE", "message": "Value \"red\" is not int."}]}}}, "message": "Invalid Form Body"}`,
+			want: []FieldError{
+				{Path: "content", Code: "BASE_TYPE_MAX_LENGTH", Message: "Must be 2000 or fewer in length."},
+				{Path: "embed.color", Code: "NUMBER_TYPE_COERCE", Message: "Value \"red\" is not int."},
+				{Path: "embed.fields[2].name", Code: "BASE_TYPE_REQUIRED", Message: "This field is required"},
+				{Path: "embed.fields[10].name", Code: "BASE_TYPE_REQUIRED", Message: "This field is required"},
+			},
+		},
+		{
+			name: "array of objects",
+			body: `{"code": 50035, "errors": {"permission_overwrites": {"0": {"id": {"_errors": [{"code": "NUMBER_TYPE_COERCE", "message": "Value \"abc\" is not snowflake."}]}}}}, "message": "Invalid Form Body"}`,
+			want: []FieldError{
+				{Path: "permission_overwrites[0].id", Code: "NUMBER_TYPE_COERCE", Message: "Value \"abc\" is not snowflake."},
+			},
+		},
+		{
+			name: "whole payload",
+			body: `{"code": 50035, "errors": {"_errors": [{"code": "DICT_TYPE_CONVERT", "message": "Only dictionaries may be used in a DictType"}]}, "message": "Invalid Form Body"}`,
+			want: []FieldError{
+				{Code: "DICT_TYPE_CONVERT", Message: "Only dictionaries may be used in a DictType"},
+			},
+		},
+		{
+			name: "no errors",
+			body: `{"code": 10008, "message": "Unknown Message"}`,
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseFieldErrors(json.PackageCodec{}, []byte(test.body)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, wants %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestErrREST_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code": 50035, "errors": {"embed": {"fields": {"3": {"value": {"_errors": [{"code": "BASE_TYPE_MAX_LENGTH", "message": "Must be 1024 or fewer in length."}]}}}}}, "message": "Invalid Form Body"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = client.Do(context.Background(), &Request{Method: MethodPost, Endpoint: "/channels/1/messages"})
+	var restErr *ErrREST
+	if !errors.As(err, &restErr) {
+		t.Fatalf("expected an ErrREST, got %v", err)
+	}
+	if len(restErr.Errors) != 1 || restErr.Errors[0].Path != "embed.fields[3].value" {
+		t.Errorf("unexpected field errors: %+v", restErr.Errors)
+	}
+	if !strings.Contains(err.Error(), "embed.fields[3].value: BASE_TYPE_MAX_LENGTH") {
+		t.Errorf("the field path is missing from the error message: %s", err)
+	}
+}
@@ -3,8 +3,10 @@ package httd
 import (
 	"errors"
 	"github.com/andersfylling/disgord/json"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -37,46 +39,95 @@ func HeaderToTime(header http.Header) (t time.Time, err error) {
 }
 
 type RateLimitResponseStructure struct {
-	Message    string `json:"message"`     // A message saying you are being rate limited.
-	RetryAfter int64  `json:"retry_after"` // The number of milliseconds to wait before submitting another request.
-	Global     bool   `json:"global"`      // A value indicating if you are being globally rate limited or not
+	Message    string  `json:"message"`     // A message saying you are being rate limited.
+	RetryAfter float64 `json:"retry_after"` // How long to wait before submitting another request, see RetryAfterDelay.
+	Global     bool    `json:"global"`      // A value indicating if you are being globally rate limited or not
 }
 
-// NormalizeDiscordHeader overrides header fields with body content and make sure every header field
-// uses milliseconds and not seconds. Regards rate limits only.
-func NormalizeDiscordHeader(statusCode int, header http.Header, body []byte) (h http.Header, err error) {
-	// don't care about 2 different time delay estimates for the ltBucket reset.
-	// So lets take Retry-After and X-RateLimit-Reset-After to set the reset
-	var delay int64
-	if retryAfter := header.Get(RateLimitRetryAfter); retryAfter != "" {
-		delay, _ = strconv.ParseInt(retryAfter, 10, 64)
+// RetryAfterDelay returns the retry_after field in milliseconds. It is in milliseconds until API version 8,
+// and in seconds from then on.
+func (r *RateLimitResponseStructure) RetryAfterDelay(version APIVersion) int64 {
+	if version.RetryAfterInSeconds() {
+		return int64(math.Round(r.RetryAfter * 1000))
+	}
+	return int64(math.Round(r.RetryAfter))
+}
+
+// retryAfterDelay converts a Retry-After header field to milliseconds. Discord follows the http standard of
+// seconds, as do proxies, which may send a http date instead.
+func retryAfterDelay(retryAfter string) int64 {
+	if retryAfter == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(retryAfter, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return int64(seconds * 1000)
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return int64(delay / time.Millisecond)
+		}
+	}
+	return 0
+}
+
+// resetEpoch converts a X-RateLimit-Reset header field to unix milliseconds. Discord sends seconds in every
+// API version, with fractions when millisecond precision is used, see Config.RateLimitPrecision.
+func resetEpoch(reset string) int64 {
+	epoch, err := strconv.ParseFloat(reset, 64)
+	if err != nil || epoch <= 0 {
+		return 0
+	}
+	return int64(epoch * 1000)
+}
+
+// resetAfterDelay converts a X-RateLimit-Reset-After header field, in seconds, to milliseconds
+func resetAfterDelay(resetAfter string) int64 {
+	delay, err := strconv.ParseFloat(resetAfter, 64)
+	if err != nil || delay <= 0 {
+		return 0
+	}
+	return int64(delay * 1000)
+}
+
+// NormalizeDiscordHeader returns a copy of the header where the rate limit fields are overridden by the body
+// content, and use milliseconds and not seconds. The given header is left as Discord sent it. The API
+// version decides the unit of the retry_after body field.
+func NormalizeDiscordHeader(version APIVersion, statusCode int, header http.Header, body []byte) (h http.Header, err error) {
+	header = header.Clone()
+	if header == nil {
+		header = make(http.Header)
 	}
+
+	// don't care about 2 different time delay estimates for the ltBucket reset.
+	// So lets take Retry-After and X-RateLimit-Reset-After to set the reset. The delay is in milliseconds.
+	delay := retryAfterDelay(header.Get(RateLimitRetryAfter))
 	if retry := header.Get(XRateLimitResetAfter); delay == 0 && retry != "" {
-		delayF, _ := strconv.ParseFloat(retry, 64)
-		delayF *= 1000 // seconds => milliseconds
-		delay = int64(delayF)
+		delay = resetAfterDelay(retry)
 	}
 
-	// sometimes the body might be populated too
-	if statusCode == http.StatusTooManyRequests && body != nil {
+	// sometimes the body might be populated too. Responses from a proxy or Cloudflare have other bodies, and
+	// only the header fields are used.
+	if statusCode == http.StatusTooManyRequests && len(body) > 0 {
 		var rateLimitBodyInfo *RateLimitResponseStructure
-		if err = json.Unmarshal(body, &rateLimitBodyInfo); err != nil {
-			return nil, err
-		}
-		if rateLimitBodyInfo.Global {
-			header.Set(XRateLimitGlobal, "true")
-		}
-		if delay == 0 && rateLimitBodyInfo.RetryAfter > 0 {
-			delay = rateLimitBodyInfo.RetryAfter
+		if json.Unmarshal(body, &rateLimitBodyInfo) == nil && rateLimitBodyInfo != nil {
+			if rateLimitBodyInfo.Global {
+				header.Set(XRateLimitGlobal, "true")
+			}
+			// more precise than Retry-After
+			if retryAfter := rateLimitBodyInfo.RetryAfterDelay(version); retryAfter > 0 {
+				delay = retryAfter
+			}
 		}
 	}
 
 	// convert Reset to store milliseconds and not seconds
 	// if there is no content, we create a Reset unix using the delay
-	if reset := header.Get(XRateLimitReset); reset != "" {
-		epoch, _ := strconv.ParseFloat(reset, 64)
-		epoch *= 1000 // seconds => milliseconds
-		header.Set(XRateLimitReset, strconv.FormatInt(int64(epoch), 10))
+	// a malformed reset is kept as is, unless the delay can replace it, such that it is reported by the client
+	if epoch := resetEpoch(header.Get(XRateLimitReset)); epoch > 0 {
+		header.Set(XRateLimitReset, strconv.FormatInt(epoch, 10))
 	} else if delay > 0 {
 		timestamp, err := HeaderToTime(header)
 		if err != nil {
@@ -93,3 +144,78 @@ func NormalizeDiscordHeader(statusCode int, header http.Header, body []byte) (h
 	header.Set(DisgordNormalizedHeader, "true")
 	return header, nil
 }
+
+// rateLimitHeader holds the rate limit fields of a normalized header
+type rateLimitHeader struct {
+	reset     int64 // unix milliseconds, 0 when missing
+	remaining int   // -1 when missing
+	limit     int   // 0 when missing
+
+	bucket string // the discord ltBucket hash
+	global bool
+
+	// malformed are the fields that could not be parsed, and were ignored
+	malformed []string
+	essential bool
+}
+
+// parseRateLimitHeader parses the rate limit fields of a normalized header. A ltBucket is only updated when
+// the reset and remaining fields could be parsed, see rateLimitHeader.valid.
+func parseRateLimitHeader(header http.Header, statusCode int) (h rateLimitHeader) {
+	h.remaining = -1
+	malformed := func(field string, essential bool) {
+		h.malformed = append(h.malformed, field+": "+strconv.Quote(header.Get(field)))
+		h.essential = h.essential || essential
+	}
+
+	if reset := header.Get(XRateLimitReset); reset != "" {
+		epoch, err := strconv.ParseInt(reset, 10, 64)
+		if err != nil || epoch < 0 {
+			malformed(XRateLimitReset, true)
+		} else {
+			h.reset = epoch
+		}
+	}
+
+	if remaining := header.Get(XRateLimitRemaining); remaining != "" {
+		value, err := strconv.ParseInt(remaining, 10, 64)
+		if err != nil {
+			malformed(XRateLimitRemaining, true)
+		} else if value >= 0 {
+			h.remaining = int(value)
+		}
+	} else if statusCode == http.StatusTooManyRequests {
+		// global rate limits come without the remaining header, but no requests can be sent until the reset
+		h.remaining = 0
+	}
+
+	// an empty bucket hash is sent for the global rate limit
+	h.bucket = header.Get(XRateLimitBucket)
+	if _, ok := header[XRateLimitBucket]; ok && h.bucket == "" {
+		h.global = true
+	}
+	h.global = h.global || header.Get(XRateLimitGlobal) == "true"
+
+	if limit := header.Get(XRateLimitLimit); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			malformed(XRateLimitLimit, false)
+		} else {
+			h.limit = value
+		}
+	}
+	return h
+}
+
+// valid is true when the fields needed to update a ltBucket could be parsed
+func (h rateLimitHeader) valid() bool {
+	return !h.essential
+}
+
+// err describes the malformed fields, or is nil
+func (h rateLimitHeader) err() error {
+	if len(h.malformed) == 0 {
+		return nil
+	}
+	return errors.New("malformed rate limit header fields " + strings.Join(h.malformed, ", "))
+}
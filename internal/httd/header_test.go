@@ -0,0 +1,396 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/internal/logger"
+)
+
+// testAPIVersion is the API version that the headers of the tests are captured from
+var testAPIVersion = apiVersion(6)
+
+func TestNormalizeDiscordHeader_UnparsableRateLimitBody(t *testing.T) {
+	table := []struct {
+		fixture     string
+		contentType string
+	}{
+		{"testdata/ratelimit/429-proxy.html", "text/html"},
+		{"testdata/ratelimit/429-empty.txt", ""},
+	}
+
+	for _, test := range table {
+		body, err := ioutil.ReadFile(test.fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		date := time.Now().UTC().Truncate(time.Second)
+		header := make(http.Header)
+		header.Set("Content-Type", test.contentType)
+		header.Set(RateLimitRetryAfter, "2")
+		header.Set(XRateLimitGlobal, "true")
+		header.Set("date", date.Format(time.RFC1123))
+
+		header, err = NormalizeDiscordHeader(testAPIVersion, http.StatusTooManyRequests, header, body)
+		if err != nil {
+			t.Fatalf("%s: expected the body to be tolerated, got %v", test.fixture, err)
+		}
+		wants := date.Add(2*time.Second).UnixNano() / int64(time.Millisecond)
+		if reset := header.Get(XRateLimitReset); reset != strconv.FormatInt(wants, 10) {
+			t.Errorf("%s: expected the reset to use Retry-After in seconds, got %s, wants %d", test.fixture, reset, wants)
+		}
+		if header.Get(XRateLimitGlobal) != "true" {
+			t.Errorf("%s: the global header was lost", test.fixture)
+		}
+	}
+}
+
+func TestNormalizeDiscordHeader_RetryAfterUnits(t *testing.T) {
+	table := []struct {
+		name   string
+		status int
+		header map[string]string
+		body   string
+		wants  time.Duration
+	}{
+		{"header only 429", http.StatusTooManyRequests, map[string]string{
+			RateLimitRetryAfter: "2", XRateLimitGlobal: "true",
+		}, "", 2 * time.Second},
+		{"body only 429", http.StatusTooManyRequests, nil,
+			`{"message":"You are being rate limited.","retry_after":1500,"global":false}`, 1500 * time.Millisecond},
+		{"header and body 429", http.StatusTooManyRequests, map[string]string{
+			RateLimitRetryAfter: "2", XRateLimitResetAfter: "1.5", XRateLimitBucket: "abcd1234",
+		}, `{"message":"You are being rate limited.","retry_after":1500,"global":false}`, 1500 * time.Millisecond},
+		{"reset after on 200", http.StatusOK, map[string]string{
+			XRateLimitResetAfter: "0.25", XRateLimitRemaining: "4", XRateLimitBucket: "abcd1234",
+		}, `{"id":"1"}`, 250 * time.Millisecond},
+		{"fractional retry after", http.StatusTooManyRequests, map[string]string{
+			RateLimitRetryAfter: "0.5",
+		}, "<html></html>", 500 * time.Millisecond},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			date := time.Now().UTC().Truncate(time.Second)
+			header := make(http.Header)
+			header.Set("date", date.Format(time.RFC1123))
+			for key, value := range test.header {
+				header.Set(key, value)
+			}
+
+			var body []byte
+			if test.body != "" {
+				body = []byte(test.body)
+			}
+			header, err := NormalizeDiscordHeader(testAPIVersion, test.status, header, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wants := date.Add(test.wants).UnixNano() / int64(time.Millisecond)
+			if reset := header.Get(XRateLimitReset); reset != strconv.FormatInt(wants, 10) {
+				t.Errorf("expected the reset in %s, got %s, wants %d", test.wants, reset, wants)
+			}
+		})
+	}
+}
+
+func TestNormalizeDiscordHeader_RetryAfterVersion(t *testing.T) {
+	table := []struct {
+		version int
+		body    string
+		wants   time.Duration
+	}{
+		{6, `{"message":"You are being rate limited.","retry_after":1500,"global":false}`, 1500 * time.Millisecond},
+		{7, `{"message":"You are being rate limited.","retry_after":64,"global":false}`, 64 * time.Millisecond},
+		{8, `{"message":"You are being rate limited.","retry_after":1.5,"global":false}`, 1500 * time.Millisecond},
+		{9, `{"message":"You are being rate limited.","retry_after":64.57,"global":false}`, 64570 * time.Millisecond},
+	}
+
+	for _, test := range table {
+		version, err := NewAPIVersion(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		date := time.Now().UTC().Truncate(time.Second)
+		header := make(http.Header)
+		header.Set("date", date.Format(time.RFC1123))
+		header, err = NormalizeDiscordHeader(version, http.StatusTooManyRequests, header, []byte(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wants := date.Add(test.wants).UnixNano() / int64(time.Millisecond)
+		if reset := header.Get(XRateLimitReset); reset != strconv.FormatInt(wants, 10) {
+			t.Errorf("v%d: expected the reset in %s, got %s, wants %d", test.version, test.wants, reset, wants)
+		}
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	if delay := retryAfterDelay("3"); delay != 3000 {
+		t.Errorf("expected seconds to be converted, got %dms", delay)
+	}
+	if delay := retryAfterDelay(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); delay < 55000 || delay > 60000 {
+		t.Errorf("expected a http date to be supported, got %dms", delay)
+	}
+	if delay := retryAfterDelay("soon"); delay != 0 {
+		t.Errorf("expected an invalid value to be ignored, got %dms", delay)
+	}
+}
+
+func TestClient_DoUnparsableRateLimitBody(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/ratelimit/429-proxy.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set(RateLimitRetryAfter, "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:          6,
+		BotToken:            "sdfgsdfg",
+		HTTPClient:          srv.Client(),
+		UserAgentSourceURL:  "test",
+		UserAgentVersion:    "test",
+		APIBaseURL:          srv.URL,
+		MaxRateLimitRetries: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1"})
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+	if rateLimitErr.RetryAfter < 25*time.Second {
+		t.Errorf("expected to retry after about 30 seconds, got %s", rateLimitErr.RetryAfter)
+	}
+
+	// the bucket is exhausted until the reset
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, _, err = client.Do(ctx, &Request{Endpoint: "/channels/1"})
+	if !errors.As(err, &rateLimitErr) {
+		t.Errorf("expected the bucket to be updated, got %v", err)
+	}
+}
+
+func TestNormalizeDiscordHeader_Precision(t *testing.T) {
+	date := "Tue, 02 Aug 2016 21:23:42 GMT"
+	table := []struct {
+		name   string
+		header map[string]string
+		wants  int64
+	}{
+		// v6 with X-RateLimit-Precision: millisecond, and v9, which always uses it
+		{"v6 millisecond", map[string]string{
+			XRateLimitLimit: "5", XRateLimitRemaining: "4", XRateLimitReset: "1470173023.123", XRateLimitResetAfter: "1.123",
+		}, 1470173023123},
+		{"v6 second", map[string]string{
+			XRateLimitLimit: "5", XRateLimitRemaining: "4", XRateLimitReset: "1470173024", XRateLimitResetAfter: "2",
+		}, 1470173024000},
+		{"v9", map[string]string{
+			XRateLimitLimit: "5", XRateLimitRemaining: "4", XRateLimitReset: "1470173023.123", XRateLimitResetAfter: "1.123",
+			XRateLimitBucket: "abcd1234",
+		}, 1470173023123},
+		{"v9 reset after only", map[string]string{XRateLimitResetAfter: "1.123"}, 1470173023123},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			header := make(http.Header)
+			header.Set("date", date)
+			for key, value := range test.header {
+				header.Set(key, value)
+			}
+
+			header, err := NormalizeDiscordHeader(testAPIVersion, http.StatusOK, header, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reset := header.Get(XRateLimitReset); reset != strconv.FormatInt(test.wants, 10) {
+				t.Errorf("expected the reset %d, got %s", test.wants, reset)
+			}
+		})
+	}
+}
+
+func TestPrecisionHeader(t *testing.T) {
+	v6, _ := NewAPIVersion(6)
+	v9, _ := NewAPIVersion(9)
+	table := []struct {
+		version   APIVersion
+		precision RateLimitPrecision
+		wants     string
+		fails     bool
+	}{
+		{v6, "", "millisecond", false},
+		{v6, RateLimitPrecisionSecond, "second", false},
+		{v9, "", "", false},
+		{v9, RateLimitPrecisionMillisecond, "", false},
+		{v9, RateLimitPrecisionSecond, "", true},
+		{v6, "minute", "", true},
+	}
+
+	for _, test := range table {
+		header, err := precisionHeader(test.version, test.precision)
+		if (err != nil) != test.fails {
+			t.Errorf("v%d %q: unexpected error %v", test.version.Version(), test.precision, err)
+		}
+		if header != test.wants {
+			t.Errorf("v%d %q: expected the header %q, got %q", test.version.Version(), test.precision, test.wants, header)
+		}
+	}
+}
+
+type errorRecorder struct {
+	logger.Empty
+	errors []string
+}
+
+func (r *errorRecorder) Error(v ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(v...))
+}
+
+func TestClient_DoMalformedRateLimitHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(XRateLimitReset, "soon")
+		w.Header().Set(XRateLimitRemaining, "0")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	log := &errorRecorder{}
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		Logger:             log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, _, err = client.Do(ctx, &Request{Endpoint: "/channels/1"})
+		cancel()
+		if err != nil {
+			t.Fatalf("the malformed header should be ignored, got %v", err)
+		}
+	}
+	var malformed int
+	for _, msg := range log.errors {
+		if strings.Contains(msg, XRateLimitReset+`: "soon"`) {
+			malformed++
+		}
+	}
+	if malformed != 2 {
+		t.Errorf("expected the malformed reset to be logged for both responses, got %q", log.errors)
+	}
+}
+
+func TestNormalizeDiscordHeader_MalformedReset(t *testing.T) {
+	header := http.Header{}
+	header.Set(XRateLimitReset, "soon")
+	header, _ = NormalizeDiscordHeader(testAPIVersion, http.StatusOK, header, nil)
+	if reset := header.Get(XRateLimitReset); reset != "soon" {
+		t.Errorf("expected the malformed reset to be kept, got %s", reset)
+	}
+
+	// the delay replaces it
+	header = http.Header{}
+	header.Set(XRateLimitReset, "soon")
+	header.Set(XRateLimitResetAfter, "2")
+	header, _ = NormalizeDiscordHeader(testAPIVersion, http.StatusOK, header, nil)
+	if parsed := parseRateLimitHeader(header, http.StatusOK); !parsed.valid() || parsed.reset == 0 {
+		t.Errorf("expected the reset to be set from the delay, got %s", header.Get(XRateLimitReset))
+	}
+}
+
+func TestNormalizeDiscordHeader_Copy(t *testing.T) {
+	header := http.Header{}
+	header.Set(XRateLimitReset, "1470173023.123")
+	body := []byte(`{"retry_after":2000,"global":true}`)
+	normalized, _ := NormalizeDiscordHeader(testAPIVersion, http.StatusTooManyRequests, header, body)
+
+	if reset := normalized.Get(XRateLimitReset); reset != "1470173023123" {
+		t.Errorf("expected the reset in milliseconds, got %s", reset)
+	}
+	if reset := header.Get(XRateLimitReset); reset != "1470173023.123" {
+		t.Errorf("expected the original header to be untouched, got reset %s", reset)
+	}
+	if header.Get(XRateLimitGlobal) != "" || header.Get(DisgordNormalizedHeader) != "" {
+		t.Errorf("expected no fields to be added to the original header, got %v", header)
+	}
+}
+
+func TestClient_DoOriginalHeader(t *testing.T) {
+	resetTime := time.Unix(time.Now().Add(time.Minute).Unix(), 0)
+	reset := strconv.FormatInt(resetTime.Unix(), 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(XRateLimitReset, reset)
+		w.Header().Set(XRateLimitRemaining, "4")
+		w.Header().Set(XRateLimitLimit, "5")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var hooked http.Header
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		Hooks: RequestHooks{
+			AfterResponse: func(id uint64, req *http.Request, resp *http.Response, err error) {
+				hooked = resp.Header
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info RateLimitInfo
+	ctx := WithRateLimitInfo(context.Background(), &info)
+	resp, _, err := client.Do(ctx, &Request{Endpoint: "/channels/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, header := range map[string]http.Header{"hook": hooked, "response": resp.Header} {
+		if got := header.Get(XRateLimitReset); got != reset {
+			t.Errorf("expected the %s to see the reset Discord sent, got %s", name, got)
+		}
+		if header.Get(DisgordNormalizedHeader) != "" {
+			t.Errorf("expected the %s to see the header Discord sent", name)
+		}
+	}
+
+	// the rate limits use the normalized fields
+	if info.Remaining != 4 || !info.Reset.Equal(resetTime) {
+		t.Errorf("expected the rate limit of the response, got %+v", info)
+	}
+}
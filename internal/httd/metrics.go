@@ -0,0 +1,52 @@
+package httd
+
+import (
+	"net/http"
+	"time"
+)
+
+// Metrics receives statistics about the REST traffic, see Config.Metrics. The methods are called
+// concurrently.
+type Metrics interface {
+	// ObserveRequest is called for every request sent to Discord, including retries. bucket is the rate limit
+	// bucket hash given by Discord, or the hashed endpoint when Discord did not specify one. status is zero
+	// when no response was received. latency is the round trip time, and rateLimitWait is the time the request
+	// was queued in the rate limit bucket before it was sent.
+	ObserveRequest(bucket string, method string, status int, latency, rateLimitWait time.Duration)
+
+	// IncRateLimited is called for every 429 Too Many Requests response.
+	IncRateLimited(global bool)
+}
+
+// NopMetrics discards all the statistics, and is the default Metrics
+type NopMetrics struct{}
+
+var _ Metrics = NopMetrics{}
+
+func (NopMetrics) ObserveRequest(string, string, int, time.Duration, time.Duration) {}
+func (NopMetrics) IncRateLimited(bool)                                              {}
+
+// observe reports a request to the metrics. queued is when the request entered the bucket, and sent and
+// received frame the round trip. A request that was never sent is not reported.
+func (c *Client) observe(r *Request, resp *http.Response, queued, sent, received time.Time) {
+	if sent.IsZero() {
+		return
+	}
+
+	bucket := r.hashedEndpoint
+	var status int
+	if resp != nil {
+		status = resp.StatusCode
+		if hash := resp.Header.Get(XRateLimitBucket); hash != "" {
+			bucket = hash
+		}
+	}
+	if received.IsZero() {
+		received = time.Now()
+	}
+
+	c.metrics.ObserveRequest(bucket, r.Method.String(), status, received.Sub(sent), sent.Sub(queued))
+	if status == http.StatusTooManyRequests {
+		c.metrics.IncRateLimited(resp.Header.Get(XRateLimitGlobal) == "true")
+	}
+}
@@ -0,0 +1,45 @@
+package httd
+
+import "time"
+
+// Pacing decides when the remaining requests of a ltBucket are sent, see Manager.SetPacing
+type Pacing int
+
+const (
+	// PacingBurst sends the remaining requests right away, and waits for the reset once they are used up
+	PacingBurst Pacing = iota
+
+	// PacingSpread spaces the remaining requests evenly until the reset, such that repeated requests, like
+	// typing indicators or message edits, do not cluster at the start of every window
+	PacingSpread
+)
+
+// pacedDelay is how long to wait after a request is granted before the next one, when the remaining
+// requests, including the granted one, are spread evenly until the reset
+func pacedDelay(now, reset time.Time, remaining int) time.Duration {
+	if remaining <= 0 || !reset.After(now) {
+		return 0
+	}
+	return reset.Sub(now) / time.Duration(remaining)
+}
+
+// SetPacing changes how the remaining requests of the buckets are sent. Defaults to PacingBurst. The global
+// ltBucket is not paced.
+func (r *Manager) SetPacing(pacing Pacing) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pacing = pacing
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		for _, bucket := range s.buckets {
+			if bucket == r.global {
+				continue
+			}
+			bucket.mu.Lock()
+			bucket.pacing = pacing
+			bucket.mu.Unlock()
+		}
+		s.mu.RUnlock()
+	}
+}
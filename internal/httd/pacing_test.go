@@ -0,0 +1,94 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPacedDelay(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		reset     time.Duration
+		remaining int
+		delay     time.Duration
+	}{
+		{time.Second, 4, 250 * time.Millisecond},
+		{time.Second, 1, time.Second},
+		{5 * time.Second, 5, time.Second},
+		{time.Second, 0, 0},
+		{-time.Second, 4, 0},
+	}
+	for _, test := range tests {
+		if delay := pacedDelay(now, now.Add(test.reset), test.remaining); delay != test.delay {
+			t.Errorf("expected a delay of %s with %d remaining within %s, got %s", test.delay, test.remaining, test.reset, delay)
+		}
+	}
+
+	// the spacing stays even as the window runs out
+	reset := now.Add(time.Second)
+	for remaining := 4; remaining > 0; remaining-- {
+		delay := pacedDelay(now, reset, remaining)
+		if delay != 250*time.Millisecond {
+			t.Errorf("expected grants 250ms apart, got %s with %d remaining", delay, remaining)
+		}
+		now = now.Add(delay)
+	}
+}
+
+func TestManager_SetPacing(t *testing.T) {
+	mngr := NewManager(nil)
+	mngr.SetPacing(PacingSpread)
+
+	id := "POST:/channels/1/typing"
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.mu.Lock()
+		b.remaining = 4
+		b.resetTime = time.Now().Add(200 * time.Millisecond)
+		b.discordResetTime = b.resetTime
+		b.mu.Unlock()
+	})
+
+	var sent []time.Time
+	for i := 0; i < 4; i++ {
+		mngr.Bucket(id, func(bucket RESTBucket) {
+			_, _, err := bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+				sent = append(sent, time.Now())
+				return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}}, nil, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < 40*time.Millisecond {
+			t.Errorf("request %d was sent %s after the previous one, expected the 4 requests to be spread over 200ms", i, gap)
+		}
+	}
+
+	// bursts are the default
+	mngr = NewManager(nil)
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.remaining = 4
+		b.resetTime = time.Now().Add(time.Minute)
+		b.discordResetTime = b.resetTime
+
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			release, err := b.Acquire(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			release(&http.Response{StatusCode: http.StatusNoContent, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}})
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("expected the requests to be sent right away, they took %s", elapsed)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	invalid := []string{
+		"ftp://10.0.0.1:21",
+		"socks5://",
+		"http://user:s3cr3t@[::1",
+	}
+	for _, proxyURL := range invalid {
+		if _, err := NewTransport(proxyURL, nil); err == nil {
+			t.Errorf("expected %q to be rejected", proxyURL)
+		} else if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("the proxy password is part of the error: %s", err)
+		}
+	}
+
+	transport, err := NewTransport("socks5://127.0.0.1:1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = transport.DialContext(context.Background(), "tcp", "discord.com:443")
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.1:1") {
+		t.Errorf("expected the dial error to include the proxy address, got %v", err)
+	}
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		Proxy:              proxy.URL,
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         "http://discord.invalid/api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/gateway/bot"}); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://discord.invalid/api/v6/gateway/bot" {
+		t.Errorf("expected the request to go through the proxy, got %q", proxied)
+	}
+
+	_, err = NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         &http.Client{},
+		Proxy:              proxy.URL,
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+	})
+	if err == nil {
+		t.Error("expected the proxy to be rejected together with a custom http client")
+	}
+}
@@ -0,0 +1,122 @@
+package httd
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RateLimitEvent describes a request that was delayed or rejected because of a rate limit, or that received
+// a 429 Too Many Requests response. See Config.OnRateLimit.
+type RateLimitEvent struct {
+	Method string
+
+	// Endpoint is the requested path, without webhook tokens
+	Endpoint string
+
+	// HashedEndpoint is the local bucket key of the endpoint, Route is the endpoint without the major
+	// parameter, and Major is the guild, channel or webhook the bucket is shared by
+	HashedEndpoint string
+	Route          string
+	Major          string
+
+	// Bucket is the Discord bucket hash, when known
+	Bucket string
+	Global bool
+
+	// Wait is how long the request is delayed until the bucket resets, or the Retry-After of a 429 response
+	Wait time.Duration
+
+	// Rejected is true when the request was not sent because it would have waited too long, see
+	// Config.MaxRateLimitDelay
+	Rejected bool
+
+	// TooManyRequests is true when Discord responded with 429 Too Many Requests
+	TooManyRequests bool
+
+	// GlobalActivated is true when a global 429 response has started the global rate limit, after which every
+	// request waits for it for Wait, and GlobalDeactivated when it has ended and requests are sent at full
+	// speed again. These events are not about a single request, and only have Global and Wait set.
+	GlobalActivated   bool
+	GlobalDeactivated bool
+}
+
+// onRateLimitKey holds the callback the bucket calls before it delays a request, see Config.OnRateLimit
+type onRateLimitKey struct{}
+
+// notifyRateLimited tells the client that the bucket delays the request
+func notifyRateLimited(ctx context.Context, wait time.Duration, global bool, bucket string) {
+	if cb, ok := ctx.Value(onRateLimitKey{}).(func(wait time.Duration, global bool, bucket string)); ok {
+		cb(wait, global, bucket)
+	}
+}
+
+func (c *Client) rateLimitEvent(r *Request) RateLimitEvent {
+	route, major := majorParameter(r.hashedEndpoint)
+	return RateLimitEvent{
+		Method:         r.Method.String(),
+		Endpoint:       RedactEndpoint(r.Endpoint),
+		HashedEndpoint: r.hashedEndpoint,
+		Route:          route,
+		Major:          major,
+	}
+}
+
+// withRateLimitEvents makes the bucket report delayed requests to Config.OnRateLimit
+func (c *Client) withRateLimitEvents(ctx context.Context, r *Request) context.Context {
+	if c.onRateLimit == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, onRateLimitKey{}, func(wait time.Duration, global bool, bucket string) {
+		event := c.rateLimitEvent(r)
+		event.Wait, event.Global, event.Bucket = wait, global, bucket
+		c.onRateLimit(event)
+	})
+}
+
+// globalRateLimitEvents reports when the global rate limit starts and ends, see Manager.OnGlobalRateLimit
+func globalRateLimitEvents(onRateLimit func(event RateLimitEvent)) func(active bool, wait time.Duration) {
+	return func(active bool, wait time.Duration) {
+		onRateLimit(RateLimitEvent{
+			Global:            true,
+			Wait:              wait,
+			GlobalActivated:   active,
+			GlobalDeactivated: !active,
+		})
+	}
+}
+
+// OnGlobalRateLimit calls cb when a global 429 response starts the global rate limit, with how long it lasts,
+// and when it has ended. Requests keep waiting for the global ltBucket for a short cooldown after its reset,
+// such that a global rate limit that comes right back does not flap.
+func (r *Manager) OnGlobalRateLimit(cb func(active bool, wait time.Duration)) {
+	r.global.mu.Lock()
+	r.global.onGlobal = cb
+	r.global.mu.Unlock()
+}
+
+// rateLimitRejected reports a request that was not sent because of a rate limit
+func (c *Client) rateLimitRejected(r *Request, err *RateLimitError) {
+	if c.onRateLimit == nil {
+		return
+	}
+	event := c.rateLimitEvent(r)
+	event.Wait, event.Global, event.Bucket, event.Rejected = err.RetryAfter, err.Global, err.Bucket, true
+	if event.Bucket == r.hashedEndpoint {
+		event.Bucket = ""
+	}
+	c.onRateLimit(event)
+}
+
+// tooManyRequests reports a 429 response
+func (c *Client) tooManyRequests(r *Request, resp *http.Response) {
+	if c.onRateLimit == nil {
+		return
+	}
+	event := c.rateLimitEvent(r)
+	event.Wait = rateLimitDelay(resp.Header)
+	event.Global = resp.Header.Get(XRateLimitGlobal) == "true"
+	event.Bucket = resp.Header.Get(XRateLimitBucket)
+	event.TooManyRequests = true
+	c.onRateLimit(event)
+}
@@ -0,0 +1,187 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_OnRateLimit(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Header().Set(XRateLimitBucket, "abcd1234")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":10,"global":false}`))
+			return
+		}
+
+		reset := float64(time.Now().Add(200*time.Millisecond).UnixNano()) / float64(time.Second)
+		w.Header().Set(XRateLimitBucket, "abcd1234")
+		w.Header().Set(XRateLimitRemaining, "0")
+		w.Header().Set(XRateLimitReset, strconv.FormatFloat(reset, 'f', 3, 64))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var events []RateLimitEvent
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		OnRateLimit: func(event RateLimitEvent) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 429, retried, and exhausts the bucket
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1/messages"}); err != nil {
+		t.Fatal(err)
+	}
+	// rejected
+	ctx := WithMaxRateLimitDelay(context.Background(), -1)
+	if _, _, err = client.Do(ctx, &Request{Endpoint: "/channels/1/messages"}); err == nil {
+		t.Fatal("expected the request to be rejected")
+	}
+	// delayed
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/channels/1/messages"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %+v", events)
+	}
+	for _, event := range events {
+		if event.Method != "GET" || event.Endpoint != "/channels/1/messages" || event.Major != "channels/1" {
+			t.Errorf("unexpected request details: %+v", event)
+		}
+		if event.Route != "GET:/channels/{major}/messages" || event.HashedEndpoint == "" {
+			t.Errorf("unexpected bucket key: %+v", event)
+		}
+		if event.Wait <= 0 || event.Global {
+			t.Errorf("unexpected wait: %+v", event)
+		}
+	}
+	if !events[0].TooManyRequests || events[0].Bucket != "abcd1234" || events[0].Wait != 10*time.Millisecond {
+		t.Errorf("expected the 429 response first, got %+v", events[0])
+	}
+	if !events[1].Rejected || events[1].TooManyRequests {
+		t.Errorf("expected the rejected request second, got %+v", events[1])
+	}
+	if events[2].Rejected || events[2].TooManyRequests || events[2].Bucket != "abcd1234" {
+		t.Errorf("expected the delayed request last, got %+v", events[2])
+	}
+}
+
+func TestClient_GlobalRateLimitLifecycle(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+
+		w.Header()["Date"] = nil // the reset is relative to the local clock
+		w.Header().Set("Content-Type", "application/json")
+		if first {
+			w.Header().Set(XRateLimitGlobal, "true")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":300,"global":true}`))
+			return
+		}
+		w.Header().Set(XRateLimitBucket, "abcd1234")
+		w.Header().Set(XRateLimitRemaining, "50")
+		w.Header().Set(XRateLimitReset, strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var events []RateLimitEvent
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		OnRateLimit: func(event RateLimitEvent) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventsSince := func(i int) []RateLimitEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]RateLimitEvent(nil), events[i:]...)
+	}
+
+	// a global 429 starts the global rate limit
+	ctx := WithMaxRateLimitDelay(context.Background(), -1)
+	if _, _, err = client.Do(ctx, &Request{Endpoint: "/channels/1/messages"}); err == nil {
+		t.Fatal("expected the 429 response to be returned")
+	}
+	started := eventsSince(0)
+	if len(started) != 2 || !started[0].GlobalActivated || started[0].Wait <= 0 || !started[1].TooManyRequests {
+		t.Fatalf("expected the global rate limit to start, got %+v", started)
+	}
+
+	// requests to other routes are delayed until the reset
+	start := time.Now()
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/guilds/1"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the request to wait for the global rate limit, it took %s", elapsed)
+	}
+	delayed := eventsSince(2)
+	if len(delayed) != 1 || !delayed[0].Global || delayed[0].Wait <= 0 || delayed[0].GlobalDeactivated {
+		t.Fatalf("expected the request to be delayed by the global rate limit, got %+v", delayed)
+	}
+
+	// the global rate limit ends once the cooldown has passed as well, and requests are sent at full speed
+	time.Sleep(globalCooldown + 50*time.Millisecond)
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/guilds/1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("expected the requests to be sent right away, they took %s", elapsed)
+	}
+	ended := eventsSince(3)
+	if len(ended) != 1 || !ended[0].GlobalDeactivated || !ended[0].Global {
+		t.Fatalf("expected the global rate limit to end once, got %+v", ended)
+	}
+
+	manager := client.buckets.(*Manager)
+	manager.global.mu.RLock()
+	defer manager.global.mu.RUnlock()
+	if manager.global.active() {
+		t.Error("expected the global ltBucket to be inactive")
+	}
+}
@@ -0,0 +1,62 @@
+package httd
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitInfo describes the rate limit bucket of a request after a successful response, as reported by the
+// rate limit header fields. Use WithRateLimitInfo to capture it.
+type RateLimitInfo struct {
+	// Bucket is the Discord bucket hash when known, otherwise the hashed endpoint
+	Bucket string
+
+	// Remaining is the number of requests that can be sent before Reset
+	Remaining uint
+	Limit     uint
+
+	// Reset is when the bucket is refilled. It is zero when the response holds no rate limit information.
+	Reset time.Time
+
+	// Waited is the time spent waiting for the bucket to allow the request, summed over every attempt
+	Waited time.Duration
+}
+
+type rateLimitInfoKey struct{}
+
+// WithRateLimitInfo returns a context that makes Client.Do and Client.DoStream write the rate limit details
+// of a successful response to info. The context can be reused, but info is overwritten by every request.
+func WithRateLimitInfo(ctx context.Context, info *RateLimitInfo) context.Context {
+	return context.WithValue(ctx, rateLimitInfoKey{}, info)
+}
+
+func rateLimitInfoFrom(ctx context.Context) *RateLimitInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(rateLimitInfoKey{}).(*RateLimitInfo)
+	return info
+}
+
+// newRateLimitInfo reads the normalized header fields of a response
+func newRateLimitInfo(r *Request, header http.Header, waited time.Duration) RateLimitInfo {
+	info := RateLimitInfo{
+		Bucket: header.Get(XRateLimitBucket),
+		Waited: waited,
+	}
+	if info.Bucket == "" {
+		info.Bucket = r.hashedEndpoint
+	}
+	if remaining, err := strconv.ParseUint(header.Get(XRateLimitRemaining), 10, 64); err == nil {
+		info.Remaining = uint(remaining)
+	}
+	if limit, err := strconv.ParseUint(header.Get(XRateLimitLimit), 10, 64); err == nil {
+		info.Limit = uint(limit)
+	}
+	if reset, err := strconv.ParseInt(header.Get(XRateLimitReset), 10, 64); err == nil && reset > 0 {
+		info.Reset = time.Unix(0, reset*int64(time.Millisecond))
+	}
+	return info
+}
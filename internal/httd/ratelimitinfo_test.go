@@ -0,0 +1,62 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClient_DoRateLimitInfo(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v6/channels/404" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":10003,"message":"Unknown Channel"}`))
+			return
+		}
+		w.Header().Set(XRateLimitBucket, "abcd1234")
+		w.Header().Set(XRateLimitLimit, "5")
+		w.Header().Set(XRateLimitRemaining, "3")
+		w.Header().Set(XRateLimitReset, strconv.FormatFloat(float64(reset.UnixNano())/float64(time.Second), 'f', 3, 64))
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info RateLimitInfo
+	ctx := WithRateLimitInfo(context.Background(), &info)
+	if _, _, err = client.Do(ctx, &Request{Endpoint: "/channels/1"}); err != nil {
+		t.Fatal(err)
+	}
+	if info.Bucket != "abcd1234" || info.Limit != 5 || info.Remaining != 3 {
+		t.Errorf("unexpected rate limit info: %+v", info)
+	}
+	if !info.Reset.Equal(reset) {
+		t.Errorf("expected the bucket to reset at %s, got %s", reset, info.Reset)
+	}
+
+	before := info
+	if _, _, err = client.Do(ctx, &Request{Endpoint: "/channels/404"}); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if info != before {
+		t.Errorf("a failed request changed the rate limit info to %+v", info)
+	}
+}
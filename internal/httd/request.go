@@ -4,25 +4,28 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 )
 
-type httpMethod string
+// HTTPMethod is the method of a Request, such as MethodGet. Any method of net/http can be converted to it.
+type HTTPMethod string
 
-var _ fmt.Stringer = (*httpMethod)(nil)
+var _ fmt.Stringer = (*HTTPMethod)(nil)
 
-func (method httpMethod) String() string {
+func (method HTTPMethod) String() string {
 	return string(method)
 }
 
 const (
-	MethodGet    httpMethod = http.MethodGet
-	MethodDelete httpMethod = http.MethodDelete
-	MethodPost   httpMethod = http.MethodPost
-	MethodPatch  httpMethod = http.MethodPatch
-	MethodPut    httpMethod = http.MethodPut
+	MethodGet    HTTPMethod = http.MethodGet
+	MethodDelete HTTPMethod = http.MethodDelete
+	MethodPost   HTTPMethod = http.MethodPost
+	MethodPatch  HTTPMethod = http.MethodPatch
+	MethodPut    HTTPMethod = http.MethodPut
 )
 
 var regexpURLSnowflakes = regexp.MustCompile(RegexpURLSnowflakes)
@@ -30,18 +33,58 @@ var regexpURLReactionPrefix = regexp.MustCompile(`\/channels\/` + RegexpSnowflak
 var regexpURLReactionEmoji = regexp.MustCompile(`\/channels\/[0-9]+\/messages\/\{id\}\/reactions\/` + RegexpEmoji + `\/?`)
 var regexpURLReactionEmojiSegment = regexp.MustCompile(`\/reactions\/` + RegexpEmoji)
 
+// Priority decides the order in which requests waiting for the same rate limit bucket are sent. Requests of
+// the same priority are sent in the order they were queued.
+type Priority int
+
+const (
+	// PriorityLow is for background work, such as fetching the message history, that can wait for
+	// other requests
+	PriorityLow Priority = -1
+
+	// PriorityNormal is the default
+	PriorityNormal Priority = 0
+
+	// PriorityHigh is for requests a user waits on, such as the response to a command
+	PriorityHigh Priority = 1
+)
+
 // Request is populated before executing a Discord request to correctly generate a http request
 type Request struct {
 	Ctx context.Context
 
-	Method      httpMethod
+	Method      HTTPMethod
 	Endpoint    string
 	Body        interface{} // will automatically marshal to JSON if the ContentType is httd.ContentTypeJSON
 	ContentType string
 
+	// BodyFactory creates the body for every attempt, and replaces Body. Readers other than *bytes.Buffer,
+	// *bytes.Reader and *strings.Reader can not be read twice, so requests with such a Body are never
+	// retried. A factory that reopens the source, such as a file, allows them to be retried.
+	BodyFactory func() (io.Reader, error)
+
 	// Reason is a X-Audit-Log-Reason header field that will show up on the audit log for this action.
 	Reason string
 
+	// SkipAuthorization removes the bot token from the request. Used for endpoints that
+	// authenticate using a token in the URL, such as webhook tokens.
+	SkipAuthorization bool
+
+	// Idempotent marks a POST or PATCH request as safe to send more than once, such that it is retried on
+	// server errors like GET, PUT and DELETE requests are. See RetryPolicy.
+	Idempotent bool
+
+	// Priority moves the request ahead of, or behind, other requests waiting for the same rate limit bucket
+	Priority Priority
+
+	// MaxRateLimitDelay replaces Config.MaxRateLimitDelay for this request. A negative value never waits
+	// for the rate limits, and zero uses the context or Config.MaxRateLimitDelay.
+	MaxRateLimitDelay time.Duration
+
+	// RetryPolicy replaces Config.RetryPolicy for this request. Zero MaxAttempts, BaseDelay and MaxDelay are
+	// taken from Config.RetryPolicy.
+	RetryPolicy *RetryPolicy
+
 	bodyReader     io.Reader
 	hashedEndpoint string
 }
@@ -61,12 +104,113 @@ func (r *Request) PopulateMissing() {
 	r.hashedEndpoint = r.HashEndpoint()
 }
 
+// readCloserFactory adapts BodyFactory to http.Request.GetBody
+func (r *Request) readCloserFactory() (io.ReadCloser, error) {
+	body, err := r.BodyFactory()
+	if err != nil {
+		return nil, err
+	}
+	if rc, ok := body.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return ioutil.NopCloser(body), nil
+}
+
+// idempotent checks if the request can be sent again without side effects
+func (r *Request) idempotent() bool {
+	switch r.Method {
+	case MethodGet, MethodPut, MethodDelete:
+		return true
+	default:
+		return r.Idempotent
+	}
+}
+
+// RedactEndpoint replaces webhook and interaction tokens in an endpoint or URL with {token}, such that it can
+// be logged. The values of query parameters that hold secrets, such as token, are replaced as well, while the
+// rest of the query string is kept.
+// /webhooks/{webhook.id}/{webhook.token}/... => /webhooks/{webhook.id}/{token}/...
+// /interactions/{interaction.id}/{interaction.token}/callback => /interactions/{interaction.id}/{token}/callback
+// /oauth2/token?code=abc&redirect_uri=... => /oauth2/token?code={token}&redirect_uri=...
+func RedactEndpoint(endpoint string) string {
+	return redactTokens(endpoint, "{token}")
+}
+
+// redactURL replaces webhook and interaction tokens with [REDACTED] for the debug output, see Config.Debug
+func redactURL(u string) string {
+	return redactTokens(u, redacted)
+}
+
+// sensitiveQueryParams are the query parameters whose values are redacted
+var sensitiveQueryParams = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"client_secret": true,
+	"code":          true,
+}
+
+func redactTokens(endpoint, replacement string) string {
+	var query string
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint, query = endpoint[:i], endpoint[i+1:]
+	}
+
+	endpoint = redactToken(endpoint, "/webhooks/", replacement)
+	endpoint = redactToken(endpoint, "/interactions/", replacement)
+	if query == "" {
+		return endpoint
+	}
+	return endpoint + "?" + redactQuery(query, replacement)
+}
+
+// redactQuery replaces the values of sensitive query parameters, and keeps the order of the parameters
+func redactQuery(query, replacement string) string {
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key := param
+		if j := strings.IndexByte(param, '='); j >= 0 {
+			key = param[:j]
+		}
+		if sensitiveQueryParams[strings.ToLower(key)] {
+			params[i] = key + "=" + replacement
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// redactToken replaces the path segment after the id that follows prefix
+func redactToken(endpoint, prefix, replacement string) string {
+	i := strings.Index(endpoint, prefix)
+	if i < 0 {
+		return endpoint
+	}
+
+	segments := strings.SplitN(endpoint[i+len(prefix):], "/", 3)
+	if len(segments) < 2 || segments[1] == "" {
+		return endpoint
+	}
+	segments[1] = replacement
+	return endpoint[:i+len(prefix)] + strings.Join(segments, "/")
+}
+
 func (r *Request) HashEndpoint() string {
 	endpoint := strings.Split(r.Endpoint, "?")[0]
+
+	// webhook and interaction tokens are not snowflakes, but they should not affect the bucket either.
+	endpoint = RedactEndpoint(endpoint)
+
+	// invite codes neither, every invite shares the rate limit of the route
+	if strings.HasPrefix(endpoint, "/invites/") {
+		segments := strings.SplitN(endpoint[len("/invites/"):], "/", 2)
+		segments[0] = "{code}"
+		endpoint = "/invites/" + strings.Join(segments, "/")
+	}
+
 	matches := regexpURLSnowflakes.FindAllString(endpoint, -1)
 
 	var isMajor bool
-	for _, prefix := range []string{"/guilds", "/channels", "/webhooks"} {
+	for _, prefix := range []string{"/guilds", "/channels", "/webhooks", "/interactions"} {
 		if strings.HasPrefix(endpoint, prefix) {
 			isMajor = true
 			break
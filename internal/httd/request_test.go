@@ -22,9 +22,16 @@ func TestRequest_RateLimitID(t *testing.T) {
 		"/channels/345345/sdfsdf":          "GET:/channels/345345/sdfsdf",
 		"/channels/345345/sdfsdf/32987234": "GET:/channels/345345/sdfsdf/{id}",
 		// major
-		"/webhooks/345345":                 "GET:/webhooks/345345",
-		"/webhooks/345345/sdfsdf":          "GET:/webhooks/345345/sdfsdf",
-		"/webhooks/345345/sdfsdf/32987234": "GET:/webhooks/345345/sdfsdf/{id}",
+		"/webhooks/345345":                  "GET:/webhooks/345345",
+		"/webhooks/345345/sdfsdf":           "GET:/webhooks/345345/{token}",
+		"/webhooks/345345/sdfsdf/32987234":  "GET:/webhooks/345345/{token}/{id}",
+		"/webhooks/345345/8sdf-_sdf/slack":  "GET:/webhooks/345345/{token}/slack",
+		"/webhooks/345345/764sdfsdf?wait=1": "GET:/webhooks/345345/{token}",
+		// major
+		"/interactions/345345/aW50ZXJhY3Rpb24/callback": "GET:/interactions/345345/{token}/callback",
+		// invites
+		"/invites/abcXYZ":                  "GET:/invites/{code}",
+		"/invites/abcXYZ?with_counts=true": "GET:/invites/{code}",
 		// major + reaction
 		"/channels/540519296640614416/messages/540519319814275089/reactions/DeepinScreenshot_selectarea_2019:540519588153262081/@me":             "GET:/channels/540519296640614416/messages/{id}/reactions/{emoji}/@me",
 		"/channels/540519296640614416/messages/540519319814275089/reactions/DeepinScreenshot_selectarea_2019:540519588153262081/":                "GET:/channels/540519296640614416/messages/{id}/reactions/{emoji}",
@@ -51,3 +58,25 @@ func TestRequest_RateLimitID(t *testing.T) {
 		}
 	}
 }
+
+func TestRedactEndpoint(t *testing.T) {
+	table := map[string]string{
+		"/webhooks/1/s3cr3t":                                     "/webhooks/1/{token}",
+		"/webhooks/1/s3cr3t/messages/2":                          "/webhooks/1/{token}/messages/2",
+		"https://discord.com/api/v6/webhooks/1/s3cr3t?wait=true": "https://discord.com/api/v6/webhooks/1/{token}?wait=true",
+		"/webhooks/1/s3cr3t/messages/2?thread_id=3":              "/webhooks/1/{token}/messages/2?thread_id=3",
+		"/channels/1/messages?before=2&limit=100":                "/channels/1/messages?before=2&limit=100",
+		"/oauth2/token?code=s3cr3t&redirect_uri=x":               "/oauth2/token?code={token}&redirect_uri=x",
+		"/oauth2/authorize?access_token=s3cr3t":                  "/oauth2/authorize?access_token={token}",
+		"/webhooks/1":                                            "/webhooks/1",
+		"/channels/1/webhooks":                                   "/channels/1/webhooks",
+		"/channels/1/messages/2":                                 "/channels/1/messages/2",
+		"/interactions/1/s3cr3t/callback":                        "/interactions/1/{token}/callback",
+	}
+
+	for endpoint, wants := range table {
+		if got := RedactEndpoint(endpoint); got != wants {
+			t.Errorf("RedactEndpoint(%q) = %q, wants %q", endpoint, got, wants)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package httd
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// lastRequestID is shared by every Client, such that IDs are unique within the process even with many shards
+var lastRequestID uint64
+
+// nextRequestID returns a new request ID. IDs start at 1 and increase monotonically.
+func nextRequestID() uint64 {
+	return atomic.AddUint64(&lastRequestID, 1)
+}
+
+// RequestHooks are called for every attempt of a request, with the request ID that is attached to ErrREST.
+// Retries of a request share the ID.
+type RequestHooks struct {
+	// BeforeRequest is called right before the request is sent, after it waited for the rate limit bucket
+	BeforeRequest func(id uint64, req *http.Request, attempt int)
+
+	// AfterResponse is called when a response is received, or the request failed. resp is nil when err is a
+	// transport error. The response body has already been read and closed.
+	AfterResponse func(id uint64, req *http.Request, resp *http.Response, err error)
+}
+
+func (h *RequestHooks) before(id uint64, req *http.Request, attempt int) {
+	if h.BeforeRequest != nil {
+		h.BeforeRequest(id, req, attempt)
+	}
+}
+
+func (h *RequestHooks) after(id uint64, req *http.Request, resp *http.Response, err error) {
+	if h.AfterResponse != nil {
+		h.AfterResponse(id, req, resp, err)
+	}
+}
+
+func formatRequestID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
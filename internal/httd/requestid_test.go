@@ -0,0 +1,82 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClient_DoRequestID(t *testing.T) {
+	var headers []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("X-Request-ID"))
+		switch r.URL.Path {
+		case "/v6/flaky":
+			if len(headers) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case "/v6/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":10008,"message":"Unknown Message"}`))
+		}
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var before, after []uint64
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		RetryPolicy:        RetryPolicy{MaxAttempts: 2, BaseDelay: 1, MaxDelay: 1},
+		RequestIDHeader:    "X-Request-ID",
+		Hooks: RequestHooks{
+			BeforeRequest: func(id uint64, req *http.Request, attempt int) {
+				mu.Lock()
+				before = append(before, id)
+				mu.Unlock()
+			},
+			AfterResponse: func(id uint64, req *http.Request, resp *http.Response, err error) {
+				mu.Lock()
+				after = append(after, id)
+				mu.Unlock()
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = client.Do(context.Background(), &Request{Endpoint: "/flaky"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 2 || before[0] != before[1] || len(after) != 2 || after[1] != before[0] {
+		t.Fatalf("expected the retry to share the request ID, got %v and %v", before, after)
+	}
+	if headers[0] != formatRequestID(before[0]) || headers[1] != headers[0] {
+		t.Errorf("expected the request ID to be sent, got %v", headers)
+	}
+
+	_, _, err = client.Do(context.Background(), &Request{Endpoint: "/missing"})
+	var restErr *ErrREST
+	if !errors.As(err, &restErr) {
+		t.Fatalf("expected an ErrREST, got %v", err)
+	}
+	if id := after[len(after)-1]; restErr.RequestID != id || id == before[0] {
+		t.Errorf("expected the error to hold the new request ID %d, got %d", id, restErr.RequestID)
+	}
+	if !strings.Contains(err.Error(), "(request "+formatRequestID(restErr.RequestID)+")") {
+		t.Errorf("expected the request ID to be part of the error message, got %q", err.Error())
+	}
+}
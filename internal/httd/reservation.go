@@ -0,0 +1,128 @@
+package httd
+
+import (
+	"context"
+	"time"
+)
+
+// Reservation holds requests of a ltBucket aside for a batch, see Manager.Reserve. Requests that are sent with
+// a context from WithReservation use the reserved requests, and are not held up by the rate limit of the
+// ltBucket. The global rate limit still applies.
+type Reservation struct {
+	// Granted is the number of requests that were reserved. It is zero while the rate limit of the ltBucket is
+	// not known, in which case a single request should be sent to learn it.
+	Granted int
+
+	// Next is when the ltBucket has another request once the granted ones are used: now when it has requests
+	// left, otherwise the reset. It is the zero time when the ltBucket is not limited.
+	Next time.Time
+
+	bucket *ltBucket
+	reset  time.Time // the discord reset of the window the requests were reserved in
+	used   int       // guarded by the mutex of the ltBucket
+}
+
+type reservationKey struct{}
+
+// WithReservation returns a context that makes requests use the requests held by the reservation, until they
+// are used up
+func WithReservation(ctx context.Context, res *Reservation) context.Context {
+	return context.WithValue(ctx, reservationKey{}, res)
+}
+
+func reservationFrom(ctx context.Context) *Reservation {
+	res, _ := ctx.Value(reservationKey{}).(*Reservation)
+	return res
+}
+
+// holds is true when the reservation has a request of the window the ltBucket is in. The ltBucket must be
+// locked.
+func (res *Reservation) holds(b *ltBucket, now time.Time) bool {
+	return res != nil && res.bucket == b && res.used < res.Granted && b.reserved > 0 &&
+		b.discordResetTime.Equal(res.reset) && b.resetTime.After(now)
+}
+
+// reserveN takes up to n of the remaining requests, and holds them for the reservation
+func (b *ltBucket) reserveN(now time.Time, n int) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.simulateReset(now)
+
+	res := &Reservation{bucket: b, reset: b.discordResetTime}
+	if b.remaining < 0 || !b.resetTime.After(now) {
+		// not limited until the next response
+		return res
+	}
+	res.Granted = n
+	if res.Granted > b.remaining {
+		res.Granted = b.remaining
+	}
+	if res.Granted < 0 {
+		res.Granted = 0
+	}
+	b.remaining -= res.Granted
+	b.reserved += res.Granted
+
+	res.Next = now
+	if b.remaining == 0 {
+		res.Next = b.resetTime
+	}
+	return res
+}
+
+// cancel gives the requests of the reservation that were not used back to the ltBucket, unless the window
+// they were reserved in has ended
+func (res *Reservation) cancel() {
+	b := res.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unused := res.Granted - res.used
+	res.used = res.Granted
+	if unused <= 0 || !b.discordResetTime.Equal(res.reset) || !b.resetTime.After(time.Now()) {
+		return
+	}
+	if unused > b.reserved {
+		// discord has reported fewer remaining requests than were reserved
+		unused = b.reserved
+	}
+	b.reserved -= unused
+	b.remaining += unused
+}
+
+// Reserve holds up to n requests of the ltBucket of the local endpoint hash aside, without waiting, such that
+// a batch can be planned around the requests that can be sent right away. Send the requests with a context
+// from WithReservation, and call CancelReservation to give back the requests that were not used. The
+// reserved requests are taken back when Discord reports fewer remaining requests than expected, and they
+// expire at the reset; the requests are then rate limited as usual.
+func (r *Manager) Reserve(localHash string, n int) *Reservation {
+	_, bucket := r.bucket(localHash)
+	return bucket.reserveN(time.Now(), n)
+}
+
+// CancelReservation gives the requests of the reservation that were not used back to the ltBucket
+func (r *Manager) CancelReservation(res *Reservation) {
+	if res != nil && res.bucket != nil {
+		res.cancel()
+	}
+}
+
+// Reserve holds up to n requests of the rate limit bucket of the endpoint aside, when the RESTBucketManager
+// supports it, such as the default Manager. See Manager.Reserve. It is nil otherwise, which WithReservation
+// and CancelReservation accept.
+func (c *Client) Reserve(method, endpoint string, n int) *Reservation {
+	manager, ok := c.buckets.(interface {
+		Reserve(localHash string, n int) *Reservation
+	})
+	if !ok {
+		return nil
+	}
+	req := &Request{Method: HTTPMethod(method), Endpoint: endpoint}
+	return manager.Reserve(req.HashEndpoint(), n)
+}
+
+// CancelReservation gives the requests of the reservation that were not used back to the rate limit bucket
+func (c *Client) CancelReservation(res *Reservation) {
+	if res != nil && res.bucket != nil {
+		res.cancel()
+	}
+}
@@ -0,0 +1,103 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestManager_Reserve(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "DELETE:/channels/1/messages/{id}"
+	reset := time.Now().Add(time.Minute)
+	respond := func(remaining int) bucketTransaction {
+		return func() (*http.Response, []byte, error) {
+			resp := &http.Response{StatusCode: http.StatusNoContent, Header: make(http.Header)}
+			resp.Header.Set(XRateLimitRemaining, strconv.Itoa(remaining))
+			resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+			resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+			return resp, nil, nil
+		}
+	}
+	send := func(ctx context.Context, remaining int) (err error) {
+		mngr.Bucket(id, func(bucket RESTBucket) {
+			_, _, err = bucket.Transaction(WithMaxRateLimitDelay(ctx, -1), respond(remaining))
+		})
+		return err
+	}
+
+	// the rate limit is not known yet
+	if res := mngr.Reserve(id, 3); res.Granted != 0 || !res.Next.IsZero() {
+		t.Errorf("expected nothing to be reserved before the first response, got %+v", res)
+	}
+	if err := send(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	res := mngr.Reserve(id, 3)
+	if res.Granted != 3 || res.Next.After(time.Now()) {
+		t.Fatalf("expected 3 requests to be reserved, with more left, got %+v", res)
+	}
+	other := mngr.Reserve(id, 3)
+	if other.Granted != 2 || other.Next.Before(time.Now().Add(50*time.Second)) {
+		t.Errorf("expected the last 2 requests to be reserved until the reset, got %+v", other)
+	}
+
+	// requests without the reservation are limited, the reserved ones are not
+	if err := send(context.Background(), 4); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected a request without a reservation to be rate limited, got %v", err)
+	}
+	ctx := WithReservation(context.Background(), res)
+	if err := send(ctx, 4); err != nil {
+		t.Errorf("expected the reserved request to be sent, got %v", err)
+	}
+
+	// discord reports fewer remaining requests than the 3 that are still reserved, which takes 2 back
+	if err := send(ctx, 1); err != nil {
+		t.Errorf("expected the reserved request to be sent, got %v", err)
+	}
+	if err := send(WithReservation(context.Background(), other), 0); err != nil {
+		t.Errorf("expected the last reserved request to be sent, got %v", err)
+	}
+	if err := send(ctx, 0); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected the reservation to be taken back, got %v", err)
+	}
+}
+
+func TestManager_CancelReservation(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "DELETE:/channels/1/messages/{id}"
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.mu.Lock()
+		b.remaining = 5
+		b.resetTime = time.Now().Add(time.Minute)
+		b.discordResetTime = b.resetTime
+		b.mu.Unlock()
+	})
+
+	res := mngr.Reserve(id, 4)
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(WithReservation(context.Background(), res), func() (*http.Response, []byte, error) {
+			resp := &http.Response{StatusCode: http.StatusNoContent, Header: make(http.Header)}
+			resp.Header, _ = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+			return resp, nil, nil
+		})
+	})
+	mngr.CancelReservation(res)
+	mngr.CancelReservation(res)
+
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+		if b.remaining != 4 || b.reserved != 0 {
+			t.Errorf("expected the 3 unused requests to be given back once, got %d remaining and %d reserved", b.remaining, b.reserved)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package httd
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// DefaultRetryPolicy sends a request up to three times when Discord fails with a 5xx status code or the
+// connection has a temporary error.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   250 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.2,
+}
+
+// RetryPolicy decides how often, and how long to wait before, a failed request is sent again. Only
+// idempotent requests are retried, see Request.Idempotent, and requests with a body that can not be read
+// twice are never retried, see Request.BodyFactory. Rate limited requests are handled separately, see
+// Config.MaxRateLimitRetries. Every attempt waits for the rate limit bucket again.
+//
+// Config.RetryPolicy is used unless a request has its own, see Request.RetryPolicy and WithRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent. Use 1 to disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the wait before the first retry, and is doubled for every retry after that.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between two attempts.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction, in the range [0, 1], that the wait is randomly shortened by. It spreads out
+	// the retries of requests that failed at the same time.
+	Jitter float64
+
+	// ShouldRetry decides if a failed attempt is sent again. status is zero when err is a transport error.
+	// By default 500, 502, 503 and 504 responses and temporary transport errors are retried.
+	ShouldRetry func(status int, err error) bool
+
+	// Backoff is the wait before sending the request again, after the given number of failed attempts. It
+	// replaces BaseDelay, MaxDelay and Jitter.
+	Backoff func(attempt int) time.Duration
+}
+
+// withDefaults fills in MaxAttempts, BaseDelay and MaxDelay from the client policy when they are zero
+func (p RetryPolicy) withDefaults(client RetryPolicy) RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = client.MaxAttempts
+	}
+	if p.BaseDelay == 0 && p.MaxDelay == 0 {
+		p.BaseDelay, p.MaxDelay = client.BaseDelay, client.MaxDelay
+	}
+	return p
+}
+
+// shouldRetry checks if the status code or the transport error of an attempt might go away
+func (p *RetryPolicy) shouldRetry(statusCode int, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(statusCode, err)
+	}
+	if err != nil {
+		return temporary(err)
+	}
+	return retryStatusCodes[statusCode]
+}
+
+// delay is the wait before sending the request again, after the given number of failed attempts
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	if p.Backoff != nil {
+		return p.Backoff(attempt)
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context that makes Client.Do and Client.DoStream use the given policy instead of
+// Config.RetryPolicy. A Request.RetryPolicy takes precedence.
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// requestRetryPolicy picks the policy of the request, the context or the client, in that order
+func (c *Client) requestRetryPolicy(ctx context.Context, r *Request) RetryPolicy {
+	if r.RetryPolicy != nil {
+		return r.RetryPolicy.withDefaults(c.retryPolicy)
+	}
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy.withDefaults(c.retryPolicy)
+	}
+	return c.retryPolicy
+}
@@ -0,0 +1,87 @@
+package httd
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// QueueLimits bounds the requests waiting for a ltBucket, such that requests to a hot route fail right away
+// under sustained load instead of piling up, see Manager.SetQueueLimits. Zero values are unlimited.
+type QueueLimits struct {
+	// MaxQueued is how many requests may wait for a ltBucket
+	MaxQueued int
+
+	// MaxWait is how long a new request may be estimated to wait for the requests in front of it to be sent
+	MaxWait time.Duration
+}
+
+// queueWait estimates how long a request waits when ahead requests are queued in front of it: until the
+// remaining requests are used up, and then a window for every limit requests. Windows that are not known
+// are not included. The ltBucket must be locked.
+func (b *ltBucket) queueWait(now time.Time, ahead int) time.Duration {
+	limit, window := b.simulatedWindow()
+	remaining, reset := b.remaining, b.resetTime
+	if !reset.After(now) {
+		// the next request starts a new window
+		remaining, reset = limit, now.Add(window)
+	}
+	if remaining < 0 || ahead < remaining {
+		return 0
+	}
+	wait := reset.Sub(now)
+	if limit > 0 && window > 0 {
+		wait += time.Duration((ahead-remaining)/limit) * window
+	}
+	return wait
+}
+
+// shed rejects a new request when the queue of the ltBucket is full, or would take longer than allowed to
+// drain. The limits are checked before the request is queued, so concurrent requests can exceed them by a few.
+func (b *ltBucket) shed(now time.Time) error {
+	b.mu.RLock()
+	limits := b.queueLimits
+	b.mu.RUnlock()
+	if limits.MaxQueued <= 0 && limits.MaxWait <= 0 {
+		return nil
+	}
+
+	ahead := b.queue.Len()
+	b.mu.RLock()
+	wait := b.queueWait(now, ahead)
+	hash := b.hash
+	b.mu.RUnlock()
+	if global := b.global; global != nil && global != b {
+		if globalWait := global.exhaustedFor(now); globalWait > wait {
+			wait = globalWait
+		}
+	}
+
+	full := limits.MaxQueued > 0 && ahead >= limits.MaxQueued
+	if !full && (limits.MaxWait <= 0 || wait <= limits.MaxWait) {
+		return nil
+	}
+	atomic.AddInt64(&b.shedRequests, 1)
+	return &RateLimitError{RetryAfter: wait, Bucket: hash}
+}
+
+// SetQueueLimits bounds the requests waiting for each ltBucket. Requests beyond the limits fail right away
+// with a RateLimitError, such that the caller can shed load. Defaults to no limits. The global ltBucket is
+// not limited.
+func (r *Manager) SetQueueLimits(limits QueueLimits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queueLimits = limits
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		for _, bucket := range s.buckets {
+			if bucket == r.global {
+				continue
+			}
+			bucket.mu.Lock()
+			bucket.queueLimits = limits
+			bucket.mu.Unlock()
+		}
+		s.mu.RUnlock()
+	}
+}
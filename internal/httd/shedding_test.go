@@ -0,0 +1,128 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLtBucket_QueueWait(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		remaining int
+		reset     time.Duration
+		ahead     int
+		wait      time.Duration
+	}{
+		{3, time.Second, 2, 0},
+		{3, time.Second, 3, time.Second},
+		{0, time.Second, 4, time.Second},
+		{0, time.Second, 5, time.Second + 5*time.Second},
+		{0, time.Second, 12, time.Second + 10*time.Second},
+		{-1, time.Second, 12, 0},
+		// the reset has passed, and the next request starts a new window
+		{0, -time.Second, 4, 0},
+		{0, -time.Second, 5, 5 * time.Second},
+	}
+	for _, test := range tests {
+		b := newLeakyBucket(nil)
+		b.limit = 5
+		b.remaining = test.remaining
+		b.resetTime = now.Add(test.reset)
+		b.discordResetTime = b.resetTime
+		b.window.observe(5 * time.Second)
+		if wait := b.queueWait(now, test.ahead); wait != test.wait {
+			t.Errorf("expected %d requests ahead with %d remaining to wait %s, got %s", test.ahead, test.remaining, test.wait, wait)
+		}
+	}
+}
+
+func TestManager_SetQueueLimits(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "POST:/channels/1/messages"
+	exhaust := func(bucket RESTBucket) {
+		b := bucket.(*ltBucket)
+		b.mu.Lock()
+		b.limit = 5
+		b.remaining = 0
+		b.resetTime = time.Now().Add(time.Minute)
+		b.discordResetTime = b.resetTime
+		b.mu.Unlock()
+	}
+	mngr.Bucket(id, exhaust)
+
+	// no limits by default
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		if err := bucket.(*ltBucket).shed(time.Now()); err != nil {
+			t.Errorf("expected no queue limits, got %v", err)
+		}
+	})
+
+	mngr.SetQueueLimits(QueueLimits{MaxWait: 10 * time.Second})
+	var sent bool
+	start := time.Now()
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		_, _, err := bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			sent = true
+			return nil, nil, nil
+		})
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) || !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected the request to be shed, got %v", err)
+		}
+		if rateLimitErr.RetryAfter < 50*time.Second {
+			t.Errorf("expected the estimated wait, got %s", rateLimitErr.RetryAfter)
+		}
+	})
+	if sent || time.Since(start) > time.Second {
+		t.Error("expected the request to fail right away")
+	}
+
+	// new buckets get the limits as well
+	mngr.Bucket("POST:/channels/2/messages", func(bucket RESTBucket) {
+		exhaust(bucket)
+		if err := bucket.(*ltBucket).shed(time.Now()); err == nil {
+			t.Error("expected a new ltBucket to be limited")
+		}
+	})
+
+	if stats := mngr.Stats(); stats.Shed != 2 {
+		t.Errorf("expected 2 shed requests, got %d", stats.Shed)
+	}
+}
+
+func TestLtBucket_MaxQueued(t *testing.T) {
+	bucket := newLeakyBucket(nil)
+	bucket.queueLimits = QueueLimits{MaxQueued: 2}
+	bucket.remaining = 0
+	bucket.resetTime = time.Now().Add(200 * time.Millisecond)
+	bucket.discordResetTime = bucket.resetTime
+
+	// one request waits for the reset while holding the ltBucket, two wait in the queue
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, _, err := bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+				return nil, nil, nil
+			})
+			results <- err
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for bucket.queue.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := bucket.Acquire(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected the request to be shed when the queue is full, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("expected the queued requests to be sent, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package httd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClientClosed is returned for requests sent after Client.Shutdown was called
+var ErrClientClosed = errors.New("httd: client is shut down")
+
+// lifecycle tracks the requests in flight, such that Shutdown can wait for them
+type lifecycle struct {
+	sync.Mutex
+	closed  bool
+	active  int
+	drained chan struct{}
+}
+
+func (c *Client) begin() error {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	if c.lifecycle.closed {
+		return ErrClientClosed
+	}
+	c.lifecycle.active++
+	return nil
+}
+
+func (c *Client) end() {
+	c.lifecycle.Lock()
+	defer c.lifecycle.Unlock()
+	c.lifecycle.active--
+	if c.lifecycle.closed && c.lifecycle.active == 0 {
+		close(c.lifecycle.drained)
+	}
+}
+
+// Shutdown stops the client from accepting new requests, which fail with ErrClientClosed, and waits for the
+// requests in flight to finish, including the ones waiting for a rate limit to reset. Streamed bodies count
+// as in flight until they are closed. Idle connections are closed once every request is done, and the eviction
+// of unused rate limit buckets is stopped right away, see Config.BucketTTL.
+//
+// When ctx expires first, its error is returned and the remaining requests are left to finish on their own.
+func (c *Client) Shutdown(ctx context.Context) error {
+	if c.ownedManager != nil {
+		c.ownedManager.Stop()
+	}
+
+	c.lifecycle.Lock()
+	if !c.lifecycle.closed {
+		c.lifecycle.closed = true
+		c.lifecycle.drained = make(chan struct{})
+		if c.lifecycle.active == 0 {
+			close(c.lifecycle.drained)
+		}
+	}
+	drained := c.lifecycle.drained
+	c.lifecycle.Unlock()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if closer, ok := c.httpClient.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+	return nil
+}
+
+// endOnClose calls end once the body is closed, such as to mark a streamed request as done
+type endOnClose struct {
+	io.ReadCloser
+	once sync.Once
+	end  func()
+}
+
+func (b *endOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.end)
+	return err
+}
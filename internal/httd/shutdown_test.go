@@ -0,0 +1,124 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Shutdown(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v6/slow" {
+			received <- struct{}{}
+			<-release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, _, err := client.Do(context.Background(), &Request{Endpoint: "/slow"})
+		inflight <- err
+	}()
+	<-received
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := client.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded while a request is in flight, got %v", err)
+		}
+	})
+
+	t.Run("rejects new requests", func(t *testing.T) {
+		if _, _, err := client.Do(context.Background(), &Request{Endpoint: "/fast"}); !errors.Is(err, ErrClientClosed) {
+			t.Errorf("expected ErrClientClosed, got %v", err)
+		}
+		if _, _, err := client.DoStream(context.Background(), &Request{Endpoint: "/fast"}); !errors.Is(err, ErrClientClosed) {
+			t.Errorf("expected ErrClientClosed for streams, got %v", err)
+		}
+	})
+
+	t.Run("drains", func(t *testing.T) {
+		done := make(chan error, 1)
+		go func() {
+			done <- client.Shutdown(context.Background())
+		}()
+
+		select {
+		case err := <-done:
+			t.Fatalf("shutdown returned before the request finished: %v", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+		if err := <-inflight; err != nil {
+			t.Errorf("the request in flight failed: %s", err)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("unexpected shutdown error: %s", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("shutdown did not return after the request finished")
+		}
+	})
+}
+
+func TestClient_ShutdownStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, body, err := client.DoStream(context.Background(), &Request{Endpoint: "/file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected an open stream to hold up the shutdown, got %v", err)
+	}
+
+	_ = body.Close()
+	_ = body.Close()
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error after the stream was closed: %s", err)
+	}
+}
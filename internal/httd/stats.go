@@ -0,0 +1,179 @@
+package httd
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StatsContendedBuckets is the number of buckets listed in RateLimitStats.Contended
+const StatsContendedBuckets = 10
+
+// RateLimitStats is a snapshot of the rate limit buckets, see Manager.Stats. It can be marshalled to JSON,
+// such as for a debug endpoint.
+type RateLimitStats struct {
+	// Buckets is the number of buckets, excluding the global bucket
+	Buckets int `json:"buckets"`
+
+	// Limited is the number of buckets that have no requests left until they reset
+	Limited int `json:"limited"`
+
+	// Groups counts the buckets per discord bucket hash. Endpoints that share a hash have a bucket per
+	// guild, channel or webhook. Buckets of endpoints without a known hash are not included.
+	Groups map[string]int `json:"groups"`
+
+	// Contended are the buckets where requests have waited the longest, longest first
+	Contended []BucketStats `json:"contended"`
+
+	Global BucketStats `json:"global"`
+
+	// GlobalUtilization is how much of Config.GlobalRateLimit is used, from 0 to 1. It is only known by the
+	// Client, see Client.GlobalUtilization.
+	GlobalUtilization float64 `json:"global_utilization"`
+
+	// Waited is the total time requests have waited for the buckets, excluding the global bucket
+	Waited time.Duration `json:"waited_ns"`
+
+	// RateLimits is how often the buckets ran out of requests before their reset, excluding the global
+	// bucket. Evicted buckets are not counted.
+	RateLimits int64 `json:"rate_limits"`
+
+	// Shed is the number of requests that were rejected by the queue limits of the buckets, see
+	// Manager.SetQueueLimits. Evicted buckets are not counted.
+	Shed int64 `json:"shed"`
+
+	// ClockDrift is how far the local clock is estimated to be ahead of the discord clock, which is added to
+	// the bucket resets. It includes up to a second from the rounded Date header.
+	ClockDrift time.Duration `json:"clock_drift_ns"`
+
+	// InvalidRequests is the number of 401, 403, 404 and 429 responses within InvalidRequestWindow. It is
+	// only known by the Client, see Client.RateLimitStats.
+	InvalidRequests      int           `json:"invalid_requests"`
+	InvalidRequestWindow time.Duration `json:"invalid_request_window_ns"`
+}
+
+// BucketStats describes a single rate limit bucket
+type BucketStats struct {
+	// Key identifies the bucket, as the discord bucket hash followed by the major parameter, or the hashed
+	// endpoint while the discord hash is not known
+	Key  string `json:"key"`
+	Hash string `json:"hash,omitempty"`
+
+	// Remaining is -1 until the first response, and Limit is 0 until discord has told it
+	Remaining int       `json:"remaining"`
+	Limit     int       `json:"limit"`
+	Reset     time.Time `json:"reset"`
+	Limited   bool      `json:"limited"`
+
+	// Queued is the number of requests waiting for the bucket
+	Queued int `json:"queued"`
+
+	// Waited is the total time requests have waited for the bucket
+	Waited time.Duration `json:"waited_ns"`
+
+	// RateLimits is how often the bucket ran out of requests before the reset
+	RateLimits int64 `json:"rate_limits"`
+
+	// Shed is the number of requests that were rejected because the queue of the bucket was full
+	Shed int64 `json:"shed"`
+
+	// Window is how long the bucket is limited once the reset has passed before a response tells the next
+	// one, from the recently observed rate limit windows. It is zero when requests are not paced until then.
+	Window time.Duration `json:"window_ns"`
+}
+
+func (b *ltBucket) stats(key string) BucketStats {
+	b.mu.RLock()
+	stats := BucketStats{
+		Key:       key,
+		Hash:      b.hash,
+		Remaining: b.remaining,
+		Limit:     b.limit,
+		Reset:     b.resetTime,
+		Limited:   b.remaining == 0 && b.resetTime.After(time.Now()),
+	}
+	_, stats.Window = b.simulatedWindow()
+	b.mu.RUnlock()
+
+	stats.Queued = b.queue.Len()
+	stats.Waited = time.Duration(atomic.LoadInt64(&b.waited))
+	stats.RateLimits = atomic.LoadInt64(&b.rateLimits)
+	stats.Shed = atomic.LoadInt64(&b.shedRequests)
+	return stats
+}
+
+// Stats returns a snapshot of the buckets. A shard of the manager is only locked while its buckets are listed,
+// and every bucket is read on its own, such that requests are not held up.
+func (r *Manager) Stats() RateLimitStats {
+	keys := make(map[*ltBucket]string)
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		for key, bucket := range s.buckets {
+			if bucket == r.global {
+				continue
+			}
+			// merged buckets are found under the old keys as well, prefer the key of the discord hash
+			previous, ok := keys[bucket]
+			if !ok {
+				keys[bucket] = key
+				continue
+			}
+			bucket.mu.RLock()
+			hash := bucket.hash
+			bucket.mu.RUnlock()
+			if hash != "" && !strings.HasPrefix(previous, hash) {
+				keys[bucket] = key
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	stats := RateLimitStats{
+		Groups:     make(map[string]int),
+		Contended:  make([]BucketStats, 0, len(keys)),
+		Global:     r.global.stats(GlobalHash),
+		ClockDrift: r.global.drift.estimate(),
+	}
+	for bucket, key := range keys {
+		bucketStats := bucket.stats(key)
+		stats.Buckets++
+		stats.Waited += bucketStats.Waited
+		stats.RateLimits += bucketStats.RateLimits
+		stats.Shed += bucketStats.Shed
+		if bucketStats.Limited {
+			stats.Limited++
+		}
+		if bucketStats.Hash != "" {
+			stats.Groups[bucketStats.Hash]++
+		}
+		if bucketStats.Waited > 0 || bucketStats.Queued > 0 {
+			stats.Contended = append(stats.Contended, bucketStats)
+		}
+	}
+
+	sort.Slice(stats.Contended, func(i, j int) bool {
+		a, b := stats.Contended[i], stats.Contended[j]
+		if a.Waited != b.Waited {
+			return a.Waited > b.Waited
+		}
+		return a.Key < b.Key
+	})
+	if len(stats.Contended) > StatsContendedBuckets {
+		stats.Contended = stats.Contended[:StatsContendedBuckets]
+	}
+	return stats
+}
+
+// RateLimitStats returns a snapshot of the rate limit buckets, when the RESTBucketManager supports it, such
+// as the default Manager, the utilization of the global throttle and the number of invalid requests
+func (c *Client) RateLimitStats() (stats RateLimitStats) {
+	if manager, ok := c.buckets.(interface{ Stats() RateLimitStats }); ok {
+		stats = manager.Stats()
+	}
+	stats.GlobalUtilization = c.throttle.utilization()
+	stats.InvalidRequests = c.invalidRequests.count()
+	stats.InvalidRequestWindow = InvalidRequestWindow
+	return stats
+}
@@ -0,0 +1,78 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/json"
+)
+
+func TestManager_Stats(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+	send := func(mngr *Manager, id string, remaining int) {
+		mngr.Bucket(id, func(bucket RESTBucket) {
+			_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+				resp := &http.Response{Header: make(http.Header), StatusCode: http.StatusOK}
+				resp.Header.Set(XRateLimitBucket, "abcd1234")
+				resp.Header.Set(XRateLimitLimit, "5")
+				resp.Header.Set(XRateLimitRemaining, strconv.Itoa(remaining))
+				resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+				resp.Header.Set("date", time.Now().UTC().Format(time.RFC1123))
+
+				var err error
+				resp.Header, err = NormalizeDiscordHeader(testAPIVersion, resp.StatusCode, resp.Header, nil)
+				return resp, nil, err
+			})
+		})
+	}
+
+	mngr := NewManager(nil)
+	send(mngr, "GET:/channels/1/messages", 4)
+	send(mngr, "DELETE:/channels/1/messages/{id}", 3)
+	send(mngr, "GET:/channels/2/messages", 0)
+
+	stats := mngr.Stats()
+	if stats.Buckets != 2 || stats.Groups["abcd1234"] != 2 {
+		t.Errorf("expected 2 buckets of one hash, got %d buckets and groups %v", stats.Buckets, stats.Groups)
+	}
+	if stats.Limited != 1 {
+		t.Errorf("expected 1 limited bucket, got %d", stats.Limited)
+	}
+	if stats.RateLimits != 1 {
+		t.Errorf("expected the limited bucket to be counted once, got %d", stats.RateLimits)
+	}
+	if len(stats.Contended) != 2 || stats.Contended[0].Waited < stats.Contended[1].Waited {
+		t.Fatalf("expected the buckets to be sorted by their wait, got %+v", stats.Contended)
+	}
+	for _, bucket := range stats.Contended {
+		switch bucket.Key {
+		case "abcd1234:channels/1":
+			if bucket.Remaining != 3 || bucket.Limit != 5 || bucket.Limited {
+				t.Errorf("unexpected stats for channel 1: %+v", bucket)
+			}
+		case "abcd1234:channels/2":
+			if bucket.Remaining != 0 || !bucket.Limited || !bucket.Reset.After(time.Now()) {
+				t.Errorf("unexpected stats for channel 2: %+v", bucket)
+			}
+		default:
+			t.Errorf("unexpected bucket key %q", bucket.Key)
+		}
+	}
+	if stats.Global.Key != GlobalHash || stats.Global.Limited {
+		t.Errorf("unexpected global bucket: %+v", stats.Global)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded RateLimitStats
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.Buckets != 2 {
+		t.Errorf("expected the stats to survive a JSON round trip, got %+v, %v", decoded, err)
+	}
+}
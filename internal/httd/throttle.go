@@ -0,0 +1,124 @@
+package httd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultGlobalRateLimit is the number of requests per second Discord allows a bot token across every route,
+// see Config.GlobalRateLimit
+const DefaultGlobalRateLimit = 50
+
+// globalThrottle keeps the requests below the global rate limit, before Discord responds with a global 429.
+// It is a token bucket that holds up to limit tokens, and refills limit tokens per second, such that requests
+// are spread out instead of bursting at the start of every second. A request that finds no token reserves the
+// next one, and waits until it is refilled.
+//
+// The tokens are kept as the time at which the bucket is full again, such that a token is taken with a single
+// compare-and-swap and concurrent requests never lock each other out.
+type globalThrottle struct {
+	full     int64 // unix nano at which every token is refilled, accessed atomically
+	interval int64 // nanoseconds to refill a single token
+	burst    int64 // nanoseconds to refill every token
+}
+
+func newGlobalThrottle(perSecond int) *globalThrottle {
+	if perSecond <= 0 {
+		return nil
+	}
+	interval := int64(time.Second) / int64(perSecond)
+	return &globalThrottle{
+		full:     time.Now().UnixNano(),
+		interval: interval,
+		burst:    interval * int64(perSecond),
+	}
+}
+
+// reserve takes a token, and returns how long until it is refilled. When there is no token left, accept decides
+// whether the next one is reserved for a request that waits for it.
+func (t *globalThrottle) reserve(accept func(wait time.Duration) bool) (wait time.Duration, ok bool) {
+	for {
+		now := time.Now().UnixNano()
+		full := atomic.LoadInt64(&t.full)
+		next := full
+		if next < now {
+			next = now
+		}
+		next += t.interval
+
+		if wait = time.Duration(next - now - t.burst); wait < 0 {
+			wait = 0
+		} else if wait > 0 && !accept(wait) {
+			return wait, false
+		}
+		if atomic.CompareAndSwapInt64(&t.full, full, next) {
+			return wait, true
+		}
+	}
+}
+
+// refund gives a token back, for a request that was not sent
+func (t *globalThrottle) refund() {
+	if t != nil {
+		atomic.AddInt64(&t.full, -t.interval)
+	}
+}
+
+// wait takes a token for one request, and waits for it to be refilled when there are none left. Like the
+// buckets, it returns a RateLimitError instead when the wait exceeds the deadline of the context or the
+// max rate limit delay, see WithMaxRateLimitDelay.
+func (t *globalThrottle) wait(ctx context.Context) error {
+	if _, ok := t.take(); ok {
+		return nil
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	within, limited := maxRateLimitDelay(ctx)
+	wait, ok := t.reserve(func(wait time.Duration) bool {
+		return !(limited && wait > within) && !(hasDeadline && deadline.Before(time.Now().Add(wait)))
+	})
+	if !ok {
+		return &RateLimitError{RetryAfter: wait, Global: true, Bucket: GlobalHash}
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	notifyRateLimited(ctx, wait, true, GlobalHash)
+	if !sleep(ctx, wait) {
+		// give the reserved token back to the requests behind this one
+		t.refund()
+		return fmt.Errorf("time out: %w", ctx.Err())
+	}
+	return nil
+}
+
+// take takes a token when there is one, without waiting, and otherwise returns how long until the next one
+// is refilled
+func (t *globalThrottle) take() (wait time.Duration, ok bool) {
+	if t == nil {
+		return 0, true
+	}
+	return t.reserve(func(time.Duration) bool {
+		return false
+	})
+}
+
+// utilization is the part of the tokens that is used, where 1 means that requests are waiting
+func (t *globalThrottle) utilization() float64 {
+	if t == nil {
+		return 0
+	}
+
+	used := float64(atomic.LoadInt64(&t.full)-time.Now().UnixNano()) / float64(t.burst)
+	return math.Max(0, math.Min(1, used))
+}
+
+// GlobalUtilization tells how much of Config.GlobalRateLimit the recent requests have used, from 0 to 1, where
+// 1 means further requests wait for the throttle. It is always 0 when the throttle is disabled.
+func (c *Client) GlobalUtilization() float64 {
+	return c.throttle.utilization()
+}
@@ -0,0 +1,166 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGlobalThrottle(t *testing.T) {
+	throttle := newGlobalThrottle(5)
+	for i := 0; i < 5; i++ {
+		if err := throttle.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if utilization := throttle.utilization(); utilization < 0.95 {
+		t.Errorf("expected the tokens to be used up, got %f", utilization)
+	}
+
+	// a token is refilled every 200ms
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := throttle.wait(ctx)
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || !rateLimitErr.Global {
+		t.Fatalf("expected a global rate limit error, got %v", err)
+	}
+
+	start := time.Now()
+	if err = throttle.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond || waited > time.Second {
+		t.Errorf("expected the request to wait for the next token, waited %s", waited)
+	}
+
+	// tokens are refilled gradually, not all at once
+	time.Sleep(450 * time.Millisecond)
+	if utilization := throttle.utilization(); utilization < 0.4 || utilization > 0.8 {
+		t.Errorf("expected about two tokens to be refilled, got %f", utilization)
+	}
+}
+
+func TestGlobalThrottle_MaxRateLimitDelay(t *testing.T) {
+	throttle := newGlobalThrottle(1)
+	if err := throttle.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"max delay", WithMaxRateLimitDelay(context.Background(), 100*time.Millisecond)},
+		// CancelRequestWhenRateLimited is a max delay of zero
+		{"cancel when rate limited", WithMaxRateLimitDelay(context.Background(), 0)},
+	}
+	for _, test := range table {
+		start := time.Now()
+		err := throttle.wait(test.ctx)
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) || !rateLimitErr.Global || rateLimitErr.RetryAfter < 500*time.Millisecond {
+			t.Errorf("%s: expected a global rate limit error, got %v", test.name, err)
+		}
+		if waited := time.Since(start); waited > 50*time.Millisecond {
+			t.Errorf("%s: expected the request to be rejected right away, waited %s", test.name, waited)
+		}
+	}
+
+	// a rejected request does not take a token
+	if utilization := throttle.utilization(); utilization > 1 {
+		t.Errorf("expected the utilization to be capped, got %f", utilization)
+	}
+}
+
+func TestGlobalThrottle_Cancel(t *testing.T) {
+	throttle := newGlobalThrottle(1)
+	_ = throttle.wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if err := throttle.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to be canceled, got %v", err)
+	}
+
+	if reserved := atomic.LoadInt64(&throttle.full) - time.Now().UnixNano(); reserved > throttle.burst {
+		t.Errorf("expected the reserved token to be given back, the bucket is full in %s", time.Duration(reserved))
+	}
+}
+
+func TestClient_throttleBeforeBucket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+		GlobalRateLimit:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func() error {
+		_, _, err := client.Do(context.Background(), &Request{Endpoint: "/channels/1/messages"})
+		return err
+	}
+	if err = do(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next request waits a second for the global throttle, without holding the bucket
+	done := make(chan error, 1)
+	go func() {
+		done <- do()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	hash := (&Request{Method: MethodGet, Endpoint: "/channels/1/messages"}).HashEndpoint()
+	release, _, ok := client.buckets.(acquirer).TryAcquire(hash)
+	if !ok {
+		t.Fatal("expected the bucket to be free while the request waits for the global throttle")
+	}
+	release(nil)
+
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGlobalThrottle_Concurrent(t *testing.T) {
+	throttle := newGlobalThrottle(1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = throttle.wait(context.Background())
+		}()
+	}
+	wg.Wait()
+	if utilization := throttle.utilization(); utilization < 0.05 || utilization > 0.1 {
+		t.Errorf("expected every request to be counted, got %f", utilization)
+	}
+
+	var disabled *globalThrottle
+	if err := disabled.wait(context.Background()); err != nil || disabled.utilization() != 0 {
+		t.Error("a disabled throttle should let every request through")
+	}
+}
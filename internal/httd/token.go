@@ -0,0 +1,46 @@
+package httd
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// TokenResetURL is where a bot token can be regenerated, under the Bot tab of the application
+const TokenResetURL = "https://discord.com/developers/applications"
+
+// diagnoseToken checks if the token looks like a bot token, which is three dot separated base64 parts where
+// the first one encodes the bot's user ID. The token itself is never part of the returned problem.
+func diagnoseToken(token string) (problem string) {
+	if token == "" {
+		return "the bot token is empty"
+	}
+	if strings.TrimSpace(token) != token {
+		return "the bot token has leading or trailing whitespace"
+	}
+	if strings.HasPrefix(strings.ToLower(token), "bot ") || strings.HasPrefix(strings.ToLower(token), "bearer ") {
+		return "the bot token includes the authorization type, which is added automatically"
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "the bot token does not have three dot separated parts, it might be the client secret or public key instead"
+	}
+	userID, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[0], "="))
+	if err != nil || len(userID) == 0 || strings.Trim(string(userID), "0123456789") != "" {
+		return "the first part of the bot token does not hold a user ID"
+	}
+	return ""
+}
+
+// unauthorizedError explains why a 401 response might have happened
+func unauthorizedError(header http.Header, token string, err *ErrREST) *UnauthorizedError {
+	e := &UnauthorizedError{
+		AuthorizationSent: header.Get(Authorization) != "",
+		Err:               err,
+	}
+	if e.AuthorizationSent {
+		e.TokenProblem = diagnoseToken(token)
+	}
+	return e
+}
@@ -0,0 +1,84 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// not a real token, the first part is the base64 encoded user ID 123456789012345678
+const wellFormedToken = "MTIzNDU2Nzg5MDEyMzQ1Njc4.X4Yf2w.a1B2c3D4e5F6g7H8i9J0k1L2m3N"
+
+func TestDiagnoseToken(t *testing.T) {
+	table := map[string]bool{
+		wellFormedToken:                        true,
+		"":                                     false,
+		" " + wellFormedToken:                  false,
+		"Bot " + wellFormedToken:               false,
+		"bearer " + wellFormedToken:            false,
+		"8f4d1e0c2a7b9e3d5c6f8a1b2c3d4e5f":     false, // client secret
+		"bm90IGEgdXNlciBpZA.X4Yf2w.a1B2c3D4e5": false, // "not a user id"
+		"!!!.X4Yf2w.a1B2c3D4e5":                false,
+	}
+	for token, valid := range table {
+		problem := diagnoseToken(token)
+		if valid != (problem == "") {
+			t.Errorf("token %q: expected valid=%t, got problem %q", token, valid, problem)
+		}
+		if token != "" && strings.Contains(problem, strings.TrimSpace(token)) {
+			t.Errorf("the token is part of the problem description %q", problem)
+		}
+	}
+}
+
+func TestClient_DoUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":0,"message":"401: Unauthorized"}`))
+	}))
+	defer srv.Close()
+
+	for _, token := range []string{"Bot " + wellFormedToken, wellFormedToken} {
+		client, err := NewClient(&Config{
+			APIVersion:         6,
+			BotToken:           token,
+			HTTPClient:         srv.Client(),
+			UserAgentSourceURL: "test",
+			UserAgentVersion:   "test",
+			APIBaseURL:         srv.URL,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, skip := range []bool{false, true} {
+			_, _, err = client.Do(context.Background(), &Request{Endpoint: "/users/@me", SkipAuthorization: skip})
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Fatalf("expected the error to match ErrUnauthorized, got %v", err)
+			}
+			var unauthorized *UnauthorizedError
+			var restErr *ErrREST
+			if !errors.As(err, &unauthorized) || !errors.As(err, &restErr) {
+				t.Fatalf("expected an UnauthorizedError that unwraps to an ErrREST, got %T", err)
+			}
+			if restErr.HTTPCode != http.StatusUnauthorized {
+				t.Errorf("expected http code 401, got %d", restErr.HTTPCode)
+			}
+			if unauthorized.AuthorizationSent == skip {
+				t.Errorf("expected AuthorizationSent to be %t", !skip)
+			}
+			if wellFormed := token == wellFormedToken; !skip && wellFormed != (unauthorized.TokenProblem == "") {
+				t.Errorf("unexpected token problem %q", unauthorized.TokenProblem)
+			}
+			if strings.Contains(err.Error(), wellFormedToken) {
+				t.Error("the token is part of the error message")
+			}
+		}
+	}
+}
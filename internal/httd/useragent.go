@@ -0,0 +1,61 @@
+package httd
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/andersfylling/disgord/internal/constant"
+)
+
+// disgordModulePath is looked up in the build info to find the disgord version that the bot was built with
+const disgordModulePath = "github.com/andersfylling/disgord"
+
+// readBuildInfo is replaced in tests
+var readBuildInfo = debug.ReadBuildInfo
+
+// userAgentDefaults derives the source url and version of the User-Agent header from the build info. The
+// version of this source tree is used when the build info holds no module version, which is the case when
+// disgord is the main module or replaced by a local directory.
+func userAgentDefaults() (sourceURL, version string, ok bool) {
+	info, ok := readBuildInfo()
+	if !ok || info == nil {
+		return "", "", false
+	}
+
+	version = constant.Version
+	modules := append([]*debug.Module{&info.Main}, info.Deps...)
+	for _, module := range modules {
+		if module.Path != disgordModulePath {
+			continue
+		}
+		if module.Replace != nil {
+			module = module.Replace
+		}
+		if module.Version != "" && module.Version != "(devel)" {
+			version = module.Version
+		}
+		break
+	}
+	return "https://" + disgordModulePath, version, true
+}
+
+// buildUserAgent builds the User-Agent header, which Discord requires to be on the form
+// `DiscordBot ($url, $versionNumber)`. Missing fields default to the disgord module found in the build info.
+func buildUserAgent(conf *Config) (string, error) {
+	sourceURL, version := conf.UserAgentSourceURL, conf.UserAgentVersion
+	if sourceURL == "" || version == "" {
+		defaultURL, defaultVersion, ok := userAgentDefaults()
+		if !ok {
+			return "", errors.New("both a source(url) and a version must be present for sending requests to the Discord REST API, and no build info was found to derive them from")
+		}
+		if sourceURL == "" {
+			sourceURL = defaultURL
+		}
+		if version == "" {
+			version = defaultVersion
+		}
+	}
+
+	return fmt.Sprintf(UserAgentFormat, sourceURL, version, conf.UserAgentExtra), nil
+}
@@ -0,0 +1,84 @@
+// +build !integration
+
+package httd
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/andersfylling/disgord/internal/constant"
+)
+
+func TestBuildUserAgent(t *testing.T) {
+	defer func(original func() (*debug.BuildInfo, bool)) {
+		readBuildInfo = original
+	}(readBuildInfo)
+
+	asDependency := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/bot", Version: "(devel)"},
+			Deps: []*debug.Module{
+				{Path: "github.com/andersfylling/snowflake/v4", Version: "v4.0.2"},
+				{Path: "github.com/andersfylling/disgord", Version: "v0.19.1"},
+			},
+		}, true
+	}
+	asMainModule := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{Main: debug.Module{Path: "github.com/andersfylling/disgord", Version: "(devel)"}}, true
+	}
+	asReplaced := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/bot"},
+			Deps: []*debug.Module{
+				{Path: "github.com/andersfylling/disgord", Version: "v0.19.1", Replace: &debug.Module{Path: "../disgord"}},
+			},
+		}, true
+	}
+	unavailable := func() (*debug.BuildInfo, bool) {
+		return nil, false
+	}
+
+	testCases := []struct {
+		name      string
+		buildInfo func() (*debug.BuildInfo, bool)
+		conf      Config
+		userAgent string
+		fails     bool
+	}{
+		{"dependency", asDependency, Config{},
+			"DiscordBot (https://github.com/andersfylling/disgord, v0.19.1) ", false},
+		{"dependency with extra", asDependency, Config{UserAgentExtra: "my-bot"},
+			"DiscordBot (https://github.com/andersfylling/disgord, v0.19.1) my-bot", false},
+		{"main module", asMainModule, Config{},
+			"DiscordBot (https://github.com/andersfylling/disgord, " + constant.Version + ") ", false},
+		{"replaced by a directory", asReplaced, Config{},
+			"DiscordBot (https://github.com/andersfylling/disgord, " + constant.Version + ") ", false},
+		{"override", asDependency, Config{UserAgentSourceURL: "https://example.com", UserAgentVersion: "v1.0.0", UserAgentExtra: "my-bot"},
+			"DiscordBot (https://example.com, v1.0.0) my-bot", false},
+		{"override version", asDependency, Config{UserAgentVersion: "v1.0.0"},
+			"DiscordBot (https://github.com/andersfylling/disgord, v1.0.0) ", false},
+		{"override without build info", unavailable, Config{UserAgentSourceURL: "https://example.com", UserAgentVersion: "v1.0.0"},
+			"DiscordBot (https://example.com, v1.0.0) ", false},
+		{"no build info", unavailable, Config{}, "", true},
+		{"partial override without build info", unavailable, Config{UserAgentVersion: "v1.0.0"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			readBuildInfo = tc.buildInfo
+			userAgent, err := buildUserAgent(&tc.conf)
+			if tc.fails {
+				if err == nil {
+					t.Errorf("expected an error, got user agent %q", userAgent)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if userAgent != tc.userAgent {
+				t.Errorf("expected user agent %q, got %q", tc.userAgent, userAgent)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package httd
+
+import (
+	"math"
+	"time"
+)
+
+// WaitTimeUnknown is the wait time of endpoints whose rate limit is not known, see Manager.WaitTime
+const WaitTimeUnknown = time.Duration(math.MaxInt64)
+
+// WaitTime estimates how long a request to the local endpoint hash would wait for its ltBucket and the global
+// ltBucket if it was sent now. It is zero when the ltBucket has requests left, and WaitTimeUnknown when no
+// request has used the endpoint yet. Requests that are already queued are not included. Nothing is reserved
+// or created, such that it can be called for every pending request, eg. to sort a send queue by readiness.
+func (r *Manager) WaitTime(localHash string) time.Duration {
+	s := &r.shards[r.proxyShard(localHash)]
+	s.mu.RLock()
+	pID, ok := s.proxy[localHash]
+	s.mu.RUnlock()
+	if !ok {
+		return WaitTimeUnknown
+	}
+	bucket := r.lookupBucket(localHash, pID)
+	if bucket == nil {
+		return WaitTimeUnknown
+	}
+
+	now := time.Now()
+	wait := bucket.exhaustedFor(now)
+	if global := r.global.exhaustedFor(now); global > wait {
+		wait = global
+	}
+	return wait
+}
+
+// WaitTime estimates how long a request to the endpoint would wait for the rate limits if it was sent now,
+// when the RESTBucketManager supports it, such as the default Manager. See Manager.WaitTime.
+func (c *Client) WaitTime(method, endpoint string) time.Duration {
+	manager, ok := c.buckets.(interface {
+		WaitTime(localHash string) time.Duration
+	})
+	if !ok {
+		return WaitTimeUnknown
+	}
+	req := &Request{Method: HTTPMethod(method), Endpoint: endpoint}
+	return manager.WaitTime(req.HashEndpoint())
+}
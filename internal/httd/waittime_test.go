@@ -0,0 +1,98 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestManager_WaitTime(t *testing.T) {
+	mngr := NewManager(nil)
+	id := "POST:/channels/1/messages"
+	if wait := mngr.WaitTime(id); wait != WaitTimeUnknown {
+		t.Errorf("expected an unused endpoint to be unknown, got %s", wait)
+	}
+	if buckets, endpoints := managerSize(mngr); buckets != 0 || endpoints != 0 {
+		t.Error("WaitTime must not create buckets")
+	}
+
+	reset := time.Now().Add(time.Minute)
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+			resp.Header.Set(XRateLimitRemaining, "1")
+			resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.UnixNano()/int64(time.Millisecond), 10))
+			resp.Header.Set(DisgordNormalizedHeader, "true")
+			return resp, nil, nil
+		})
+	})
+	for i := 0; i < 3; i++ {
+		if wait := mngr.WaitTime(id); wait != 0 {
+			t.Fatalf("expected a request to be left, got a wait of %s", wait)
+		}
+	}
+
+	// the last request is taken
+	mngr.Bucket(id, func(bucket RESTBucket) {
+		_, _, _ = bucket.Transaction(context.Background(), func() (*http.Response, []byte, error) {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+			resp.Header.Set(XRateLimitRemaining, "0")
+			resp.Header.Set(XRateLimitReset, strconv.FormatInt(reset.UnixNano()/int64(time.Millisecond), 10))
+			resp.Header.Set(DisgordNormalizedHeader, "true")
+			return resp, nil, nil
+		})
+	})
+	if wait := mngr.WaitTime(id); wait < 50*time.Second || wait > time.Minute {
+		t.Errorf("expected to wait for the reset, got %s", wait)
+	}
+	if wait := mngr.WaitTime("POST:/channels/2/messages"); wait != WaitTimeUnknown {
+		t.Errorf("expected other channels to be unknown, got %s", wait)
+	}
+
+	// the global rate limit is longer
+	mngr.global.mu.Lock()
+	mngr.global.remaining = 0
+	mngr.global.resetTime = time.Now().Add(time.Hour)
+	mngr.global.mu.Unlock()
+	if wait := mngr.WaitTime(id); wait < 59*time.Minute {
+		t.Errorf("expected to wait for the global rate limit, got %s", wait)
+	}
+}
+
+func TestClient_WaitTime(t *testing.T) {
+	reset := time.Now().Add(time.Minute)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(XRateLimitRemaining, "0")
+		w.Header().Set(XRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(&Config{
+		APIVersion:         6,
+		BotToken:           "sdfgsdfg",
+		HTTPClient:         srv.Client(),
+		UserAgentSourceURL: "test",
+		UserAgentVersion:   "test",
+		APIBaseURL:         srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := "/channels/486833611564253186/messages/540519319814275089"
+	if wait := client.WaitTime(http.MethodGet, endpoint); wait != WaitTimeUnknown {
+		t.Errorf("expected the wait time to be unknown, got %s", wait)
+	}
+	if _, _, err = client.Do(context.Background(), &Request{Method: MethodGet, Endpoint: endpoint}); err != nil {
+		t.Fatal(err)
+	}
+	if wait := client.WaitTime(http.MethodGet, "/channels/486833611564253186/messages/540519296640614416"); wait <= 0 || wait == WaitTimeUnknown {
+		t.Errorf("expected other messages of the channel to wait for the reset, got %s", wait)
+	}
+}
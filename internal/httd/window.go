@@ -0,0 +1,43 @@
+package httd
+
+import (
+	"sort"
+	"time"
+)
+
+// windowSamples is the number of observed rate limit windows that the simulated window is the median of
+const windowSamples = 5
+
+// resetWindow tracks how long the rate limit windows of a ltBucket last, to simulate the next reset when the
+// previous one has passed before a response tells it. The median of the latest windows is used, such that a
+// single outlier, such as a response delayed by a proxy, does not slow the ltBucket down for good. It is
+// guarded by the mutex of the ltBucket.
+type resetWindow struct {
+	samples [windowSamples]time.Duration
+	count   int
+	next    int
+}
+
+func (w *resetWindow) observe(window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	w.samples[w.next] = window
+	w.next = (w.next + 1) % windowSamples
+	if w.count < windowSamples {
+		w.count++
+	}
+}
+
+// estimate is the median of the latest windows, or zero before the first one is observed
+func (w *resetWindow) estimate() time.Duration {
+	if w.count == 0 {
+		return 0
+	}
+	samples := make([]time.Duration, w.count)
+	copy(samples, w.samples[:w.count])
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+	return samples[w.count/2]
+}
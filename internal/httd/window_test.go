@@ -0,0 +1,73 @@
+// +build !integration
+
+package httd
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResetWindow(t *testing.T) {
+	var w resetWindow
+	if estimate := w.estimate(); estimate != 0 {
+		t.Errorf("expected no window before the first sample, got %s", estimate)
+	}
+
+	w.observe(time.Second)
+	w.observe(time.Second)
+	w.observe(time.Minute)
+	if estimate := w.estimate(); estimate != time.Second {
+		t.Errorf("expected the outlier to be ignored, got %s", estimate)
+	}
+
+	// the latest windows replace the older ones
+	for i := 0; i < windowSamples; i++ {
+		w.observe(2 * time.Second)
+	}
+	if estimate := w.estimate(); estimate != 2*time.Second {
+		t.Errorf("expected the estimate to follow the latest windows, got %s", estimate)
+	}
+}
+
+func TestLtBucket_SimulatedReset(t *testing.T) {
+	bucket := newLeakyBucket(newLeakyBucket(nil))
+
+	// the first request of every window tells how long it lasts, one of them was delayed by a proxy
+	windows := []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 5 * time.Second, 50 * time.Millisecond}
+	for i, window := range windows {
+		header := http.Header{}
+		header.Set(XRateLimitLimit, "2")
+		header.Set(XRateLimitRemaining, "1")
+		reset := time.Now().Add(window).Add(time.Duration(i) * time.Millisecond)
+		header.Set(XRateLimitReset, strconv.FormatInt(reset.UnixNano()/int64(time.Millisecond), 10))
+		header.Set(DisgordNormalizedHeader, "true")
+		bucket.updateAfterRequest(header, http.StatusOK)
+	}
+	if stats := bucket.stats("test"); stats.Window < 40*time.Millisecond || stats.Window > 60*time.Millisecond {
+		t.Errorf("expected a window of about 50ms, got %s", stats.Window)
+	}
+
+	// the reset passes before the next response
+	bucket.mu.Lock()
+	bucket.resetTime = time.Now().Add(-time.Millisecond)
+	bucket.remaining = 0
+	bucket.mu.Unlock()
+
+	noHeaders := func() (*http.Response, []byte, error) {
+		resp := &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{DisgordNormalizedHeader: []string{"true"}}}
+		return resp, nil, nil
+	}
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, _, err := bucket.Transaction(context.Background(), noHeaders); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// two requests per window
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected the requests to be paced by the usual window, they took %s", elapsed)
+	}
+}
@@ -10,13 +10,27 @@ const (
 	NoTicket Ticket = -1
 )
 
+type queuedTicket struct {
+	ticket   Ticket
+	priority int
+	ready    chan struct{}
+}
+
+// TicketQueue serves tickets in the order they were created, except that tickets of a higher priority are
+// served before those of a lower priority. Waiting tickets are woken through Ready when they might be served,
+// such that only the ticket in front competes for the resource.
 type TicketQueue struct {
 	mu         sync.Mutex
-	tickets    []Ticket
+	tickets    []queuedTicket
 	nextTicket Ticket
 }
 
 func (q *TicketQueue) NewTicket() (ticket Ticket) {
+	return q.NewPriorityTicket(0)
+}
+
+// NewPriorityTicket queues a ticket behind every ticket of the same or a higher priority
+func (q *TicketQueue) NewPriorityTicket(priority int) (ticket Ticket) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	defer func() {
@@ -24,31 +38,77 @@ func (q *TicketQueue) NewTicket() (ticket Ticket) {
 	}()
 
 	ticket = q.nextTicket
-	q.tickets = append(q.tickets, ticket)
+	i := len(q.tickets)
+	for i > 0 && q.tickets[i-1].priority < priority {
+		i--
+	}
+	q.tickets = append(q.tickets, queuedTicket{})
+	copy(q.tickets[i+1:], q.tickets[i:])
+	q.tickets[i] = queuedTicket{ticket: ticket, priority: priority, ready: make(chan struct{}, 1)}
+	if i == 0 {
+		q.notifyLocked()
+	}
 
 	return ticket
 }
 
-func (q *TicketQueue) Delete(ticket Ticket) {
+// Ready returns a channel that receives when the ticket might be served, either because it reached the front
+// of the queue or because Notify was called while it was in front. It is nil for unknown tickets.
+func (q *TicketQueue) Ready(ticket Ticket) <-chan struct{} {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	var i int
-	var ok bool
-	for i = range q.tickets {
-		if q.tickets[i] == ticket {
-			ok = true
-			break
+	for i := range q.tickets {
+		if q.tickets[i].ticket == ticket {
+			return q.tickets[i].ready
 		}
 	}
-	if !ok {
+	return nil
+}
+
+// IsNext checks if the ticket is in front of the queue
+func (q *TicketQueue) IsNext(ticket Ticket) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tickets) > 0 && q.tickets[0].ticket == ticket
+}
+
+// Notify wakes the ticket in front of the queue, such as when the resource it waits for is released
+func (q *TicketQueue) Notify() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notifyLocked()
+}
+
+func (q *TicketQueue) notifyLocked() {
+	if len(q.tickets) == 0 {
 		return
 	}
+	select {
+	case q.tickets[0].ready <- struct{}{}:
+	default:
+	}
+}
 
-	if i == len(q.tickets)-1 {
-		q.tickets = q.tickets[:i]
-	} else {
-		q.tickets = append(q.tickets[i:], q.tickets[i+1:]...)
+// Len is the number of tickets waiting to be served
+func (q *TicketQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tickets)
+}
+
+func (q *TicketQueue) Delete(ticket Ticket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.tickets {
+		if q.tickets[i].ticket == ticket {
+			q.tickets = append(q.tickets[:i], q.tickets[i+1:]...)
+			if i == 0 {
+				q.notifyLocked()
+			}
+			return
+		}
 	}
 }
 
@@ -60,7 +120,7 @@ func (q *TicketQueue) Next(ticket Ticket, cb func() bool) bool {
 		return false
 	}
 
-	if q.tickets[0] != ticket {
+	if q.tickets[0].ticket != ticket {
 		return false
 	}
 
@@ -0,0 +1,42 @@
+// +build !integration
+
+package util
+
+import (
+	"testing"
+)
+
+func TestTicketQueue_Ready(t *testing.T) {
+	q := TicketQueue{}
+	first := q.NewTicket()
+	second := q.NewTicket()
+
+	select {
+	case <-q.Ready(first):
+	default:
+		t.Error("expected the ticket in front to be ready")
+	}
+	select {
+	case <-q.Ready(second):
+		t.Error("the second ticket must wait for the first")
+	default:
+	}
+
+	if !q.Next(first, func() bool { return true }) {
+		t.Fatal("expected the first ticket to be served")
+	}
+	q.Notify()
+	select {
+	case <-q.Ready(second):
+	default:
+		t.Error("expected the second ticket to be woken")
+	}
+	if !q.IsNext(second) {
+		t.Error("expected the second ticket to be next")
+	}
+
+	q.Delete(second)
+	if q.Ready(second) != nil {
+		t.Error("deleted tickets have no ready channel")
+	}
+}
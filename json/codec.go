@@ -0,0 +1,62 @@
+package json
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Codec marshals and unmarshals JSON, see SetCodec
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StdCodec is the default Codec, which uses encoding/json
+type StdCodec struct{}
+
+var _ Codec = StdCodec{}
+
+func (StdCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// PackageCodec uses the Marshal and Unmarshal variables of this package, such that a codec set with SetCodec, or
+// functions assigned to the variables, are used. It is the codec of clients that are not given one.
+type PackageCodec struct{}
+
+var _ Codec = PackageCodec{}
+
+func (PackageCodec) Marshal(v interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+func (PackageCodec) Unmarshal(data []byte, v interface{}) error {
+	return Unmarshal(data, v)
+}
+
+// Encode writes the JSON encoding of v, followed by a newline, to w. Unlike Marshal it does not allocate the
+// result, such that REST request bodies can be written to reused buffers.
+var Encode = func(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// SetCodec replaces Marshal, Encode and Unmarshal with the given codec for the whole process. Every REST request
+// and response of clients without their own codec goes through these, including the rate limit and error
+// responses handled by httd. Call it before creating a client. To use a codec for a single client, see
+// disgord.Config.RESTCodec.
+func SetCodec(codec Codec) {
+	Marshal = codec.Marshal
+	Unmarshal = codec.Unmarshal
+	Encode = func(w io.Writer, v interface{}) error {
+		data, err := codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+}
@@ -0,0 +1,53 @@
+package disgord
+
+import "strings"
+
+// zeroWidthSpace is invisible in the Discord client, but breaks up markdown and mention tokens.
+const zeroWidthSpace = "\u200b"
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"|", `\|`,
+	">", `\>`,
+	"`", "\\`",
+)
+
+var mentionEscaper = strings.NewReplacer(
+	"@everyone", "@"+zeroWidthSpace+"everyone",
+	"@here", "@"+zeroWidthSpace+"here",
+	"<@", "<@"+zeroWidthSpace,
+)
+
+// EscapeMarkdown escapes the markdown characters *, _, ~, |, >, ` and \ such that the string is displayed
+// as written. Note that escaping has no effect inside code blocks, see EscapeCodeBlock.
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// EscapeCodeBlock makes it safe to wrap a string in a code block by inserting a zero width space between
+// consecutive backticks, such that the content can not close the code block. A trailing backtick is also
+// separated from the closing fence.
+func EscapeCodeBlock(s string) string {
+	if !strings.Contains(s, "`") {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s) + 3*strings.Count(s, "`"))
+	for i := 0; i < len(s); i++ {
+		sb.WriteByte(s[i])
+		if s[i] == '`' && (i+1 == len(s) || s[i+1] == '`') {
+			sb.WriteString(zeroWidthSpace)
+		}
+	}
+	return sb.String()
+}
+
+// EscapeMentions stops @everyone, @here, and user and role mentions from pinging anyone,
+// by inserting a zero width space after the @.
+func EscapeMentions(s string) string {
+	return mentionEscaper.Replace(s)
+}
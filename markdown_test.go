@@ -0,0 +1,80 @@
+// +build !integration
+
+package disgord
+
+import "testing"
+
+func TestEscapeMarkdown(t *testing.T) {
+	table := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"plain text", "plain text"},
+		{"*bold*", `\*bold\*`},
+		{"**bold**", `\*\*bold\*\*`},
+		{"_italic_", `\_italic\_`},
+		{"__underline__", `\_\_underline\_\_`},
+		{"~~strike~~", `\~\~strike\~\~`},
+		{"||spoiler||", `\|\|spoiler\|\|`},
+		{"> quote", `\> quote`},
+		{">>> quote", `\>\>\> quote`},
+		{"`code`", "\\`code\\`"},
+		{"```go\nfmt.Println()\n```", "\\`\\`\\`go\nfmt.Println()\n\\`\\`\\`"},
+		{`\*not bold\*`, `\\\*not bold\\\*`},
+		{"snake_case and 2*3", `snake\_case and 2\*3`},
+		{"@everyone", "@everyone"},
+	}
+
+	for _, test := range table {
+		if got := EscapeMarkdown(test.in); got != test.want {
+			t.Errorf("EscapeMarkdown(%q): got %q, wants %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestEscapeCodeBlock(t *testing.T) {
+	table := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"no backticks", "no backticks"},
+		{"a ` b", "a ` b"},
+		{"a `` b", "a `\u200b` b"},
+		{"```", "`\u200b`\u200b`\u200b"},
+		{"x ```\ny", "x `\u200b`\u200b`\ny"},
+		{"ends with `", "ends with `\u200b"},
+		{"*not escaped*", "*not escaped*"},
+	}
+
+	for _, test := range table {
+		if got := EscapeCodeBlock(test.in); got != test.want {
+			t.Errorf("EscapeCodeBlock(%q): got %q, wants %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestEscapeMentions(t *testing.T) {
+	table := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"hello", "hello"},
+		{"@everyone", "@\u200beveryone"},
+		{"hi @here!", "hi @\u200bhere!"},
+		{"<@1234567890>", "<@\u200b1234567890>"},
+		{"<@!1234567890>", "<@\u200b!1234567890>"},
+		{"<@&1234567890>", "<@\u200b&1234567890>"},
+		{"<#1234567890>", "<#1234567890>"},
+		{"mail@example.com", "mail@example.com"},
+		{"@everyone <@1>", "@\u200beveryone <@\u200b1>"},
+	}
+
+	for _, test := range table {
+		if got := EscapeMentions(test.in); got != test.want {
+			t.Errorf("EscapeMentions(%q): got %q, wants %q", test.in, got, test.want)
+		}
+	}
+}
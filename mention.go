@@ -0,0 +1,214 @@
+package disgord
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	unknownUserMention    = "@unknown-user"
+	unknownRoleMention    = "@unknown-role"
+	unknownChannelMention = "#unknown-channel"
+)
+
+// MentionResolver looks up readable names for the snowflakes found in message mentions.
+// The boolean is false when the name is not known.
+type MentionResolver interface {
+	UserName(id Snowflake) (name string, ok bool)
+	RoleName(id Snowflake) (name string, ok bool)
+	ChannelName(id Snowflake) (name string, ok bool)
+}
+
+// NewMentionResolver creates a MentionResolver that uses the mentions of the given message, and falls back to
+// the cache when the name is not found in the message, such as Client.Cache(). cache can be nil.
+//
+// Users are resolved to their nickname when the member is known, otherwise their username.
+func NewMentionResolver(m *Message, cache CacheGetter) MentionResolver {
+	return &messageMentionResolver{msg: m, cache: cache}
+}
+
+type messageMentionResolver struct {
+	msg   *Message
+	cache CacheGetter
+}
+
+var _ MentionResolver = (*messageMentionResolver)(nil)
+
+func (r *messageMentionResolver) UserName(id Snowflake) (string, bool) {
+	msg := r.msg
+	if msg.Author != nil && msg.Author.ID == id && msg.Member != nil && msg.Member.Nick != "" {
+		return msg.Member.Nick, true
+	}
+
+	if r.cache != nil && !msg.GuildID.IsZero() {
+		if member, err := r.cache.GetMember(msg.GuildID, id); err == nil && member != nil {
+			if member.Nick != "" {
+				return member.Nick, true
+			}
+			if member.User != nil && member.User.Username != "" {
+				return member.User.Username, true
+			}
+		}
+	}
+
+	for _, user := range msg.Mentions {
+		if user != nil && user.ID == id {
+			return user.Username, true
+		}
+	}
+	if msg.Author != nil && msg.Author.ID == id {
+		return msg.Author.Username, true
+	}
+
+	if r.cache != nil {
+		if user, err := r.cache.GetUser(id); err == nil && user != nil && user.Username != "" {
+			return user.Username, true
+		}
+	}
+	return "", false
+}
+
+func (r *messageMentionResolver) RoleName(id Snowflake) (string, bool) {
+	if r.cache == nil || r.msg.GuildID.IsZero() {
+		return "", false
+	}
+
+	roles, err := r.cache.GetGuildRoles(r.msg.GuildID)
+	if err != nil {
+		return "", false
+	}
+	for _, role := range roles {
+		if role != nil && role.ID == id {
+			return role.Name, true
+		}
+	}
+	return "", false
+}
+
+func (r *messageMentionResolver) ChannelName(id Snowflake) (string, bool) {
+	for _, channel := range r.msg.MentionChannels {
+		if channel != nil && channel.ID == id {
+			return channel.Name, true
+		}
+	}
+
+	if r.cache != nil {
+		if channel, err := r.cache.GetChannel(id); err == nil && channel != nil && channel.Name != "" {
+			return channel.Name, true
+		}
+	}
+	return "", false
+}
+
+// ContentWithMentionsReplaced returns the message content where user, role and channel mentions are replaced
+// by readable names, such as "@Alice" instead of "<@1234567890>". Mentions that can not be resolved are
+// rendered as "@unknown-user", "@unknown-role" or "#unknown-channel". Code blocks and inline code are left
+// untouched.
+//
+// When resolver is nil, only the message itself is used to look up names. See NewMentionResolver.
+func (m *Message) ContentWithMentionsReplaced(resolver MentionResolver) string {
+	if resolver == nil {
+		resolver = NewMentionResolver(m, nil)
+	}
+	return replaceMentions(m.Content, resolver)
+}
+
+func replaceMentions(content string, resolver MentionResolver) string {
+	var sb strings.Builder
+	sb.Grow(len(content))
+
+	for i := 0; i < len(content); {
+		switch content[i] {
+		case '`':
+			end := codeSpanEnd(content, i)
+			sb.WriteString(content[i:end])
+			i = end
+		case '<':
+			replacement, length := parseMention(content[i:], resolver)
+			if length == 0 {
+				sb.WriteByte('<')
+				i++
+				continue
+			}
+			sb.WriteString(replacement)
+			i += length
+		default:
+			sb.WriteByte(content[i])
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+// codeSpanEnd returns the index right after the code span or code block that starts at content[start].
+// A backtick run without a closing run of the same length is not code, and only the run itself is skipped.
+func codeSpanEnd(content string, start int) int {
+	run := start
+	for run < len(content) && content[run] == '`' {
+		run++
+	}
+	fence := content[start:run]
+
+	for i := run; i < len(content); {
+		next := strings.Index(content[i:], fence)
+		if next < 0 {
+			break
+		}
+		closing := i + next
+		end := closing + len(fence)
+		if end < len(content) && content[end] == '`' {
+			// longer backtick run, which can not close this span
+			for end < len(content) && content[end] == '`' {
+				end++
+			}
+			i = end
+			continue
+		}
+		return end
+	}
+
+	return run
+}
+
+// parseMention parses a user, role or channel mention at the start of s. It returns the readable
+// replacement and the length of the mention, or a zero length when s does not start with a mention.
+func parseMention(s string, resolver MentionResolver) (replacement string, length int) {
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return "", 0
+	}
+	token := s[1:end]
+
+	var prefix, unknown string
+	var lookup func(Snowflake) (string, bool)
+	switch {
+	case strings.HasPrefix(token, "@&"):
+		token = token[2:]
+		prefix, unknown, lookup = "@", unknownRoleMention, resolver.RoleName
+	case strings.HasPrefix(token, "@!"):
+		token = token[2:]
+		prefix, unknown, lookup = "@", unknownUserMention, resolver.UserName
+	case strings.HasPrefix(token, "@"):
+		token = token[1:]
+		prefix, unknown, lookup = "@", unknownUserMention, resolver.UserName
+	case strings.HasPrefix(token, "#"):
+		token = token[1:]
+		prefix, unknown, lookup = "#", unknownChannelMention, resolver.ChannelName
+	default:
+		return "", 0
+	}
+
+	if token == "" || strings.TrimLeft(token, "0123456789") != "" {
+		return "", 0
+	}
+	id, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return "", 0
+	}
+
+	if name, ok := lookup(Snowflake(id)); ok && name != "" {
+		return prefix + name, end + 1
+	}
+	return unknown, end + 1
+}
@@ -0,0 +1,42 @@
+// +build !integration
+
+package disgord
+
+import "testing"
+
+func TestMessage_ContentWithMentionsReplaced(t *testing.T) {
+	msg := &Message{
+		Author: &User{ID: 1, Username: "Author"},
+		Member: &Member{Nick: "AuthorNick"},
+		Mentions: []*User{
+			{ID: 1234567890, Username: "Alice"},
+		},
+		MentionChannels: []*MentionChannel{
+			{ID: 42, Name: "general"},
+		},
+	}
+
+	table := []struct {
+		content string
+		want    string
+	}{
+		{"hello <@1234567890>", "hello @Alice"},
+		{"hello <@!1234567890>!", "hello @Alice!"},
+		{"<@1> said hi", "@AuthorNick said hi"},
+		{"who is <@999>?", "who is @unknown-user?"},
+		{"ping <@&555>", "ping @unknown-role"},
+		{"see <#42> and <#43>", "see #general and #unknown-channel"},
+		{"keep `<@1234567890>` as is", "keep `<@1234567890>` as is"},
+		{"```\n<@1234567890>\n``` <@1234567890>", "```\n<@1234567890>\n``` @Alice"},
+		{"``a ` <@1>`` <@1>", "``a ` <@1>`` @AuthorNick"},
+		{"unclosed ` <@1234567890>", "unclosed ` @Alice"},
+		{"a < b and <@abc> and <:emoji:123>", "a < b and <@abc> and <:emoji:123>"},
+	}
+
+	for _, test := range table {
+		msg.Content = test.content
+		if got := msg.ContentWithMentionsReplaced(nil); got != test.want {
+			t.Errorf("content %q: got %q, wants %q", test.content, got, test.want)
+		}
+	}
+}
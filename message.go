@@ -5,20 +5,45 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/andersfylling/disgord/internal/endpoint"
 	"github.com/andersfylling/disgord/internal/httd"
 )
 
+// MessageActivityType is the kind of rich presence invite sent with a message
+type MessageActivityType uint
+
 // different message acticity types
 const (
-	_ = iota
+	_ MessageActivityType = iota
 	MessageActivityTypeJoin
 	MessageActivityTypeSpectate
 	MessageActivityTypeListen
 	MessageActivityTypeJoinRequest
 )
 
+var _ fmt.Stringer = MessageActivityType(0)
+
+func (t MessageActivityType) String() string {
+	switch t {
+	case MessageActivityTypeJoin:
+		return "join"
+	case MessageActivityTypeSpectate:
+		return "spectate"
+	case MessageActivityTypeListen:
+		return "listen"
+	case MessageActivityTypeJoinRequest:
+		return "join request"
+	default:
+		return "MessageActivityType(" + strconv.FormatUint(uint64(t), 10) + ")"
+	}
+}
+
 type MessageFlag uint
 
 const (
@@ -32,6 +57,9 @@ const (
 	MessageFlagSupressEmbeds
 )
 
+// MessageFlagSuppressNotifications this message will not trigger push and desktop notifications
+const MessageFlagSuppressNotifications MessageFlag = 1 << 12
+
 // The different message types usually generated by Discord. eg. "a new user joined"
 type MessageType uint // TODO: once auto generated, un-export this.
 
@@ -49,12 +77,25 @@ const (
 	MessageTypeUserPremiumGuildSubscriptionTier2
 	MessageTypeUserPremiumGuildSubscriptionTier3
 	MessageTypeChannelFollowAdd
+	_
+	MessageTypeGuildDiscoveryDisqualified
+	MessageTypeGuildDiscoveryRequalified
+	_
+	_
+	_
+	MessageTypeReply
 )
 
 const (
 	AttachmentSpoilerPrefix = "SPOILER_"
 )
 
+// MaxPinnedMessages is the maximum number of messages that can be pinned in a single channel.
+const MaxPinnedMessages = 50
+
+// pinCountTTL decides for how long a channel pin count can be reused before the pins are fetched again
+const pinCountTTL = 10 * time.Second
+
 // NewMessage ...
 func NewMessage() *Message {
 	return &Message{}
@@ -71,8 +112,28 @@ func NewMessage() *Message {
 
 // MessageActivity https://discord.com/developers/docs/resources/channel#message-object-message-activity-structure
 type MessageActivity struct {
-	Type    int    `json:"type"`
-	PartyID string `json:"party_id"`
+	Type    MessageActivityType `json:"type"`
+	PartyID string              `json:"party_id"`
+}
+
+// spotifyPartyProvider is the prefix of the party ID for Spotify listen along invites
+const spotifyPartyProvider = "spotify"
+
+// ParsePartyID splits the party ID into the provider and the provider specific ID. Spotify listen along
+// invites use party IDs such as "spotify:228846961774559232". The provider is empty for game parties,
+// where the party ID is defined by the game.
+func (a MessageActivity) ParsePartyID() (provider, id string) {
+	i := strings.IndexByte(a.PartyID, ':')
+	if i < 0 {
+		return "", a.PartyID
+	}
+	return a.PartyID[:i], a.PartyID[i+1:]
+}
+
+// IsSpotify checks if the activity is a Spotify listen along invite
+func (a MessageActivity) IsSpotify() bool {
+	provider, _ := a.ParsePartyID()
+	return provider == spotifyPartyProvider
 }
 
 type MentionChannel struct {
@@ -82,10 +143,11 @@ type MentionChannel struct {
 	Name    string    `json:"name"`
 }
 
+// MessageReference https://discord.com/developers/docs/resources/channel#message-object-message-reference-structure
 type MessageReference struct {
-	MessageID Snowflake `json:"message_id"`
-	ChannelID Snowflake `json:"channel_id"`
-	GuildID   Snowflake `json:"guild_id"`
+	MessageID Snowflake `json:"message_id,omitempty"`
+	ChannelID Snowflake `json:"channel_id,omitempty"`
+	GuildID   Snowflake `json:"guild_id,omitempty"`
 }
 
 // MessageApplication https://discord.com/developers/docs/resources/channel#message-object-message-application-structure
@@ -99,29 +161,30 @@ type MessageApplication struct {
 
 // Message https://discord.com/developers/docs/resources/channel#message-object-message-structure
 type Message struct {
-	ID               Snowflake          `json:"id"`
-	ChannelID        Snowflake          `json:"channel_id"`
-	Author           *User              `json:"author"`
-	Member           *Member            `json:"member"`
-	Content          string             `json:"content"`
-	Timestamp        Time               `json:"timestamp"`
-	EditedTimestamp  Time               `json:"edited_timestamp"` // ?
-	Tts              bool               `json:"tts"`
-	MentionEveryone  bool               `json:"mention_everyone"`
-	Mentions         []*User            `json:"mentions"`
-	MentionRoles     []Snowflake        `json:"mention_roles"`
-	MentionChannels  []*MentionChannel  `json:"mention_channels"`
-	Attachments      []*Attachment      `json:"attachments"`
-	Embeds           []*Embed           `json:"embeds"`
-	Reactions        []*Reaction        `json:"reactions"` // ?
-	Nonce            interface{}        `json:"nonce"`     // NOT A SNOWFLAKE! DONT TOUCH!
-	Pinned           bool               `json:"pinned"`
-	WebhookID        Snowflake          `json:"webhook_id"` // ?
-	Type             MessageType        `json:"type"`
-	Activity         MessageActivity    `json:"activity"`
-	Application      MessageApplication `json:"application"`
-	MessageReference *MessageReference  `json:"message_reference"`
-	Flags            MessageFlag        `json:"flags"`
+	ID                Snowflake          `json:"id"`
+	ChannelID         Snowflake          `json:"channel_id"`
+	Author            *User              `json:"author"`
+	Member            *Member            `json:"member"`
+	Content           string             `json:"content"`
+	Timestamp         Time               `json:"timestamp"`
+	EditedTimestamp   Time               `json:"edited_timestamp"` // ?
+	Tts               bool               `json:"tts"`
+	MentionEveryone   bool               `json:"mention_everyone"`
+	Mentions          []*User            `json:"mentions"`
+	MentionRoles      []Snowflake        `json:"mention_roles"`
+	MentionChannels   []*MentionChannel  `json:"mention_channels"`
+	Attachments       []*Attachment      `json:"attachments"`
+	Embeds            []*Embed           `json:"embeds"`
+	Reactions         []*Reaction        `json:"reactions"` // ?
+	Nonce             interface{}        `json:"nonce"`     // NOT A SNOWFLAKE! DONT TOUCH!
+	Pinned            bool               `json:"pinned"`
+	WebhookID         Snowflake          `json:"webhook_id"` // ?
+	Type              MessageType        `json:"type"`
+	Activity          MessageActivity    `json:"activity"`
+	Application       MessageApplication `json:"application"`
+	MessageReference  *MessageReference  `json:"message_reference"`
+	ReferencedMessage *Message           `json:"referenced_message"` // only set for MessageTypeReply
+	Flags             MessageFlag        `json:"flags"`
 
 	// GuildID is not set when using a REST request. Only socket events.
 	GuildID Snowflake `json:"guild_id"`
@@ -139,10 +202,221 @@ var _ discordDeleter = (*Message)(nil)
 var _ Copier = (*Message)(nil)
 var _ DeepCopier = (*Message)(nil)
 
+// PartialMessage is a message where only the fields present in the JSON payload are set. Discord sends
+// partial messages on MESSAGE_UPDATE, for example when embeds are unfurled, and these lack the author
+// and content. See Message.ApplyPartial.
+type PartialMessage struct {
+	ID        Snowflake `json:"id"`
+	ChannelID Snowflake `json:"channel_id"`
+	GuildID   Snowflake `json:"guild_id"`
+
+	Author            *User               `json:"author"`
+	Member            *Member             `json:"member"`
+	Content           *string             `json:"content"`
+	Timestamp         *Time               `json:"timestamp"`
+	EditedTimestamp   *Time               `json:"edited_timestamp"`
+	Tts               *bool               `json:"tts"`
+	MentionEveryone   *bool               `json:"mention_everyone"`
+	Mentions          *[]*User            `json:"mentions"`
+	MentionRoles      *[]Snowflake        `json:"mention_roles"`
+	MentionChannels   *[]*MentionChannel  `json:"mention_channels"`
+	Attachments       *[]*Attachment      `json:"attachments"`
+	Embeds            *[]*Embed           `json:"embeds"`
+	Reactions         *[]*Reaction        `json:"reactions"`
+	Nonce             interface{}         `json:"nonce"`
+	Pinned            *bool               `json:"pinned"`
+	WebhookID         *Snowflake          `json:"webhook_id"`
+	Type              *MessageType        `json:"type"`
+	Activity          *MessageActivity    `json:"activity"`
+	Application       *MessageApplication `json:"application"`
+	MessageReference  *MessageReference   `json:"message_reference"`
+	ReferencedMessage *Message            `json:"referenced_message"`
+	Flags             *MessageFlag        `json:"flags"`
+}
+
+// ApplyPartial overwrites the fields of the message that are present in the partial message.
+// Fields missing from the partial payload, or sent as null, are left untouched. An empty list,
+// such as the embeds being removed, is applied.
+func (m *Message) ApplyPartial(p *PartialMessage) {
+	if p == nil {
+		return
+	}
+
+	if !p.ID.IsZero() {
+		m.ID = p.ID
+	}
+	if !p.ChannelID.IsZero() {
+		m.ChannelID = p.ChannelID
+	}
+	if !p.GuildID.IsZero() {
+		m.GuildID = p.GuildID
+	}
+	if p.Author != nil {
+		m.Author = p.Author
+	}
+	if p.Member != nil {
+		m.Member = p.Member
+	}
+	if p.Content != nil {
+		m.Content = *p.Content
+	}
+	if p.Timestamp != nil {
+		m.Timestamp = *p.Timestamp
+	}
+	if p.EditedTimestamp != nil {
+		m.EditedTimestamp = *p.EditedTimestamp
+	}
+	if p.Tts != nil {
+		m.Tts = *p.Tts
+	}
+	if p.MentionEveryone != nil {
+		m.MentionEveryone = *p.MentionEveryone
+	}
+	if p.Mentions != nil {
+		m.Mentions = *p.Mentions
+	}
+	if p.MentionRoles != nil {
+		m.MentionRoles = *p.MentionRoles
+	}
+	if p.MentionChannels != nil {
+		m.MentionChannels = *p.MentionChannels
+	}
+	if p.Attachments != nil {
+		m.Attachments = *p.Attachments
+	}
+	if p.Embeds != nil {
+		m.Embeds = *p.Embeds
+	}
+	if p.Reactions != nil {
+		m.Reactions = *p.Reactions
+	}
+	if p.Nonce != nil {
+		m.Nonce = p.Nonce
+	}
+	if p.Pinned != nil {
+		m.Pinned = *p.Pinned
+	}
+	if p.WebhookID != nil {
+		m.WebhookID = *p.WebhookID
+	}
+	if p.Type != nil {
+		m.Type = *p.Type
+	}
+	if p.Activity != nil {
+		m.Activity = *p.Activity
+	}
+	if p.Application != nil {
+		m.Application = *p.Application
+	}
+	if p.MessageReference != nil {
+		m.MessageReference = p.MessageReference
+	}
+	if p.ReferencedMessage != nil {
+		m.ReferencedMessage = p.ReferencedMessage
+	}
+	if p.Flags != nil {
+		m.Flags = *p.Flags
+	}
+
+	m.updateInternals()
+}
+
+// DeepCopy returns a copy of the partial message where the same fields are set
+func (p *PartialMessage) DeepCopy() (copy interface{}) {
+	message := &Message{}
+	message.ApplyPartial(p)
+	message = message.DeepCopy().(*Message)
+
+	partial := &PartialMessage{
+		ID:        p.ID,
+		ChannelID: p.ChannelID,
+		GuildID:   p.GuildID,
+		Nonce:     p.Nonce,
+	}
+	if p.Author != nil {
+		partial.Author = message.Author
+	}
+	if p.Member != nil {
+		partial.Member = p.Member.DeepCopy().(*Member)
+	}
+	if p.Content != nil {
+		partial.Content = &message.Content
+	}
+	if p.Timestamp != nil {
+		partial.Timestamp = &message.Timestamp
+	}
+	if p.EditedTimestamp != nil {
+		partial.EditedTimestamp = &message.EditedTimestamp
+	}
+	if p.Tts != nil {
+		partial.Tts = &message.Tts
+	}
+	if p.MentionEveryone != nil {
+		partial.MentionEveryone = &message.MentionEveryone
+	}
+	if p.Mentions != nil {
+		partial.Mentions = &message.Mentions
+	}
+	if p.MentionRoles != nil {
+		partial.MentionRoles = &message.MentionRoles
+	}
+	if p.MentionChannels != nil {
+		partial.MentionChannels = &message.MentionChannels
+	}
+	if p.Attachments != nil {
+		partial.Attachments = &message.Attachments
+	}
+	if p.Embeds != nil {
+		partial.Embeds = &message.Embeds
+	}
+	if p.Reactions != nil {
+		partial.Reactions = &message.Reactions
+	}
+	if p.Pinned != nil {
+		partial.Pinned = &message.Pinned
+	}
+	if p.WebhookID != nil {
+		partial.WebhookID = &message.WebhookID
+	}
+	if p.Type != nil {
+		partial.Type = &message.Type
+	}
+	if p.Activity != nil {
+		partial.Activity = &message.Activity
+	}
+	if p.Application != nil {
+		partial.Application = &message.Application
+	}
+	if p.MessageReference != nil {
+		partial.MessageReference = message.MessageReference
+	}
+	if p.ReferencedMessage != nil {
+		partial.ReferencedMessage = message.ReferencedMessage
+	}
+	if p.Flags != nil {
+		partial.Flags = &message.Flags
+	}
+	return partial
+}
+
 func (m *Message) String() string {
 	return "message{" + m.ID.String() + "}"
 }
 
+// NonceString returns the nonce as a string, regardless of it being sent as a JSON string or integer.
+func (m *Message) NonceString() string {
+	switch nonce := m.Nonce.(type) {
+	case nil:
+		return ""
+	case string:
+		return nonce
+	case float64:
+		return strconv.FormatFloat(nonce, 'f', -1, 64)
+	default:
+		return fmt.Sprint(nonce)
+	}
+}
+
 // DiscordURL returns the Discord link to the message. This can be used to jump
 // directly to a message from within the client.
 //
@@ -192,7 +466,95 @@ func (m *Message) updateInternals() {
 // WARNING! Note that, when fetching messages using the REST API the
 // guildID might be empty -> giving a false positive.
 func (m *Message) IsDirectMessage() bool {
-	return m.Type == MessageTypeDefault && m.GuildID.IsZero()
+	return (m.Type == MessageTypeDefault || m.Type == MessageTypeReply) && m.GuildID.IsZero()
+}
+
+// IsActivityInvite checks if the message holds a rich presence invite, such as a game invite or a Spotify
+// listen along invite. See MessageActivity.IsSpotify.
+func (m *Message) IsActivityInvite() bool {
+	return m.Activity.Type != 0
+}
+
+// Args splits the message content into arguments, shell style. Arguments are separated by whitespace,
+// double quoted groups are kept together without the quotes, and code blocks and inline code are returned
+// as a single argument including the backticks. An unbalanced quote or code fence makes the remaining
+// content a single argument.
+func (m *Message) Args() []string {
+	return splitArgs(m.Content)
+}
+
+// ArgsAfterPrefix returns the arguments that follows the given prefix, see Args. The bool is false when
+// the content does not start with the prefix. Leading whitespace is ignored.
+//
+// A user mention prefix, such as "<@botID>", also matches the nickname form "<@!botID>" and vice versa.
+func (m *Message) ArgsAfterPrefix(prefix string) ([]string, bool) {
+	content := strings.TrimLeftFunc(m.Content, unicode.IsSpace)
+	for _, p := range mentionPrefixForms(prefix) {
+		if strings.HasPrefix(content, p) {
+			return splitArgs(content[len(p):]), true
+		}
+	}
+	return nil, false
+}
+
+// mentionPrefixForms returns both forms of a user mention prefix, or just the prefix
+// when it is not a user mention.
+func mentionPrefixForms(prefix string) []string {
+	if !strings.HasPrefix(prefix, "<@") || !strings.HasSuffix(prefix, ">") || strings.HasPrefix(prefix, "<@&") {
+		return []string{prefix}
+	}
+
+	id := strings.TrimPrefix(prefix[2:len(prefix)-1], "!")
+	return []string{"<@" + id + ">", "<@!" + id + ">"}
+}
+
+func splitArgs(content string) (args []string) {
+	content = strings.TrimSpace(content)
+	for len(content) > 0 {
+		var arg string
+		switch content[0] {
+		case '"':
+			if end := strings.IndexByte(content[1:], '"'); end >= 0 {
+				arg, content = content[1:end+1], content[end+2:]
+			} else {
+				arg, content = content[1:], ""
+			}
+		case '`':
+			fence := len(content) - len(strings.TrimLeft(content, "`"))
+			if end := codeSpanEnd(content, 0); end > fence {
+				arg, content = content[:end], content[end:]
+			} else {
+				arg, content = content, ""
+			}
+		default:
+			end := strings.IndexFunc(content, unicode.IsSpace)
+			if end < 0 {
+				end = len(content)
+			}
+			arg, content = content[:end], content[end:]
+		}
+
+		args = append(args, arg)
+		content = strings.TrimLeftFunc(content, unicode.IsSpace)
+	}
+
+	return args
+}
+
+// ReactionCount returns the number of reactions using the given emoji, which can be a unicode string,
+// a custom emoji reference or a *Emoji. See Emoji.Equal.
+func (m *Message) ReactionCount(emoji interface{}) int {
+	for _, reaction := range m.Reactions {
+		if reaction != nil && reaction.Emoji.Equal(emoji) {
+			return int(reaction.Count)
+		}
+	}
+	return 0
+}
+
+// HasReaction checks if anyone has reacted to the message using the given emoji. See ReactionCount.
+func (m *Message) HasReaction(emoji interface{}) bool {
+	return m.ReactionCount(emoji) > 0
 }
 
 // DeepCopy see interface at struct.go#DeepCopier
@@ -231,11 +593,21 @@ func (m *Message) CopyOverTo(other interface{}) (err error) {
 	message.SpoilerTagAllAttachments = m.SpoilerTagAllAttachments
 	message.SpoilerTagContent = m.SpoilerTagContent
 	message.Nonce = m.Nonce
+	message.Flags = m.Flags
 
 	if m.Author != nil {
 		message.Author = m.Author.DeepCopy().(*User)
 	}
 
+	if m.MessageReference != nil {
+		reference := *m.MessageReference
+		message.MessageReference = &reference
+	}
+
+	if m.ReferencedMessage != nil {
+		message.ReferencedMessage = m.ReferencedMessage.DeepCopy().(*Message)
+	}
+
 	for _, mention := range m.Mentions {
 		message.Mentions = append(message.Mentions, mention.DeepCopy().(*User))
 	}
@@ -272,7 +644,7 @@ type MessageSender interface {
 
 // Send sends this message to discord.
 func (m *Message) Send(ctx context.Context, client MessageSender, flags ...Flag) (msg *Message, err error) {
-	nonce := fmt.Sprint(m.Nonce)
+	nonce := m.NonceString()
 	if len(nonce) > 25 {
 		return nil, errors.New("nonce can not be more than 25 characters")
 	}
@@ -299,9 +671,64 @@ type msgSender interface {
 	SendMsg(ctx context.Context, channelID Snowflake, data ...interface{}) (msg *Message, err error)
 }
 
-// Reply input any type as an reply. int, string, an object, etc.
+// Reply input any type as an reply. int, string, an object, etc. The created message references this message,
+// such that Discord displays it as a reply. A *CreateMessageParams can be given as the base of the reply, otherwise
+// the data is handled just like Client.SendMsg.
+//
+// By default the author of this message is not mentioned. Supply AllowedMentions with RepliedUser set to true,
+// either directly or as part of the CreateMessageParams, to ping the author.
 func (m *Message) Reply(ctx context.Context, client msgSender, data ...interface{}) (*Message, error) {
-	return client.SendMsg(ctx, m.ChannelID, data...)
+	var base *CreateMessageParams
+	rest := make([]interface{}, 0, len(data)+1)
+	rest = append(rest, nil) // placeholder for the reply params, as they overwrite any content before them
+	for i := range data {
+		switch t := data[i].(type) {
+		case *CreateMessageParams:
+			base = t
+		case CreateMessageParams:
+			base = &t
+		default:
+			rest = append(rest, t)
+		}
+	}
+	rest[0] = m.replyParams(base)
+
+	return client.SendMsg(ctx, m.ChannelID, rest...)
+}
+
+// ReplyString creates a reply to this message with the given content. Unlike Reply, the content is sent as is.
+func (m *Message) ReplyString(ctx context.Context, s Session, content string) (*Message, error) {
+	if m.ChannelID.IsZero() {
+		return nil, errors.New("missing channel ID")
+	}
+
+	params := m.replyParams(nil)
+	params.Content = content
+	return s.Channel(m.ChannelID).WithContext(ctx).CreateMessage(params)
+}
+
+// replyParams returns a copy of the given params, referencing this message. Unless specified, the allowed
+// mentions will not ping the author of this message.
+func (m *Message) replyParams(base *CreateMessageParams) *CreateMessageParams {
+	params := &CreateMessageParams{}
+	if base != nil {
+		*params = *base
+	}
+	if params.MessageReference == nil {
+		params.MessageReference = &MessageReference{
+			MessageID: m.ID,
+			ChannelID: m.ChannelID,
+			GuildID:   m.GuildID,
+		}
+	}
+	if params.AllowedMentions == nil {
+		params.AllowedMentions = &AllowedMentions{
+			Parse:       []string{AllowedMentionsEveryone, AllowedMentionsUsers, AllowedMentionsRoles},
+			RepliedUser: false,
+		}
+	}
+
+	return params
 }
 
 func (m *Message) React(ctx context.Context, s Session, emoji interface{}, flags ...Flag) error {
@@ -406,6 +833,7 @@ func (m messageQueryBuilder) Get(ctx context.Context, flags ...Flag) (message *M
 
 	msg, _ := m.client.cache.GetMessage(m.cid, m.mid)
 	if msg != nil {
+		m.client.backfillGuildID(m.cid, []*Message{msg}, mergeFlags(flags))
 		return msg, nil
 	}
 
@@ -418,7 +846,34 @@ func (m messageQueryBuilder) Get(ctx context.Context, flags ...Flag) (message *M
 		return &Message{}
 	}
 
-	return getMessage(r.Execute)
+	if message, err = getMessage(r.Execute); err != nil {
+		return nil, err
+	}
+	m.client.backfillGuildID(m.cid, []*Message{message}, mergeFlags(flags))
+	return message, nil
+}
+
+// backfillGuildID sets the guild ID of messages fetched over REST, as Discord only includes it in
+// gateway events. The guild ID is found using the channel cache, and is left empty on a cache miss.
+func (c *Client) backfillGuildID(channelID Snowflake, msgs []*Message, flags Flag) {
+	if flags.IgnoreGuildIDBackfill() || len(msgs) == 0 {
+		return
+	}
+
+	channel, err := c.cache.GetChannel(channelID)
+	if err != nil || channel == nil || channel.GuildID.IsZero() {
+		return
+	}
+
+	for _, msg := range msgs {
+		if msg == nil || !msg.GuildID.IsZero() {
+			continue
+		}
+		msg.GuildID = channel.GuildID
+		if msg.Member != nil && msg.Member.GuildID.IsZero() {
+			msg.Member.GuildID = channel.GuildID
+		}
+	}
 }
 
 // Update Edit a previously sent message. You can only edit messages that have been sent by the
@@ -482,8 +937,19 @@ func (m messageQueryBuilder) Delete(ctx context.Context, flags ...Flag) (err err
 //  Endpoint                /channels/{channel.id}/pins/{message.id}
 //  Discord documentation   https://discord.com/developers/docs/resources/channel#add-pinned-channel-message
 //  Reviewed                2018-06-10
-//  Comment                 -
+//  Comment                 A channel can hold at most 50 pinned messages, after which ErrMaxPinsReached is
+//                          returned. Use the CheckPinLimit flag to verify the pin count before sending the request.
 func (m messageQueryBuilder) Pin(ctx context.Context, flags ...Flag) (err error) {
+	if mergeFlags(flags).CheckPinLimit() {
+		var pins int
+		if pins, err = m.client.pinCount(ctx, m.cid); err != nil {
+			return err
+		}
+		if pins >= MaxPinnedMessages {
+			return ErrMaxPinsReached
+		}
+	}
+
 	r := m.client.newRESTRequest(&httd.Request{
 		Method:   httd.MethodPut,
 		Endpoint: endpoint.ChannelPin(m.cid, m.mid),
@@ -491,8 +957,16 @@ func (m messageQueryBuilder) Pin(ctx context.Context, flags ...Flag) (err error)
 	}, flags)
 	r.expectsStatusCode = http.StatusNoContent
 
-	_, err = r.Execute()
-	return err
+	if _, err = r.Execute(); err != nil {
+		if HasJSONErrorCode(err, JSONErrorCodeMaxPinsReached) {
+			m.client.pins.set(m.cid, MaxPinnedMessages)
+			return ErrMaxPinsReached
+		}
+		return err
+	}
+
+	m.client.pins.add(m.cid, 1)
+	return nil
 }
 
 // UnpinMessageID [REST] Delete a pinned message in a channel. Requires the 'MANAGE_MESSAGES' permission.
@@ -517,8 +991,12 @@ func (m messageQueryBuilder) Unpin(ctx context.Context, flags ...Flag) (err erro
 	}, flags)
 	r.expectsStatusCode = http.StatusNoContent
 
-	_, err = r.Execute()
-	return err
+	if _, err = r.Execute(); err != nil {
+		return err
+	}
+
+	m.client.pins.add(m.cid, -1)
+	return nil
 }
 
 // DeleteAllReactions [REST] Deletes all reactions on a message. This endpoint requires the 'MANAGE_MESSAGES'
@@ -546,6 +1024,68 @@ func (m messageQueryBuilder) DeleteAllReactions(flags ...Flag) error {
 	return err
 }
 
+// pinCount returns the number of pinned messages in a channel. The count is reused for
+// pinCountTTL, such that pinning several messages in a row does not fetch the pins every time.
+func (c *Client) pinCount(ctx context.Context, channelID Snowflake) (int, error) {
+	if pins, ok := c.pins.get(channelID); ok {
+		return pins, nil
+	}
+
+	msgs, err := c.Channel(channelID).WithContext(ctx).GetPinnedMessages()
+	if err != nil {
+		return 0, err
+	}
+
+	c.pins.set(channelID, len(msgs))
+	return len(msgs), nil
+}
+
+type pinCountEntry struct {
+	pins      int
+	fetchedAt time.Time
+}
+
+// pinCounts keeps track of the recent pin count of channels
+type pinCounts struct {
+	sync.Mutex
+	entries map[Snowflake]*pinCountEntry
+}
+
+func (p *pinCounts) get(channelID Snowflake) (pins int, ok bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	entry, exists := p.entries[channelID]
+	if !exists || time.Since(entry.fetchedAt) > pinCountTTL {
+		return 0, false
+	}
+	return entry.pins, true
+}
+
+func (p *pinCounts) set(channelID Snowflake, pins int) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.entries == nil {
+		p.entries = make(map[Snowflake]*pinCountEntry)
+	}
+	p.entries[channelID] = &pinCountEntry{pins: pins, fetchedAt: time.Now()}
+}
+
+// add adjusts a known pin count, unknown or expired counts are left alone to be fetched on demand
+func (p *pinCounts) add(channelID Snowflake, delta int) {
+	p.Lock()
+	defer p.Unlock()
+
+	entry, exists := p.entries[channelID]
+	if !exists || time.Since(entry.fetchedAt) > pinCountTTL {
+		return
+	}
+	if entry.pins += delta; entry.pins < 0 {
+		entry.pins = 0
+	}
+}
+
 //////////////////////////////////////////////////////
 //
 // REST Wrappers
@@ -3,7 +3,12 @@
 package disgord
 
 import (
+	"context"
+	"io/ioutil"
 	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/json"
 )
 
 func TestMessage_updateInternals(t *testing.T) {
@@ -78,3 +83,426 @@ func TestMessage_DeepCopy(t *testing.T) {
 	// 	t.Errorf("expect messages to be equal after deep copy.\n Got \n%s,\n\n wants \n%s", prettyPrint(c), prettyPrint(original))
 	// }
 }
+
+func TestPinCounts(t *testing.T) {
+	var pins pinCounts
+	channelID := Snowflake(4)
+
+	if _, ok := pins.get(channelID); ok {
+		t.Error("expected no pin count for an unknown channel")
+	}
+
+	pins.add(channelID, 1)
+	if _, ok := pins.get(channelID); ok {
+		t.Error("adding to an unknown pin count should not create an entry")
+	}
+
+	pins.set(channelID, MaxPinnedMessages-1)
+	pins.add(channelID, 1)
+	if count, ok := pins.get(channelID); !ok || count != MaxPinnedMessages {
+		t.Errorf("expected pin count %d, got %d", MaxPinnedMessages, count)
+	}
+
+	pins.entries[channelID].fetchedAt = time.Now().Add(-pinCountTTL - time.Second)
+	if _, ok := pins.get(channelID); ok {
+		t.Error("expected pin count to have expired")
+	}
+}
+
+type replyRecorder struct {
+	channelID Snowflake
+	data      []interface{}
+}
+
+func (r *replyRecorder) SendMsg(_ context.Context, channelID Snowflake, data ...interface{}) (*Message, error) {
+	r.channelID = channelID
+	r.data = data
+	return &Message{}, nil
+}
+
+func TestMessage_Reply(t *testing.T) {
+	msg := &Message{ID: 3, ChannelID: 2, GuildID: 1}
+
+	t.Run("default", func(t *testing.T) {
+		recorder := &replyRecorder{}
+		if _, err := msg.Reply(context.Background(), recorder, "hello"); err != nil {
+			t.Fatal(err)
+		}
+		if recorder.channelID != msg.ChannelID {
+			t.Errorf("expected reply in channel %d, got %d", msg.ChannelID, recorder.channelID)
+		}
+		if len(recorder.data) != 2 || recorder.data[1] != "hello" {
+			t.Fatalf("expected the reply params to be followed by the data, got %+v", recorder.data)
+		}
+
+		params := recorder.data[0].(*CreateMessageParams)
+		if params.MessageReference == nil || params.MessageReference.MessageID != msg.ID {
+			t.Errorf("expected a reference to message %d, got %+v", msg.ID, params.MessageReference)
+		}
+		if params.AllowedMentions == nil || params.AllowedMentions.RepliedUser {
+			t.Error("expected the replied user to not be mentioned by default")
+		}
+	})
+
+	t.Run("base params", func(t *testing.T) {
+		recorder := &replyRecorder{}
+		base := &CreateMessageParams{
+			Content:         "hi",
+			AllowedMentions: &AllowedMentions{RepliedUser: true},
+		}
+		if _, err := msg.Reply(context.Background(), recorder, base); err != nil {
+			t.Fatal(err)
+		}
+
+		params := recorder.data[0].(*CreateMessageParams)
+		if params == base {
+			t.Error("the given params should not be modified")
+		}
+		if params.Content != "hi" || !params.AllowedMentions.RepliedUser {
+			t.Errorf("expected the base params to be used, got %+v", params)
+		}
+		if params.MessageReference == nil || base.MessageReference != nil {
+			t.Error("expected only the copy to hold the message reference")
+		}
+	})
+}
+
+func TestMessage_NonceString(t *testing.T) {
+	table := []struct {
+		data string
+		want string
+	}{
+		{`{"nonce":null}`, ""},
+		{`{"nonce":"a1b2c3"}`, "a1b2c3"},
+		{`{"nonce":"672548373402738688"}`, "672548373402738688"},
+		{`{"nonce":67254837340}`, "67254837340"},
+	}
+
+	for _, test := range table {
+		msg := &Message{}
+		if err := json.Unmarshal([]byte(test.data), msg); err != nil {
+			t.Fatal(err)
+		}
+		if got := msg.NonceString(); got != test.want {
+			t.Errorf("got nonce %q, wants %q", got, test.want)
+		}
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nonce) == 0 || len(nonce) > 25 {
+		t.Errorf("generated nonce must be 1 to 25 characters, got %q", nonce)
+	}
+}
+
+func TestMessage_Args(t *testing.T) {
+	table := []struct {
+		content string
+		want    []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"ping", []string{"ping"}},
+		{"  ban  user   reason  ", []string{"ban", "user", "reason"}},
+		{"say \"hello world\" now", []string{"say", "hello world", "now"}},
+		{"say \"\" now", []string{"say", "", "now"}},
+		{"say \"unbalanced quote here", []string{"say", "unbalanced quote here"}},
+		{"eval ```go\nfmt.Println(\"a b\")\n``` after", []string{"eval", "```go\nfmt.Println(\"a b\")\n```", "after"}},
+		{"run `ls -la` now", []string{"run", "`ls -la`", "now"}},
+		{"run ```unclosed code", []string{"run", "```unclosed code"}},
+		{"tab\tnew\nline\u00a0nbsp\u3000ideographic", []string{"tab", "new", "line", "nbsp", "ideographic"}},
+		{"unicode ✓ ö", []string{"unicode", "✓", "ö"}},
+	}
+
+	for _, test := range table {
+		msg := &Message{Content: test.content}
+		got := msg.Args()
+		if len(got) != len(test.want) {
+			t.Errorf("content %q: got %q, wants %q", test.content, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("content %q: got %q, wants %q", test.content, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMessage_ArgsAfterPrefix(t *testing.T) {
+	table := []struct {
+		content string
+		prefix  string
+		want    []string
+		ok      bool
+	}{
+		{"!ping a b", "!", []string{"ping", "a", "b"}, true},
+		{"  !ping", "!", []string{"ping"}, true},
+		{"?ping", "!", nil, false},
+		{"!", "!", nil, true},
+		{"<@123> ping", "<@123>", []string{"ping"}, true},
+		{"<@!123> ping", "<@123>", []string{"ping"}, true},
+		{"<@123> ping", "<@!123>", []string{"ping"}, true},
+		{"<@1234> ping", "<@123>", nil, false},
+		{"<@&123> ping", "<@123>", nil, false},
+	}
+
+	for _, test := range table {
+		msg := &Message{Content: test.content}
+		got, ok := msg.ArgsAfterPrefix(test.prefix)
+		if ok != test.ok || len(got) != len(test.want) {
+			t.Errorf("content %q, prefix %q: got %q %t, wants %q %t", test.content, test.prefix, got, ok, test.want, test.ok)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("content %q, prefix %q: got %q, wants %q", test.content, test.prefix, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMessage_ApplyPartial(t *testing.T) {
+	original := func(t *testing.T) *Message {
+		data, err := ioutil.ReadFile("testdata/channel/message_update.json")
+		check(err, t)
+
+		message := &Message{}
+		if err = json.Unmarshal(data, message); err != nil {
+			t.Fatal(err)
+		}
+		executeInternalUpdater(message)
+		return message
+	}
+	update := func(t *testing.T, file string) *MessageUpdate {
+		data, err := ioutil.ReadFile(file)
+		check(err, t)
+
+		evt := &MessageUpdate{}
+		if err = json.Unmarshal(data, evt); err != nil {
+			t.Fatal(err)
+		}
+		executeInternalUpdater(evt)
+		return evt
+	}
+
+	t.Run("embed-unfurl", func(t *testing.T) {
+		message := original(t)
+		evt := update(t, "testdata/channel/message_update_embed_unfurl.json")
+		if evt.Partial == nil {
+			t.Fatal("partial message was not set")
+		}
+		if evt.Partial.Content != nil || evt.Partial.Author != nil {
+			t.Error("fields missing from the payload should not be set")
+		}
+
+		message.ApplyPartial(evt.Partial)
+		if message.Content != "updating msg" {
+			t.Errorf("content was overwritten, got %q", message.Content)
+		}
+		if message.Author == nil || message.Author.Username != "Anders" {
+			t.Error("author was overwritten")
+		}
+		if message.EditedTimestamp.IsZero() {
+			t.Error("edited timestamp was overwritten")
+		}
+		if len(message.Embeds) != 1 || message.Embeds[0].Title != "andersfylling/disgord" {
+			t.Errorf("embeds were not applied, got %+v", message.Embeds)
+		}
+	})
+
+	t.Run("embeds-removed", func(t *testing.T) {
+		message := original(t)
+		message.Embeds = []*Embed{{Title: "unfurled"}}
+
+		evt := update(t, "testdata/channel/message_update_embeds_removed.json")
+		message.ApplyPartial(evt.Partial)
+		if len(message.Embeds) != 0 {
+			t.Error("embeds should have been removed")
+		}
+		if message.Flags != MessageFlagSupressEmbeds {
+			t.Errorf("flags were not applied, got %d", message.Flags)
+		}
+		if message.Content != "updating msg" {
+			t.Errorf("content was overwritten, got %q", message.Content)
+		}
+	})
+
+	t.Run("deep-copy", func(t *testing.T) {
+		evt := update(t, "testdata/channel/message_update_embed_unfurl.json")
+		partial := evt.Partial.DeepCopy().(*PartialMessage)
+		if partial.Content != nil || partial.Author != nil {
+			t.Error("fields missing from the payload should not be set in the copy")
+		}
+		if partial.Embeds == nil || len(*partial.Embeds) != 1 {
+			t.Fatal("embeds were not copied")
+		}
+
+		(*partial.Embeds)[0].Title = "changed"
+		if (*evt.Partial.Embeds)[0].Title != "andersfylling/disgord" {
+			t.Error("the copy shares embeds with the original")
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		message := original(t)
+		message.ApplyPartial(nil)
+		if message.Content != "updating msg" {
+			t.Error("message was modified")
+		}
+	})
+}
+
+func TestMessage_ReplyRoundTrip(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/channel/message_reply.json")
+	check(err, t)
+
+	message := &Message{}
+	if err = json.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func(t *testing.T, message *Message) {
+		if message.Type != MessageTypeReply {
+			t.Errorf("expected type reply, got %d", message.Type)
+		}
+		if message.MessageReference == nil || message.MessageReference.MessageID != 776503210587537408 {
+			t.Errorf("missing message reference, got %+v", message.MessageReference)
+		}
+		if message.ReferencedMessage == nil || message.ReferencedMessage.Content != "ping" {
+			t.Errorf("missing referenced message, got %+v", message.ReferencedMessage)
+		}
+	}
+	verify(t, message)
+
+	data, err = json.Marshal(message)
+	check(err, t)
+	decoded := &Message{}
+	if err = json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	verify(t, decoded)
+	verify(t, message.DeepCopy().(*Message))
+}
+
+func TestMessage_ReactionCount(t *testing.T) {
+	msg := &Message{
+		Reactions: []*Reaction{
+			{Count: 3, Emoji: &Emoji{Name: "👍"}},
+			{Count: 2, Emoji: &Emoji{ID: 540519588153262081, Name: "blob"}},
+		},
+	}
+
+	if count := msg.ReactionCount("👍"); count != 3 {
+		t.Errorf("expected 3 reactions, got %d", count)
+	}
+	if count := msg.ReactionCount(&Emoji{ID: 540519588153262081}); count != 2 {
+		t.Errorf("expected 2 reactions, got %d", count)
+	}
+	if !msg.HasReaction("<:blob:540519588153262081>") {
+		t.Error("expected the custom emoji reaction to be found")
+	}
+	if msg.HasReaction("👎") {
+		t.Error("did not expect a reaction")
+	}
+}
+
+// channelCache is a cache that only holds channels
+type channelCache struct {
+	CacheNop
+	channels map[Snowflake]*Channel
+}
+
+func (c *channelCache) GetChannel(id Snowflake) (*Channel, error) {
+	return c.channels[id], nil
+}
+
+func TestClient_backfillGuildID(t *testing.T) {
+	client := &Client{cache: &channelCache{channels: map[Snowflake]*Channel{
+		1: {ID: 1, GuildID: 10, Type: ChannelTypeGuildText},
+		2: {ID: 2, Type: ChannelTypeDM},
+	}}}
+
+	t.Run("guild channel", func(t *testing.T) {
+		msgs := []*Message{{ID: 100, ChannelID: 1, Member: &Member{}}, {ID: 101, ChannelID: 1}}
+		client.backfillGuildID(1, msgs, 0)
+		for _, msg := range msgs {
+			if msg.GuildID != 10 {
+				t.Errorf("expected guild ID 10, got %d", msg.GuildID)
+			}
+		}
+		if msgs[0].Member.GuildID != 10 {
+			t.Error("expected the member guild ID to be set")
+		}
+	})
+
+	t.Run("dm channel", func(t *testing.T) {
+		msg := &Message{ID: 100, ChannelID: 2}
+		client.backfillGuildID(2, []*Message{msg}, 0)
+		if !msg.GuildID.IsZero() || !msg.IsDirectMessage() {
+			t.Errorf("expected a direct message, got guild ID %d", msg.GuildID)
+		}
+	})
+
+	t.Run("cache miss", func(t *testing.T) {
+		msg := &Message{ID: 100, ChannelID: 3}
+		client.backfillGuildID(3, []*Message{msg}, 0)
+		if !msg.GuildID.IsZero() {
+			t.Errorf("expected no guild ID, got %d", msg.GuildID)
+		}
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		msg := &Message{ID: 100, ChannelID: 1}
+		client.backfillGuildID(1, []*Message{msg}, IgnoreGuildIDBackfill)
+		if !msg.GuildID.IsZero() {
+			t.Errorf("expected the backfill to be skipped, got guild ID %d", msg.GuildID)
+		}
+	})
+}
+
+func TestMessage_Activity(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/channel/message_activity_spotify.json")
+	check(err, t)
+
+	message := &Message{}
+	if err = json.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+
+	if !message.IsActivityInvite() {
+		t.Error("expected an activity invite")
+	}
+	if message.Activity.Type != MessageActivityTypeListen {
+		t.Errorf("expected a listen activity, got %s", message.Activity.Type)
+	}
+	if !message.Activity.IsSpotify() {
+		t.Error("expected a Spotify invite")
+	}
+	if provider, id := message.Activity.ParsePartyID(); provider != "spotify" || id != "228846961774559232" {
+		t.Errorf("unexpected party ID parts: %q, %q", provider, id)
+	}
+
+	copied := message.DeepCopy().(*Message)
+	if copied.Activity != message.Activity {
+		t.Errorf("activity was not copied, got %+v", copied.Activity)
+	}
+
+	game := MessageActivity{Type: MessageActivityTypeJoin, PartyID: "b1b4b5a3-7c6d"}
+	if game.IsSpotify() {
+		t.Error("a game invite is not a Spotify invite")
+	}
+	if provider, id := game.ParsePartyID(); provider != "" || id != game.PartyID {
+		t.Errorf("unexpected party ID parts: %q, %q", provider, id)
+	}
+	if (&Message{}).IsActivityInvite() {
+		t.Error("a message without an activity is not an invite")
+	}
+	if s := MessageActivityType(9).String(); s != "MessageActivityType(9)" {
+		t.Errorf("unexpected string for an unknown type: %s", s)
+	}
+}
@@ -0,0 +1,69 @@
+// Package metrics holds ready made implementations of disgord.RESTMetrics.
+package metrics
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+
+	"github.com/andersfylling/disgord"
+)
+
+// Expvar publishes the REST statistics as expvar maps, which are served as JSON on /debug/vars when the
+// expvar package is imported by a http server. The maps are:
+//
+//  requests            request count per "{method} {bucket} {status}"
+//  rate_limited        429 responses per bucket
+//  latency_ms          total round trip time per bucket, divide by the request count for the average
+//  ratelimit_wait_ms   total time spent waiting on the rate limit bucket, per bucket
+//  rate_limited_scope  429 responses per scope, "bucket" or "global"
+type Expvar struct {
+	requests         *expvar.Map
+	rateLimited      *expvar.Map
+	latency          *expvar.Map
+	rateLimitWait    *expvar.Map
+	rateLimitedScope *expvar.Map
+}
+
+var _ disgord.RESTMetrics = (*Expvar)(nil)
+
+// NewExpvar creates an Expvar published under the given name. It panics if the name is already in use,
+// like expvar.Publish.
+func NewExpvar(name string) *Expvar {
+	m := &Expvar{
+		requests:         new(expvar.Map).Init(),
+		rateLimited:      new(expvar.Map).Init(),
+		latency:          new(expvar.Map).Init(),
+		rateLimitWait:    new(expvar.Map).Init(),
+		rateLimitedScope: new(expvar.Map).Init(),
+	}
+
+	root := expvar.NewMap(name)
+	root.Set("requests", m.requests)
+	root.Set("rate_limited", m.rateLimited)
+	root.Set("latency_ms", m.latency)
+	root.Set("ratelimit_wait_ms", m.rateLimitWait)
+	root.Set("rate_limited_scope", m.rateLimitedScope)
+	return m
+}
+
+func (m *Expvar) ObserveRequest(bucket string, method string, status int, latency, rateLimitWait time.Duration) {
+	m.requests.Add(method+" "+bucket+" "+strconv.Itoa(status), 1)
+	m.latency.AddFloat(bucket, milliseconds(latency))
+	m.rateLimitWait.AddFloat(bucket, milliseconds(rateLimitWait))
+	if status == 429 {
+		m.rateLimited.Add(bucket, 1)
+	}
+}
+
+func (m *Expvar) IncRateLimited(global bool) {
+	if global {
+		m.rateLimitedScope.Add("global", 1)
+	} else {
+		m.rateLimitedScope.Add("bucket", 1)
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,44 @@
+// +build !integration
+
+package metrics
+
+import (
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestExpvar(t *testing.T) {
+	m := NewExpvar("disgord_test")
+	m.ObserveRequest("abc", "GET", 200, 20*time.Millisecond, 0)
+	m.ObserveRequest("abc", "GET", 429, 10*time.Millisecond, 5*time.Millisecond)
+	m.IncRateLimited(false)
+
+	root, ok := expvar.Get("disgord_test").(*expvar.Map)
+	if !ok {
+		t.Fatal("the metrics were not published")
+	}
+
+	get := func(name, key string) string {
+		v := root.Get(name).(*expvar.Map).Get(key)
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+	if got := get("requests", "GET abc 200"); got != "1" {
+		t.Errorf("expected one successful request, got %s", got)
+	}
+	if got := get("rate_limited", "abc"); got != "1" {
+		t.Errorf("expected one 429 for the bucket, got %s", got)
+	}
+	if got := get("latency_ms", "abc"); got != "30" {
+		t.Errorf("expected a total latency of 30ms, got %s", got)
+	}
+	if got := get("ratelimit_wait_ms", "abc"); got != "5" {
+		t.Errorf("expected a total wait of 5ms, got %s", got)
+	}
+	if got := get("rate_limited_scope", "bucket"); got != "1" {
+		t.Errorf("expected one bucket rate limit, got %s", got)
+	}
+}
@@ -0,0 +1,151 @@
+// Package prometheus exports the REST rate limit statistics through the Prometheus client library. The
+// Collector counts the requests as the disgord.RESTMetrics of a client, and reads the buckets from
+// Client.RateLimitStats when it is collected:
+//
+//  collector := prometheus.New()
+//  client := disgord.New(disgord.Config{BotToken: token, RESTMetrics: collector})
+//  collector.UseStats(client.RateLimitStats)
+//
+//  registry := prom.NewRegistry() // github.com/prometheus/client_golang/prometheus
+//  registry.MustRegister(collector)
+//  http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+//
+// It can be registered with prom.MustRegister as well, to be served by promhttp.Handler next to the Go
+// runtime metrics. The package is a separate module, such that disgord itself does not depend on the
+// Prometheus client library.
+//
+// Buckets are labelled by their Discord bucket hash, which is shared by every channel and guild. Requests to
+// endpoints without a known hash are labelled by the route, without the channel, guild, webhook or interaction
+// ID and without tokens, such that the number of series stays small. See disgord.RESTBucketRoute.
+package prometheus
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exports the REST rate limits to Prometheus, see New
+type Collector struct {
+	requests        *prom.CounterVec
+	rateLimitWait   *prom.CounterVec
+	tooManyRequests *prom.CounterVec
+
+	mu    sync.Mutex
+	stats func() disgord.RateLimitStats
+
+	buckets           *prom.Desc
+	groupBuckets      *prom.Desc
+	limitedBuckets    *prom.Desc
+	bucketRateLimits  *prom.Desc
+	globalLimited     *prom.Desc
+	globalRateLimits  *prom.Desc
+	globalUtilization *prom.Desc
+	invalidRequests   *prom.Desc
+}
+
+var _ disgord.RESTMetrics = (*Collector)(nil)
+var _ prom.Collector = (*Collector)(nil)
+
+// New creates a Collector. Give it to disgord.Config.RESTMetrics to count the requests, and to UseStats to
+// export the buckets, then register it with a prometheus.Registerer.
+func New() *Collector {
+	c := &Collector{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Name: "disgord_rest_requests_total",
+			Help: "REST requests sent to Discord, including retries.",
+		}, []string{"group", "method", "status"}),
+		rateLimitWait: prom.NewCounterVec(prom.CounterOpts{
+			Name: "disgord_rest_rate_limit_wait_seconds_total",
+			Help: "Time requests were queued in their rate limit bucket before they were sent.",
+		}, []string{"group"}),
+		tooManyRequests: prom.NewCounterVec(prom.CounterOpts{
+			Name: "disgord_rest_too_many_requests_total",
+			Help: "429 Too Many Requests responses, by the scope of the rate limit.",
+		}, []string{"scope"}),
+
+		buckets:           prom.NewDesc("disgord_rest_buckets", "Rate limit buckets, excluding the global bucket.", nil, nil),
+		groupBuckets:      prom.NewDesc("disgord_rest_group_buckets", "Rate limit buckets per Discord bucket hash, one per channel, guild or webhook.", []string{"group"}, nil),
+		limitedBuckets:    prom.NewDesc("disgord_rest_limited_buckets", "Rate limit buckets without requests left until they reset.", nil, nil),
+		bucketRateLimits:  prom.NewDesc("disgord_rest_bucket_rate_limits_total", "Times a rate limit bucket ran out of requests before its reset.", nil, nil),
+		globalLimited:     prom.NewDesc("disgord_rest_global_limited", "Whether the global rate limit is in effect.", nil, nil),
+		globalRateLimits:  prom.NewDesc("disgord_rest_global_rate_limits_total", "Times the global rate limit came into effect.", nil, nil),
+		globalUtilization: prom.NewDesc("disgord_rest_global_utilization", "Part of the global request throttle that is used, from 0 to 1.", nil, nil),
+		invalidRequests:   prom.NewDesc("disgord_rest_invalid_requests", "401, 403, 404 and 429 responses within the invalid request window of Discord.", nil, nil),
+	}
+
+	// both scopes are exported from the start, such that a rate of zero can be told from a missing series
+	c.tooManyRequests.WithLabelValues("bucket")
+	c.tooManyRequests.WithLabelValues("global")
+	return c
+}
+
+// UseStats exports the buckets returned by stats, such as Client.RateLimitStats
+func (c *Collector) UseStats(stats func() disgord.RateLimitStats) {
+	c.mu.Lock()
+	c.stats = stats
+	c.mu.Unlock()
+}
+
+func (c *Collector) ObserveRequest(bucket string, method string, status int, _, rateLimitWait time.Duration) {
+	group := disgord.RESTBucketRoute(bucket)
+	c.requests.WithLabelValues(group, method, strconv.Itoa(status)).Inc()
+	c.rateLimitWait.WithLabelValues(group).Add(rateLimitWait.Seconds())
+}
+
+func (c *Collector) IncRateLimited(global bool) {
+	scope := "bucket"
+	if global {
+		scope = "global"
+	}
+	c.tooManyRequests.WithLabelValues(scope).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.requests.Describe(ch)
+	c.rateLimitWait.Describe(ch)
+	c.tooManyRequests.Describe(ch)
+	ch <- c.buckets
+	ch <- c.groupBuckets
+	ch <- c.limitedBuckets
+	ch <- c.bucketRateLimits
+	ch <- c.globalLimited
+	ch <- c.globalRateLimits
+	ch <- c.globalUtilization
+	ch <- c.invalidRequests
+}
+
+// Collect implements prometheus.Collector. The buckets are only exported once UseStats is called.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.requests.Collect(ch)
+	c.rateLimitWait.Collect(ch)
+	c.tooManyRequests.Collect(ch)
+
+	c.mu.Lock()
+	statsFunc := c.stats
+	c.mu.Unlock()
+	if statsFunc == nil {
+		return
+	}
+
+	stats := statsFunc()
+	ch <- prom.MustNewConstMetric(c.buckets, prom.GaugeValue, float64(stats.Buckets))
+	for group, buckets := range stats.Groups {
+		ch <- prom.MustNewConstMetric(c.groupBuckets, prom.GaugeValue, float64(buckets), group)
+	}
+	ch <- prom.MustNewConstMetric(c.limitedBuckets, prom.GaugeValue, float64(stats.Limited))
+	ch <- prom.MustNewConstMetric(c.bucketRateLimits, prom.CounterValue, float64(stats.RateLimits))
+
+	var limited float64
+	if stats.Global.Limited {
+		limited = 1
+	}
+	ch <- prom.MustNewConstMetric(c.globalLimited, prom.GaugeValue, limited)
+	ch <- prom.MustNewConstMetric(c.globalRateLimits, prom.CounterValue, float64(stats.Global.RateLimits))
+	ch <- prom.MustNewConstMetric(c.globalUtilization, prom.GaugeValue, stats.GlobalUtilization)
+	ch <- prom.MustNewConstMetric(c.invalidRequests, prom.GaugeValue, float64(stats.InvalidRequests))
+}
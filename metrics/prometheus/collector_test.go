@@ -0,0 +1,111 @@
+// +build !integration
+
+package prometheus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := New()
+	c.ObserveRequest("abcd1234", "POST", 200, 20*time.Millisecond, 500*time.Millisecond)
+	c.ObserveRequest("abcd1234", "POST", 200, 20*time.Millisecond, time.Second)
+	c.ObserveRequest("GET:/channels/486833611564253186/pins", "GET", 429, 10*time.Millisecond, 0)
+	c.ObserveRequest("GET:/channels/540519296640614416/pins", "GET", 200, 10*time.Millisecond, 0)
+	c.ObserveRequest("POST:/interactions/486833611564253186/{token}/callback", "POST", 204, 10*time.Millisecond, 0)
+	c.ObserveRequest("POST:/interactions/540519296640614416/{token}/callback", "POST", 204, 10*time.Millisecond, 0)
+	c.IncRateLimited(false)
+	c.UseStats(func() disgord.RateLimitStats {
+		return disgord.RateLimitStats{
+			Buckets:           3,
+			Limited:           1,
+			Groups:            map[string]int{"abcd1234": 2},
+			RateLimits:        4,
+			Global:            disgord.BucketStats{Limited: true, RateLimits: 1},
+			GlobalUtilization: 0.5,
+		}
+	})
+
+	registry := prom.NewPedanticRegistry()
+	registry.MustRegister(c)
+	srv := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	expected := []string{
+		`disgord_rest_requests_total{group="abcd1234",method="POST",status="200"} 2`,
+		// the channels and interactions are not part of the labels
+		`disgord_rest_requests_total{group="GET:/channels/{major}/pins",method="GET",status="200"} 1`,
+		`disgord_rest_requests_total{group="GET:/channels/{major}/pins",method="GET",status="429"} 1`,
+		`disgord_rest_requests_total{group="POST:/interactions/{major}/{token}/callback",method="POST",status="204"} 2`,
+		`disgord_rest_rate_limit_wait_seconds_total{group="abcd1234"} 1.5`,
+		`disgord_rest_too_many_requests_total{scope="bucket"} 1`,
+		`disgord_rest_too_many_requests_total{scope="global"} 0`,
+		`disgord_rest_buckets 3`,
+		`disgord_rest_group_buckets{group="abcd1234"} 2`,
+		`disgord_rest_limited_buckets 1`,
+		`disgord_rest_bucket_rate_limits_total 4`,
+		`disgord_rest_global_limited 1`,
+		`disgord_rest_global_rate_limits_total 1`,
+		`disgord_rest_global_utilization 0.5`,
+		"# TYPE disgord_rest_requests_total counter",
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected the line %s in\n%s", line, body)
+		}
+	}
+}
+
+func TestCollector_WithoutStats(t *testing.T) {
+	c := New()
+	c.ObserveRequest("abcd1234", "GET", 200, 0, 0)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP disgord_rest_requests_total REST requests sent to Discord, including retries.
+# TYPE disgord_rest_requests_total counter
+disgord_rest_requests_total{group="abcd1234",method="GET",status="200"} 1
+`), "disgord_rest_requests_total", "disgord_rest_buckets"); err != nil {
+		t.Error(err)
+	}
+}
+
+func ExampleCollector() {
+	collector := New()
+	collector.UseStats(func() disgord.RateLimitStats {
+		// client.RateLimitStats
+		return disgord.RateLimitStats{Buckets: 2}
+	})
+
+	registry := prom.NewRegistry()
+	registry.MustRegister(collector)
+	srv := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, "disgord_rest_buckets ") {
+			fmt.Println(line)
+		}
+	}
+	// Output: disgord_rest_buckets 2
+}
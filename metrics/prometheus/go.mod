@@ -0,0 +1,12 @@
+module github.com/andersfylling/disgord/metrics/prometheus
+
+go 1.13
+
+require (
+	github.com/andersfylling/disgord v0.16.5
+	github.com/prometheus/client_golang v1.12.2
+	golang.org/x/net v0.11.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+)
+
+replace github.com/andersfylling/disgord => ../../
@@ -45,17 +45,16 @@ func (r *Reaction) CopyOverTo(other interface{}) (err error) {
 	return
 }
 
+// emojiReference returns the emoji reference used in the reaction endpoints, see Emoji.APIName.
 func emojiReference(i interface{}) (string, error) {
-	emojiCode := ""
-	if e, ok := i.(*Emoji); ok {
-		emojiCode = e.IDReference()
-	} else if _, ok := i.(string); ok {
-		emojiCode = i.(string) // unicode
-		emojiCode = unwrapEmoji(emojiCode)
-	} else {
+	switch t := i.(type) {
+	case *Emoji:
+		return t.APIName(), nil
+	case string:
+		return parseEmoji(t).APIName(), nil // unicode
+	default:
 		return "", errors.New("emoji type can only be a unicode string or a *Emoji struct")
 	}
-	return emojiCode, nil
 }
 
 func unwrapEmoji(e string) string {
@@ -134,6 +133,7 @@ func (r reactionQueryBuilder) Create(flags ...Flag) error {
 		Method:   httd.MethodPut,
 		Endpoint: endpoint.ChannelMessageReactionMe(r.cid, r.mid, emojiCode),
 		Ctx:      r.ctx,
+		Priority: httd.PriorityHigh,
 	}, flags)
 	req.expectsStatusCode = http.StatusNoContent
 
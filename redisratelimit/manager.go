@@ -0,0 +1,338 @@
+// Package redisratelimit shares the REST rate limits between processes through Redis, for bots that are
+// sharded across several processes with the same bot token. Each process keeps its own buckets, and asks
+// Redis before a request is sent whether another process has used up the bucket:
+//
+//  limiter := redisratelimit.New(redisratelimit.Config{Redis: scripter})
+//  defer limiter.Close()
+//
+//  client := disgord.New(disgord.Config{BotToken: token, RESTBucketManager: limiter})
+//
+// The bucket state is read and written by Lua scripts, such that concurrent processes share the remaining
+// requests atomically. When Redis can not be reached, requests are only rate limited locally until it
+// can be reached again, instead of waiting for Redis.
+package redisratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord/internal/httd"
+)
+
+// Scripter runs a Lua script on Redis. Wrap the Redis client of your choice, such as go-redis:
+//
+//  type scripter struct{ *redis.Client }
+//
+//  func (s scripter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//  	return s.Client.Eval(ctx, script, keys, args...).Result()
+//  }
+//
+//  func (s scripter) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+//  	return s.Client.EvalSha(ctx, sha1, keys, args...).Result()
+//  }
+//
+// Scripts are run with EvalSha, such that they are not sent with every call, and with Eval when Redis
+// responds that it does not know the script yet.
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Defaults of Config
+const (
+	DefaultPrefix        = "disgord:ratelimit:"
+	DefaultTimeout       = 50 * time.Millisecond
+	DefaultFlushInterval = 10 * time.Millisecond
+	DefaultRetryInterval = 5 * time.Second
+)
+
+// maxBatch is the number of bucket updates sent to Redis in a single script call
+const maxBatch = 64
+
+type Config struct {
+	Redis Scripter
+
+	// Prefix is put in front of every Redis key, such that several bots can share a Redis server.
+	// Defaults to DefaultPrefix.
+	Prefix string
+
+	// Timeout is how long a request waits for Redis before it is sent without it. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// FlushInterval is how often the rate limit details of the latest responses are written to Redis in a
+	// single batch. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+
+	// RetryInterval is how long Redis is skipped after it failed, such that requests are not held up by an
+	// unreachable server. Defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+}
+
+// Manager is a httd.RESTBucketManager that shares the rate limits through Redis, see New
+type Manager struct {
+	local  *httd.Manager
+	redis  Scripter
+	prefix string
+
+	timeout       time.Duration
+	retryInterval time.Duration
+
+	mu        sync.Mutex
+	downUntil time.Time
+	pending   map[string]update
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+var _ httd.RESTBucketManager = (*Manager)(nil)
+
+// update is the rate limit state of a bucket after a response
+type update struct {
+	remaining int64
+	reset     int64 // unix milliseconds
+}
+
+// New creates a Manager, and starts writing the rate limit details of responses to Redis in the background.
+// Call Close to stop it.
+func New(conf Config) *Manager {
+	if conf.Prefix == "" {
+		conf.Prefix = DefaultPrefix
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	if conf.FlushInterval == 0 {
+		conf.FlushInterval = DefaultFlushInterval
+	}
+	if conf.RetryInterval == 0 {
+		conf.RetryInterval = DefaultRetryInterval
+	}
+
+	m := &Manager{
+		local:         httd.NewManager(nil),
+		redis:         conf.Redis,
+		prefix:        conf.Prefix,
+		timeout:       conf.Timeout,
+		retryInterval: conf.RetryInterval,
+		pending:       make(map[string]update),
+		flush:         make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go m.run(conf.FlushInterval)
+	return m
+}
+
+// Close writes the pending updates to Redis, and stops the background writer
+func (m *Manager) Close() error {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+	<-m.done
+	return nil
+}
+
+func (m *Manager) Bucket(localHash string, cb func(bucket httd.RESTBucket)) {
+	m.local.Bucket(localHash, func(bucket httd.RESTBucket) {
+		cb(&sharedBucket{local: bucket, manager: m, key: m.prefix + m.local.BucketKey(localHash)})
+	})
+}
+
+func (m *Manager) BucketGrouping() (group map[string][]string) {
+	return m.local.BucketGrouping()
+}
+
+// WaitTime estimates the wait for the local buckets of this process, see httd.Manager.WaitTime. Requests
+// can still wait for another process to free up the shared bucket.
+func (m *Manager) WaitTime(localHash string) time.Duration {
+	return m.local.WaitTime(localHash)
+}
+
+// Reserve holds requests of the local bucket of this process aside, see httd.Manager.Reserve. Requests that
+// use the reservation can still wait for another process to free up the shared bucket.
+func (m *Manager) Reserve(localHash string, n int) *httd.Reservation {
+	return m.local.Reserve(localHash, n)
+}
+
+// CancelReservation gives the unused requests of the reservation back to the local bucket
+func (m *Manager) CancelReservation(res *httd.Reservation) {
+	m.local.CancelReservation(res)
+}
+
+// Stats describes the local buckets of this process
+func (m *Manager) Stats() httd.RateLimitStats {
+	return m.local.Stats()
+}
+
+func (m *Manager) available() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Now().After(m.downUntil)
+}
+
+func (m *Manager) failed() {
+	m.mu.Lock()
+	m.downUntil = time.Now().Add(m.retryInterval)
+	m.mu.Unlock()
+}
+
+// eval runs the script by its digest, and sends the script itself when Redis does not have it in its cache,
+// such as after a restart
+func (m *Manager) eval(ctx context.Context, s script, keys []string, args ...interface{}) (interface{}, error) {
+	result, err := m.redis.EvalSha(ctx, s.sha1, keys, args...)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		result, err = m.redis.Eval(ctx, s.src, keys, args...)
+	}
+	return result, err
+}
+
+// acquire takes a request from the shared bucket, and returns how long to wait when it is exhausted
+func (m *Manager) acquire(ctx context.Context, key string) (wait time.Duration, global bool) {
+	if !m.available() {
+		return 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	result, err := m.eval(ctx, acquireScript, []string{key, m.prefix + httd.GlobalHash}, now)
+	if err != nil {
+		m.failed()
+		return 0, false
+	}
+	values, _ := result.([]interface{})
+	if len(values) != 2 {
+		return 0, false
+	}
+	ms, _ := values[0].(int64)
+	isGlobal, _ := values[1].(int64)
+	return time.Duration(ms) * time.Millisecond, isGlobal == 1
+}
+
+// queue stores the rate limit details of a response until the next flush
+func (m *Manager) queue(key string, header http.Header) {
+	reset, err := strconv.ParseInt(header.Get(httd.XRateLimitReset), 10, 64)
+	if err != nil || reset == 0 {
+		return
+	}
+	remaining, err := strconv.ParseInt(header.Get(httd.XRateLimitRemaining), 10, 64)
+	if err != nil {
+		// global rate limits come without the remaining header
+		remaining = 0
+	}
+	if header.Get(httd.XRateLimitGlobal) == "true" {
+		key = m.prefix + httd.GlobalHash
+	}
+
+	m.mu.Lock()
+	previous, exists := m.pending[key]
+	if !exists || reset > previous.reset || (reset == previous.reset && remaining < previous.remaining) {
+		m.pending[key] = update{remaining: remaining, reset: reset}
+	}
+	full := len(m.pending) >= maxBatch
+	m.mu.Unlock()
+
+	if full {
+		select {
+		case m.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (m *Manager) run(interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			m.write()
+			return
+		case <-ticker.C:
+		case <-m.flush:
+		}
+		m.write()
+	}
+}
+
+// write sends the pending updates to Redis in batches. Updates are dropped while Redis is unreachable, as
+// they are outdated by the time it is back.
+func (m *Manager) write() {
+	m.mu.Lock()
+	pending := m.pending
+	if len(pending) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	m.pending = make(map[string]update)
+	m.mu.Unlock()
+	if !m.available() {
+		return
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	keys := make([]string, 0, maxBatch)
+	args := make([]interface{}, 0, 3*maxBatch)
+	send := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		defer cancel()
+		if _, err := m.eval(ctx, updateScript, keys, args...); err != nil {
+			m.failed()
+		}
+		keys, args = keys[:0], args[:0]
+	}
+	for key, u := range pending {
+		// the state is kept a little longer than the reset, to cover the clock difference of the processes
+		expiry := u.reset - now + int64(time.Second/time.Millisecond)
+		if expiry <= 0 {
+			continue
+		}
+		keys = append(keys, key)
+		args = append(args, u.remaining, u.reset, expiry)
+		if len(keys) == maxBatch {
+			send()
+		}
+	}
+	if len(keys) > 0 {
+		send()
+	}
+}
+
+// sharedBucket waits for the local bucket, and then for the shared bucket in Redis
+type sharedBucket struct {
+	local   httd.RESTBucket
+	manager *Manager
+	key     string
+}
+
+func (b *sharedBucket) Transaction(ctx context.Context, do func() (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	return b.local.Transaction(ctx, func() (*http.Response, []byte, error) {
+		if wait, global := b.manager.acquire(ctx, b.key); wait > 0 {
+			if deadline, ok := ctx.Deadline(); ok && deadline.Before(time.Now().Add(wait)) {
+				return nil, nil, &httd.RateLimitError{RetryAfter: wait, Global: global}
+			}
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return nil, nil, fmt.Errorf("time out: %w", ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		resp, body, err := do()
+		if err == nil && resp != nil {
+			b.manager.queue(b.key, resp.Header)
+		}
+		return resp, body, err
+	})
+}
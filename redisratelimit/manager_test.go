@@ -0,0 +1,308 @@
+// +build !integration
+
+package redisratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord/internal/httd"
+)
+
+// fakeRedis runs the scripts of the package on an in-memory store, such that the tests do not need a Redis
+// server. Like Redis, it only knows a script by its digest after it has been sent with EVAL.
+type fakeRedis struct {
+	sync.Mutex
+	hashes  map[string]map[string]int64
+	expires map[string]time.Time
+	cached  map[string]bool
+
+	calls    int // every call, including failed ones
+	runs     int // scripts that were run
+	evals    int
+	evalShas int
+	down     bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		hashes:  make(map[string]map[string]int64),
+		expires: make(map[string]time.Time),
+		cached:  make(map[string]bool),
+	}
+}
+
+var errNoScript = errors.New("NOSCRIPT No matching script. Please use EVAL.")
+
+func (r *fakeRedis) Eval(ctx context.Context, src string, keys []string, args ...interface{}) (interface{}, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.calls++
+	r.evals++
+	if r.down {
+		return nil, errors.New("dial tcp: connection refused")
+	}
+	s := newScript(src)
+	r.cached[s.sha1] = true
+	return r.run(s.sha1, keys, args)
+}
+
+func (r *fakeRedis) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.calls++
+	r.evalShas++
+	if r.down {
+		return nil, errors.New("dial tcp: connection refused")
+	}
+	if !r.cached[sha1] {
+		return nil, errNoScript
+	}
+	return r.run(sha1, keys, args)
+}
+
+// hash returns the fields of a key, or nil when it does not exist or has expired
+func (r *fakeRedis) hash(key string) map[string]int64 {
+	if expiry, ok := r.expires[key]; ok && time.Now().After(expiry) {
+		delete(r.hashes, key)
+		delete(r.expires, key)
+	}
+	return r.hashes[key]
+}
+
+func (r *fakeRedis) run(sha1 string, keys []string, args []interface{}) (interface{}, error) {
+	r.runs++
+	switch sha1 {
+	case acquireScript.sha1:
+		now := args[0].(int64)
+		if reset, ok := r.hash(keys[1])["reset"]; ok && reset > now {
+			return []interface{}{reset - now, int64(1)}, nil
+		}
+		state := r.hash(keys[0])
+		remaining, hasRemaining := state["remaining"]
+		reset, hasReset := state["reset"]
+		if !hasRemaining || !hasReset || reset <= now {
+			return []interface{}{int64(0), int64(0)}, nil
+		}
+		if remaining <= 0 {
+			return []interface{}{reset - now, int64(0)}, nil
+		}
+		state["remaining"]--
+		return []interface{}{int64(0), int64(0)}, nil
+	case updateScript.sha1:
+		for i, key := range keys {
+			remaining, reset, expiry := args[i*3].(int64), args[i*3+1].(int64), args[i*3+2].(int64)
+			state := r.hash(key)
+			if state == nil {
+				state = make(map[string]int64)
+				r.hashes[key] = state
+			}
+			currentRemaining, hasRemaining := state["remaining"]
+			if currentReset, ok := state["reset"]; !ok || reset > currentReset {
+				state["remaining"], state["reset"] = remaining, reset
+			} else if reset == currentReset && (!hasRemaining || remaining < currentRemaining) {
+				state["remaining"] = remaining
+			}
+			r.expires[key] = time.Now().Add(time.Duration(expiry) * time.Millisecond)
+		}
+		return int64(len(keys)), nil
+	}
+	return nil, errNoScript
+}
+
+func response(remaining int, reset time.Time) func() (*http.Response, []byte, error) {
+	return func() (*http.Response, []byte, error) {
+		header := http.Header{}
+		header.Set(httd.XRateLimitRemaining, strconv.Itoa(remaining))
+		header.Set(httd.XRateLimitReset, strconv.FormatInt(reset.UnixNano()/int64(time.Millisecond), 10))
+		header.Set(httd.DisgordNormalizedHeader, "true")
+		return &http.Response{StatusCode: http.StatusOK, Header: header}, nil, nil
+	}
+}
+
+func send(m *Manager, ctx context.Context, endpoint string, do func() (*http.Response, []byte, error)) (err error) {
+	m.Bucket(endpoint, func(bucket httd.RESTBucket) {
+		_, _, err = bucket.Transaction(ctx, do)
+	})
+	return err
+}
+
+func TestManager_SharedBucket(t *testing.T) {
+	redis := newFakeRedis()
+
+	// two processes using the same bot token
+	a := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer a.Close()
+	b := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer b.Close()
+
+	reset := time.Now().Add(time.Minute)
+	if err := send(a, context.Background(), "/channels/1/messages", response(1, reset)); err != nil {
+		t.Fatal(err)
+	}
+	a.write()
+
+	// b takes the last request of the bucket, and has to wait for the next one
+	if err := send(b, context.Background(), "/channels/1/messages", response(1, reset)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var sent bool
+	err := send(a, ctx, "/channels/1/messages", func() (*http.Response, []byte, error) {
+		sent = true
+		return response(0, reset)()
+	})
+	var rateLimitErr *httd.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+	if sent {
+		t.Error("the request was sent while the shared bucket was exhausted")
+	}
+	if rateLimitErr.RetryAfter < 50*time.Second || rateLimitErr.Global {
+		t.Errorf("expected to wait for the bucket to reset, got %+v", rateLimitErr)
+	}
+
+	// other buckets are not affected
+	if err := send(b, ctx, "/guilds/1/members", response(5, reset)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestManager_GlobalRateLimit(t *testing.T) {
+	redis := newFakeRedis()
+	a := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer a.Close()
+	b := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer b.Close()
+
+	_ = send(a, context.Background(), "/channels/1/messages", func() (*http.Response, []byte, error) {
+		header := http.Header{}
+		header.Set(httd.XRateLimitGlobal, "true")
+		header.Set(httd.DisgordNormalizedHeader, "true")
+		header.Set(httd.XRateLimitReset, strconv.FormatInt(time.Now().Add(time.Minute).UnixNano()/int64(time.Millisecond), 10))
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header}, nil, nil
+	})
+	a.write()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := send(b, ctx, "/guilds/1/members", response(5, time.Now().Add(time.Minute)))
+	var rateLimitErr *httd.RateLimitError
+	if !errors.As(err, &rateLimitErr) || !rateLimitErr.Global {
+		t.Fatalf("expected a global rate limit error, got %v", err)
+	}
+}
+
+func TestManager_BatchedUpdates(t *testing.T) {
+	redis := newFakeRedis()
+	m := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer m.Close()
+
+	reset := time.Now().Add(time.Minute)
+	for i := 0; i < 10; i++ {
+		endpoint := "/channels/" + strconv.Itoa(i%3) + "/messages"
+		if err := send(m, context.Background(), endpoint, response(10-i, reset)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	redis.Lock()
+	before := redis.runs
+	redis.Unlock()
+	m.write()
+	redis.Lock()
+	writes := redis.runs - before
+	redis.Unlock()
+	if writes != 1 {
+		t.Errorf("expected the updates to be written in one call, got %d", writes)
+	}
+
+	// the lowest remaining of each bucket is kept
+	key := DefaultPrefix + m.local.BucketKey("/channels/0/messages")
+	redis.Lock()
+	defer redis.Unlock()
+	if remaining := redis.hashes[key]["remaining"]; remaining != 1 {
+		t.Errorf("expected 1 remaining, got %d", remaining)
+	}
+	if expiry, ok := redis.expires[key]; !ok || expiry.Before(reset) {
+		t.Error("expected the bucket to expire after the reset")
+	}
+}
+
+func TestManager_RedisUnavailable(t *testing.T) {
+	redis := newFakeRedis()
+	m := New(Config{Redis: redis, FlushInterval: time.Hour, RetryInterval: time.Hour})
+	defer m.Close()
+	redis.Lock()
+	redis.down = true
+	redis.Unlock()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := send(m, context.Background(), "/channels/1/messages", response(5, time.Now().Add(time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.write()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("requests were held up by redis for %s", elapsed)
+	}
+
+	redis.Lock()
+	calls := redis.calls
+	redis.Unlock()
+	if calls != 1 {
+		t.Errorf("expected redis to be skipped after it failed, got %d calls", calls)
+	}
+}
+
+func TestManager_EvalSha(t *testing.T) {
+	redis := newFakeRedis()
+	m := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer m.Close()
+
+	// the script is sent once, when redis does not know it yet
+	for i := 0; i < 3; i++ {
+		if err := send(m, context.Background(), "/channels/1/messages", response(5, time.Now().Add(time.Minute))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	redis.Lock()
+	defer redis.Unlock()
+	if redis.evals != 1 || redis.evalShas != 3 {
+		t.Errorf("expected 1 EVAL and 3 EVALSHA calls, got %d and %d", redis.evals, redis.evalShas)
+	}
+}
+
+func TestManager_Timeout(t *testing.T) {
+	redis := newFakeRedis()
+	a := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer a.Close()
+	b := New(Config{Redis: redis, FlushInterval: time.Hour})
+	defer b.Close()
+
+	reset := time.Now().Add(time.Minute)
+	if err := send(a, context.Background(), "/channels/1/messages", response(0, reset)); err != nil {
+		t.Fatal(err)
+	}
+	a.write()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	err := send(b, ctx, "/channels/1/messages", response(0, reset))
+	if !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "time out") {
+		t.Errorf("expected a wrapped time out, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package redisratelimit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// script is a Lua script, and the SHA1 digest that Redis knows it by once it is cached
+type script struct {
+	src  string
+	sha1 string
+}
+
+func newScript(src string) script {
+	digest := sha1.Sum([]byte(src))
+	return script{src: src, sha1: hex.EncodeToString(digest[:])}
+}
+
+// acquireScript takes a request from a bucket, unless the bucket or the global bucket is exhausted, in which
+// case it returns the milliseconds until the reset and whether it is the global bucket. Buckets without
+// state, or that have reset, are not limited until a response tells otherwise.
+//
+// KEYS[1] bucket, KEYS[2] global bucket
+// ARGV[1] the current unix time in milliseconds
+var acquireScript = newScript(`
+local now = tonumber(ARGV[1])
+local globalReset = tonumber(redis.call('HGET', KEYS[2], 'reset'))
+if globalReset and globalReset > now then
+	return {globalReset - now, 1}
+end
+
+local state = redis.call('HMGET', KEYS[1], 'remaining', 'reset')
+local remaining = tonumber(state[1])
+local reset = tonumber(state[2])
+if not remaining or not reset or reset <= now then
+	return {0, 0}
+end
+if remaining <= 0 then
+	return {reset - now, 0}
+end
+redis.call('HINCRBY', KEYS[1], 'remaining', -1)
+return {0, 0}
+`)
+
+// updateScript stores the rate limit state of a batch of responses. A more recent reset replaces the state,
+// and for the same reset the lowest remaining wins, as the responses of the processes arrive out of order.
+//
+// KEYS the buckets
+// ARGV three values per bucket: remaining, the reset as unix time in milliseconds, and the key expiry in
+// milliseconds
+var updateScript = newScript(`
+for i, key in ipairs(KEYS) do
+	local remaining = tonumber(ARGV[i*3-2])
+	local reset = tonumber(ARGV[i*3-1])
+	local state = redis.call('HMGET', key, 'remaining', 'reset')
+	local currentRemaining = tonumber(state[1])
+	local currentReset = tonumber(state[2])
+	if not currentReset or reset > currentReset then
+		redis.call('HSET', key, 'remaining', remaining, 'reset', reset)
+	elseif reset == currentReset and (not currentRemaining or remaining < currentRemaining) then
+		redis.call('HSET', key, 'remaining', remaining)
+	end
+	redis.call('PEXPIRE', key, ARGV[i*3])
+end
+return #KEYS
+`)
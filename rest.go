@@ -1,12 +1,14 @@
 package disgord
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/andersfylling/disgord/json"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/andersfylling/disgord/internal/gateway"
 	"github.com/andersfylling/disgord/internal/httd"
@@ -14,6 +16,143 @@ import (
 
 type ErrRest = httd.ErrREST
 
+// FieldError describes a rejected field of an Invalid Form Body error, see ErrRest.Errors
+type FieldError = httd.FieldError
+
+// RetryPolicy decides how REST requests are retried on server errors and temporary transport errors.
+// See Config.RetryPolicy.
+type RetryPolicy = httd.RetryPolicy
+
+// DefaultRetryPolicy is used unless Config.RetryPolicy is set
+var DefaultRetryPolicy = httd.DefaultRetryPolicy
+
+// RESTMetrics receives statistics about every REST request, see Config.RESTMetrics, and the metrics and
+// metrics/prometheus packages for ready made expvar and Prometheus implementations.
+type RESTMetrics = httd.Metrics
+
+// RESTBucketRoute groups the buckets given to RESTMetrics by their route, without the guild, channel, webhook
+// or interaction ID and without tokens, such that metrics labelled by it have a bounded number of series
+func RESTBucketRoute(bucket string) string {
+	return httd.BucketRoute(bucket)
+}
+
+// RateLimitInfo holds the remaining requests and reset time of the rate limit bucket used by a REST call,
+// see CaptureRateLimit
+type RateLimitInfo = httd.RateLimitInfo
+
+// RateLimitStats is a snapshot of the REST rate limit buckets, see Client.RateLimitStats
+type RateLimitStats = httd.RateLimitStats
+
+// BucketStats describes a single REST rate limit bucket, see RateLimitStats
+type BucketStats = httd.BucketStats
+
+// RESTWaitTimeUnknown is returned by Client.RESTWaitTime when the rate limit of an endpoint is not known
+const RESTWaitTimeUnknown = httd.WaitTimeUnknown
+
+// RESTReservation holds requests of a REST rate limit bucket aside for a batch, see Client.RESTReserve
+type RESTReservation = httd.Reservation
+
+// RESTHooks are called before and after every REST request is sent, with the request ID that is part of the
+// ErrRest returned when the request fails. Retries share the ID.
+type RESTHooks = httd.RequestHooks
+
+// RateLimitPrecision is the precision of the rate limit resets, see Config.RESTRateLimitPrecision
+type RateLimitPrecision = httd.RateLimitPrecision
+
+const (
+	RateLimitPrecisionMillisecond = httd.RateLimitPrecisionMillisecond
+	RateLimitPrecisionSecond      = httd.RateLimitPrecisionSecond
+)
+
+// RESTPacing decides when the remaining requests of a rate limit bucket are sent, see Config.RESTPacing
+type RESTPacing = httd.Pacing
+
+const (
+	RESTPacingBurst  = httd.PacingBurst
+	RESTPacingSpread = httd.PacingSpread
+)
+
+// RESTQueueLimits bounds the requests waiting for a rate limit bucket, see Config.RESTQueueLimits
+type RESTQueueLimits = httd.QueueLimits
+
+// RateLimitEvent describes a REST request that was delayed or rejected because of a rate limit, or that
+// received a 429 Too Many Requests response. See Config.OnRateLimit.
+type RateLimitEvent = httd.RateLimitEvent
+
+// RESTBucketManager rate limits the REST requests, see Config.RESTBucketManager. The redisratelimit package
+// shares the rate limits between processes.
+type RESTBucketManager = httd.RESTBucketManager
+
+// RESTBucket is a rate limit bucket of a RESTBucketManager
+type RESTBucket = httd.RESTBucket
+
+// CaptureRateLimit returns a context that writes the rate limit details of a successful REST call to info.
+// Give it to a query builder using WithContext:
+//
+//  var info disgord.RateLimitInfo
+//  ctx := disgord.CaptureRateLimit(context.Background(), &info)
+//  msg, err := client.Channel(channelID).WithContext(ctx).CreateMessage(params)
+//  // info.Remaining requests can be sent before info.Reset
+//
+// info is left untouched when the call fails or is answered by the cache.
+func CaptureRateLimit(ctx context.Context, info *RateLimitInfo) context.Context {
+	return httd.WithRateLimitInfo(ctx, info)
+}
+
+// WithRetryPolicy returns a context that makes REST calls use the given policy instead of Config.RetryPolicy,
+// such as giving up quickly on an interactive reply while fetching history keeps retrying:
+//
+//  ctx := disgord.WithRetryPolicy(context.Background(), disgord.RetryPolicy{MaxAttempts: 1})
+//  msg, err := client.Channel(channelID).WithContext(ctx).CreateMessage(params)
+//
+// Zero MaxAttempts, BaseDelay and MaxDelay are taken from Config.RetryPolicy.
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return httd.WithRetryPolicy(ctx, policy)
+}
+
+// WithMaxRateLimitDelay returns a context that makes REST calls wait at most delay for a rate limit instead of
+// Config.MaxRateLimitDelay, such as an interactive reply that may wait a little while background jobs give up
+// right away:
+//
+//  ctx := disgord.WithMaxRateLimitDelay(context.Background(), 2*time.Second)
+//  msg, err := client.Channel(channelID).WithContext(ctx).CreateMessage(params)
+//
+// A negative delay never waits, and returns a RateLimitError with the time until the rate limit resets.
+func WithMaxRateLimitDelay(ctx context.Context, delay time.Duration) context.Context {
+	return httd.WithMaxRateLimitDelay(ctx, delay)
+}
+
+// WithRESTReservation returns a context that makes REST calls use the requests held by the reservation,
+// instead of waiting for the rate limit bucket, see Client.RESTReserve
+func WithRESTReservation(ctx context.Context, res *RESTReservation) context.Context {
+	return httd.WithReservation(ctx, res)
+}
+
+type captureBodyKey struct{}
+
+// CaptureBody returns a context that copies the raw response body of a successful REST call to body, such that
+// fields unknown to disgord can be decoded by the caller. The typed result is returned as usual. Give it to
+// the REST method, or to a query builder using WithContext:
+//
+//  var raw []byte
+//  ctx := disgord.CaptureBody(context.Background(), &raw)
+//  msg, err := client.Channel(channelID).Message(messageID).Get(ctx)
+//  // json.Unmarshal(raw, &myMessage)
+//
+// body is left untouched when the call fails or the result is taken from the cache.
+func CaptureBody(ctx context.Context, body *[]byte) context.Context {
+	return context.WithValue(ctx, captureBodyKey{}, body)
+}
+
+func captureBody(ctx context.Context, body []byte) {
+	if ctx == nil {
+		return
+	}
+	if target, ok := ctx.Value(captureBodyKey{}).(*[]byte); ok && target != nil {
+		*target = append((*target)[:0], body...)
+	}
+}
+
 // URLQueryStringer converts a struct of values to a valid URL query string
 type URLQueryStringer interface {
 	URLQueryString() string
@@ -112,8 +251,15 @@ func (r *rest) stepDoRequest() (resp *http.Response, body []byte, err error) {
 	return
 }
 
+// isEmptyBody is true for responses without content. A JSON null counts as empty too, as decoding it would
+// return a zero value object from the pool.
+func isEmptyBody(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) == 0 || bytes.Equal(body, []byte("null"))
+}
+
 func (r *rest) processContent(body []byte) (v interface{}, err error) {
-	if len(body) == 0 {
+	if isEmptyBody(body) {
 		return nil, nil // nothing more to do
 	}
 
@@ -123,7 +269,7 @@ func (r *rest) processContent(body []byte) (v interface{}, err error) {
 	}
 
 	obj := r.Get()
-	if err = json.Unmarshal(body, obj); err != nil {
+	if err = codecOf(r.c.req).Unmarshal(body, obj); err != nil {
 		r.Put(obj)
 		return nil, err
 	}
@@ -143,9 +289,15 @@ func (r *rest) Execute() (v interface{}, err error) {
 		err = &httd.ErrREST{
 			HTTPCode: resp.StatusCode,
 			Msg:      "unexpected http response code. Got " + resp.Status + ", wants " + http.StatusText(r.expectsStatusCode),
+			Method:   r.conf.Method.String(),
+			Endpoint: httd.RedactEndpoint(r.conf.Endpoint),
 		}
 		return nil, err
 	}
+	captureBody(r.conf.Ctx, body)
+	if resp.StatusCode == http.StatusNoContent || r.expectsStatusCode == http.StatusNoContent {
+		return nil, nil
+	}
 
 	var obj interface{}
 	if obj, err = r.processContent(body); err != nil {
@@ -244,10 +396,11 @@ func (b *RESTBuilder) execute() (v interface{}, err error) {
 			return nil, err
 		}
 	}
+	captureBody(b.config.Ctx, body)
 
-	if len(body) > 1 && b.itemFactory != nil {
+	if !isEmptyBody(body) && b.itemFactory != nil {
 		v = b.itemFactory()
-		if err = json.Unmarshal(body, v); err != nil {
+		if err = codecOf(b.client).Unmarshal(body, v); err != nil {
 			return nil, err
 		}
 		executeInternalUpdater(v)
@@ -360,7 +513,7 @@ func (c *Client) GetGateway(ctx context.Context) (gateway *gateway.Gateway, err
 		return
 	}
 
-	err = json.Unmarshal(body, &gateway)
+	err = codecOf(c.req).Unmarshal(body, &gateway)
 	return
 }
 
@@ -383,17 +536,78 @@ func (c *Client) GetGatewayBot(ctx context.Context) (gateway *gateway.GatewayBot
 		return
 	}
 
-	err = json.Unmarshal(body, &gateway)
+	err = codecOf(c.req).Unmarshal(body, &gateway)
 	return
 }
 
+// RESTRequestConfig describes a REST request to Discord, see Client.RESTRequest.
+type RESTRequestConfig = httd.Request
+
+// HTTPMethod is the Method of a RESTRequestConfig, eg. HTTPMethod(http.MethodPost)
+type HTTPMethod = httd.HTTPMethod
+
+// RESTRequester sends REST requests, and is implemented by the built in REST client. A failed request must
+// return an error, usually an ErrRest. See Config.RESTRequester.
+type RESTRequester = httd.Requester
+
+// ContentTypeJSON is the ContentType to use when a RESTRequestConfig has a Body, which is then marshalled to JSON.
+const ContentTypeJSON = httd.ContentTypeJSON
+
+// RESTRequest [REST] sends a request to any endpoint of the Discord REST API, which is useful for endpoints that
+// are not yet supported by disgord. The request is rate limited and the errors are handled the same way as in the
+// built in methods. The response body is unmarshalled into the value returned by factory, and the value is returned
+// once populated. When factory is nil, the response is returned as a *json.RawMessage.
+//
+// The rate limit bucket is decided by the Endpoint, where the first snowflake after /channels, /guilds or /webhooks
+// is the major parameter, while any other snowflake is ignored. Always use the real IDs in the endpoint, eg.
+// "/channels/486833611564253186/messages/540519319814275089/new-feature", such that the request shares the
+// rate limit bucket of other requests to the same channel, guild or webhook.
+//
+//	msg, err := client.RESTRequest(&disgord.RESTRequestConfig{
+//		Method:      http.MethodPost,
+//		Endpoint:    "/channels/" + channelID.String() + "/messages",
+//		Body:        &disgord.CreateMessageParams{Content: "hello"},
+//		ContentType: disgord.ContentTypeJSON,
+//	}, func() interface{} {
+//		return &disgord.Message{}
+//	})
+func (c *Client) RESTRequest(req *RESTRequestConfig, factory func() interface{}, flags ...Flag) (interface{}, error) {
+	if req == nil {
+		return nil, errors.New("missing request configuration")
+	}
+	if req.Endpoint == "" || req.Endpoint[0] != '/' {
+		return nil, errors.New("endpoint must start with a /, eg. /channels/{channel.id}")
+	}
+	if factory == nil {
+		factory = func() interface{} {
+			return &json.RawMessage{}
+		}
+	}
+
+	r := c.newRESTRequest(req, flags)
+	r.factory = factory
+	return r.Execute()
+}
+
+// codecOf returns the JSON codec of the requester, see Config.RESTCodec. Requesters without a codec, such as a
+// custom RESTRequester, use the variables of the json package.
+func codecOf(req httd.Requester) json.Codec {
+	if c, ok := req.(interface{ Codec() json.Codec }); ok {
+		return c.Codec()
+	}
+	return json.PackageCodec{}
+}
+
+// errEmptyBody is returned when an object was expected, but the response had no content
+var errEmptyBody = errors.New("the response body was empty")
+
 func exec(f func() (interface{}, error), flags ...Flag) (v interface{}, err error) {
 	if v, err = f(); err != nil {
 		return nil, err
 	}
 
 	if v == nil {
-		return nil, errors.New("object was nil")
+		return nil, errEmptyBody
 	}
 
 	return v, nil
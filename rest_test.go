@@ -4,10 +4,16 @@ package disgord
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/andersfylling/disgord/httdtesting"
 	"github.com/andersfylling/disgord/internal/httd"
+	"github.com/andersfylling/disgord/json"
 )
 
 func verifyQueryString(t *testing.T, params URLQueryStringer, wants string) {
@@ -41,3 +47,96 @@ func TestParamHolder_URLQueryString(t *testing.T) {
 	params = urlQuery{}
 	verifyQueryString(t, params, "")
 }
+
+func TestCaptureBody(t *testing.T) {
+	mock := httdtesting.New(t)
+	mock.Expect("GET", "/channels/2/messages/3").Respond(http.StatusOK, `{"id":"3","channel_id":"2","new_field":true}`)
+	mock.Expect("GET", "/channels/2/messages/4").Respond(http.StatusNotFound, `{"code":10008,"message":"Unknown Message"}`)
+	defer mock.AssertExpectations()
+
+	client := New(Config{BotToken: "testing", RESTRequester: mock, DisableCache: true})
+
+	var raw []byte
+	ctx := CaptureBody(context.Background(), &raw)
+	msg, err := client.Channel(2).Message(3).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 3 {
+		t.Errorf("expected the typed message to be returned, got %+v", msg)
+	}
+
+	var extra struct {
+		NewField bool `json:"new_field"`
+	}
+	if err = json.Unmarshal(raw, &extra); err != nil || !extra.NewField {
+		t.Errorf("expected the raw body to hold the unknown field, got %q", string(raw))
+	}
+
+	captured := string(raw)
+	if _, err = client.Channel(2).Message(4).Get(ctx); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if string(raw) != captured {
+		t.Errorf("a failed request changed the captured body to %q", string(raw))
+	}
+}
+
+func TestRest_EmptyBody(t *testing.T) {
+	mock := httdtesting.New(t)
+	mock.Expect("DELETE", "/channels/2/messages/3").Respond(http.StatusNoContent, "")
+	mock.Expect("PUT", "/channels/2/pins/3").Respond(http.StatusNoContent, "")
+	mock.Expect("GET", "/channels/2/messages/3").Respond(http.StatusOK, "")
+	mock.Expect("GET", "/channels/2/messages/3").Respond(http.StatusOK, "null\n")
+	defer mock.AssertExpectations()
+
+	client := New(Config{BotToken: "testing", RESTRequester: mock, DisableCache: true})
+	ctx := context.Background()
+
+	if err := client.Channel(2).Message(3).Delete(ctx); err != nil {
+		t.Errorf("unexpected error when deleting a message: %s", err)
+	}
+	if err := client.Channel(2).Message(3).Pin(ctx); err != nil {
+		t.Errorf("unexpected error when pinning a message: %s", err)
+	}
+
+	for _, body := range []string{"empty", "null"} {
+		msg, err := client.Channel(2).Message(3).Get(ctx)
+		if msg != nil {
+			t.Errorf("%s body: expected no message, got %+v", body, msg)
+		}
+		if !errors.Is(err, errEmptyBody) {
+			t.Errorf("%s body: expected an empty body error, got %v", body, err)
+		}
+	}
+}
+
+func TestRESTHooks_RequestID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"code":50013,"message":"Missing Permissions"}`))
+	}))
+	defer srv.Close()
+
+	var logged uint64
+	client := New(Config{
+		BotToken:     "testing",
+		APIBaseURL:   srv.URL,
+		HTTPClient:   srv.Client(),
+		DisableCache: true,
+		RESTHooks: RESTHooks{
+			AfterResponse: func(id uint64, req *http.Request, resp *http.Response, err error) {
+				logged = id
+			},
+		},
+	})
+
+	_, err := client.Channel(2).CreateMessage(&CreateMessageParams{Content: "hi"})
+	if err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if logged == 0 || !strings.Contains(err.Error(), "(request "+strconv.FormatUint(logged, 10)+")") {
+		t.Errorf("expected the error to hold the request ID %d, got %q", logged, err.Error())
+	}
+}
@@ -71,6 +71,9 @@ func (b *guildAuditLogsBuilder) Execute() (log *AuditLog, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*AuditLog), nil
 }
 
@@ -139,6 +142,9 @@ func (b *createChannelInviteBuilder) Execute() (invite *Invite, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Invite), nil
 }
 
@@ -238,6 +244,9 @@ func (b *updateChannelBuilder) Execute() (channel *Channel, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Channel), nil
 }
 
@@ -288,6 +297,9 @@ func (b *createGuildEmojiBuilder) Execute() (emoji *Emoji, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Emoji), nil
 }
 
@@ -344,6 +356,9 @@ func (b *updateGuildEmojiBuilder) Execute() (emoji *Emoji, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Emoji), nil
 }
 
@@ -457,6 +472,9 @@ func (b *updateGuildBuilder) Execute() (guild *Guild, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Guild), nil
 }
 
@@ -514,6 +532,9 @@ func (b *updateGuildEmbedBuilder) Execute() (embed *GuildEmbed, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*GuildEmbed), nil
 }
 
@@ -645,6 +666,9 @@ func (b *updateMessageBuilder) Execute() (message *Message, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Message), nil
 }
 
@@ -760,6 +784,9 @@ func (b *updateGuildRoleBuilder) Execute() (role *Role, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Role), nil
 }
 
@@ -804,6 +831,9 @@ func (b *createDMBuilder) Execute() (channel *Channel, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Channel), nil
 }
 
@@ -848,6 +878,9 @@ func (b *createGroupDMBuilder) Execute() (channel *Channel, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Channel), nil
 }
 
@@ -912,6 +945,9 @@ func (b *getCurrentUserGuildsBuilder) Execute() (guilds []*Guild, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	tmp := v.(*[]*Guild)
 	return *tmp, nil
 }
@@ -992,6 +1028,9 @@ func (b *getUserConnectionsBuilder) Execute() (cons []*UserConnection, err error
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	tmp := v.(*[]*UserConnection)
 	return *tmp, nil
 }
@@ -1037,6 +1076,9 @@ func (b *getUserDMsBuilder) Execute() (channels []*Channel, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	tmp := v.(*[]*Channel)
 	return *tmp, nil
 }
@@ -1094,6 +1136,9 @@ func (b *updateCurrentUserBuilder) Execute() (user *User, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*User), nil
 }
 
@@ -1157,5 +1202,73 @@ func (b *updateWebhookBuilder) Execute() (webhook *Webhook, err error) {
 	if v, err = b.r.execute(); err != nil {
 		return nil, err
 	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
 	return v.(*Webhook), nil
 }
+
+// UpdateWebhookMessageBuilder is the interface for the builder.
+type UpdateWebhookMessageBuilder interface {
+	Execute() (message *Message, err error)
+	IgnoreCache() UpdateWebhookMessageBuilder
+	CancelOnRatelimit() UpdateWebhookMessageBuilder
+	URLParam(name string, v interface{}) UpdateWebhookMessageBuilder
+	Set(name string, v interface{}) UpdateWebhookMessageBuilder
+	SetContent(content string) UpdateWebhookMessageBuilder
+	SetEmbeds(embeds []*Embed) UpdateWebhookMessageBuilder
+	SetAllowedMentions(allowedMentions *AllowedMentions) UpdateWebhookMessageBuilder
+}
+
+// IgnoreCache will not fetch the data from the cache if available, and always execute a
+// a REST request. However, the response will always update the cache to keep it synced.
+func (b *updateWebhookMessageBuilder) IgnoreCache() UpdateWebhookMessageBuilder {
+	b.r.IgnoreCache()
+	return b
+}
+
+// CancelOnRatelimit will disable waiting if the request is rate limited by Discord.
+func (b *updateWebhookMessageBuilder) CancelOnRatelimit() UpdateWebhookMessageBuilder {
+	b.r.CancelOnRatelimit()
+	return b
+}
+
+// URLParam adds or updates an existing URL parameter.
+// eg. URLParam("age", 34) will cause the URL `/test` to become `/test?age=34`
+func (b *updateWebhookMessageBuilder) URLParam(name string, v interface{}) UpdateWebhookMessageBuilder {
+	b.r.queryParam(name, v)
+	return b
+}
+
+// Set adds or updates an existing a body parameter
+// eg. Set("age", 34) will cause the body `{}` to become `{"age":34}`
+func (b *updateWebhookMessageBuilder) Set(name string, v interface{}) UpdateWebhookMessageBuilder {
+	b.r.body[name] = v
+	return b
+}
+
+func (b *updateWebhookMessageBuilder) SetContent(content string) UpdateWebhookMessageBuilder {
+	b.r.param("content", content)
+	return b
+}
+
+func (b *updateWebhookMessageBuilder) SetEmbeds(embeds []*Embed) UpdateWebhookMessageBuilder {
+	b.r.param("embeds", embeds)
+	return b
+}
+
+func (b *updateWebhookMessageBuilder) SetAllowedMentions(allowedMentions *AllowedMentions) UpdateWebhookMessageBuilder {
+	b.r.param("allowed_mentions", allowedMentions)
+	return b
+}
+
+func (b *updateWebhookMessageBuilder) Execute() (message *Message, err error) {
+	var v interface{}
+	if v, err = b.r.execute(); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errEmptyBody
+	}
+	return v.(*Message), nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/andersfylling/disgord/internal/endpoint"
 	"github.com/andersfylling/disgord/internal/httd"
+	"github.com/andersfylling/disgord/json"
 )
 
 type roles []*Role
@@ -60,6 +61,26 @@ type Role struct {
 	guildID Snowflake
 }
 
+// UnmarshalJSON accepts the permissions as both a number and a string, as they are serialized as strings from
+// API v8. Permissions stays a uint64 for compatibility, see PermissionBits.
+func (r *Role) UnmarshalJSON(data []byte) error {
+	type role Role
+	v := struct {
+		*role
+		Permissions PermissionBit `json:"permissions"`
+	}{role: (*role)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.Permissions = uint64(v.Permissions)
+	return nil
+}
+
+// PermissionBits returns the permissions of the role as a PermissionBit
+func (r *Role) PermissionBits() PermissionBit {
+	return PermissionBit(r.Permissions)
+}
+
 var _ Mentioner = (*Role)(nil)
 var _ Reseter = (*Role)(nil)
 var _ DeepCopier = (*Role)(nil)
@@ -86,6 +86,8 @@ type Session interface {
 	AvgHeartbeatLatency() (duration time.Duration, err error)
 	// returns the latency for each given shard id. shardID => latency
 	HeartbeatLatencies() (latencies map[uint]time.Duration, err error)
+	// HeartbeatLatency returns the latency averaged across the shards, or zero before the first heartbeat ACK
+	HeartbeatLatency() time.Duration
 
 	RESTRatelimitBuckets() (group map[string][]string)
 
@@ -109,6 +111,7 @@ type Session interface {
 	// Status update functions
 	UpdateStatus(s *UpdateStatusPayload) error
 	UpdateStatusString(s string) error
+	SetActivity(activity *Activity) error
 
 	GetGuilds(ctx context.Context, params *GetCurrentUserGuildsParams, flags ...Flag) ([]*Guild, error)
 	GetConnectedGuilds() []Snowflake
@@ -22,11 +22,15 @@ func CopyMsgEvt(evt interface{}) interface{} {
 			ShardID: t.ShardID,
 		}
 	case *disgord.MessageUpdate:
-		return &disgord.MessageUpdate{
+		evt := &disgord.MessageUpdate{
 			Message: t.Message.DeepCopy().(*disgord.Message),
 			Ctx:     t.Ctx,
 			ShardID: t.ShardID,
 		}
+		if t.Partial != nil {
+			evt.Partial = t.Partial.DeepCopy().(*disgord.PartialMessage)
+		}
+		return evt
 	case *disgord.MessageDelete:
 		return &disgord.MessageDelete{
 			MessageID: t.MessageID,
@@ -0,0 +1,30 @@
+// +build !integration
+
+package std
+
+import (
+	"testing"
+
+	"github.com/andersfylling/disgord"
+)
+
+func TestCopyMsgEvt(t *testing.T) {
+	content := "hello"
+	evt := &disgord.MessageUpdate{
+		Message: &disgord.Message{ID: 1, Content: content},
+		Partial: &disgord.PartialMessage{ID: 1, Content: &content},
+	}
+
+	cpy, ok := CopyMsgEvt(evt).(*disgord.MessageUpdate)
+	if !ok {
+		t.Fatal("expected a message update")
+	}
+	if cpy.Partial == nil || cpy.Partial.Content == nil || *cpy.Partial.Content != content {
+		t.Fatal("the partial message was not copied")
+	}
+
+	*cpy.Partial.Content = "changed"
+	if content != "hello" {
+		t.Error("the copy shares the partial message content with the original")
+	}
+}
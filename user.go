@@ -1,11 +1,13 @@
 package disgord
 
 import (
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/andersfylling/disgord/internal/endpoint"
 	"github.com/andersfylling/disgord/internal/httd"
@@ -600,6 +602,73 @@ func (c userQueryBuilder) CreateDM(flags ...Flag) (ret *Channel, err error) {
 	return getChannel(r.Execute)
 }
 
+// dmChannelCacheSize is how many DM channels are kept, see dmChannels
+const dmChannelCacheSize = 1000
+
+type dmChannel struct {
+	recipientID Snowflake
+	channel     *Channel
+}
+
+// dmChannels keeps track of the DM channel of recent recipients, such that DM channels are only created once.
+// The least recently used channels are evicted once size is exceeded, and are created again when needed.
+type dmChannels struct {
+	sync.Mutex
+	size     int // dmChannelCacheSize when zero
+	channels map[Snowflake]*list.Element
+	order    *list.List // front is the most recently used
+}
+
+func (d *dmChannels) get(recipientID Snowflake) (channel *Channel, ok bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	elem, ok := d.channels[recipientID]
+	if !ok {
+		return nil, false
+	}
+	d.order.MoveToFront(elem)
+	return elem.Value.(*dmChannel).channel.DeepCopy().(*Channel), true
+}
+
+func (d *dmChannels) set(recipientID Snowflake, channel *Channel) {
+	d.Lock()
+	defer d.Unlock()
+
+	entry := &dmChannel{recipientID: recipientID, channel: channel.DeepCopy().(*Channel)}
+	if elem, exists := d.channels[recipientID]; exists {
+		elem.Value = entry
+		d.order.MoveToFront(elem)
+		return
+	}
+
+	if d.channels == nil {
+		d.channels = make(map[Snowflake]*list.Element)
+		d.order = list.New()
+	}
+	size := d.size
+	if size == 0 {
+		size = dmChannelCacheSize
+	}
+
+	d.channels[recipientID] = d.order.PushFront(entry)
+	for d.order.Len() > size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.channels, oldest.Value.(*dmChannel).recipientID)
+	}
+}
+
+func (d *dmChannels) delete(recipientID Snowflake) {
+	d.Lock()
+	defer d.Unlock()
+
+	if elem, exists := d.channels[recipientID]; exists {
+		d.order.Remove(elem)
+		delete(d.channels, recipientID)
+	}
+}
+
 type CurrentUserQueryBuilder interface {
 	WithContext(ctx context.Context) CurrentUserQueryBuilder
 
@@ -81,3 +81,31 @@ func TestGetCurrentUserGuildsParams(t *testing.T) {
 	params.SetDefaultLimit()
 	verifyQueryString(t, params.r.urlParams, wants)
 }
+
+func TestDMChannels(t *testing.T) {
+	d := &dmChannels{size: 2}
+	d.set(1, &Channel{ID: 11})
+	d.set(2, &Channel{ID: 12})
+	if channel, ok := d.get(1); !ok || channel.ID != 11 {
+		t.Fatal("expected the DM channel of recipient 1")
+	}
+
+	// recipient 2 is the least recently used
+	d.set(3, &Channel{ID: 13})
+	if _, ok := d.get(2); ok {
+		t.Error("expected the least recently used DM channel to be evicted")
+	}
+	for _, id := range []Snowflake{1, 3} {
+		if _, ok := d.get(id); !ok {
+			t.Errorf("expected the DM channel of recipient %d to be kept", id)
+		}
+	}
+	if len(d.channels) != 2 || d.order.Len() != 2 {
+		t.Errorf("expected 2 DM channels, got %d", len(d.channels))
+	}
+
+	d.delete(1)
+	if _, ok := d.get(1); ok || d.order.Len() != 1 {
+		t.Error("expected the DM channel of recipient 1 to be removed")
+	}
+}
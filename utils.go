@@ -2,8 +2,10 @@ package disgord
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"reflect"
 	"strings"
 	"syscall"
 
@@ -76,6 +78,41 @@ func ValidateHandlerInputs(inputs ...interface{}) (err error) {
 	return nil
 }
 
+// validateEventHandlers makes sure that the handlers accept the event type, such that a mismatch is
+// reported on registration instead of panicking once the event is dispatched. Handlers of unknown events are
+// not checked.
+func validateEventHandlers(evt string, inputs ...interface{}) error {
+	resource := defineResource(evt)
+	if resource == nil {
+		return nil
+	}
+	evtType := reflect.TypeOf(resource)
+
+	for _, input := range inputs {
+		switch input.(type) {
+		case Middleware, HandlerCtrl, SimpleHandler, SimplestHandler, chan interface{}, chan<- interface{}:
+			continue
+		}
+
+		var accepts reflect.Type
+		switch t := reflect.TypeOf(input); t.Kind() {
+		case reflect.Func:
+			if t.NumIn() == 2 {
+				accepts = t.In(1)
+			}
+		case reflect.Chan:
+			accepts = t.Elem()
+		}
+		if accepts != evtType {
+			return disgorderr.NewHandlerSpecErr(
+				disgorderr.HandlerSpecErrCodeEventMismatch,
+				fmt.Sprintf("handler of type %T does not accept %s events, which are of type %s", input, evt, evtType))
+		}
+	}
+
+	return nil
+}
+
 // https://discord.com/developers/docs/resources/user#avatar-data
 func validAvatarPrefix(avatar string) (valid bool) {
 	if avatar == "" {
@@ -197,3 +197,39 @@ func TestValidateHandlerInputs(t *testing.T) {
 		})
 	})
 }
+
+func TestValidateEventHandlers(t *testing.T) {
+	var e *disgorderr.HandlerSpecErr
+
+	valid := []interface{}{
+		func(s Session, evt *MessageCreate) {},
+		MessageCreateHandler(func(s Session, evt *MessageCreate) {}),
+		make(chan *MessageCreate),
+		func(s Session) {},
+		func() {},
+		make(chan interface{}),
+		Middleware(func(evt interface{}) interface{} { return evt }),
+		&Ctrl{},
+	}
+	for _, handler := range valid {
+		if err := validateEventHandlers(EvtMessageCreate, handler); err != nil {
+			t.Errorf("expected %T to handle message creates, got %s", handler, err)
+		}
+	}
+
+	invalid := []interface{}{
+		func(s Session, evt *GuildCreate) {},
+		make(chan *Ready),
+	}
+	for _, handler := range invalid {
+		err := validateEventHandlers(EvtMessageCreate, handler)
+		if !errors.As(err, &e) || e.Code() != disgorderr.HandlerSpecErrCodeEventMismatch {
+			t.Errorf("expected %T to be rejected for message creates, got %v", handler, err)
+		}
+	}
+
+	// unknown events are only warned about
+	if err := validateEventHandlers("MESSAGE_CREATED", func(s Session, evt *GuildCreate) {}); err != nil {
+		t.Errorf("expected handlers of unknown events to not be checked, got %s", err)
+	}
+}
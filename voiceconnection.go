@@ -24,6 +24,7 @@ type voiceRepository struct {
 
 	pendingStates  map[Snowflake]chan *VoiceStateUpdate
 	pendingServers map[Snowflake]chan *VoiceServerUpdate
+	active         map[Snowflake]*voiceImpl
 }
 
 // VoiceConnection is the interface used to interact with active voice connections.
@@ -42,6 +43,10 @@ type VoiceConnection interface {
 	// SendDCA reads from a Reader expecting a DCA encoded stream/file and sends them as frames.
 	SendDCA(r io.Reader) error
 
+	// Write sends p as a single frame of opus data, see SendOpusFrame, such that an opus encoder can write
+	// directly to the voice connection.
+	Write(p []byte) (n int, err error)
+
 	// MoveTo moves from the current voice channel to the given.
 	MoveTo(channelID Snowflake) error
 
@@ -57,24 +62,76 @@ type voiceImpl struct {
 
 	ready atomic.Bool
 
+	socket *voiceSocket
+
+	send  chan []byte
+	close chan struct{}
+
+	guildID   Snowflake
+	channelID Snowflake
+	sessionID string
+	selfMute  bool
+	c         *Client
+}
+
+var _ VoiceConnection = (*voiceImpl)(nil)
+
+// voiceSocket is the websocket and UDP connection to a voice server. It is replaced when Discord moves the
+// voice connection to another voice server.
+type voiceSocket struct {
 	ws  *gateway.VoiceClient
 	udp net.Conn
 
 	ssrc      uint32
 	secretKey [32]byte
-	send      chan []byte
-	close     chan struct{}
+}
+
+func (s *voiceSocket) close() error {
+	var err1, err2 error
+	if s.udp != nil {
+		err1 = s.udp.Close()
+	}
+	// if discord have already closed the connection
+	// there is no need to disconnect again
+	if s.ws != nil && !s.ws.IsDisconnected() {
+		err2 = s.ws.Disconnect()
+	}
+
+	if err1 != nil || err2 != nil {
+		var errMsg string
+		if err1 != nil {
+			errMsg += err1.Error()
+		}
+		if err2 != nil {
+			errMsg += err2.Error()
+		}
 
-	guildID Snowflake
-	c       *Client
+		return errors.New(errMsg)
+	}
+
+	return nil
 }
 
+const (
+	// voiceCloseSessionInvalid is sent when the voice session is no longer valid, and a new one must be requested
+	voiceCloseSessionInvalid = 4006
+
+	// voiceCloseDisconnected is sent when the bot was disconnected from the channel, but also when the voice
+	// server changed, in which case a voice server update follows
+	voiceCloseDisconnected = 4014
+
+	// voiceServerUpdateTimeout is how long a voice connection closed by voiceCloseDisconnected waits for a
+	// voice server update, before it is considered disconnected
+	voiceServerUpdateTimeout = 5 * time.Second
+)
+
 func newVoiceRepository(c *Client) (voice *voiceRepository) {
 	voice = &voiceRepository{
 		c: c,
 
 		pendingStates:  make(map[Snowflake]chan *VoiceStateUpdate),
 		pendingServers: make(map[Snowflake]chan *VoiceServerUpdate),
+		active:         make(map[Snowflake]*voiceImpl),
 	}
 	c.On(EvtVoiceServerUpdate, voice.onVoiceServerUpdate)
 	c.On(EvtVoiceStateUpdate, voice.onVoiceStateUpdate)
@@ -92,6 +149,42 @@ func (r *voiceRepository) VoiceConnectOptions(guildID, channelID Snowflake, self
 		return
 	}
 
+	var (
+		state  *VoiceStateUpdate
+		server *VoiceServerUpdate
+	)
+	if state, server, err = r.join(guildID, channelID, selfMute); err != nil {
+		return
+	}
+
+	voice := &voiceImpl{
+		guildID:   guildID,
+		channelID: channelID,
+		sessionID: state.SessionID,
+		selfMute:  selfMute,
+		c:         r.c,
+		send:      make(chan []byte),
+		close:     make(chan struct{}),
+	}
+	if voice.socket, err = r.dial(voice, state.SessionID, server); err != nil {
+		close(voice.close)
+		return
+	}
+	voice.ready.Store(true)
+
+	r.Lock()
+	r.active[guildID] = voice
+	r.Unlock()
+
+	go voice.opusSendLoop()
+	go voice.watcherDiscordCloseEvt()
+
+	ret = voice
+	return
+}
+
+// join asks Discord to join the channel, and waits for the voice session and voice server
+func (r *voiceRepository) join(guildID, channelID Snowflake, selfMute bool) (state *VoiceStateUpdate, server *VoiceServerUpdate, err error) {
 	// Set up some listeners for this connection attempt
 	stateCh := make(chan *VoiceStateUpdate, 1)
 	serverCh := make(chan *VoiceServerUpdate, 1)
@@ -118,11 +211,6 @@ func (r *voiceRepository) VoiceConnectOptions(guildID, channelID Snowflake, self
 		return
 	}
 
-	var (
-		state  *VoiceStateUpdate
-		server *VoiceServerUpdate
-	)
-
 	// Wait for the VoiceStateUpdate and VoiceServerUpdate, or else time out
 	timeout := time.After(10 * time.Second)
 waiter:
@@ -141,53 +229,49 @@ waiter:
 			return
 		}
 	}
+	return state, server, nil
+}
 
-	voice := voiceImpl{
-		guildID: guildID,
-		c:       r.c,
-		send:    make(chan []byte),
-		close:   make(chan struct{}),
-	}
+// dial connects to the voice server, and sets up the UDP connection for sending voice data
+func (r *voiceRepository) dial(voice *voiceImpl, sessionID string, server *VoiceServerUpdate) (socket *voiceSocket, err error) {
+	socket = &voiceSocket{}
 	// Defer a cleanup just in case
-	defer func(v *voiceImpl) {
-		if !v.ready.Load() {
-			if v.ws != nil {
-				_ = v.ws.Disconnect()
-			}
-			if v.udp != nil {
-				_ = v.udp.Close()
-			}
-			close(v.close)
+	defer func() {
+		if err != nil {
+			_ = socket.close()
 		}
-	}(&voice)
+	}()
 
 	// Connect to the websocket
-	voice.ws, err = gateway.NewVoiceClient(&gateway.VoiceConfig{
+	socket.ws, err = gateway.NewVoiceClient(&gateway.VoiceConfig{
 		GuildID:        server.GuildID,
 		UserID:         r.c.myID,
-		SessionID:      state.SessionID,
+		SessionID:      sessionID,
 		Token:          server.Token,
 		HTTPClient:     r.c.config.HTTPClient,
 		Endpoint:       "wss://" + strings.TrimSuffix(server.Endpoint, ":80") + "/?v=4",
 		Logger:         r.c.log,
 		SystemShutdown: r.c.shutdownChan,
+		CloseListener: func(code int, _ string) {
+			voice.onSocketClosed(socket, code)
+		},
 	})
 	if err != nil {
 		return
 	}
 
 	var ready *gateway.VoiceReady
-	if ready, err = voice.ws.Connect(); err != nil {
+	if ready, err = socket.ws.Connect(); err != nil {
 		return
 	}
-	voice.ssrc = ready.SSRC
+	socket.ssrc = ready.SSRC
 
 	// Connect to UDP
 	dialer := net.Dial
 	if r.c.config.Proxy != nil {
 		dialer = r.c.config.Proxy.Dial
 	}
-	voice.udp, err = dialer("udp", ready.IP+":"+strconv.Itoa(ready.Port))
+	socket.udp, err = dialer("udp", ready.IP+":"+strconv.Itoa(ready.Port))
 	if err != nil {
 		return
 	}
@@ -195,14 +279,14 @@ waiter:
 	// SendOpusFrame our SSRC with no further data for the IP discovery process.
 	ssrcBuffer := make([]byte, 70)
 	binary.BigEndian.PutUint32(ssrcBuffer, ready.SSRC)
-	_, err = voice.udp.Write(ssrcBuffer)
+	_, err = socket.udp.Write(ssrcBuffer)
 	if err != nil {
 		return
 	}
 
 	ipBuffer := make([]byte, 70)
 	var n int
-	n, err = voice.udp.Read(ipBuffer)
+	n, err = socket.udp.Read(ipBuffer)
 	if err != nil {
 		return
 	}
@@ -224,7 +308,7 @@ waiter:
 	// libSodium/NaCl and golang.org/x/crypto/nacl/secretbox use. If both Discord and Go both start supporting more
 	// modes "out of the box" we might want to consider implementing a "preferred mode selection" algorithm here.
 	var session *gateway.VoiceSessionDescription
-	session, err = voice.ws.SendUDPInfo(&gateway.VoiceSelectProtocolParams{
+	session, err = socket.ws.SendUDPInfo(&gateway.VoiceSelectProtocolParams{
 		Mode:    "xsalsa20_poly1305",
 		Address: ip,
 		Port:    port,
@@ -237,14 +321,8 @@ waiter:
 		return
 	}
 
-	voice.secretKey = session.SecretKey
-	voice.ready.Store(true)
-
-	go voice.opusSendLoop()
-	go voice.watcherDiscordCloseEvt()
-
-	ret = &voice
-	return
+	socket.secretKey = session.SecretKey
+	return socket, nil
 }
 
 func (r *voiceRepository) onVoiceStateUpdate(_ Session, event *VoiceStateUpdate) {
@@ -259,6 +337,15 @@ func (r *voiceRepository) onVoiceStateUpdate(_ Session, event *VoiceStateUpdate)
 		r.Unlock()
 
 		ch <- event
+	} else if voice, exists := r.active[event.VoiceState.GuildID]; exists {
+		r.Unlock()
+
+		voice.Lock()
+		voice.sessionID = event.SessionID
+		if !event.ChannelID.IsZero() {
+			voice.channelID = event.ChannelID
+		}
+		voice.Unlock()
 	} else {
 		r.Unlock()
 	}
@@ -268,15 +355,30 @@ func (r *voiceRepository) onVoiceServerUpdate(_ Session, event *VoiceServerUpdat
 	r.Lock()
 
 	if ch, exists := r.pendingServers[event.GuildID]; exists {
-		delete(r.pendingStates, event.GuildID)
+		delete(r.pendingServers, event.GuildID)
 		r.Unlock()
 
 		ch <- event
+	} else if voice, exists := r.active[event.GuildID]; exists {
+		r.Unlock()
+
+		// the voice region changed, or the voice server failed
+		go voice.moveServer(event)
 	} else {
 		r.Unlock()
 	}
 }
 
+// remove forgets the voice connection, unless it was replaced by a newer one for the same guild
+func (r *voiceRepository) remove(voice *voiceImpl) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.active[voice.guildID] == voice {
+		delete(r.active, voice.guildID)
+	}
+}
+
 func (v *voiceImpl) StartSpeaking() error {
 	return v.speakingImpl(true)
 }
@@ -293,9 +395,9 @@ func (v *voiceImpl) speakingImpl(b bool) error {
 		return errors.New("attempting to interact with a closed voice connection")
 	}
 
-	return v.ws.Emit(cmd.VoiceSpeaking, &voiceSpeakingData{
+	return v.socket.ws.Emit(cmd.VoiceSpeaking, &voiceSpeakingData{
 		Speaking: b,
-		SSRC:     v.ssrc,
+		SSRC:     v.socket.ssrc,
 	})
 }
 
@@ -307,6 +409,16 @@ func (v *voiceImpl) SendOpusFrame(data []byte) error {
 	return nil
 }
 
+func (v *voiceImpl) Write(p []byte) (n int, err error) {
+	// the frame is sent after Write returns, and p may be reused by then
+	frame := make([]byte, len(p))
+	copy(frame, p)
+	if err = v.SendOpusFrame(frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 func (v *voiceImpl) SendDCA(r io.Reader) error {
 	if !v.ready.Load() {
 		return errors.New("attempting to send to a closed voice connection")
@@ -348,17 +460,94 @@ func (v *voiceImpl) MoveTo(channelID Snowflake) error {
 		SelfDeaf:  true, //false,
 		SelfMute:  false,
 	})
+	v.channelID = channelID
 
 	return nil
 }
 
+// moveServer connects to the voice server Discord moved the voice connection to, without affecting the event
+// gateway. Voice data sent in the meantime is delayed until the new voice server is ready.
+func (v *voiceImpl) moveServer(server *VoiceServerUpdate) {
+	v.Lock()
+	defer v.Unlock()
+
+	if !v.ready.Load() {
+		return
+	}
+
+	_ = v.socket.close()
+	socket, err := v.c.voiceRepository.dial(v, v.sessionID, server)
+	if err != nil {
+		v.c.Logger().Error("unable to connect to the new voice server:", err)
+		_ = v.teardown()
+		return
+	}
+	v.socket = socket
+	v.c.Logger().Info("voice connection moved to", server.Endpoint)
+}
+
+// rejoin requests a new voice session after Discord invalidated the previous one
+func (v *voiceImpl) rejoin(socket *voiceSocket) {
+	v.Lock()
+	channelID, selfMute := v.channelID, v.selfMute
+	v.Unlock()
+
+	state, server, err := v.c.voiceRepository.join(v.guildID, channelID, selfMute)
+
+	v.Lock()
+	defer v.Unlock()
+	if !v.ready.Load() || v.socket != socket {
+		return
+	}
+	if err != nil {
+		v.c.Logger().Error("unable to request a new voice session:", err)
+		_ = v.teardown()
+		return
+	}
+
+	v.sessionID = state.SessionID
+	_ = v.socket.close()
+	if v.socket, err = v.c.voiceRepository.dial(v, state.SessionID, server); err != nil {
+		v.c.Logger().Error("unable to connect to the voice server:", err)
+		_ = v.teardown()
+		return
+	}
+	v.c.Logger().Info("voice session was renewed")
+}
+
+// onSocketClosed handles Discord closing the websocket of a voice server
+func (v *voiceImpl) onSocketClosed(socket *voiceSocket, code int) {
+	switch code {
+	case voiceCloseSessionInvalid:
+		v.rejoin(socket)
+	case voiceCloseDisconnected:
+		select {
+		case <-time.After(voiceServerUpdateTimeout):
+		case <-v.close:
+			return
+		}
+
+		v.Lock()
+		defer v.Unlock()
+		// the connection was not moved to another voice server, so the bot was disconnected from the channel
+		if v.ready.Load() && v.socket == socket {
+			_ = v.teardown()
+			v.c.Logger().Info("Discord closed voice connection")
+		}
+	}
+}
+
 func (v *voiceImpl) watcherDiscordCloseEvt() {
+	v.Lock()
+	active := v.socket.ws.Active()
+	v.Unlock()
+
 	for {
 		var open bool
 		select {
 		case <-v.close:
 			return
-		case _, open = <-v.ws.Active():
+		case _, open = <-active:
 		}
 		if !open {
 			break
@@ -371,7 +560,17 @@ func (v *voiceImpl) watcherDiscordCloseEvt() {
 	if !v.ready.Load() {
 		return
 	}
+	_ = v.teardown()
+
+	//for range v.ws.Receive() {} // drain
+
+	v.c.Logger().Info("Discord closed voice connection")
+}
+
+// teardown stops sending voice data and closes the voice socket. The voice connection must be locked.
+func (v *voiceImpl) teardown() error {
 	v.ready.Store(false)
+	v.c.voiceRepository.remove(v)
 
 	close(v.close)
 	// clear send channel
@@ -379,14 +578,12 @@ func (v *voiceImpl) watcherDiscordCloseEvt() {
 	case <-v.send:
 	default:
 	}
-
-	_ = v.udp.Close()
-	_ = v.ws.Disconnect()
 	close(v.send)
 
-	//for range v.ws.Receive() {} // drain
-
-	v.c.Logger().Info("Discord closed voice connection")
+	if v.socket == nil {
+		return nil
+	}
+	return v.socket.close()
 }
 
 func (v *voiceImpl) Close() (err error) {
@@ -397,46 +594,19 @@ func (v *voiceImpl) Close() (err error) {
 		return errors.New("attempting to close a closed Voice Connection")
 	}
 
-	defer func() {
-		close(v.close)
-		// clear send channel
-		select {
-		case <-v.send:
-		default:
-		}
-		close(v.send)
-	}()
-
 	// if discord have already closed the connection
 	// there is no need to send out a bunch of events
-	if v.ws.IsDisconnected() {
-		return v.udp.Close()
+	if !v.socket.ws.IsDisconnected() {
+		// Tell Discord we want to disconnect from channel/guild
+		_, _ = v.c.Emit(UpdateVoiceState, &UpdateVoiceStatePayload{
+			GuildID:   v.guildID,
+			ChannelID: 0, // disconnect "code/value" (disgord implementation specific)
+			SelfDeaf:  true,
+			SelfMute:  true,
+		})
 	}
 
-	// Tell Discord we want to disconnect from channel/guild
-	_, _ = v.c.Emit(UpdateVoiceState, &UpdateVoiceStatePayload{
-		GuildID:   v.guildID,
-		ChannelID: 0, // disconnect "code/value" (disgord implementation specific)
-		SelfDeaf:  true,
-		SelfMute:  true,
-	})
-
-	err1 := v.udp.Close()
-	err2 := v.ws.Disconnect()
-
-	if err1 != nil || err2 != nil {
-		var errMsg string
-		if err1 != nil {
-			errMsg += err1.Error()
-		}
-		if err2 != nil {
-			errMsg += err2.Error()
-		}
-
-		return errors.New(errMsg)
-	}
-
-	return nil
+	return v.teardown()
 }
 
 type voiceSpeakingData struct {
@@ -450,7 +620,6 @@ func (v *voiceImpl) opusSendLoop() {
 	header := make([]byte, 12)
 	header[0] = 0x80
 	header[1] = 0x78
-	binary.BigEndian.PutUint32(header[8:12], v.ssrc)
 
 	var (
 		sequence  uint16
@@ -474,6 +643,12 @@ func (v *voiceImpl) opusSendLoop() {
 			return
 		}
 
+		// the socket changes when the voice connection moves to another voice server
+		v.Lock()
+		socket := v.socket
+		v.Unlock()
+		binary.BigEndian.PutUint32(header[8:12], socket.ssrc)
+
 		binary.BigEndian.PutUint16(header[2:4], sequence)
 		sequence++
 
@@ -482,14 +657,14 @@ func (v *voiceImpl) opusSendLoop() {
 
 		copy(nonce[:], header)
 
-		toSend := secretbox.Seal(header, msg, &nonce, &v.secretKey)
+		toSend := secretbox.Seal(header, msg, &nonce, &socket.secretKey)
 		select {
 		case <-frequency.C:
 		case <-v.close:
 			return
 		}
 
-		_, _ = v.udp.Write(toSend)
+		_, _ = socket.udp.Write(toSend)
 		// err on udp write? hahahahahah... hahah.. good joke.
 	}
 }
@@ -0,0 +1,94 @@
+// +build !integration
+
+package disgord
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+func TestVoiceImpl_Write(t *testing.T) {
+	v := &voiceImpl{send: make(chan []byte, 1)}
+	v.ready.Store(true)
+
+	p := []byte{1, 2, 3}
+	n, err := v.Write(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(p) {
+		t.Errorf("expected %d bytes written, got %d", len(p), n)
+	}
+	p[0] = 9 // the caller reuses the buffer
+
+	if frame := <-v.send; !bytes.Equal(frame, []byte{1, 2, 3}) {
+		t.Errorf("expected the frame to be copied, got %v", frame)
+	}
+
+	v.ready.Store(false)
+	if _, err = v.Write(p); err == nil {
+		t.Error("expected writing to a closed voice connection to fail")
+	}
+}
+
+func TestVoiceImpl_opusSendLoop(t *testing.T) {
+	newSocket := func(ssrc uint32, key byte) (*voiceSocket, net.Conn) {
+		local, remote := net.Pipe()
+		socket := &voiceSocket{udp: local, ssrc: ssrc}
+		socket.secretKey[0] = key
+		return socket, remote
+	}
+	receive := func(t *testing.T, socket *voiceSocket, remote net.Conn) {
+		t.Helper()
+		_ = remote.SetReadDeadline(time.Now().Add(time.Second))
+		packet := make([]byte, 1024)
+		n, err := remote.Read(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet = packet[:n]
+
+		if ssrc := binary.BigEndian.Uint32(packet[8:12]); ssrc != socket.ssrc {
+			t.Errorf("expected ssrc %d, got %d", socket.ssrc, ssrc)
+		}
+		var nonce [24]byte
+		copy(nonce[:], packet[:12])
+		msg, ok := secretbox.Open(nil, packet[12:], &nonce, &socket.secretKey)
+		if !ok {
+			t.Fatal("unable to decrypt the voice packet with the secret key of the socket")
+		}
+		if !bytes.Equal(msg, []byte("opus")) {
+			t.Errorf("expected the opus frame, got %q", msg)
+		}
+	}
+
+	first, firstRemote := newSocket(1, 1)
+	second, secondRemote := newSocket(2, 2)
+	defer firstRemote.Close()
+	defer secondRemote.Close()
+
+	v := &voiceImpl{
+		socket: first,
+		send:   make(chan []byte),
+		close:  make(chan struct{}),
+	}
+	v.ready.Store(true)
+	go v.opusSendLoop()
+	defer close(v.close)
+
+	_, _ = v.Write([]byte("opus"))
+	receive(t, first, firstRemote)
+
+	// moved to another voice server
+	v.Lock()
+	v.socket = second
+	v.Unlock()
+
+	_, _ = v.Write([]byte("opus"))
+	receive(t, second, secondRemote)
+}
@@ -3,6 +3,7 @@ package disgord
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/andersfylling/disgord/internal/endpoint"
@@ -21,6 +22,27 @@ type Webhook struct {
 	Token     string    `json:"token"`              //  |
 }
 
+var _ fmt.Stringer = (*Webhook)(nil)
+var _ fmt.GoStringer = (*Webhook)(nil)
+
+// String returns a description of the webhook without the token, such that it is safe to log.
+// The value receiver makes sure the token is hidden when formatting both Webhook and *Webhook.
+func (w Webhook) String() string {
+	return "webhook{name:" + w.Name + ", id:" + w.ID.String() + ", channelID:" + w.ChannelID.String() + "}"
+}
+
+// GoString is used by the %#v format verb, and hides the token as well. See String.
+func (w Webhook) GoString() string {
+	return w.String()
+}
+
+// SafeCopy returns a copy of the webhook without the token, which grants posting rights.
+func (w *Webhook) SafeCopy() *Webhook {
+	hook := w.DeepCopy().(*Webhook)
+	hook.Token = ""
+	return hook
+}
+
 // DeepCopy see interface at struct.go#DeepCopier
 func (w *Webhook) DeepCopy() (copy interface{}) {
 	copy = &Webhook{}
@@ -41,7 +63,9 @@ func (w *Webhook) CopyOverTo(other interface{}) (err error) {
 	hook.ID = w.ID
 	hook.GuildID = w.GuildID
 	hook.ChannelID = w.ChannelID
-	hook.User = w.User.DeepCopy().(*User)
+	if w.User != nil {
+		hook.User = w.User.DeepCopy().(*User)
+	}
 	hook.Name = w.Name
 	hook.Avatar = w.Avatar
 	hook.Token = w.Token
@@ -150,12 +174,26 @@ func (w webhookQueryBuilder) Delete(flags ...Flag) (err error) {
 
 // ExecuteWebhookParams JSON params for func ExecuteWebhook
 type ExecuteWebhookParams struct {
-	Content   string      `json:"content"`
-	Username  string      `json:"username"`
-	AvatarURL string      `json:"avatar_url"`
-	TTS       bool        `json:"tts"`
-	File      interface{} `json:"file"`
-	Embeds    []*Embed    `json:"embeds"`
+	Content   string   `json:"content,omitempty"`
+	Username  string   `json:"username,omitempty"`   // override the default username of the webhook
+	AvatarURL string   `json:"avatar_url,omitempty"` // override the default avatar of the webhook
+	TTS       bool     `json:"tts,omitempty"`
+	Embeds    []*Embed `json:"embeds,omitempty"`
+
+	Files []CreateMessageFileParams `json:"-"` // Always omit as this is included in multipart, not JSON payload
+
+	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"`
+
+	// Wait lets Discord return the created message. Used by Client.ExecuteWebhook.
+	Wait bool `json:"-"`
+}
+
+func (p *ExecuteWebhookParams) prepare() (postBody interface{}, contentType string, err error) {
+	if len(p.Files) == 0 {
+		return p, httd.ContentTypeJSON, nil
+	}
+
+	return multipartBody(p, p.Files)
 }
 
 type execWebhookParams struct {
@@ -208,10 +246,16 @@ type WebhookWithTokenQueryBuilder interface {
 	Delete(flags ...Flag) error
 
 	Execute(params *ExecuteWebhookParams, wait bool, URLSuffix string, flags ...Flag) (*Message, error)
+
+	// UpdateMessage Edits a previously-sent webhook message from the same token.
+	UpdateMessage(messageID Snowflake, flags ...Flag) UpdateWebhookMessageBuilder
+
+	// DeleteMessage Deletes a message that was created by the webhook. Returns a 204 NO CONTENT response on success.
+	DeleteMessage(messageID Snowflake, flags ...Flag) error
 }
 
 func (w webhookQueryBuilder) WithToken(token string) WebhookWithTokenQueryBuilder {
-	return &webhookWithTokenQueryBuilder{client: w.client, webhookID: w.webhookID, token: token}
+	return &webhookWithTokenQueryBuilder{ctx: w.ctx, client: w.client, webhookID: w.webhookID, token: token}
 }
 
 type webhookWithTokenQueryBuilder struct {
@@ -321,11 +365,12 @@ func (w webhookWithTokenQueryBuilder) Execute(params *ExecuteWebhookParams, wait
 		return nil, errors.New("webhook token is required")
 	}
 
-	var contentType string
-	if params.File == nil {
-		contentType = httd.ContentTypeJSON
-	} else {
-		contentType = "multipart/form-data"
+	var (
+		postBody    interface{}
+		contentType string
+	)
+	if postBody, contentType, err = params.prepare(); err != nil {
+		return nil, err
 	}
 
 	urlparams := &execWebhookParams{wait}
@@ -333,7 +378,7 @@ func (w webhookWithTokenQueryBuilder) Execute(params *ExecuteWebhookParams, wait
 		Method:      httd.MethodPost,
 		Ctx:         w.ctx,
 		Endpoint:    endpoint.WebhookToken(w.webhookID, w.token) + URLSuffix + urlparams.URLQueryString(),
-		Body:        params,
+		Body:        postBody,
 		ContentType: contentType,
 	}, flags)
 	// Discord only returns the message when wait=true.
@@ -347,6 +392,91 @@ func (w webhookWithTokenQueryBuilder) Execute(params *ExecuteWebhookParams, wait
 	return nil, err
 }
 
+// UpdateMessage [REST] Edits a previously-sent webhook message from the same token.
+//  Method                  PATCH
+//  Endpoint                /webhooks/{webhook.id}/{webhook.token}/messages/{message.id}
+//  Discord documentation   https://discord.com/developers/docs/resources/webhook#edit-webhook-message
+//  Reviewed                2020-12-05
+//  Comment                 All parameters to this endpoint are optional and nullable. Does not require
+//                          authentication, the bot token is therefore not sent.
+func (w webhookWithTokenQueryBuilder) UpdateMessage(messageID Snowflake, flags ...Flag) UpdateWebhookMessageBuilder {
+	builder := &updateWebhookMessageBuilder{}
+	builder.r.itemFactory = func() interface{} {
+		return &Message{}
+	}
+	builder.r.flags = flags
+	builder.r.addPrereq(w.webhookID.IsZero(), "given webhook ID was not set")
+	builder.r.addPrereq(w.token == "", "given webhook token was not set")
+	builder.r.addPrereq(messageID.IsZero(), "given message ID was not set, there is nothing to modify")
+	builder.r.setup(w.client.req, &httd.Request{
+		Method:            httd.MethodPatch,
+		Ctx:               w.ctx,
+		Endpoint:          endpoint.WebhookMessage(w.webhookID, w.token, messageID),
+		ContentType:       httd.ContentTypeJSON,
+		SkipAuthorization: true,
+	}, nil)
+
+	return builder
+}
+
+// DeleteMessage [REST] Deletes a message that was created by the webhook. Returns a 204 NO CONTENT response on success.
+//  Method                  DELETE
+//  Endpoint                /webhooks/{webhook.id}/{webhook.token}/messages/{message.id}
+//  Discord documentation   https://discord.com/developers/docs/resources/webhook#delete-webhook-message
+//  Reviewed                2020-12-05
+//  Comment                 Does not require authentication, the bot token is therefore not sent.
+func (w webhookWithTokenQueryBuilder) DeleteMessage(messageID Snowflake, flags ...Flag) error {
+	if w.webhookID.IsZero() {
+		return errors.New("webhook id is required")
+	}
+	if w.token == "" {
+		return errors.New("webhook token is required")
+	}
+	if messageID.IsZero() {
+		return errors.New("message id is required")
+	}
+
+	r := w.client.newRESTRequest(&httd.Request{
+		Method:            httd.MethodDelete,
+		Endpoint:          endpoint.WebhookMessage(w.webhookID, w.token, messageID),
+		Ctx:               w.ctx,
+		SkipAuthorization: true,
+	}, flags)
+	r.expectsStatusCode = http.StatusNoContent
+
+	_, err := r.Execute()
+	return err
+}
+
+//////////////////////////////////////////////////////
+//
+// REST Wrappers
+//
+//////////////////////////////////////////////////////
+
+// ExecuteWebhook Trigger a webhook in Discord, using the webhook token for authentication. Files are uploaded
+// as multipart/form-data, just like when creating a channel message. The created message is only returned
+// when params.Wait is true, otherwise the returned message is nil.
+func (c *Client) ExecuteWebhook(webhookID Snowflake, token string, params *ExecuteWebhookParams, flags ...Flag) (*Message, error) {
+	if params == nil {
+		return nil, errors.New("params can not be nil")
+	}
+
+	return c.Webhook(webhookID).WithToken(token).Execute(params, params.Wait, "", flags...)
+}
+
+// UpdateWebhookMessage Edits a message previously sent by the webhook. Only the webhook token is used for
+// authentication.
+func (c *Client) UpdateWebhookMessage(webhookID Snowflake, token string, messageID Snowflake, flags ...Flag) UpdateWebhookMessageBuilder {
+	return c.Webhook(webhookID).WithToken(token).UpdateMessage(messageID, flags...)
+}
+
+// DeleteWebhookMessage Deletes a message previously sent by the webhook. Only the webhook token is used for
+// authentication.
+func (c *Client) DeleteWebhookMessage(webhookID Snowflake, token string, messageID Snowflake, flags ...Flag) error {
+	return c.Webhook(webhookID).WithToken(token).DeleteMessage(messageID, flags...)
+}
+
 //////////////////////////////////////////////////////
 //
 // REST Builders
@@ -368,3 +498,11 @@ func (u *updateWebhookBuilder) SetDefaultAvatar() *updateWebhookBuilder {
 	u.r.param("avatar", nil)
 	return u
 }
+
+// updateWebhookMessageBuilder, params here
+//  https://discord.com/developers/docs/resources/webhook#edit-webhook-message-jsonform-params
+//generate-rest-params: content:string, embeds:[]*Embed, allowed_mentions:*AllowedMentions,
+//generate-rest-basic-execute: message:*Message,
+type updateWebhookMessageBuilder struct {
+	r RESTBuilder
+}
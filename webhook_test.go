@@ -1,3 +1,89 @@
 // +build !integration
 
 package disgord
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/disgord/internal/httd"
+)
+
+func TestExecuteWebhookParams_prepare(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		params := &ExecuteWebhookParams{Content: "hello"}
+		body, contentType, err := params.prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != httd.ContentTypeJSON {
+			t.Errorf("expected content type %s, got %s", httd.ContentTypeJSON, contentType)
+		}
+		if body != params {
+			t.Error("expected the params to be used as the request body")
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		params := &ExecuteWebhookParams{
+			Content:         "hello",
+			AllowedMentions: &AllowedMentions{Parse: []string{}},
+			Files: []CreateMessageFileParams{
+				{Reader: strings.NewReader("file content"), FileName: "a.txt"},
+			},
+		}
+		body, contentType, err := params.prepare()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mediaType, mediaParams, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mediaType != "multipart/form-data" {
+			t.Fatalf("expected multipart/form-data, got %s", mediaType)
+		}
+
+		form, err := multipart.NewReader(body.(*bytes.Buffer), mediaParams["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload := form.Value["payload_json"]
+		if len(payload) != 1 || !strings.Contains(payload[0], `"allowed_mentions":{"parse":[]}`) {
+			t.Errorf("unexpected payload_json: %v", payload)
+		}
+		if files := form.File["file0"]; len(files) != 1 || files[0].Filename != "a.txt" {
+			t.Errorf("expected file0 to be a.txt, got %+v", files)
+		}
+	})
+}
+
+func TestWebhook_String(t *testing.T) {
+	const token = "aV3ryS3cr3tT0k3n"
+	hook := &Webhook{ID: 1, ChannelID: 2, Name: "hook", Token: token, User: &User{Username: "anders"}}
+
+	for _, format := range []string{"%s", "%v", "%+v", "%#v"} {
+		if s := fmt.Sprintf(format, hook); strings.Contains(s, token) {
+			t.Errorf("the token was part of the %s formatted webhook: %s", format, s)
+		}
+		if s := fmt.Sprintf(format, *hook); strings.Contains(s, token) {
+			t.Errorf("the token was part of the %s formatted webhook value: %s", format, s)
+		}
+	}
+
+	safe := hook.SafeCopy()
+	if safe.Token != "" {
+		t.Error("expected the token to be removed from the copy")
+	}
+	if hook.Token != token {
+		t.Error("the original webhook should keep its token")
+	}
+	if safe.ID != hook.ID || safe.Name != hook.Name || safe.User == hook.User {
+		t.Errorf("expected a deep copy, got %+v", safe)
+	}
+}